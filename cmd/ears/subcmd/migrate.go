@@ -0,0 +1,152 @@
+// Copyright 2020 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subcmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xmidt-org/ears/internal/pkg/config"
+	"github.com/xmidt-org/ears/internal/pkg/fx/routestorerfx"
+	"github.com/xmidt-org/ears/internal/pkg/fx/tenantstorerfx"
+	"github.com/xmidt-org/ears/internal/pkg/migrate"
+	"github.com/xmidt-org/ears/pkg/cli"
+	"io"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrates routes and tenant configs to another storage layer",
+	Long: `Copies all routes and tenant configs from the storage layer configured in the
+ears config file to the storage layer configured in the config file given with --to.
+Written items are read back and verified afterwards.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		logger := log.Logger.Level(zerolog.WarnLevel)
+
+		dstPath := viper.GetString("ears.migrate.to")
+		if dstPath == "" {
+			return errors.New("missing destination config file, use --to")
+		}
+		dstConfig := viper.New()
+		dstConfig.SetConfigFile(dstPath)
+		err := dstConfig.ReadInConfig()
+		if err != nil {
+			return err
+		}
+		opts := migrate.Options{
+			DryRun:   viper.GetBool("ears.migrate.dryRun"),
+			Conflict: migrate.ConflictPolicy(viper.GetString("ears.migrate.conflict")),
+		}
+		for _, s := range viper.GetStringSlice("ears.migrate.tenants") {
+			tid, err := migrate.ParseTenant(s)
+			if err != nil {
+				return err
+			}
+			opts.Tenants = append(opts.Tenants, tid)
+		}
+
+		srcTenants, srcRoutes, err := migrateStorage(viper.GetViper(), &logger)
+		if err != nil {
+			return err
+		}
+		defer closeStorage(srcTenants, srcRoutes)
+		dstTenants, dstRoutes, err := migrateStorage(dstConfig, &logger)
+		if err != nil {
+			return err
+		}
+		defer closeStorage(dstTenants, dstRoutes)
+
+		m := migrate.NewMigrator(srcTenants.TenantStorer, srcRoutes.RouteStorer, dstTenants.TenantStorer, dstRoutes.RouteStorer, &logger)
+		report, migrateErr := m.Migrate(context.Background(), opts)
+		if report != nil {
+			out, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), string(out))
+		}
+		if migrateErr != nil {
+			return migrateErr
+		}
+		if !opts.DryRun && !report.Verified {
+			return errors.New("verification failed")
+		}
+		return nil
+	},
+}
+
+// migrateStorage builds the tenant and route storers the same way the run command does.
+// In memory storage is process local and therefore not useful on either side of a migration.
+func migrateStorage(config config.Config, logger *zerolog.Logger) (tenantstorerfx.StorageOut, routestorerfx.StorageOut, error) {
+	for _, key := range []string{"ears.storage.tenant.type", "ears.storage.route.type"} {
+		storageType := config.GetString(key)
+		if storageType == "" || storageType == "inmemory" {
+			return tenantstorerfx.StorageOut{}, routestorerfx.StorageOut{}, fmt.Errorf("%s must be a persistent storage type, got %q", key, storageType)
+		}
+	}
+	tenants, err := tenantstorerfx.ProvideTenantStorer(tenantstorerfx.StorageIn{Config: config, Logger: logger})
+	if err != nil {
+		return tenants, routestorerfx.StorageOut{}, err
+	}
+	routes, err := routestorerfx.ProvideRouteStorer(routestorerfx.StorageIn{Config: config, Logger: logger})
+	if err != nil {
+		closeStorage(tenants, routestorerfx.StorageOut{})
+		return tenants, routes, err
+	}
+	return tenants, routes, nil
+}
+
+func closeStorage(tenants tenantstorerfx.StorageOut, routes routestorerfx.StorageOut) {
+	for _, s := range []interface{}{tenants.TenantStorer, routes.RouteStorer} {
+		if c, ok := s.(io.Closer); ok {
+			c.Close()
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+	cli.ViperAddArguments(
+		migrateCmd,
+		[]cli.Argument{
+			cli.Argument{
+				Name: "to", Shorthand: "", Type: cli.ArgTypeString,
+				Default: "", LookupKey: "ears.migrate.to",
+				Description: "config file with the ears.storage section of the destination storage layer",
+			},
+			cli.Argument{
+				Name: "dryRun", Shorthand: "", Type: cli.ArgTypeBool,
+				Default: false, LookupKey: "ears.migrate.dryRun",
+				Description: "only report what would be migrated",
+			},
+			cli.Argument{
+				Name: "tenants", Shorthand: "", Type: cli.ArgTypeStringSlice,
+				Default: []string{}, LookupKey: "ears.migrate.tenants",
+				Description: "tenants to migrate as orgId/appId, all tenants if empty",
+			},
+			cli.Argument{
+				Name: "conflict", Shorthand: "", Type: cli.ArgTypeString,
+				Default: string(migrate.ConflictFail), LookupKey: "ears.migrate.conflict",
+				Description: "what to do with items which exist in the destination with a different configuration (skip, overwrite, fail)",
+			},
+		},
+	)
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/spf13/viper"
 	"github.com/xmidt-org/ears/internal/pkg/app"
 	"github.com/xmidt-org/ears/internal/pkg/appsecret"
+	"github.com/xmidt-org/ears/internal/pkg/cluster"
 	"github.com/xmidt-org/ears/internal/pkg/config"
 	"github.com/xmidt-org/ears/internal/pkg/fx/pluginmanagerfx"
 	"github.com/xmidt-org/ears/internal/pkg/fx/quotamanagerfx"
@@ -68,6 +69,7 @@ var runCmd = &cobra.Command{
 				appsecret.NewConfigVault,
 				app.ProvideLogger,
 				tablemgr.NewRoutingTableManager,
+				cluster.NewReporter,
 				app.NewAPIManager,
 				app.NewMiddleware,
 				app.NewMux,
@@ -76,6 +78,7 @@ var runCmd = &cobra.Command{
 			fx.Invoke(syncerfx.SetupDeltaSyncer),
 			fx.Invoke(tablemgr.SetupRoutingManager),
 			fx.Invoke(quotamanagerfx.SetupQuotaManager),
+			fx.Invoke(cluster.SetupReporter),
 			fx.Invoke(app.SetupAPIServer),
 		)
 		earsApp.Run()
@@ -104,7 +107,7 @@ func init() {
 			cli.Argument{
 				Name: "routeStorageType", Shorthand: "", Type: cli.ArgTypeString,
 				Default: "inmemory", LookupKey: "ears.storage.route.type",
-				Description: "persistence layer storage type for routes (inmemory, dynamodb)",
+				Description: "persistence layer storage type for routes (inmemory, dynamodb, redis, file)",
 			},
 			cli.Argument{
 				Name: "routeStorageDynamoRegion", Shorthand: "", Type: cli.ArgTypeString,
@@ -116,10 +119,15 @@ func init() {
 				Default: "dev.ears.routes", LookupKey: "ears.storage.route.table",
 				Description: "route dynamodb table name",
 			},
+			cli.Argument{
+				Name: "routeStorageFilePath", Shorthand: "", Type: cli.ArgTypeString,
+				Default: "ears_routes.log", LookupKey: "ears.storage.route.path",
+				Description: "route log file path for file storage",
+			},
 			cli.Argument{
 				Name: "tenantStorageType", Shorthand: "", Type: cli.ArgTypeString,
 				Default: "inmemory", LookupKey: "ears.storage.tenant.type",
-				Description: "persistence layer storage type for tenants (inmemory, dynamodb)",
+				Description: "persistence layer storage type for tenants (inmemory, dynamodb, redis, file)",
 			},
 			cli.Argument{
 				Name: "tenantStorageDynamoRegion", Shorthand: "", Type: cli.ArgTypeString,
@@ -131,6 +139,21 @@ func init() {
 				Default: "dev.ears.tenant", LookupKey: "ears.storage.tenant.table",
 				Description: "tenant dynamodb table name",
 			},
+			cli.Argument{
+				Name: "tenantStorageRedisEndpoint", Shorthand: "", Type: cli.ArgTypeString,
+				Default: "localhost:6379", LookupKey: "ears.storage.tenant.endpoint",
+				Description: "tenant redis endpoint",
+			},
+			cli.Argument{
+				Name: "tenantStorageFilePath", Shorthand: "", Type: cli.ArgTypeString,
+				Default: "ears_tenants.log", LookupKey: "ears.storage.tenant.path",
+				Description: "tenant log file path for file storage",
+			},
+			cli.Argument{
+				Name: "strictTenancy", Shorthand: "", Type: cli.ArgTypeBool,
+				Default: true, LookupKey: "ears.tenancy.strict",
+				Description: "only allow routes for tenants that have a tenant config",
+			},
 			cli.Argument{
 				Name: "redisEndpoint", Shorthand: "", Type: cli.ArgTypeString,
 				Default: "gears-redis-qa-001.6bteey.0001.usw2.cache.amazonaws.com:6379", LookupKey: "ears.synchronization.endpoint",
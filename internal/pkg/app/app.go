@@ -25,6 +25,7 @@ import (
 	"github.com/xmidt-org/ears/internal/pkg/rtsemconv"
 	"github.com/xmidt-org/ears/pkg/app"
 	"github.com/xmidt-org/ears/pkg/event"
+	"github.com/xmidt-org/ears/pkg/filter"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
@@ -74,6 +75,9 @@ func SetupAPIServer(lifecycle fx.Lifecycle, config config.Config, logger *zerolo
 	//initialize event logger
 	event.SetEventLogger(logger)
 
+	//directory filters may read schema and lookup files from
+	filter.SetFileDir(config.GetString("ears.filters.fileDir"))
+
 	// setup telemetry stuff
 
 	if config.GetBool("ears.opentelemetry.lightstep.active") {
@@ -21,6 +21,7 @@ import (
 	"github.com/spf13/viper"
 	"github.com/xmidt-org/ears/internal/pkg/app"
 	"github.com/xmidt-org/ears/internal/pkg/appsecret"
+	"github.com/xmidt-org/ears/internal/pkg/cluster"
 	"github.com/xmidt-org/ears/internal/pkg/config"
 	"github.com/xmidt-org/ears/internal/pkg/fx/pluginmanagerfx"
 	"github.com/xmidt-org/ears/internal/pkg/fx/quotamanagerfx"
@@ -102,12 +103,14 @@ func TestAppRunSuccess(t *testing.T) {
 			GetTestLogger,
 			app.NewAPIManager,
 			tablemgr.NewRoutingTableManager,
+			cluster.NewReporter,
 			app.NewMiddleware,
 			app.NewMux,
 		),
 		fx.Invoke(quotamanagerfx.SetupQuotaManager),
 		fx.Invoke(syncerfx.SetupDeltaSyncer),
 		fx.Invoke(tablemgr.SetupRoutingManager),
+		fx.Invoke(cluster.SetupReporter),
 		fx.Invoke(app.SetupAPIServer),
 	)
 
@@ -15,9 +15,10 @@
 package docs
 
 // swagger:route DELETE /v1/orgs/{orgId}/applications/{appId}/routes/{routeId} routes deleteRoute
-// Removes an existing route from the routing table if a route with the given ID exists.
+// Removes an existing route from the routing table if a route with the given ID exists. If an If-Match header is given, the route is only removed if its ETag matches.
 // responses:
 //   200: RouteDeleteResponse
+//   412: RouteErrorResponse
 //   500: RouteErrorResponse
 
 // Item response containing the ID of the deleted route.
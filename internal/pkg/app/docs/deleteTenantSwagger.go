@@ -15,7 +15,7 @@
 package docs
 
 // swagger:route DELETE /v1/orgs/{orgId}/applications/{appId}/config tenants deleteTenant
-// Removes an existing tenant from the system provided tenant has no routes.
+// Removes an existing tenant from the system provided tenant has no routes. With cascade the routes of the tenant are removed as well.
 // responses:
 //   200: TenantDeleteResponse
 //   500: TenantErrorResponse
@@ -31,3 +31,10 @@ type TenantDeleteResponse struct {
 	Status responseStatus `json:"status"`
 	Item   string         `json:"item"`
 }
+
+// swagger:parameters deleteTenant
+type cascadeParamWrapper struct {
+	// Also remove all routes of the tenant
+	// in: query
+	Cascade bool `json:"cascade"`
+}
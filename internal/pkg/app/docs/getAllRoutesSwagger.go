@@ -15,7 +15,7 @@
 package docs
 
 // swagger:route GET /v1/routes admin getAllRoutes
-// Gets list of all routes currently present in the routing table for all tenants.
+// Gets list of all routes currently present in the routing table for all tenants. If any query parameter is given, only matching routes are returned one page at a time.
 // responses:
 //   200: RoutesResponse
 //   500: RouteErrorResponse
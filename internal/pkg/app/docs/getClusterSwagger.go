@@ -0,0 +1,49 @@
+// Copyright 2020 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docs
+
+// swagger:route GET /v1/cluster admin getCluster
+// Gets the status of all ears instances in the cluster with a live heartbeat.
+// responses:
+//   200: ClusterResponse
+//   500: ClusterErrorResponse
+
+import (
+	"github.com/xmidt-org/ears/internal/pkg/cluster"
+)
+
+// Items response containing the status of all ears instances.
+// swagger:response clusterResponse
+type clusterResponseWrapper struct {
+	// in: body
+	Body ClusterResponse
+}
+
+// Item response containing a cluster error.
+// swagger:response clusterErrorResponse
+type clusterErrorResponseWrapper struct {
+	// in: body
+	Body ClusterErrorResponse
+}
+
+type ClusterResponse struct {
+	Status responseStatus           `json:"status"`
+	Items  []cluster.InstanceStatus `json:"items"`
+}
+
+type ClusterErrorResponse struct {
+	Status responseStatus `json:"status"`
+	Item   string         `json:"item"`
+}
@@ -0,0 +1,41 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docs
+
+// swagger:route GET /v1/orgs/{orgId}/applications/{appId}/quota/budget tenants getTenantQuotaBudget
+// Gets remaining daily and monthly event volume budget of existing tenant.
+// responses:
+//   200: QuotaBudgetResponse
+//   500: TenantErrorResponse
+
+import (
+	"github.com/xmidt-org/ears/internal/pkg/quota"
+)
+
+// Item response containing tenant event volume budget.
+// swagger:response quotaBudgetResponse
+type quotaBudgetResponseWrapper struct {
+	// in: body
+	Body QuotaBudgetResponse
+}
+
+type QuotaBudgetResponse struct {
+	Status responseStatus `json:"status"`
+	Item   TenantBudget   `json:"item"`
+}
+
+type TenantBudget struct {
+	quota.TenantBudget
+}
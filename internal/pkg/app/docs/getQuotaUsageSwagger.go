@@ -0,0 +1,41 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docs
+
+// swagger:route GET /v1/orgs/{orgId}/applications/{appId}/quota/usage tenants getTenantQuotaUsage
+// Gets event quota usage of existing tenant aggregated across all ears instances.
+// responses:
+//   200: QuotaUsageResponse
+//   500: TenantErrorResponse
+
+import (
+	"github.com/xmidt-org/ears/internal/pkg/quota"
+)
+
+// Item response containing tenant quota usage.
+// swagger:response quotaUsageResponse
+type quotaUsageResponseWrapper struct {
+	// in: body
+	Body QuotaUsageResponse
+}
+
+type QuotaUsageResponse struct {
+	Status responseStatus `json:"status"`
+	Item   TenantUsage    `json:"item"`
+}
+
+type TenantUsage struct {
+	quota.TenantUsage
+}
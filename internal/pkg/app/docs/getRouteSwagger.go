@@ -15,7 +15,7 @@
 package docs
 
 // swagger:route GET /v1/orgs/{orgId}/applications/{appId}/routes/{routeId} routes getRoute
-// Gets a route for a given ID. If no route by that ID exists and error is returned instead.
+// Gets a route for a given ID. If no route by that ID exists and error is returned instead. The ETag response header can be used in If-Match headers of subsequent updates.
 // responses:
 //   200: RouteResponse
 //   404: RouteErrorResponse
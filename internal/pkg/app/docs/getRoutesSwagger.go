@@ -15,7 +15,7 @@
 package docs
 
 // swagger:route GET /v1/orgs/{orgId}/applications/{appId}/routes routes getRoutes
-// Gets list of all routes currently present in the routing table for a single tenant.
+// Gets list of all routes currently present in the routing table for a single tenant. If any query parameter is given, only matching routes are returned one page at a time.
 // responses:
 //   200: RoutesResponse
 //   500: RouteErrorResponse
@@ -32,6 +32,36 @@ type routesResponseWrapper struct {
 type RoutesResponse struct {
 	Status responseStatus `json:"status"`
 	Items  []RouteConfig  `json:"items"`
+	// Cursor for the next page, omitted on the last page
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// swagger:parameters getRoutes getAllRoutes
+type routeListingParamWrapper struct {
+	// Only routes with this receiver plugin type
+	// in: query
+	ReceiverPlugin string `json:"receiverPlugin"`
+	// Only routes with this sender plugin type
+	// in: query
+	SenderPlugin string `json:"senderPlugin"`
+	// Only routes with this name
+	// in: query
+	Name string `json:"name"`
+	// Only routes with this origin
+	// in: query
+	Origin string `json:"origin"`
+	// Only routes with this user ID
+	// in: query
+	UserId string `json:"userId"`
+	// Only routes modified since, unix timestamp in seconds or RFC3339
+	// in: query
+	ModifiedSince string `json:"modifiedSince"`
+	// Page size hint, pages may hold fewer routes even if more routes follow
+	// in: query
+	Limit int `json:"limit"`
+	// Cursor returned with the previous page
+	// in: query
+	Cursor string `json:"cursor"`
 }
 
 type RouteConfig struct {
@@ -15,9 +15,10 @@
 package docs
 
 // swagger:route PUT /v1/orgs/{orgId}/applications/{appId}/routes/{routeId} routes putRoute
-// Adds a new route to the routing table or updates an existing route. Route ID can be given in the body. If it is omitted a hash will be calculates and used instead.
+// Adds a new route to the routing table or updates an existing route. Route ID can be given in the body. If it is omitted a hash will be calculates and used instead. If an If-Match header is given, only an existing route with a matching ETag is updated.
 // responses:
 //   200: RouteResponse
+//   412: RouteErrorResponse
 //   500: RouteErrorResponse
 
 // Item response containing a complete route configuration including sender, receiver and optional filter chain.
@@ -50,6 +51,13 @@ type routeIdParamWrapper struct {
 	RouteId string `json:"routeId"`
 }
 
+// swagger:parameters putRoute deleteRoute
+type ifMatchParamWrapper struct {
+	// ETag of the route as returned by getRoute, or * to match any existing route
+	// in: header
+	IfMatch string `json:"If-Match"`
+}
+
 type RouteResponse struct {
 	Status responseStatus `json:"status"`
 	Item   RouteConfig    `json:"item"`
@@ -74,6 +74,18 @@ func (e *BadRequestError) StatusCode() int {
 	return http.StatusBadRequest
 }
 
+type PreconditionFailedError struct {
+	message string
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return errs.String("PreconditionFailedError", map[string]interface{}{"message": e.message}, nil)
+}
+
+func (e *PreconditionFailedError) StatusCode() int {
+	return http.StatusPreconditionFailed
+}
+
 type InternalServerError struct {
 	Wrapped error
 }
@@ -19,6 +19,7 @@ import (
 	"embed"
 	"errors"
 	"github.com/goccy/go-yaml"
+	"github.com/xmidt-org/ears/internal/pkg/cluster"
 	"github.com/xmidt-org/ears/internal/pkg/quota"
 	"github.com/xmidt-org/ears/internal/pkg/rtsemconv"
 	"github.com/xmidt-org/ears/internal/pkg/tablemgr"
@@ -31,6 +32,9 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog/log"
@@ -46,18 +50,20 @@ type APIManager struct {
 	routingTableMgr            tablemgr.RoutingTableManager
 	tenantStorer               tenant.TenantStorer
 	quotaManager               *quota.QuotaManager
+	clusterReporter            *cluster.Reporter
 	addRouteSuccessRecorder    metric.BoundFloat64Counter
 	addRouteFailureRecorder    metric.BoundFloat64Counter
 	removeRouteSuccessRecorder metric.BoundFloat64Counter
 	removeRouteFailureRecorder metric.BoundFloat64Counter
 }
 
-func NewAPIManager(routingMgr tablemgr.RoutingTableManager, tenantStorer tenant.TenantStorer, quotaManager *quota.QuotaManager) (*APIManager, error) {
+func NewAPIManager(routingMgr tablemgr.RoutingTableManager, tenantStorer tenant.TenantStorer, quotaManager *quota.QuotaManager, clusterReporter *cluster.Reporter) (*APIManager, error) {
 	api := &APIManager{
 		muxRouter:       mux.NewRouter(),
 		routingTableMgr: routingMgr,
 		tenantStorer:    tenantStorer,
 		quotaManager:    quotaManager,
+		clusterReporter: clusterReporter,
 	}
 	api.muxRouter.PathPrefix("/ears/openapi").Handler(
 		http.FileServer(http.FS(WebsiteFS)),
@@ -71,11 +77,14 @@ func NewAPIManager(routingMgr tablemgr.RoutingTableManager, tenantStorer tenant.
 	api.muxRouter.HandleFunc("/ears/v1/orgs/{orgId}/applications/{appId}/config", api.getTenantConfigHandler).Methods(http.MethodGet)
 	api.muxRouter.HandleFunc("/ears/v1/orgs/{orgId}/applications/{appId}/config", api.setTenantConfigHandler).Methods(http.MethodPut)
 	api.muxRouter.HandleFunc("/ears/v1/orgs/{orgId}/applications/{appId}/config", api.deleteTenantConfigHandler).Methods(http.MethodDelete)
+	api.muxRouter.HandleFunc("/ears/v1/orgs/{orgId}/applications/{appId}/quota/usage", api.getTenantQuotaUsageHandler).Methods(http.MethodGet)
+	api.muxRouter.HandleFunc("/ears/v1/orgs/{orgId}/applications/{appId}/quota/budget", api.getTenantQuotaBudgetHandler).Methods(http.MethodGet)
 	api.muxRouter.HandleFunc("/ears/v1/routes", api.getAllRoutesHandler).Methods(http.MethodGet)
 	api.muxRouter.HandleFunc("/ears/v1/tenants", api.getAllTenantConfigsHandler).Methods(http.MethodGet)
 	api.muxRouter.HandleFunc("/ears/v1/senders", api.getAllSendersHandler).Methods(http.MethodGet)
 	api.muxRouter.HandleFunc("/ears/v1/receivers", api.getAllReceiversHandler).Methods(http.MethodGet)
 	api.muxRouter.HandleFunc("/ears/v1/filters", api.getAllFiltersHandler).Methods(http.MethodGet)
+	api.muxRouter.HandleFunc("/ears/v1/cluster", api.getClusterHandler).Methods(http.MethodGet)
 	// metrics
 	// where should meters live (api manager, uberfx, global variables,...)?
 	meter := global.Meter(rtsemconv.EARSMeterName)
@@ -139,6 +148,14 @@ func getTenant(ctx context.Context, vars map[string]string) (*tenant.Id, ApiErro
 	return &tenant.Id{OrgId: orgId, AppId: appId}, nil
 }
 
+// getIfMatch returns the entity tag of the If-Match request header without quotes or an empty string
+// if the header is not set. Weak validators are treated like strong ones.
+func getIfMatch(r *http.Request) string {
+	etag := strings.TrimSpace(r.Header.Get(HeaderIfMatch))
+	etag = strings.TrimPrefix(etag, "W/")
+	return strings.Trim(etag, `"`)
+}
+
 func (a *APIManager) addRouteHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	vars := mux.Vars(r)
@@ -150,13 +167,6 @@ func (a *APIManager) addRouteHandler(w http.ResponseWriter, r *http.Request) {
 		resp.Respond(ctx, w)
 		return
 	}
-	_, err := a.tenantStorer.GetConfig(ctx, *tid)
-	if err != nil {
-		log.Ctx(ctx).Error().Str("op", "getTenantConfigHandler").Str("error", err.Error()).Msg("error getting tenant config")
-		resp := ErrorResponse(convertToApiError(ctx, err))
-		resp.Respond(ctx, w)
-		return
-	}
 	routeId := vars["routeId"]
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -189,7 +199,11 @@ func (a *APIManager) addRouteHandler(w http.ResponseWriter, r *http.Request) {
 	trace.SpanFromContext(ctx).SetAttributes(rtsemconv.EARSRouteId.String(routeId))
 	route.TenantId.AppId = tid.AppId
 	route.TenantId.OrgId = tid.OrgId
-	err = a.routingTableMgr.AddRoute(ctx, &route)
+	if etag := getIfMatch(r); etag != "" {
+		err = a.routingTableMgr.AddRouteIfMatch(ctx, &route, etag)
+	} else {
+		err = a.routingTableMgr.AddRoute(ctx, &route)
+	}
 	if err != nil {
 		log.Ctx(ctx).Error().Str("op", "addRouteHandler").Msg(err.Error())
 		a.addRouteFailureRecorder.Add(ctx, 1.0)
@@ -216,7 +230,12 @@ func (a *APIManager) removeRouteHandler(w http.ResponseWriter, r *http.Request)
 	}
 	routeId := vars["routeId"]
 	trace.SpanFromContext(ctx).SetAttributes(rtsemconv.EARSRouteId.String(routeId))
-	err := a.routingTableMgr.RemoveRoute(ctx, *tid, routeId)
+	var err error
+	if etag := getIfMatch(r); etag != "" {
+		err = a.routingTableMgr.RemoveRouteIfMatch(ctx, *tid, routeId, etag)
+	} else {
+		err = a.routingTableMgr.RemoveRoute(ctx, *tid, routeId)
+	}
 	if err != nil {
 		log.Ctx(ctx).Error().Str("op", "removeRouteHandler").Msg(err.Error())
 		a.removeRouteFailureRecorder.Add(ctx, 1.0)
@@ -249,6 +268,7 @@ func (a *APIManager) getRouteHandler(w http.ResponseWriter, r *http.Request) {
 		resp.Respond(ctx, w)
 		return
 	}
+	w.Header().Set(HeaderETag, `"`+routeConfig.ETag(ctx)+`"`)
 	resp := ItemResponse(routeConfig)
 	resp.Respond(ctx, w)
 }
@@ -263,6 +283,18 @@ func (a *APIManager) getAllTenantRoutesHandler(w http.ResponseWriter, r *http.Re
 		resp.Respond(ctx, w)
 		return
 	}
+	listing, apiErr := getRouteListing(r)
+	if apiErr != nil {
+		log.Ctx(ctx).Error().Str("op", "GetAllTenantRoutes").Str("error", apiErr.Error()).Msg("bad query parameters")
+		resp := ErrorResponse(apiErr)
+		resp.Respond(ctx, w)
+		return
+	}
+	if listing != nil {
+		listing.filter.TenantId = tid
+		a.listRoutes(ctx, w, listing)
+		return
+	}
 	allRouteConfigs, err := a.routingTableMgr.GetAllTenantRoutes(ctx, *tid)
 	if err != nil {
 		log.Ctx(ctx).Error().Str("op", "GetAllTenantRoutes").Msg(err.Error())
@@ -277,7 +309,13 @@ func (a *APIManager) getAllTenantRoutesHandler(w http.ResponseWriter, r *http.Re
 
 func (a *APIManager) getAllRoutesHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	allRouteConfigs := make([]route.Config, 0)
+	listing, apiErr := getRouteListing(r)
+	if apiErr != nil {
+		log.Ctx(ctx).Error().Str("op", "GetAllRoutes").Str("error", apiErr.Error()).Msg("bad query parameters")
+		resp := ErrorResponse(apiErr)
+		resp.Respond(ctx, w)
+		return
+	}
 	configs, err := a.tenantStorer.GetAllConfigs(ctx)
 	if err != nil {
 		log.Ctx(ctx).Error().Str("op", "GetAllTenantRoutes").Str("error", err.Error()).Msg("tenant configs read error")
@@ -285,6 +323,16 @@ func (a *APIManager) getAllRoutesHandler(w http.ResponseWriter, r *http.Request)
 		resp.Respond(ctx, w)
 		return
 	}
+	if listing != nil {
+		// list the same routes as without pagination, those of tenants with a tenant config
+		listing.tenants = make(map[tenant.Id]bool, len(configs))
+		for _, config := range configs {
+			listing.tenants[config.Tenant] = true
+		}
+		a.listRoutes(ctx, w, listing)
+		return
+	}
+	allRouteConfigs := make([]route.Config, 0)
 	for _, config := range configs {
 		tenantRouteConfigs, err := a.routingTableMgr.GetAllTenantRoutes(ctx, config.Tenant)
 		if err != nil {
@@ -300,6 +348,85 @@ func (a *APIManager) getAllRoutesHandler(w http.ResponseWriter, r *http.Request)
 	resp.Respond(ctx, w)
 }
 
+// routeListing holds the filter and pagination query parameters of a route listing
+type routeListing struct {
+	filter  route.Filter
+	cursor  string
+	limit   int
+	tenants map[tenant.Id]bool // if set, only routes of these tenants are listed
+}
+
+// routeListingParams are the query parameters that turn a route listing into a paginated one
+var routeListingParams = []string{"receiverPlugin", "senderPlugin", "name", "origin", "userId", "modifiedSince", "limit", "cursor"}
+
+// getRouteListing parses the route listing query parameters. It returns nil if none are given,
+// in which case all routes are listed without pagination. Other query parameters are ignored.
+func getRouteListing(r *http.Request) (*routeListing, ApiError) {
+	query := r.URL.Query()
+	paginated := false
+	for _, param := range routeListingParams {
+		if _, ok := query[param]; ok {
+			paginated = true
+			break
+		}
+	}
+	if !paginated {
+		return nil, nil
+	}
+	listing := &routeListing{
+		filter: route.Filter{
+			ReceiverPlugin: query.Get("receiverPlugin"),
+			SenderPlugin:   query.Get("senderPlugin"),
+			Name:           query.Get("name"),
+			Origin:         query.Get("origin"),
+			UserId:         query.Get("userId"),
+		},
+		cursor: query.Get("cursor"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 0 {
+			return nil, &BadRequestError{"invalid limit " + limit, err}
+		}
+		listing.limit = l
+	}
+	if since := query.Get("modifiedSince"); since != "" {
+		// either unix timestamp in seconds or RFC3339
+		ts, err := strconv.ParseInt(since, 10, 64)
+		if err != nil {
+			t, err := time.Parse(time.RFC3339, since)
+			if err != nil {
+				return nil, &BadRequestError{"invalid modifiedSince " + since, err}
+			}
+			ts = t.Unix()
+		}
+		listing.filter.ModifiedSince = ts
+	}
+	return listing, nil
+}
+
+func (a *APIManager) listRoutes(ctx context.Context, w http.ResponseWriter, listing *routeListing) {
+	page, err := a.routingTableMgr.ListRoutes(ctx, listing.filter, listing.cursor, listing.limit)
+	if err != nil {
+		log.Ctx(ctx).Error().Str("op", "listRoutes").Msg(err.Error())
+		resp := ErrorResponse(convertToApiError(ctx, err))
+		resp.Respond(ctx, w)
+		return
+	}
+	if listing.tenants != nil {
+		routes := make([]route.Config, 0, len(page.Routes))
+		for _, r := range page.Routes {
+			if listing.tenants[r.TenantId] {
+				routes = append(routes, r)
+			}
+		}
+		page.Routes = routes
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("routeCount", len(page.Routes)))
+	resp := PageResponse(page.Routes, page.Cursor)
+	resp.Respond(ctx, w)
+}
+
 func (a *APIManager) getAllSendersHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	allSenders, err := a.routingTableMgr.GetAllSendersStatus(ctx)
@@ -363,6 +490,80 @@ func (a *APIManager) getTenantConfigHandler(w http.ResponseWriter, r *http.Reque
 	resp.Respond(ctx, w)
 }
 
+func (a *APIManager) getClusterHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if a.clusterReporter == nil {
+		log.Ctx(ctx).Error().Str("op", "getClusterHandler").Msg("no cluster reporter")
+		resp := ErrorResponse(&NotImplementedError{})
+		resp.Respond(ctx, w)
+		return
+	}
+	instances, err := a.clusterReporter.Instances(ctx)
+	if err != nil {
+		log.Ctx(ctx).Error().Str("op", "getClusterHandler").Msg(err.Error())
+		resp := ErrorResponse(convertToApiError(ctx, err))
+		resp.Respond(ctx, w)
+		return
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("instanceCount", len(instances)))
+	resp := ItemsResponse(instances)
+	resp.Respond(ctx, w)
+}
+
+func (a *APIManager) getTenantQuotaUsageHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	tid, apiErr := getTenant(ctx, vars)
+	if apiErr != nil {
+		log.Ctx(ctx).Error().Str("op", "getTenantQuotaUsageHandler").Str("error", apiErr.Error()).Msg("orgId or appId empty")
+		resp := ErrorResponse(apiErr)
+		resp.Respond(ctx, w)
+		return
+	}
+	if a.quotaManager == nil {
+		log.Ctx(ctx).Error().Str("op", "getTenantQuotaUsageHandler").Msg("no quota manager")
+		resp := ErrorResponse(&NotImplementedError{})
+		resp.Respond(ctx, w)
+		return
+	}
+	usage, err := a.quotaManager.TenantUsage(ctx, *tid)
+	if err != nil {
+		log.Ctx(ctx).Error().Str("op", "getTenantQuotaUsageHandler").Str("error", err.Error()).Msg("error getting tenant quota usage")
+		resp := ErrorResponse(convertToApiError(ctx, err))
+		resp.Respond(ctx, w)
+		return
+	}
+	resp := ItemResponse(usage)
+	resp.Respond(ctx, w)
+}
+
+func (a *APIManager) getTenantQuotaBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	tid, apiErr := getTenant(ctx, vars)
+	if apiErr != nil {
+		log.Ctx(ctx).Error().Str("op", "getTenantQuotaBudgetHandler").Str("error", apiErr.Error()).Msg("orgId or appId empty")
+		resp := ErrorResponse(apiErr)
+		resp.Respond(ctx, w)
+		return
+	}
+	if a.quotaManager == nil {
+		log.Ctx(ctx).Error().Str("op", "getTenantQuotaBudgetHandler").Msg("no quota manager")
+		resp := ErrorResponse(&NotImplementedError{})
+		resp.Respond(ctx, w)
+		return
+	}
+	budget, err := a.quotaManager.TenantBudget(ctx, *tid)
+	if err != nil {
+		log.Ctx(ctx).Error().Str("op", "getTenantQuotaBudgetHandler").Str("error", err.Error()).Msg("error getting tenant quota budget")
+		resp := ErrorResponse(convertToApiError(ctx, err))
+		resp.Respond(ctx, w)
+		return
+	}
+	resp := ItemResponse(budget)
+	resp.Respond(ctx, w)
+}
+
 func (a *APIManager) getAllTenantConfigsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	configs, err := a.tenantStorer.GetAllConfigs(ctx)
@@ -405,6 +606,13 @@ func (a *APIManager) setTenantConfigHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	tenantConfig.Tenant = *tid
+	err = tenantConfig.Validate()
+	if err != nil {
+		log.Ctx(ctx).Error().Str("op", "setTenantConfigHandler").Str("error", err.Error()).Msg("invalid tenant config")
+		resp := ErrorResponse(&BadRequestError{"bad tenant config", err})
+		resp.Respond(ctx, w)
+		return
+	}
 	err = a.tenantStorer.SetConfig(ctx, tenantConfig)
 	if err != nil {
 		log.Ctx(ctx).Error().Str("op", "setTenantConfigHandler").Str("error", err.Error()).Msg("error setting tenant config")
@@ -435,10 +643,19 @@ func (a *APIManager) deleteTenantConfigHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 	if len(allRouteConfigs) > 0 {
-		log.Ctx(ctx).Error().Str("op", "deleteTenantConfigHandler").Msg("tenant has routes")
-		resp := ErrorResponse(convertToApiError(ctx, &BadRequestError{"tenant has routes", nil}))
-		resp.Respond(ctx, w)
-		return
+		if r.URL.Query().Get("cascade") != "true" {
+			log.Ctx(ctx).Error().Str("op", "deleteTenantConfigHandler").Msg("tenant has routes")
+			resp := ErrorResponse(convertToApiError(ctx, &BadRequestError{"tenant has routes", nil}))
+			resp.Respond(ctx, w)
+			return
+		}
+		err = a.routingTableMgr.RemoveTenantRoutes(ctx, *tid)
+		if err != nil {
+			log.Ctx(ctx).Error().Str("op", "deleteTenantConfigHandler").Str("error", err.Error()).Msg("error removing tenant routes")
+			resp := ErrorResponse(convertToApiError(ctx, err))
+			resp.Respond(ctx, w)
+			return
+		}
 	}
 	err = a.tenantStorer.DeleteConfig(ctx, *tid)
 	if err != nil {
@@ -460,6 +677,9 @@ func convertToApiError(ctx context.Context, err error) ApiError {
 	var routeValidationError *tablemgr.RouteValidationError
 	var routeRegistrationError *tablemgr.RouteRegistrationError
 	var routeNotFound *route.RouteNotFoundError
+	var tenantPolicyError *tablemgr.TenantPolicyError
+	var preconditionFailed *route.PreconditionFailedError
+	var badCursor *route.BadCursorError
 	if errors.As(err, &tenantNotFound) {
 		return &NotFoundError{"tenant " + tenantNotFound.Tenant.ToString() + " not found"}
 	} else if errors.As(err, &badTenantConfig) {
@@ -470,8 +690,14 @@ func convertToApiError(ctx context.Context, err error) ApiError {
 		return &BadRequestError{"bad route config", err}
 	} else if errors.As(err, &routeValidationError) {
 		return &BadRequestError{"bad route config", err}
+	} else if errors.As(err, &tenantPolicyError) {
+		return &BadRequestError{"route violates tenant policy", err}
 	} else if errors.As(err, &routeNotFound) {
 		return &NotFoundError{"route " + routeNotFound.RouteId + " not found"}
+	} else if errors.As(err, &badCursor) {
+		return &BadRequestError{"invalid cursor", err}
+	} else if errors.As(err, &preconditionFailed) {
+		return &PreconditionFailedError{"route " + preconditionFailed.RouteId + " does not match etag " + preconditionFailed.ETag}
 	}
 	return &InternalServerError{err}
 }
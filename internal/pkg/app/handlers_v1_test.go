@@ -24,6 +24,7 @@ import (
 	"github.com/rs/zerolog/log"
 	goldie "github.com/sebdah/goldie/v2"
 	"github.com/spf13/viper"
+	"github.com/xmidt-org/ears/internal/pkg/cluster"
 	"github.com/xmidt-org/ears/internal/pkg/config"
 	"github.com/xmidt-org/ears/internal/pkg/db"
 	"github.com/xmidt-org/ears/internal/pkg/db/dynamo"
@@ -41,8 +42,10 @@ import (
 	"github.com/xmidt-org/ears/pkg/plugins/decode"
 	"github.com/xmidt-org/ears/pkg/plugins/dedup"
 	"github.com/xmidt-org/ears/pkg/plugins/encode"
+	"github.com/xmidt-org/ears/pkg/plugins/enrich"
 	"github.com/xmidt-org/ears/pkg/plugins/hash"
 	http_plugin "github.com/xmidt-org/ears/pkg/plugins/http"
+	"github.com/xmidt-org/ears/pkg/plugins/jq"
 	"github.com/xmidt-org/ears/pkg/plugins/js"
 	"github.com/xmidt-org/ears/pkg/plugins/kafka"
 	"github.com/xmidt-org/ears/pkg/plugins/kinesis"
@@ -53,6 +56,7 @@ import (
 	"github.com/xmidt-org/ears/pkg/plugins/regex"
 	"github.com/xmidt-org/ears/pkg/plugins/split"
 	"github.com/xmidt-org/ears/pkg/plugins/sqs"
+	"github.com/xmidt-org/ears/pkg/plugins/switcher"
 	"github.com/xmidt-org/ears/pkg/plugins/trace"
 	"github.com/xmidt-org/ears/pkg/plugins/transform"
 	"github.com/xmidt-org/ears/pkg/plugins/ttl"
@@ -519,6 +523,10 @@ func setupRestApi(config config.Config, storageMgr route.RouteStorer, setupQuota
 			name:   "dedup",
 			plugin: toArr(dedup.NewPluginVersion("dedup", "", ""))[0].(pkgplugin.Pluginer),
 		},
+		{
+			name:   "enrich",
+			plugin: toArr(enrich.NewPluginVersion("enrich", "", ""))[0].(pkgplugin.Pluginer),
+		},
 		{
 			name:   "batch",
 			plugin: toArr(batch.NewPluginVersion("batch", "", ""))[0].(pkgplugin.Pluginer),
@@ -555,6 +563,14 @@ func setupRestApi(config config.Config, storageMgr route.RouteStorer, setupQuota
 			name:   "split",
 			plugin: toArr(split.NewPluginVersion("split", "", ""))[0].(pkgplugin.Pluginer),
 		},
+		{
+			name:   "jq",
+			plugin: toArr(jq.NewPluginVersion("jq", "", ""))[0].(pkgplugin.Pluginer),
+		},
+		{
+			name:   "switch",
+			plugin: toArr(switcher.NewPluginVersion("switch", "", ""))[0].(pkgplugin.Pluginer),
+		},
 		{
 			name:   "unwrap",
 			plugin: toArr(unwrap.NewPluginVersion("unwrap", "", ""))[0].(pkgplugin.Pluginer),
@@ -578,8 +594,8 @@ func setupRestApi(config config.Config, storageMgr route.RouteStorer, setupQuota
 	if err != nil {
 		return &EarsRuntime{config, nil, nil, storageMgr, nil, nil}, err
 	}
-	routingMgr := tablemgr.NewRoutingTableManager(pluginMgr, storageMgr, tableSyncer, &log.Logger, config)
 	tenantStorer := db.NewTenantInmemoryStorer()
+	routingMgr := tablemgr.NewRoutingTableManager(pluginMgr, storageMgr, tenantStorer, tableSyncer, &log.Logger, config)
 	ctx := context.Background()
 	ctx = log.Logger.WithContext(ctx)
 	tid1 := tenant.Id{OrgId: "myorg", AppId: "myapp"}
@@ -598,7 +614,8 @@ func setupRestApi(config config.Config, storageMgr route.RouteStorer, setupQuota
 			return &EarsRuntime{config, nil, nil, storageMgr, nil, nil}, err
 		}
 	}
-	apiMgr, err := NewAPIManager(routingMgr, tenantStorer, quotaMgr)
+	clusterReporter := cluster.NewReporter(tableSyncer, routingMgr, quotaMgr, &log.Logger)
+	apiMgr, err := NewAPIManager(routingMgr, tenantStorer, quotaMgr, clusterReporter)
 	if err != nil {
 		return &EarsRuntime{config, nil, nil, storageMgr, nil, nil}, err
 	}
@@ -713,7 +730,7 @@ func checkEventsSent(routeFileName string, testPrefix string, pluginMgr plugin.M
 func TestRestVersionHandler(t *testing.T) {
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest(http.MethodGet, "/version", nil)
-	api, err := NewAPIManager(&tablemgr.DefaultRoutingTableManager{}, nil, nil)
+	api, err := NewAPIManager(&tablemgr.DefaultRoutingTableManager{}, nil, nil, nil)
 	if err != nil {
 		t.Fatalf("Fail to setup api manager: %s\n", err.Error())
 	}
@@ -1278,6 +1295,25 @@ func TestRestPostRouteHandlerBadPluginName(t *testing.T) {
 	g.AssertJson(t, "addroutebadpluginname", data)
 }
 
+func TestRestPostRouteHandlerSwitchUnknownSender(t *testing.T) {
+	w := httptest.NewRecorder()
+	routeFileName := "testdata/simpleFilterSwitchUnknownSenderRoute.json"
+	simpleRouteReader, err := os.Open(routeFileName)
+	if err != nil {
+		t.Fatalf("cannot read file: %s", err.Error())
+	}
+	runtime := setupSimpleApi(t, "inmemory")
+	r := httptest.NewRequest(http.MethodPost, "/ears/v1"+tenantPath+"/routes", simpleRouteReader)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	g := goldie.New(t)
+	var data interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &data)
+	if err != nil {
+		t.Fatalf("cannot unmarshal response %s into json %s", w.Body.String(), err.Error())
+	}
+	g.AssertJson(t, "addrouteswitchunknownsender", data)
+}
+
 func TestRestPostRouteHandlerNoSender(t *testing.T) {
 	w := httptest.NewRecorder()
 	routeFileName := "testdata/simpleRouteNoSender.json"
@@ -1495,3 +1531,636 @@ func TestTenantConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestTenantQuotaUsage(t *testing.T) {
+	config, err := getConfig()
+	if err != nil {
+		t.Fatalf("cannot get config: %s", err.Error())
+	}
+	storageMgr, err := getStorageLayer(t, config, "inmemory")
+	if err != nil {
+		t.Fatalf("cannot get stroage manager: %s", err.Error())
+	}
+	runtime, err := setupRestApi(config, storageMgr, true)
+	if err != nil {
+		t.Fatalf("cannot create api manager: %s\n", err.Error())
+	}
+	runtime.deltaSyncer.StartListeningForSyncRequests()
+	defer runtime.deltaSyncer.StopListeningForSyncRequests()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/ears/v1/orgs/myorg/applications/myapp/quota/usage", nil)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Getting quota usage does not return 200. Instead, returns %d\n", w.Code)
+	}
+	var data struct {
+		Item quota.TenantUsage `json:"item"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &data)
+	if err != nil {
+		t.Fatalf("cannot unmarshal response %s into json %s", w.Body.String(), err.Error())
+	}
+	if data.Item.EventsPerSec != 100 {
+		t.Fatalf("unexpected eventsPerSec %d (%d)", data.Item.EventsPerSec, 100)
+	}
+	if data.Item.Policy != tenant.QuotaPolicyWait {
+		t.Fatalf("unexpected policy %s (%s)", data.Item.Policy, tenant.QuotaPolicyWait)
+	}
+	if len(data.Item.Instances) != 1 {
+		t.Fatalf("unexpected number of instances %d (%d)", len(data.Item.Instances), 1)
+	}
+	if data.Item.Instances[0].InstanceId != runtime.deltaSyncer.GetInstanceId() {
+		t.Fatalf("unexpected instance id %s (%s)", data.Item.Instances[0].InstanceId, runtime.deltaSyncer.GetInstanceId())
+	}
+	// unknown tenant
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/ears/v1/orgs/yourorg/applications/yourapp/quota/usage", nil)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Getting quota usage does not return 404. Instead, returns %d\n", w.Code)
+	}
+}
+
+func TestTenantQuotaBudget(t *testing.T) {
+	config, err := getConfig()
+	if err != nil {
+		t.Fatalf("cannot get config: %s", err.Error())
+	}
+	storageMgr, err := getStorageLayer(t, config, "inmemory")
+	if err != nil {
+		t.Fatalf("cannot get stroage manager: %s", err.Error())
+	}
+	runtime, err := setupRestApi(config, storageMgr, true)
+	if err != nil {
+		t.Fatalf("cannot create api manager: %s\n", err.Error())
+	}
+	runtime.deltaSyncer.StartListeningForSyncRequests()
+	defer runtime.deltaSyncer.StopListeningForSyncRequests()
+	configReader := strings.NewReader(`{"quota": {"eventsPerSec": 10, "eventsPerDay": 1000, "eventsPerMonth": 20000}}`)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/ears/v1/orgs/budgetorg/applications/budgetapp/config", configReader)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Setting tenant config does not return 200. Instead, returns %d\n", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/ears/v1/orgs/budgetorg/applications/budgetapp/quota/budget", nil)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Getting quota budget does not return 200. Instead, returns %d\n", w.Code)
+	}
+	var data struct {
+		Item quota.TenantBudget `json:"item"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &data)
+	if err != nil {
+		t.Fatalf("cannot unmarshal response %s into json %s", w.Body.String(), err.Error())
+	}
+	if data.Item.Day.Limit != 1000 || data.Item.Day.Remaining != 1000 {
+		t.Fatalf("unexpected daily budget %+v", data.Item.Day)
+	}
+	if data.Item.Month.Limit != 20000 || data.Item.Month.Remaining != 20000 {
+		t.Fatalf("unexpected monthly budget %+v", data.Item.Month)
+	}
+	// unknown tenant
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/ears/v1/orgs/yourorg/applications/yourapp/quota/budget", nil)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Getting quota budget does not return 404. Instead, returns %d\n", w.Code)
+	}
+}
+
+func TestTenantLifecycle(t *testing.T) {
+	config, err := getConfig()
+	if err != nil {
+		t.Fatalf("cannot get config: %s", err.Error())
+	}
+	storageMgr, err := getStorageLayer(t, config, "inmemory")
+	if err != nil {
+		t.Fatalf("cannot get stroage manager: %s", err.Error())
+	}
+	runtime, err := setupRestApi(config, storageMgr, true)
+	if err != nil {
+		t.Fatalf("cannot create api manager: %s\n", err.Error())
+	}
+	runtime.deltaSyncer.StartListeningForSyncRequests()
+	defer runtime.deltaSyncer.StopListeningForSyncRequests()
+	path := "/ears/v1/orgs/lifecycleorg/applications/lifecycleapp"
+	routeFileName := "testdata/simpleRoute.json"
+	// no route without tenant
+	routeReader, err := os.Open(routeFileName)
+	if err != nil {
+		t.Fatalf("cannot read file: %s", err.Error())
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, path+"/routes/r100", routeReader)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Adding route for unknown tenant does not return 404. Instead, returns %d\n", w.Code)
+	}
+	// add tenant and route
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, path+"/config", strings.NewReader(`{"quota": {"eventsPerSec": 10}}`))
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Setting tenant config does not return 200. Instead, returns %d\n", w.Code)
+	}
+	routeReader, err = os.Open(routeFileName)
+	if err != nil {
+		t.Fatalf("cannot read file: %s", err.Error())
+	}
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, path+"/routes/r100", routeReader)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Adding route does not return 200. Instead, returns %d\n", w.Code)
+	}
+	// tenant with routes cannot be deleted
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodDelete, path+"/config", nil)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code == http.StatusOK {
+		t.Fatalf("Deleting tenant with routes returns 200\n")
+	}
+	// unless the deletion cascades to its routes
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodDelete, path+"/config?cascade=true", nil)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Deleting tenant with cascade does not return 200. Instead, returns %d\n", w.Code)
+	}
+	routes, err := runtime.routingTableManager.GetAllTenantRoutes(context.Background(), tenant.Id{OrgId: "lifecycleorg", AppId: "lifecycleapp"})
+	if err != nil {
+		t.Fatalf("cannot get tenant routes: %s", err.Error())
+	}
+	if len(routes) != 0 {
+		t.Fatalf("unexpected number of tenant routes %d (%d)", len(routes), 0)
+	}
+	registeredRoutes, err := runtime.routingTableManager.GetAllRegisteredRoutes()
+	if err != nil {
+		t.Fatalf("cannot get registered routes: %s", err.Error())
+	}
+	for _, rt := range registeredRoutes {
+		if rt.Id == "r100" && rt.TenantId.OrgId == "lifecycleorg" {
+			t.Fatalf("route is still registered after tenant deletion")
+		}
+	}
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, path+"/config", nil)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Getting tenant does not return 404. Instead, returns %d\n", w.Code)
+	}
+}
+
+func TestTenantPolicy(t *testing.T) {
+	config, err := getConfig()
+	if err != nil {
+		t.Fatalf("cannot get config: %s", err.Error())
+	}
+	storageMgr, err := getStorageLayer(t, config, "inmemory")
+	if err != nil {
+		t.Fatalf("cannot get stroage manager: %s", err.Error())
+	}
+	runtime, err := setupRestApi(config, storageMgr, true)
+	if err != nil {
+		t.Fatalf("cannot create api manager: %s\n", err.Error())
+	}
+	runtime.deltaSyncer.StartListeningForSyncRequests()
+	defer runtime.deltaSyncer.StopListeningForSyncRequests()
+	path := "/ears/v1/orgs/policyorg/applications/policyapp"
+	tenantConfig := `
+		{
+			"quota": {
+				"eventsPerSec": 10
+			},
+			"deniedPlugins": ["kafka"],
+			"allowedHosts": ["*.example.com"],
+			"defaults": {
+				"debug": {
+					"maxHistory": 7
+				}
+			}
+		}
+		`
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, path+"/config", strings.NewReader(tenantConfig))
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Setting tenant config does not return 200. Instead, returns %d\n", w.Code)
+	}
+	routeTemplate := `
+		{
+			"userId": "boris",
+			"receiver": {
+				"plugin": "debug",
+				"name": "policyReceiver",
+				"config": {
+					"intervalMs": 10,
+					"rounds": 1,
+					"payload": {"foo": "bar"}
+				}
+			},
+			"sender": %s
+		}
+		`
+	badRoutes := map[string]string{
+		"deniedPlugin":   `{"plugin": "kafka", "name": "policyKafka", "config": {"brokers": "broker.example.com:9092", "topic": "test"}}`,
+		"disallowedUrl":  `{"plugin": "http", "name": "policyHttp", "config": {"url": "http://www.notexample.com/events", "method": "POST"}}`,
+		"disallowedHost": `{"plugin": "redis", "name": "policyRedis", "config": {"endpoint": "localhost:6379", "channel": "test"}}`,
+	}
+	for name, sender := range badRoutes {
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest(http.MethodPut, path+"/routes/"+name, strings.NewReader(fmt.Sprintf(routeTemplate, sender)))
+		runtime.apiManager.muxRouter.ServeHTTP(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("Adding route %s does not return 400. Instead, returns %d\n", name, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "TenantPolicyError") {
+			t.Fatalf("Adding route %s does not return tenant policy error: %s\n", name, w.Body.String())
+		}
+	}
+	// defaults are merged into plugin configs
+	w = httptest.NewRecorder()
+	sender := `{"plugin": "debug", "name": "policySender", "config": {"destination": "stdout"}}`
+	r = httptest.NewRequest(http.MethodPut, path+"/routes/policyroute", strings.NewReader(fmt.Sprintf(routeTemplate, sender)))
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Adding route does not return 200. Instead, returns %d: %s\n", w.Code, w.Body.String())
+	}
+	rt, err := runtime.routingTableManager.GetRoute(context.Background(), tenant.Id{OrgId: "policyorg", AppId: "policyapp"}, "policyroute")
+	if err != nil {
+		t.Fatalf("cannot get route: %s", err.Error())
+	}
+	for _, pc := range []route.PluginConfig{rt.Receiver, rt.Sender} {
+		cfg, ok := pc.Config.(map[string]interface{})
+		if !ok {
+			t.Fatalf("unexpected %s config type %T", pc.Name, pc.Config)
+		}
+		if fmt.Sprint(cfg["maxHistory"]) != "7" {
+			t.Fatalf("default maxHistory not merged into %s config: %v", pc.Name, cfg)
+		}
+	}
+	err = runtime.routingTableManager.RemoveRoute(context.Background(), rt.TenantId, rt.Id)
+	if err != nil {
+		t.Fatalf("cannot remove route: %s", err.Error())
+	}
+}
+
+func TestRouteETag(t *testing.T) {
+	config, err := getConfig()
+	if err != nil {
+		t.Fatalf("cannot get config: %s", err.Error())
+	}
+	storageMgr, err := getStorageLayer(t, config, "inmemory")
+	if err != nil {
+		t.Fatalf("cannot get stroage manager: %s", err.Error())
+	}
+	runtime, err := setupRestApi(config, storageMgr, true)
+	if err != nil {
+		t.Fatalf("cannot create api manager: %s\n", err.Error())
+	}
+	runtime.deltaSyncer.StartListeningForSyncRequests()
+	defer runtime.deltaSyncer.StopListeningForSyncRequests()
+	path := "/ears/v1/orgs/myorg/applications/myapp/routes/r100"
+	routeBytes, err := os.ReadFile("testdata/simpleRoute.json")
+	if err != nil {
+		t.Fatalf("cannot read file: %s", err.Error())
+	}
+	updatedRoute := strings.Replace(string(routeBytes), `"simpleRoute"`, `"updatedRoute"`, 1)
+	// if-match on a route that does not exist yet
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, path, bytes.NewReader(routeBytes))
+	r.Header.Set(HeaderIfMatch, `"*"`)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Conditional add of new route does not return 412. Instead, returns %d\n", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, path, bytes.NewReader(routeBytes))
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Adding route does not return 200. Instead, returns %d\n", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, path, nil)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	etag := w.Header().Get(HeaderETag)
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Getting route does not return 200 with etag. Instead, returns %d %s\n", w.Code, etag)
+	}
+	// a stale etag is rejected and leaves the route untouched
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, path, strings.NewReader(updatedRoute))
+	r.Header.Set(HeaderIfMatch, `"stale"`)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Update with stale etag does not return 412. Instead, returns %d\n", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodDelete, path, nil)
+	r.Header.Set(HeaderIfMatch, `"stale"`)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Delete with stale etag does not return 412. Instead, returns %d\n", w.Code)
+	}
+	routeConfig, err := runtime.routingTableManager.GetRoute(context.Background(), tenant.Id{OrgId: "myorg", AppId: "myapp"}, "r100")
+	if err != nil {
+		t.Fatalf("cannot get route: %s", err.Error())
+	}
+	if routeConfig.Name != "simpleRoute" {
+		t.Fatalf("route was modified by request with stale etag: %s\n", routeConfig.Name)
+	}
+	// the current etag is accepted once
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, path, strings.NewReader(updatedRoute))
+	r.Header.Set(HeaderIfMatch, etag)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Update with current etag does not return 200. Instead, returns %d\n", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodDelete, path, nil)
+	r.Header.Set(HeaderIfMatch, etag)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Delete with outdated etag does not return 412. Instead, returns %d\n", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, path, nil)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	newEtag := w.Header().Get(HeaderETag)
+	if newEtag == etag {
+		t.Fatalf("etag did not change after update\n")
+	}
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodDelete, path, nil)
+	r.Header.Set(HeaderIfMatch, newEtag)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Delete with current etag does not return 200. Instead, returns %d\n", w.Code)
+	}
+}
+
+func TestRouteListing(t *testing.T) {
+	config, err := getConfig()
+	if err != nil {
+		t.Fatalf("cannot get config: %s", err.Error())
+	}
+	storageMgr, err := getStorageLayer(t, config, "inmemory")
+	if err != nil {
+		t.Fatalf("cannot get stroage manager: %s", err.Error())
+	}
+	runtime, err := setupRestApi(config, storageMgr, true)
+	if err != nil {
+		t.Fatalf("cannot create api manager: %s\n", err.Error())
+	}
+	runtime.deltaSyncer.StartListeningForSyncRequests()
+	defer runtime.deltaSyncer.StopListeningForSyncRequests()
+	path := "/ears/v1/orgs/myorg/applications/myapp/routes"
+	routeBytes, err := os.ReadFile("testdata/simpleRoute.json")
+	if err != nil {
+		t.Fatalf("cannot read file: %s", err.Error())
+	}
+	for _, id := range []string{"r100", "r101", "r102"} {
+		body := strings.Replace(string(routeBytes), `"r100"`, `"`+id+`"`, 1)
+		body = strings.Replace(body, `"simpleRoute"`, `"route`+id+`"`, 1)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPut, path+"/"+id, strings.NewReader(body))
+		runtime.apiManager.muxRouter.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Adding route does not return 200. Instead, returns %d\n", w.Code)
+		}
+	}
+	type pageResponse struct {
+		Items  []route.Config `json:"items"`
+		Cursor string         `json:"cursor"`
+	}
+	list := func(url string) pageResponse {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		runtime.apiManager.muxRouter.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Listing routes %s does not return 200. Instead, returns %d\n", url, w.Code)
+		}
+		var page pageResponse
+		err := json.Unmarshal(w.Body.Bytes(), &page)
+		if err != nil {
+			t.Fatalf("cannot unmarshal page: %s", err.Error())
+		}
+		return page
+	}
+	// paginate through tenant routes
+	ids := make([]string, 0)
+	page := list(path + "?limit=2")
+	if len(page.Items) != 2 || page.Cursor == "" {
+		t.Fatalf("unexpected first page %d %s\n", len(page.Items), page.Cursor)
+	}
+	ids = append(ids, page.Items[0].Id, page.Items[1].Id)
+	page = list(path + "?limit=2&cursor=" + page.Cursor)
+	if len(page.Items) != 1 || page.Cursor != "" {
+		t.Fatalf("unexpected last page %d %s\n", len(page.Items), page.Cursor)
+	}
+	ids = append(ids, page.Items[0].Id)
+	if strings.Join(ids, ",") != "r100,r101,r102" {
+		t.Fatalf("unexpected routes %v\n", ids)
+	}
+	// filters on tenant and admin listing
+	page = list(path + "?name=router101&receiverPlugin=debug")
+	if len(page.Items) != 1 || page.Items[0].Id != "r101" {
+		t.Fatalf("unexpected filtered routes %v\n", page.Items)
+	}
+	page = list("/ears/v1/routes?userId=boris&senderPlugin=debug&modifiedSince=2021-01-01T00:00:00Z")
+	if len(page.Items) != 3 {
+		t.Fatalf("unexpected number of filtered routes %d (%d)\n", len(page.Items), 3)
+	}
+	page = list("/ears/v1/routes?receiverPlugin=kafka")
+	if len(page.Items) != 0 {
+		t.Fatalf("unexpected number of filtered routes %d (%d)\n", len(page.Items), 0)
+	}
+	// admin listings only hold routes of tenants with a tenant config, with or without pagination,
+	// and unrelated query parameters do not switch to pagination
+	ghost := route.Config{Id: "r103", Name: "ghost", UserId: "boris", TenantId: tenant.Id{OrgId: "ghostorg", AppId: "ghostapp"},
+		Receiver: route.PluginConfig{Plugin: "debug"}, Sender: route.PluginConfig{Plugin: "debug"}}
+	err = storageMgr.SetRoute(context.Background(), ghost)
+	if err != nil {
+		t.Fatalf("cannot store route: %s", err.Error())
+	}
+	for _, url := range []string{"/ears/v1/routes", "/ears/v1/routes?limit=0", "/ears/v1/routes?nocache=1"} {
+		page = list(url)
+		if len(page.Items) != 3 || page.Cursor != "" {
+			t.Fatalf("unexpected admin listing %s %d %s\n", url, len(page.Items), page.Cursor)
+		}
+	}
+	for _, url := range []string{path + "?limit=-1", path + "?modifiedSince=yesterday", path + "?cursor=!"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		runtime.apiManager.muxRouter.ServeHTTP(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("Listing routes %s does not return 400. Instead, returns %d\n", url, w.Code)
+		}
+	}
+}
+
+func TestCluster(t *testing.T) {
+	config, err := getConfig()
+	if err != nil {
+		t.Fatalf("cannot get config: %s", err.Error())
+	}
+	storageMgr, err := getStorageLayer(t, config, "inmemory")
+	if err != nil {
+		t.Fatalf("cannot get stroage manager: %s", err.Error())
+	}
+	runtime, err := setupRestApi(config, storageMgr, true)
+	if err != nil {
+		t.Fatalf("cannot create api manager: %s\n", err.Error())
+	}
+	runtime.deltaSyncer.StartListeningForSyncRequests()
+	defer runtime.deltaSyncer.StopListeningForSyncRequests()
+	peer, err := setupRestApi(config, storageMgr, true)
+	if err != nil {
+		t.Fatalf("cannot create api manager: %s\n", err.Error())
+	}
+	peer.deltaSyncer.StartListeningForSyncRequests()
+	defer peer.deltaSyncer.StopListeningForSyncRequests()
+	peer.apiManager.clusterReporter.Start()
+	defer peer.apiManager.clusterReporter.Stop()
+	routeBytes, err := os.ReadFile("testdata/simpleRoute.json")
+	if err != nil {
+		t.Fatalf("cannot read file: %s", err.Error())
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/ears/v1/orgs/myorg/applications/myapp/routes/r100", bytes.NewReader(routeBytes))
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Adding route does not return 200. Instead, returns %d\n", w.Code)
+	}
+	defer runtime.routingTableManager.RemoveRoute(context.Background(), myTid, "r100")
+	// the route reaches the peer through the delta syncer, the peer heartbeat may lag behind
+	time.Sleep(500 * time.Millisecond)
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/ears/v1/cluster", nil)
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Getting cluster does not return 200. Instead, returns %d\n", w.Code)
+	}
+	var resp struct {
+		Items []cluster.InstanceStatus `json:"items"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatalf("cannot unmarshal response %s into json %s", w.Body.String(), err.Error())
+	}
+	instances := make(map[string]cluster.InstanceStatus)
+	for _, instance := range resp.Items {
+		instances[instance.InstanceId] = instance
+	}
+	self, ok := instances[runtime.deltaSyncer.GetInstanceId()]
+	if !ok {
+		t.Fatalf("instance missing in cluster: %s\n", w.Body.String())
+	}
+	if self.RouteCount != 1 || !self.Synchronized || self.LastHeartbeat == 0 || self.StartTime == 0 {
+		t.Fatalf("unexpected instance status %+v\n", self)
+	}
+	_, ok = instances[peer.deltaSyncer.GetInstanceId()]
+	if !ok {
+		t.Fatalf("peer instance missing in cluster: %s\n", w.Body.String())
+	}
+}
+
+func TestRoutePlacement(t *testing.T) {
+	config, err := getConfig()
+	if err != nil {
+		t.Fatalf("cannot get config: %s", err.Error())
+	}
+	storageMgr, err := getStorageLayer(t, config, "inmemory")
+	if err != nil {
+		t.Fatalf("cannot get stroage manager: %s", err.Error())
+	}
+	runtime, err := setupRestApi(config, storageMgr, true)
+	if err != nil {
+		t.Fatalf("cannot create api manager: %s\n", err.Error())
+	}
+	runtime.deltaSyncer.StartListeningForSyncRequests()
+	defer runtime.deltaSyncer.StopListeningForSyncRequests()
+	peer, err := setupRestApi(config, storageMgr, true)
+	if err != nil {
+		t.Fatalf("cannot create api manager: %s\n", err.Error())
+	}
+	peer.deltaSyncer.StartListeningForSyncRequests()
+	defer peer.deltaSyncer.StopListeningForSyncRequests()
+	routeBytes, err := os.ReadFile("testdata/simpleRoute.json")
+	if err != nil {
+		t.Fatalf("cannot read file: %s", err.Error())
+	}
+	var routeConfig route.Config
+	err = json.Unmarshal(routeBytes, &routeConfig)
+	if err != nil {
+		t.Fatalf("cannot unmarshal route: %s", err.Error())
+	}
+	routeConfig.Placement = &route.Placement{Policy: route.PlacementSingle}
+	routeBytes, err = json.Marshal(routeConfig)
+	if err != nil {
+		t.Fatalf("cannot marshal route: %s", err.Error())
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/ears/v1/orgs/myorg/applications/myapp/routes/r100", bytes.NewReader(routeBytes))
+	runtime.apiManager.muxRouter.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Adding route does not return 200. Instead, returns %d %s\n", w.Code, w.Body.String())
+	}
+	defer runtime.routingTableManager.RemoveRoute(context.Background(), myTid, "r100")
+	// the route reaches the peer through the delta syncer
+	time.Sleep(500 * time.Millisecond)
+	storedRoute, err := runtime.routingTableManager.GetRoute(context.Background(), myTid, "r100")
+	if err != nil {
+		t.Fatalf("cannot get route: %s", err.Error())
+	}
+	isRunning := func(rt *EarsRuntime) bool {
+		routes, err := rt.routingTableManager.GetAllRegisteredRoutes()
+		if err != nil {
+			t.Fatalf("cannot get registered routes: %s", err.Error())
+		}
+		for _, r := range routes {
+			if r.Id == "r100" {
+				return true
+			}
+		}
+		return false
+	}
+	isPlaced := func(rt *EarsRuntime) bool {
+		members, err := rt.deltaSyncer.GetMembers(context.Background())
+		if err != nil {
+			t.Fatalf("cannot get members: %s", err.Error())
+		}
+		return storedRoute.IsPlacedOn(context.Background(), rt.deltaSyncer.GetInstanceId(), members)
+	}
+	// other tests may leave ears instances behind so neither of the two may run the route
+	if isPlaced(runtime) && isPlaced(peer) {
+		t.Fatalf("route placed on both instances")
+	}
+	for _, rt := range []*EarsRuntime{runtime, peer} {
+		if isRunning(rt) != isPlaced(rt) {
+			t.Fatalf("route running on %s is %t, placed is %t", rt.deltaSyncer.GetInstanceId(), isRunning(rt), isPlaced(rt))
+		}
+	}
+	// the route fails over when the peer goes away, but not before the peer has stopped it
+	peerRunning := isRunning(peer)
+	peer.deltaSyncer.StopListeningForSyncRequests()
+	_, err = runtime.routingTableManager.SynchronizeAllRoutes()
+	if err != nil {
+		t.Fatalf("cannot synchronize routes: %s", err.Error())
+	}
+	if peerRunning && isRunning(runtime) {
+		t.Fatalf("route running on both instances")
+	}
+	peer.routingTableManager.UnregisterAllRoutes()
+	_, err = runtime.routingTableManager.SynchronizeAllRoutes()
+	if err != nil {
+		t.Fatalf("cannot synchronize routes: %s", err.Error())
+	}
+	if isRunning(runtime) != isPlaced(runtime) {
+		t.Fatalf("route running after failover is %t, placed is %t", isRunning(runtime), isPlaced(runtime))
+	}
+}
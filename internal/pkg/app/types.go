@@ -25,6 +25,8 @@ import (
 const (
 	HeaderTraceId  = "X-B3-TraceId"
 	HeaderTenantId = "Application-Id"
+	HeaderETag     = "ETag"
+	HeaderIfMatch  = "If-Match"
 )
 
 // #######################################################
@@ -45,9 +47,10 @@ type Response struct {
 	Status  *Status  `json:"status,omitempty" xml:"status,omitempty"`
 	Tracing *Tracing `json:"tx,omitempty" xml:"tx,omitempty"`
 
-	Item  interface{} `json:"item,omitempty" xml:"item,omitempty"`
-	Items interface{} `json:"items,omitempty" xml:"items,omitempty"`
-	Data  interface{} `json:"data,omitempty" xml:"data,omitempty"`
+	Item   interface{} `json:"item,omitempty" xml:"item,omitempty"`
+	Items  interface{} `json:"items,omitempty" xml:"items,omitempty"`
+	Data   interface{} `json:"data,omitempty" xml:"data,omitempty"`
+	Cursor string      `json:"cursor,omitempty" xml:"cursor,omitempty"`
 }
 
 func (r Response) Respond(ctx context.Context, w http.ResponseWriter) {
@@ -91,6 +94,17 @@ func ItemsResponse(item interface{}) Response {
 	}
 }
 
+// PageResponse is an items response for one page of a paginated listing
+func PageResponse(items interface{}, cursor string) Response {
+	return Response{
+		Status: &Status{
+			Code: http.StatusOK,
+		},
+		Items:  items,
+		Cursor: cursor,
+	}
+}
+
 func SimpleResponse(ctx context.Context) Response {
 	return Response{
 		Status: &Status{
@@ -0,0 +1,188 @@
+// Copyright 2020 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/rs/zerolog"
+	"github.com/xmidt-org/ears/internal/pkg/quota"
+	"github.com/xmidt-org/ears/internal/pkg/syncer"
+	"github.com/xmidt-org/ears/internal/pkg/tablemgr"
+	"github.com/xmidt-org/ears/pkg/app"
+	"go.uber.org/fx"
+	"sort"
+	"sync"
+	"time"
+)
+
+// every instance publishes its status this often. Heartbeats expire after
+// heartbeatTTL so that instances which are gone drop out of the cluster
+const HeartbeatInterval = 10 * time.Second
+const heartbeatTTL = 3 * HeartbeatInterval
+
+// InstanceStatus is the status of one ears instance as published with its heartbeat
+type InstanceStatus struct {
+	InstanceId     string                `json:"instanceId"`
+	Version        string                `json:"version"`
+	StartTime      int64                 `json:"startTime"`      // unix time in seconds
+	Uptime         int64                 `json:"uptime"`         // seconds
+	LastHeartbeat  int64                 `json:"lastHeartbeat"`  // unix time in seconds
+	RouteCount     int                   `json:"routeCount"`     // routes registered and running on the instance
+	Synchronized   bool                  `json:"synchronized"`   // routing table of the instance is up to date
+	AppliedVersion int64                 `json:"appliedVersion"` // version of the latest routing table change applied
+	QuotaShares    []quota.InstanceShare `json:"quotaShares"`
+}
+
+// Reporter publishes the status of this instance as heartbeat and collects the
+// heartbeats of all instances in the cluster
+type Reporter struct {
+	sync.Mutex
+	syncer          syncer.DeltaSyncer
+	routingTableMgr tablemgr.RoutingTableManager
+	quotaManager    *quota.QuotaManager
+	logger          *zerolog.Logger
+	startTime       time.Time
+	done            chan struct{}
+}
+
+func NewReporter(deltaSyncer syncer.DeltaSyncer, routingTableMgr tablemgr.RoutingTableManager, quotaManager *quota.QuotaManager, logger *zerolog.Logger) *Reporter {
+	return &Reporter{
+		syncer:          deltaSyncer,
+		routingTableMgr: routingTableMgr,
+		quotaManager:    quotaManager,
+		logger:          logger,
+		startTime:       time.Now(),
+	}
+}
+
+func SetupReporter(lifecycle fx.Lifecycle, logger *zerolog.Logger, reporter *Reporter) error {
+	lifecycle.Append(
+		fx.Hook{
+			OnStart: func(context.Context) error {
+				reporter.Start()
+				logger.Info().Msg("Cluster Reporter Started")
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				reporter.Stop()
+				logger.Info().Msg("Cluster Reporter Stopped")
+				return nil
+			},
+		},
+	)
+	return nil
+}
+
+// Start publishes a heartbeat right away and then every HeartbeatInterval
+func (r *Reporter) Start() {
+	r.Lock()
+	defer r.Unlock()
+	if r.done != nil {
+		return
+	}
+	done := make(chan struct{})
+	r.done = done
+	go func() {
+		ticker := time.NewTicker(HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			r.publishHeartbeat(context.Background())
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (r *Reporter) Stop() {
+	r.Lock()
+	defer r.Unlock()
+	if r.done != nil {
+		close(r.done)
+		r.done = nil
+	}
+}
+
+// Status gets the current status of this instance
+func (r *Reporter) Status(ctx context.Context) InstanceStatus {
+	now := time.Now()
+	status := InstanceStatus{
+		InstanceId:     r.syncer.GetInstanceId(),
+		Version:        app.Version,
+		StartTime:      r.startTime.Unix(),
+		Uptime:         int64(now.Sub(r.startTime).Seconds()),
+		LastHeartbeat:  now.Unix(),
+		AppliedVersion: r.syncer.GetAppliedVersion(),
+		QuotaShares:    []quota.InstanceShare{},
+	}
+	routes, err := r.routingTableMgr.GetAllRegisteredRoutes()
+	if err != nil {
+		r.logger.Error().Str("op", "Status").Msg(err.Error())
+	}
+	status.RouteCount = len(routes)
+	status.Synchronized, err = r.routingTableMgr.LastSynchronized()
+	if err != nil {
+		r.logger.Error().Str("op", "Status").Msg(err.Error())
+		status.Synchronized = false
+	}
+	if r.quotaManager != nil {
+		status.QuotaShares = r.quotaManager.InstanceShares()
+	}
+	return status
+}
+
+func (r *Reporter) publishHeartbeat(ctx context.Context) {
+	buf, err := json.Marshal(r.Status(ctx))
+	if err != nil {
+		r.logger.Error().Str("op", "publishHeartbeat").Msg(err.Error())
+		return
+	}
+	err = r.syncer.PublishHeartbeat(ctx, buf, heartbeatTTL)
+	if err != nil {
+		r.logger.Error().Str("op", "publishHeartbeat").Msg(err.Error())
+	}
+}
+
+// Instances gets the status of all instances with a live heartbeat. The status of this
+// instance is always current, the status of other instances is as of their last heartbeat.
+func (r *Reporter) Instances(ctx context.Context) ([]InstanceStatus, error) {
+	heartbeats, err := r.syncer.GetHeartbeats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	self := r.Status(ctx)
+	instances := []InstanceStatus{self}
+	for id, hb := range heartbeats {
+		if id == self.InstanceId {
+			continue
+		}
+		var status InstanceStatus
+		err = json.Unmarshal(hb, &status)
+		if err != nil {
+			r.logger.Error().Str("op", "Instances").Str("instanceId", id).Msg("bad heartbeat: " + err.Error())
+			continue
+		}
+		status.Uptime = now - status.StartTime
+		instances = append(instances, status)
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].InstanceId < instances[j].InstanceId
+	})
+	return instances, nil
+}
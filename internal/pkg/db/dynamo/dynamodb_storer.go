@@ -17,7 +17,9 @@ package dynamo
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
@@ -27,6 +29,8 @@ import (
 	"github.com/xmidt-org/ears/pkg/route"
 	"github.com/xmidt-org/ears/pkg/tenant"
 	"go.opentelemetry.io/otel/semconv/v1.4.0"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -38,6 +42,7 @@ type DynamoDbStorer struct {
 type routeItem struct {
 	KeyId  string       `json:"id"`
 	Config route.Config `json:"routeConfig"`
+	ETag   string       `json:"etag,omitempty"` // etag of the route config, used in conditional writes
 }
 
 func NewDynamoDbStorer(config config.Config) (*DynamoDbStorer, error) {
@@ -56,6 +61,15 @@ func NewDynamoDbStorer(config config.Config) (*DynamoDbStorer, error) {
 }
 
 func (d *DynamoDbStorer) getRoute(ctx context.Context, tid tenant.Id, routeId string, svc *dynamodb.DynamoDB) (*route.Config, error) {
+	item, err := d.getRouteItem(ctx, tid, routeId, svc)
+	if err != nil {
+		return nil, err
+	}
+	routeConfig := item.Config
+	return &routeConfig, nil
+}
+
+func (d *DynamoDbStorer) getRouteItem(ctx context.Context, tid tenant.Id, routeId string, svc *dynamodb.DynamoDB) (*routeItem, error) {
 	input := &dynamodb.GetItemInput{
 		Key: map[string]*dynamodb.AttributeValue{
 			"id": {
@@ -76,8 +90,7 @@ func (d *DynamoDbStorer) getRoute(ctx context.Context, tid tenant.Id, routeId st
 	if err != nil {
 		return nil, &DynamoDbMarshalError{err}
 	}
-	routeConfig := item.Config
-	return &routeConfig, nil
+	return &item, nil
 }
 
 func (d *DynamoDbStorer) GetRoute(ctx context.Context, tid tenant.Id, id string) (route.Config, error) {
@@ -153,6 +166,112 @@ func (d *DynamoDbStorer) GetAllTenantRoutes(ctx context.Context, tid tenant.Id)
 	return filterRoutes, nil
 }
 
+// filterExpression translates a route filter into a scan filter expression
+func filterExpression(filter route.Filter) *conditionExpression {
+	names := map[string]*string{"#cfg": aws.String("routeConfig")}
+	values := make(map[string]*dynamodb.AttributeValue)
+	conditions := make([]string, 0)
+	add := func(path []string, op string, value *dynamodb.AttributeValue) {
+		expr := "#cfg"
+		for _, p := range path {
+			names["#"+p] = aws.String(p)
+			expr += ".#" + p
+		}
+		placeholder := fmt.Sprintf(":v%d", len(values))
+		values[placeholder] = value
+		conditions = append(conditions, expr+" "+op+" "+placeholder)
+	}
+	str := func(path []string, value string) {
+		if value != "" {
+			add(path, "=", &dynamodb.AttributeValue{S: aws.String(value)})
+		}
+	}
+	if filter.TenantId != nil {
+		str([]string{"tenant", "orgId"}, filter.TenantId.OrgId)
+		str([]string{"tenant", "appId"}, filter.TenantId.AppId)
+	}
+	str([]string{"receiver", "plugin"}, filter.ReceiverPlugin)
+	// the sender plugin is matched after the scan, filter expressions cannot look into the named senders list
+	str([]string{"name"}, filter.Name)
+	str([]string{"origin"}, filter.Origin)
+	str([]string{"userId"}, filter.UserId)
+	if filter.ModifiedSince > 0 {
+		add([]string{"modified"}, ">=", &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(filter.ModifiedSince, 10))})
+	}
+	if len(conditions) == 0 {
+		return nil
+	}
+	return &conditionExpression{
+		expression: aws.String(strings.Join(conditions, " AND ")),
+		names:      names,
+		values:     values,
+	}
+}
+
+// ListRoutes pages through the route table with a filtered scan. The cursor is the id
+// of the LastEvaluatedKey, so pages may hold fewer routes than the limit.
+func (d *DynamoDbStorer) ListRoutes(ctx context.Context, filter route.Filter, cursor string, limit int) (route.Page, error) {
+	ctx, span := db.CreateSpan(ctx, "listRoutes", semconv.DBSystemDynamoDB, rtsemconv.DBTable.String(d.tableName))
+	defer span.End()
+	page := route.Page{Routes: make([]route.Config, 0)}
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(d.region),
+	})
+	if err != nil {
+		return page, &DynamoDbNewSessionError{err}
+	}
+	svc := dynamodb.New(sess)
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(d.tableName),
+	}
+	if limit > 0 {
+		input.Limit = aws.Int64(int64(limit))
+	}
+	if cursor != "" {
+		id, err := route.DecodeCursor(cursor)
+		if err != nil {
+			return page, err
+		}
+		input.ExclusiveStartKey = map[string]*dynamodb.AttributeValue{"id": {S: aws.String(id)}}
+	}
+	if condition := filterExpression(filter); condition != nil {
+		input.FilterExpression = condition.expression
+		input.ExpressionAttributeNames = condition.names
+		input.ExpressionAttributeValues = condition.values
+	}
+	for {
+		result, err := svc.ScanWithContext(ctx, input)
+		if err != nil {
+			return page, &DynamoDbGetItemError{err}
+		}
+		for _, item := range result.Items {
+			var r routeItem
+			err = dynamodbattribute.UnmarshalMap(item, &r)
+			if err != nil {
+				return page, &DynamoDbMarshalError{err}
+			}
+			if filter.SenderPlugin != "" && !r.Config.HasSenderPlugin(filter.SenderPlugin) {
+				continue
+			}
+			page.Routes = append(page.Routes, r.Config)
+		}
+		if result.LastEvaluatedKey == nil {
+			return page, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+		if limit > 0 && len(page.Routes) >= limit {
+			break
+		}
+		if limit > 0 {
+			input.Limit = aws.Int64(int64(limit - len(page.Routes)))
+		}
+	}
+	if id := input.ExclusiveStartKey["id"]; id != nil && id.S != nil {
+		page.Cursor = route.EncodeCursor(*id.S)
+	}
+	return page, nil
+}
+
 func (d *DynamoDbStorer) setRoute(ctx context.Context, r route.Config, svc *dynamodb.DynamoDB) error {
 	//First see if the route already exists
 	oldRoute, err := d.getRoute(ctx, r.TenantId, r.Id, svc)
@@ -168,9 +287,22 @@ func (d *DynamoDbStorer) setRoute(ctx context.Context, r route.Config, svc *dyna
 		//Set created time from the old record
 		r.Created = oldRoute.Created
 	}
+	input, err := d.putRouteInput(ctx, r)
+	if err != nil {
+		return err
+	}
+	_, err = svc.PutItemWithContext(ctx, input)
+	if err != nil {
+		return &DynamoDbPutItemError{err}
+	}
+	return nil
+}
+
+func (d *DynamoDbStorer) putRouteInput(ctx context.Context, r route.Config) (*dynamodb.PutItemInput, error) {
 	route := routeItem{
 		KeyId:  r.TenantId.KeyWithRoute(r.Id),
 		Config: r,
+		ETag:   r.ETag(ctx),
 	}
 	//item, err := dynamodbattribute.MarshalMap(route)
 	av, err := dynamodbattribute.NewEncoder(func(e *dynamodbattribute.Encoder) {
@@ -179,14 +311,82 @@ func (d *DynamoDbStorer) setRoute(ctx context.Context, r route.Config, svc *dyna
 		e.EnableEmptyCollections = true
 	}).Encode(route)
 	if err != nil {
-		return &DynamoDbMarshalError{err}
+		return nil, &DynamoDbMarshalError{err}
 	}
-	input := &dynamodb.PutItemInput{
+	return &dynamodb.PutItemInput{
 		Item:      av.M,
 		TableName: aws.String(d.tableName),
+	}, nil
+}
+
+// conditionExpression is a condition or filter expression with its attribute names and values
+type conditionExpression struct {
+	expression *string
+	names      map[string]*string
+	values     map[string]*dynamodb.AttributeValue
+}
+
+// ifMatchCondition checks the etag of a stored route and returns a condition
+// which makes sure the route has not been modified between the check and the write.
+// Routes written before etags were stored are guarded by their modified time instead.
+func (d *DynamoDbStorer) ifMatchCondition(ctx context.Context, tid tenant.Id, id string, etag string, svc *dynamodb.DynamoDB) (*routeItem, *conditionExpression, error) {
+	item, err := d.getRouteItem(ctx, tid, id, svc)
+	if err != nil {
+		var notFoundErr *route.RouteNotFoundError
+		if errors.As(err, &notFoundErr) {
+			return nil, nil, &route.PreconditionFailedError{TenantId: tid, RouteId: id, ETag: etag}
+		}
+		return nil, nil, err
 	}
-	_, err = svc.PutItemWithContext(ctx, input)
+	if !item.Config.MatchesETag(ctx, etag) {
+		return nil, nil, &route.PreconditionFailedError{TenantId: tid, RouteId: id, ETag: etag}
+	}
+	if item.ETag != "" {
+		return item, &conditionExpression{
+			expression: aws.String("#etag = :etag"),
+			names:      map[string]*string{"#etag": aws.String("etag")},
+			values:     map[string]*dynamodb.AttributeValue{":etag": {S: aws.String(item.ETag)}},
+		}, nil
+	}
+	return item, &conditionExpression{
+		expression: aws.String("attribute_exists(id) AND attribute_not_exists(#etag) AND #cfg.#modified = :modified"),
+		names:      map[string]*string{"#etag": aws.String("etag"), "#cfg": aws.String("routeConfig"), "#modified": aws.String("modified")},
+		values:     map[string]*dynamodb.AttributeValue{":modified": {N: aws.String(strconv.FormatInt(item.Config.Modified, 10))}},
+	}, nil
+}
+
+func isConditionalCheckFailed(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
+
+func (d *DynamoDbStorer) SetRouteIfMatch(ctx context.Context, r route.Config, etag string) error {
+	ctx, span := db.CreateSpan(ctx, "storeRouteIfMatch", semconv.DBSystemDynamoDB, rtsemconv.DBTable.String(d.tableName))
+	defer span.End()
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(d.region),
+	})
 	if err != nil {
+		return &DynamoDbNewSessionError{err}
+	}
+	svc := dynamodb.New(sess)
+	existing, condition, err := d.ifMatchCondition(ctx, r.TenantId, r.Id, etag, svc)
+	if err != nil {
+		return err
+	}
+	r.Modified = time.Now().Unix()
+	r.Created = existing.Config.Created
+	input, err := d.putRouteInput(ctx, r)
+	if err != nil {
+		return err
+	}
+	input.ConditionExpression = condition.expression
+	input.ExpressionAttributeNames = condition.names
+	input.ExpressionAttributeValues = condition.values
+	_, err = svc.PutItemWithContext(ctx, input)
+	if isConditionalCheckFailed(err) {
+		return &route.PreconditionFailedError{TenantId: r.TenantId, RouteId: r.Id, ETag: etag}
+	} else if err != nil {
 		return &DynamoDbPutItemError{err}
 	}
 	return nil
@@ -261,6 +461,40 @@ func (d *DynamoDbStorer) DeleteRoute(ctx context.Context, tid tenant.Id, id stri
 	return d.deleteRoute(ctx, tid, id, svc)
 }
 
+func (d *DynamoDbStorer) DeleteRouteIfMatch(ctx context.Context, tid tenant.Id, id string, etag string) error {
+	ctx, span := db.CreateSpan(ctx, "deleteRouteIfMatch", semconv.DBSystemDynamoDB, rtsemconv.DBTable.String(d.tableName))
+	defer span.End()
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(d.region),
+	})
+	if err != nil {
+		return &DynamoDbNewSessionError{err}
+	}
+	svc := dynamodb.New(sess)
+	_, condition, err := d.ifMatchCondition(ctx, tid, id, etag, svc)
+	if err != nil {
+		return err
+	}
+	input := &dynamodb.DeleteItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(tid.KeyWithRoute(id)),
+			},
+		},
+		TableName:                 aws.String(d.tableName),
+		ConditionExpression:       condition.expression,
+		ExpressionAttributeNames:  condition.names,
+		ExpressionAttributeValues: condition.values,
+	}
+	_, err = svc.DeleteItemWithContext(ctx, input)
+	if isConditionalCheckFailed(err) {
+		return &route.PreconditionFailedError{TenantId: tid, RouteId: id, ETag: etag}
+	} else if err != nil {
+		return &DynamoDbDeleteItemError{err}
+	}
+	return nil
+}
+
 func (d *DynamoDbStorer) DeleteRoutes(ctx context.Context, tid tenant.Id, ids []string) error {
 	ctx, span := db.CreateSpan(ctx, "deleteRoutes", semconv.DBSystemDynamoDB, rtsemconv.DBTable.String(d.tableName))
 	defer span.End()
@@ -36,4 +36,6 @@ func TestDynamoRouteStorer(t *testing.T) {
 		t.Fatalf("Error instantiate dynamodb %s\n", err.Error())
 	}
 	testRouteStorer(s, t)
+	testRouteStorerIfMatch(s, t)
+	testRouteStorerListing(s, t)
 }
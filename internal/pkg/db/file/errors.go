@@ -0,0 +1,47 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import "strconv"
+
+type MissingConfigError struct {
+	configName string
+}
+
+func (e *MissingConfigError) Error() string {
+	return "MissingConfigError (configName=" + e.configName + ")"
+}
+
+type FileStorageError struct {
+	Path   string
+	Source error
+}
+
+func (e *FileStorageError) Error() string {
+	return "FileStorageError (path=" + e.Path + "): " + e.Source.Error()
+}
+
+func (e *FileStorageError) Unwrap() error {
+	return e.Source
+}
+
+type CorruptLogError struct {
+	Path string
+	Line int
+}
+
+func (e *CorruptLogError) Error() string {
+	return "CorruptLogError (path=" + e.Path + ", line=" + strconv.Itoa(e.Line) + ")"
+}
@@ -0,0 +1,198 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	opSet    = "set"
+	opDelete = "del"
+
+	// a log is compacted once it holds more than minCompactRecords records and
+	// more than twice as many records as there are live keys
+	minCompactRecords = 1000
+)
+
+// record is a single line in the append-only log
+type record struct {
+	Op    string          `json:"op"`
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// logFile is the part of *os.File the journal uses
+type logFile interface {
+	io.ReadWriteSeeker
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+// journal is an append-only log of json records, one per line. The current
+// state is rebuilt by replaying the log on startup. Every append is synced to
+// disk before it returns. The journal is not safe for concurrent use, callers
+// have to hold their own lock.
+type journal struct {
+	path    string
+	file    logFile
+	records int
+}
+
+// openJournal opens (or creates) the log at path and replays all records through apply.
+// A record that cannot be parsed or applied fails the open with a CorruptLogError.
+// A trailing partial record, left behind by a crash in the middle of a write, is truncated.
+func openJournal(path string, apply func(r record) error) (*journal, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return nil, &FileStorageError{path, err}
+		}
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, &FileStorageError{path, err}
+	}
+	j := &journal{path: path, file: f}
+	offset, err := j.replay(apply)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	err = f.Truncate(offset)
+	if err != nil {
+		f.Close()
+		return nil, &FileStorageError{path, err}
+	}
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		f.Close()
+		return nil, &FileStorageError{path, err}
+	}
+	return j, nil
+}
+
+// replay applies all complete records and returns the offset after the last one
+func (j *journal) replay(apply func(r record) error) (int64, error) {
+	reader := bufio.NewReader(j.file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// anything left without a newline is an incomplete write
+			return offset, nil
+		} else if err != nil {
+			return 0, &FileStorageError{j.path, err}
+		}
+		j.records++
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) > 0 {
+			var r record
+			err = json.Unmarshal(trimmed, &r)
+			if err == nil {
+				err = apply(r)
+			}
+			if err != nil {
+				return 0, &CorruptLogError{j.path, j.records}
+			}
+		}
+		offset += int64(len(line))
+	}
+}
+
+func (j *journal) append(records ...record) error {
+	var buf bytes.Buffer
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return &FileStorageError{j.path, err}
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	offset, err := j.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return &FileStorageError{j.path, err}
+	}
+	_, err = j.file.Write(buf.Bytes())
+	if err == nil {
+		err = j.file.Sync()
+	}
+	if err != nil {
+		// drop a partial write so that later records do not follow a corrupt line
+		j.rollback(offset)
+		return &FileStorageError{j.path, err}
+	}
+	j.records += len(records)
+	return nil
+}
+
+// rollback truncates the log to offset and moves the write position back to it
+func (j *journal) rollback(offset int64) {
+	j.file.Truncate(offset)
+	j.file.Seek(offset, io.SeekStart)
+}
+
+func (j *journal) needsCompaction(live int) bool {
+	return j.records > minCompactRecords && j.records > 2*live
+}
+
+// compact replaces the log with a snapshot of the live records. The snapshot
+// is written to a temporary file first and then renamed over the log so that
+// a crash during compaction leaves either the old or the new log behind.
+func (j *journal) compact(snapshot []record) error {
+	tmpPath := j.path + ".compact"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return &FileStorageError{tmpPath, err}
+	}
+	w := bufio.NewWriter(tmp)
+	for _, r := range snapshot {
+		line, err := json.Marshal(r)
+		if err != nil {
+			tmp.Close()
+			return &FileStorageError{tmpPath, err}
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	err = w.Flush()
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		tmp.Close()
+		return &FileStorageError{tmpPath, err}
+	}
+	err = os.Rename(tmpPath, j.path)
+	if err != nil {
+		tmp.Close()
+		return &FileStorageError{j.path, err}
+	}
+	j.file.Close()
+	j.file = tmp
+	j.records = len(snapshot)
+	return nil
+}
+
+func (j *journal) close() error {
+	return j.file.Close()
+}
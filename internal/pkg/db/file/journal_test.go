@@ -0,0 +1,123 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestJournalCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	state := make(map[string]string)
+	apply := func(r record) error {
+		if r.Op == opDelete {
+			delete(state, r.Key)
+		} else {
+			state[r.Key] = string(r.Value)
+		}
+		return nil
+	}
+	j, err := openJournal(path, apply)
+	if err != nil {
+		t.Fatalf("openJournal error: %s\n", err.Error())
+	}
+	for i := 0; i < 5; i++ {
+		err = j.append(record{Op: opSet, Key: "a", Value: []byte(`1`)}, record{Op: opSet, Key: "b", Value: []byte(`2`)})
+		if err != nil {
+			t.Fatalf("append error: %s\n", err.Error())
+		}
+	}
+	err = j.append(record{Op: opDelete, Key: "b"})
+	if err != nil {
+		t.Fatalf("append error: %s\n", err.Error())
+	}
+	if j.records != 11 {
+		t.Fatalf("Expect 11 records but get %d instead\n", j.records)
+	}
+	err = j.compact([]record{{Op: opSet, Key: "a", Value: []byte(`1`)}})
+	if err != nil {
+		t.Fatalf("compact error: %s\n", err.Error())
+	}
+	err = j.append(record{Op: opSet, Key: "c", Value: []byte(`3`)})
+	if err != nil {
+		t.Fatalf("append error: %s\n", err.Error())
+	}
+	j.close()
+
+	state = make(map[string]string)
+	j, err = openJournal(path, apply)
+	if err != nil {
+		t.Fatalf("openJournal error: %s\n", err.Error())
+	}
+	defer j.close()
+	if j.records != 2 || len(state) != 2 || state["a"] != "1" || state["c"] != "3" {
+		t.Fatalf("Unexpected state after compaction %d %v\n", j.records, state)
+	}
+}
+
+// failingFile writes half of the next buffer and then fails, like a full disk
+type failingFile struct {
+	logFile
+	fail bool
+}
+
+func (f *failingFile) Write(p []byte) (int, error) {
+	if f.fail {
+		f.fail = false
+		n, _ := f.logFile.Write(p[:len(p)/2])
+		return n, syscall.ENOSPC
+	}
+	return f.logFile.Write(p)
+}
+
+func TestJournalFailedAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	state := make(map[string]string)
+	apply := func(r record) error {
+		state[r.Key] = string(r.Value)
+		return nil
+	}
+	j, err := openJournal(path, apply)
+	if err != nil {
+		t.Fatalf("openJournal error: %s\n", err.Error())
+	}
+	err = j.append(record{Op: opSet, Key: "a", Value: []byte(`1`)})
+	if err != nil {
+		t.Fatalf("append error: %s\n", err.Error())
+	}
+	f := &failingFile{logFile: j.file, fail: true}
+	j.file = f
+	err = j.append(record{Op: opSet, Key: "b", Value: []byte(`"a longer value that is cut in half"`)})
+	if err == nil {
+		t.Fatalf("Expect append to fail\n")
+	}
+	err = j.append(record{Op: opSet, Key: "c", Value: []byte(`3`)})
+	if err != nil {
+		t.Fatalf("append error: %s\n", err.Error())
+	}
+	j.close()
+
+	state = make(map[string]string)
+	j, err = openJournal(path, apply)
+	if err != nil {
+		t.Fatalf("openJournal error: %s\n", err.Error())
+	}
+	defer j.close()
+	if j.records != 2 || len(state) != 2 || state["a"] != "1" || state["c"] != "3" {
+		t.Fatalf("Unexpected state after failed append %d %v\n", j.records, state)
+	}
+}
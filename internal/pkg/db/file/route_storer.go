@@ -0,0 +1,251 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/xmidt-org/ears/internal/pkg/config"
+	"github.com/xmidt-org/ears/internal/pkg/db"
+	"github.com/xmidt-org/ears/internal/pkg/rtsemconv"
+	"github.com/xmidt-org/ears/pkg/route"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"go.opentelemetry.io/otel/semconv/v1.4.0"
+	"sync"
+	"time"
+)
+
+// RouteStorer persists routes in an append-only json log on local disk.
+// It is meant for single node deployments without access to redis or dynamodb.
+type RouteStorer struct {
+	path    string
+	routes  map[string]route.Config
+	journal *journal
+	lock    *sync.RWMutex
+}
+
+func NewRouteStorer(config config.Config) (*RouteStorer, error) {
+	path := config.GetString("ears.storage.route.path")
+	if path == "" {
+		return nil, &MissingConfigError{"ears.storage.route.path"}
+	}
+	s := &RouteStorer{
+		path:   path,
+		routes: make(map[string]route.Config),
+		lock:   &sync.RWMutex{},
+	}
+	j, err := openJournal(path, s.apply)
+	if err != nil {
+		return nil, err
+	}
+	s.journal = j
+	return s, nil
+}
+
+func (s *RouteStorer) apply(r record) error {
+	switch r.Op {
+	case opSet:
+		var config route.Config
+		err := json.Unmarshal(r.Value, &config)
+		if err != nil {
+			return err
+		}
+		s.routes[r.Key] = config
+	case opDelete:
+		delete(s.routes, r.Key)
+	}
+	return nil
+}
+
+func (s *RouteStorer) span(ctx context.Context, op string) (context.Context, func()) {
+	ctx, span := db.CreateSpan(ctx, op, rtsemconv.DBSystemFile, semconv.DBConnectionStringKey.String(s.path))
+	return ctx, func() { span.End() }
+}
+
+func (s *RouteStorer) GetAllRoutes(ctx context.Context) ([]route.Config, error) {
+	_, end := s.span(ctx, "getRoutes")
+	defer end()
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	routes := make([]route.Config, 0, len(s.routes))
+	for _, r := range s.routes {
+		routes = append(routes, r)
+	}
+	return routes, nil
+}
+
+func (s *RouteStorer) GetRoute(ctx context.Context, tid tenant.Id, id string) (route.Config, error) {
+	_, end := s.span(ctx, "getRoute")
+	defer end()
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	r, ok := s.routes[tid.KeyWithRoute(id)]
+	if !ok {
+		return route.Config{}, &route.RouteNotFoundError{TenantId: tid, RouteId: id}
+	}
+	return r, nil
+}
+
+func (s *RouteStorer) GetAllTenantRoutes(ctx context.Context, id tenant.Id) ([]route.Config, error) {
+	_, end := s.span(ctx, "getTenantRoutes")
+	defer end()
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	routes := make([]route.Config, 0)
+	for _, r := range s.routes {
+		if r.TenantId.Equal(id) {
+			routes = append(routes, r)
+		}
+	}
+	return routes, nil
+}
+
+func (s *RouteStorer) ListRoutes(ctx context.Context, filter route.Filter, cursor string, limit int) (route.Page, error) {
+	_, end := s.span(ctx, "listRoutes")
+	defer end()
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return db.PageRoutes(s.routes, filter, cursor, limit)
+}
+
+func (s *RouteStorer) SetRoute(ctx context.Context, r route.Config) error {
+	_, end := s.span(ctx, "storeRoute")
+	defer end()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.setRoutes([]route.Config{r})
+}
+
+func (s *RouteStorer) SetRoutes(ctx context.Context, routes []route.Config) error {
+	_, end := s.span(ctx, "storeRoutes")
+	defer end()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.setRoutes(routes)
+}
+
+func (s *RouteStorer) SetRouteIfMatch(ctx context.Context, r route.Config, etag string) error {
+	ctx, end := s.span(ctx, "storeRouteIfMatch")
+	defer end()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	existing, ok := s.routes[r.TenantId.KeyWithRoute(r.Id)]
+	if !ok || !existing.MatchesETag(ctx, etag) {
+		return &route.PreconditionFailedError{TenantId: r.TenantId, RouteId: r.Id, ETag: etag}
+	}
+	return s.setRoutes([]route.Config{r})
+}
+
+func (s *RouteStorer) setRoutes(routes []route.Config) error {
+	now := time.Now().Unix()
+	records := make([]record, 0, len(routes))
+	updated := make(map[string]route.Config)
+	for _, r := range routes {
+		key := r.TenantId.KeyWithRoute(r.Id)
+		r.Modified = now
+		if existing, ok := updated[key]; ok {
+			r.Created = existing.Created
+		} else if existing, ok := s.routes[key]; ok {
+			r.Created = existing.Created
+		} else {
+			r.Created = now
+		}
+		val, err := json.Marshal(r)
+		if err != nil {
+			return &FileStorageError{s.path, err}
+		}
+		records = append(records, record{Op: opSet, Key: key, Value: val})
+		updated[key] = r
+	}
+	err := s.journal.append(records...)
+	if err != nil {
+		return err
+	}
+	for key, r := range updated {
+		s.routes[key] = r
+	}
+	return s.compactIfNeeded()
+}
+
+func (s *RouteStorer) DeleteRoute(ctx context.Context, tid tenant.Id, id string) error {
+	_, end := s.span(ctx, "deleteRoute")
+	defer end()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.deleteRoutes(tid, []string{id})
+}
+
+func (s *RouteStorer) DeleteRoutes(ctx context.Context, tid tenant.Id, ids []string) error {
+	_, end := s.span(ctx, "deleteRoutes")
+	defer end()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.deleteRoutes(tid, ids)
+}
+
+func (s *RouteStorer) DeleteRouteIfMatch(ctx context.Context, tid tenant.Id, id string, etag string) error {
+	ctx, end := s.span(ctx, "deleteRouteIfMatch")
+	defer end()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	existing, ok := s.routes[tid.KeyWithRoute(id)]
+	if !ok || !existing.MatchesETag(ctx, etag) {
+		return &route.PreconditionFailedError{TenantId: tid, RouteId: id, ETag: etag}
+	}
+	return s.deleteRoutes(tid, []string{id})
+}
+
+func (s *RouteStorer) deleteRoutes(tid tenant.Id, ids []string) error {
+	records := make([]record, 0, len(ids))
+	for _, id := range ids {
+		key := tid.KeyWithRoute(id)
+		if _, ok := s.routes[key]; ok {
+			records = append(records, record{Op: opDelete, Key: key})
+		}
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	err := s.journal.append(records...)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		delete(s.routes, r.Key)
+	}
+	return s.compactIfNeeded()
+}
+
+func (s *RouteStorer) compactIfNeeded() error {
+	if !s.journal.needsCompaction(len(s.routes)) {
+		return nil
+	}
+	snapshot := make([]record, 0, len(s.routes))
+	for key, r := range s.routes {
+		val, err := json.Marshal(r)
+		if err != nil {
+			return &FileStorageError{s.path, err}
+		}
+		snapshot = append(snapshot, record{Op: opSet, Key: key, Value: val})
+	}
+	return s.journal.compact(snapshot)
+}
+
+// Close releases the underlying log file
+func (s *RouteStorer) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.journal.close()
+}
@@ -0,0 +1,159 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/xmidt-org/ears/internal/pkg/config"
+	"github.com/xmidt-org/ears/internal/pkg/db"
+	"github.com/xmidt-org/ears/internal/pkg/rtsemconv"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"go.opentelemetry.io/otel/semconv/v1.4.0"
+	"sync"
+	"time"
+)
+
+// TenantStorer persists tenant configs in an append-only json log on local disk.
+type TenantStorer struct {
+	path    string
+	configs map[string]tenant.Config
+	journal *journal
+	lock    *sync.RWMutex
+}
+
+func NewTenantStorer(config config.Config) (*TenantStorer, error) {
+	path := config.GetString("ears.storage.tenant.path")
+	if path == "" {
+		return nil, &MissingConfigError{"ears.storage.tenant.path"}
+	}
+	s := &TenantStorer{
+		path:    path,
+		configs: make(map[string]tenant.Config),
+		lock:    &sync.RWMutex{},
+	}
+	j, err := openJournal(path, s.apply)
+	if err != nil {
+		return nil, err
+	}
+	s.journal = j
+	return s, nil
+}
+
+func (s *TenantStorer) apply(r record) error {
+	switch r.Op {
+	case opSet:
+		var config tenant.Config
+		err := json.Unmarshal(r.Value, &config)
+		if err != nil {
+			return err
+		}
+		s.configs[r.Key] = config
+	case opDelete:
+		delete(s.configs, r.Key)
+	}
+	return nil
+}
+
+func (s *TenantStorer) span(ctx context.Context, op string) (context.Context, func()) {
+	ctx, span := db.CreateSpan(ctx, op, rtsemconv.DBSystemFile, semconv.DBConnectionStringKey.String(s.path))
+	return ctx, func() { span.End() }
+}
+
+func (s *TenantStorer) GetAllConfigs(ctx context.Context) ([]tenant.Config, error) {
+	_, end := s.span(ctx, "getAllTenantConfigs")
+	defer end()
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	configs := make([]tenant.Config, 0, len(s.configs))
+	for _, config := range s.configs {
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+func (s *TenantStorer) GetConfig(ctx context.Context, id tenant.Id) (*tenant.Config, error) {
+	_, end := s.span(ctx, "getTenantConfig")
+	defer end()
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	config, ok := s.configs[id.Key()]
+	if !ok {
+		return nil, &tenant.TenantNotFoundError{Tenant: id}
+	}
+	return &config, nil
+}
+
+func (s *TenantStorer) SetConfig(ctx context.Context, config tenant.Config) error {
+	_, end := s.span(ctx, "setTenantConfig")
+	defer end()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	config.Modified = time.Now().Unix()
+	val, err := json.Marshal(config)
+	if err != nil {
+		return &tenant.BadConfigError{}
+	}
+	key := config.Tenant.Key()
+	err = s.journal.append(record{Op: opSet, Key: key, Value: val})
+	if err != nil {
+		return &tenant.InternalStorageError{Wrapped: err}
+	}
+	s.configs[key] = config
+	return s.compactIfNeeded()
+}
+
+func (s *TenantStorer) DeleteConfig(ctx context.Context, id tenant.Id) error {
+	_, end := s.span(ctx, "deleteTenantConfig")
+	defer end()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := id.Key()
+	if _, ok := s.configs[key]; !ok {
+		return &tenant.TenantNotFoundError{Tenant: id}
+	}
+	err := s.journal.append(record{Op: opDelete, Key: key})
+	if err != nil {
+		return &tenant.InternalStorageError{Wrapped: err}
+	}
+	delete(s.configs, key)
+	return s.compactIfNeeded()
+}
+
+func (s *TenantStorer) compactIfNeeded() error {
+	if !s.journal.needsCompaction(len(s.configs)) {
+		return nil
+	}
+	snapshot := make([]record, 0, len(s.configs))
+	for key, config := range s.configs {
+		val, err := json.Marshal(config)
+		if err != nil {
+			return &tenant.InternalStorageError{Wrapped: err}
+		}
+		snapshot = append(snapshot, record{Op: opSet, Key: key, Value: val})
+	}
+	err := s.journal.compact(snapshot)
+	if err != nil {
+		return &tenant.InternalStorageError{Wrapped: err}
+	}
+	return nil
+}
+
+// Close releases the underlying log file
+func (s *TenantStorer) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.journal.close()
+}
@@ -0,0 +1,143 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !integration
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"github.com/spf13/viper"
+	"github.com/xmidt-org/ears/internal/pkg/config"
+	"github.com/xmidt-org/ears/internal/pkg/db/file"
+	"github.com/xmidt-org/ears/pkg/route"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func fileConfig(dir string) config.Config {
+	v := viper.New()
+	v.Set("ears.storage.route.path", filepath.Join(dir, "routes.log"))
+	v.Set("ears.storage.tenant.path", filepath.Join(dir, "tenants.log"))
+	return v
+}
+
+func TestFileRouteStorer(t *testing.T) {
+	s, err := file.NewRouteStorer(fileConfig(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Error instantiate file storer %s\n", err.Error())
+	}
+	defer s.Close()
+	testRouteStorer(s, t)
+	testRouteStorerIfMatch(s, t)
+	testRouteStorerListing(s, t)
+}
+
+func TestFileTenantStorer(t *testing.T) {
+	s, err := file.NewTenantStorer(fileConfig(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Error instantiate file storer %s\n", err.Error())
+	}
+	defer s.Close()
+	testTenantStorer(s, t)
+}
+
+func TestFileStorerRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	tid := tenant.Id{OrgId: "myOrg", AppId: "myApp"}
+
+	rs, err := file.NewRouteStorer(fileConfig(dir))
+	if err != nil {
+		t.Fatalf("Error instantiate file storer %s\n", err.Error())
+	}
+	err = rs.SetRoutes(ctx, []route.Config{
+		{Id: "r1", TenantId: tid, Name: "first"},
+		{Id: "r2", TenantId: tid, Name: "second"},
+	})
+	if err != nil {
+		t.Fatalf("SetRoutes error: %s\n", err.Error())
+	}
+	err = rs.DeleteRoute(ctx, tid, "r1")
+	if err != nil {
+		t.Fatalf("DeleteRoute error: %s\n", err.Error())
+	}
+	rs.Close()
+
+	ts, err := file.NewTenantStorer(fileConfig(dir))
+	if err != nil {
+		t.Fatalf("Error instantiate file storer %s\n", err.Error())
+	}
+	err = ts.SetConfig(ctx, tenant.Config{Tenant: tid, Quota: tenant.Quota{EventsPerSec: 10}})
+	if err != nil {
+		t.Fatalf("SetConfig error: %s\n", err.Error())
+	}
+	ts.Close()
+
+	//simulate a crash in the middle of writing a record
+	f, err := os.OpenFile(filepath.Join(dir, "routes.log"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile error: %s\n", err.Error())
+	}
+	f.WriteString(`{"op":"set","key":"partial`)
+	f.Close()
+
+	rs, err = file.NewRouteStorer(fileConfig(dir))
+	if err != nil {
+		t.Fatalf("Error reopen file storer %s\n", err.Error())
+	}
+	defer rs.Close()
+	routes, err := rs.GetAllRoutes(ctx)
+	if err != nil {
+		t.Fatalf("GetAllRoutes error: %s\n", err.Error())
+	}
+	if len(routes) != 1 || routes[0].Id != "r2" || routes[0].Created == 0 {
+		t.Fatalf("Unexpected routes after restart %+v\n", routes)
+	}
+	_, err = rs.GetRoute(ctx, tid, "r1")
+	var routeNotFound *route.RouteNotFoundError
+	if !errors.As(err, &routeNotFound) {
+		t.Fatalf("Expect RouteNotFoundError for deleted route, got %v\n", err)
+	}
+	//the partial record must be gone so that new records are appended cleanly
+	err = rs.SetRoute(ctx, route.Config{Id: "r3", TenantId: tid, Name: "third"})
+	if err != nil {
+		t.Fatalf("SetRoute error: %s\n", err.Error())
+	}
+	rs.Close()
+	rs, err = file.NewRouteStorer(fileConfig(dir))
+	if err != nil {
+		t.Fatalf("Error reopen file storer %s\n", err.Error())
+	}
+	routes, _ = rs.GetAllRoutes(ctx)
+	if len(routes) != 2 {
+		t.Fatalf("Expect 2 routes but get %d instead\n", len(routes))
+	}
+
+	ts, err = file.NewTenantStorer(fileConfig(dir))
+	if err != nil {
+		t.Fatalf("Error reopen file storer %s\n", err.Error())
+	}
+	defer ts.Close()
+	config, err := ts.GetConfig(ctx, tid)
+	if err != nil {
+		t.Fatalf("GetConfig error: %s\n", err.Error())
+	}
+	if config.Quota.EventsPerSec != 10 || config.Modified == 0 {
+		t.Fatalf("Unexpected tenant config after restart %+v\n", config)
+	}
+}
@@ -87,6 +87,22 @@ func (s *InMemoryRouteStorer) GetAllTenantRoutes(ctx context.Context, id tenant.
 	return routes, nil
 }
 
+func (s *InMemoryRouteStorer) ListRoutes(ctx context.Context, filter route.Filter, cursor string, limit int) (route.Page, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	_, span := CreateSpan(ctx, "listRoutes", rtsemconv.DBSystemInMemory)
+	defer span.End()
+	routes := make(map[string]route.Config)
+	for _, t := range s.tenants {
+		for _, r := range t {
+			if filter.TenantId == nil || r.TenantId.Equal(*filter.TenantId) {
+				routes[r.TenantId.KeyWithRoute(r.Id)] = *r
+			}
+		}
+	}
+	return PageRoutes(routes, filter, cursor, limit)
+}
+
 func (s *InMemoryRouteStorer) setRoute(r route.Config) {
 	r.Modified = time.Now().Unix()
 	var tenant map[string]*route.Config
@@ -128,6 +144,19 @@ func (s *InMemoryRouteStorer) SetRoutes(ctx context.Context, routes []route.Conf
 	return nil
 }
 
+func (s *InMemoryRouteStorer) SetRouteIfMatch(ctx context.Context, r route.Config, etag string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ctx, span := CreateSpan(ctx, "storeRouteIfMatch", rtsemconv.DBSystemInMemory)
+	defer span.End()
+	existing, ok := s.tenants[r.TenantId.Key()][r.Id]
+	if !ok || !existing.MatchesETag(ctx, etag) {
+		return &route.PreconditionFailedError{TenantId: r.TenantId, RouteId: r.Id, ETag: etag}
+	}
+	s.setRoute(r)
+	return nil
+}
+
 func (s *InMemoryRouteStorer) DeleteRoute(ctx context.Context, tid tenant.Id, id string) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -157,3 +186,16 @@ func (s *InMemoryRouteStorer) DeleteRoutes(ctx context.Context, tid tenant.Id, i
 	}
 	return nil
 }
+
+func (s *InMemoryRouteStorer) DeleteRouteIfMatch(ctx context.Context, tid tenant.Id, id string, etag string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ctx, span := CreateSpan(ctx, "deleteRouteIfMatch", rtsemconv.DBSystemInMemory)
+	defer span.End()
+	existing, ok := s.tenants[tid.Key()][id]
+	if !ok || !existing.MatchesETag(ctx, etag) {
+		return &route.PreconditionFailedError{TenantId: tid, RouteId: id, ETag: etag}
+	}
+	delete(s.tenants[tid.Key()], id)
+	return nil
+}
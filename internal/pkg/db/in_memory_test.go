@@ -24,4 +24,6 @@ import (
 func TestInMemoryRouteStorer(t *testing.T) {
 	s := db.NewInMemoryRouteStorer(nil)
 	testRouteStorer(s, t)
+	testRouteStorerIfMatch(s, t)
+	testRouteStorerListing(s, t)
 }
@@ -0,0 +1,372 @@
+// Copyright 2020 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+	"github.com/xmidt-org/ears/internal/pkg/db"
+	"github.com/xmidt-org/ears/internal/pkg/rtsemconv"
+	"github.com/xmidt-org/ears/pkg/route"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"go.opentelemetry.io/otel/semconv/v1.4.0"
+	"regexp"
+	"time"
+)
+
+type PostgresDbStorer struct {
+	client    *sql.DB
+	endpoint  string
+	tableName string
+	table     string // quoted table name
+	logger    *zerolog.Logger
+}
+
+type Config interface {
+	GetString(key string) string
+	GetInt(key string) int
+	GetBool(key string) bool
+}
+
+const (
+	defaultRouteTableName  = "ears_routes"
+	defaultTenantTableName = "ears_tenants"
+)
+
+var tableNameRegex = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+
+// open connects to postgres and creates the table unless it exists, the schema takes the quoted table name
+func open(endpoint string, tableName string, schema string) (*sql.DB, string, error) {
+	if !tableNameRegex.MatchString(tableName) {
+		return nil, "", &BadTableNameError{tableName}
+	}
+	table := pq.QuoteIdentifier(tableName)
+	client, err := sql.Open("postgres", endpoint)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not connect to postgres: %v", err)
+	}
+	_, err = client.Exec(fmt.Sprintf(schema, table))
+	if err != nil {
+		client.Close()
+		return nil, "", fmt.Errorf("could not create table %s in postgres: %v", tableName, err)
+	}
+	return client, table, nil
+}
+
+// withTx runs fn in a transaction which is committed if fn succeeds and rolled back otherwise
+func withTx(ctx context.Context, client *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := client.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction in postgres: %v", err)
+	}
+	err = fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("could not commit transaction in postgres: %v", err)
+	}
+	return nil
+}
+
+// routes are keyed by tenant.Id.KeyWithRoute so that listing in key order matches the other storers
+const routeSchema = `CREATE TABLE IF NOT EXISTS %s (
+	id     TEXT PRIMARY KEY,
+	org_id TEXT NOT NULL,
+	app_id TEXT NOT NULL,
+	config TEXT NOT NULL
+)`
+
+func NewPostgresDbStorer(config Config, logger *zerolog.Logger) (*PostgresDbStorer, error) {
+	endpoint := config.GetString("ears.storage.route.endpoint")
+	if endpoint == "" {
+		return nil, &MissingConfigError{"ears.storage.route.endpoint"}
+	}
+	tableName := config.GetString("ears.storage.route.tableName")
+	if tableName == "" {
+		tableName = defaultRouteTableName
+	}
+	client, table, err := open(endpoint, tableName, routeSchema)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info().Msg("connected to postgres storage layer, table " + tableName)
+	return &PostgresDbStorer{
+		client:    client,
+		endpoint:  endpoint,
+		tableName: tableName,
+		table:     table,
+		logger:    logger,
+	}, nil
+}
+
+func (d *PostgresDbStorer) Close() error {
+	return d.client.Close()
+}
+
+// queryRoutes runs a query returning route configs in its last column
+func (d *PostgresDbStorer) queryRoutes(ctx context.Context, query string, args ...interface{}) ([]route.Config, []string, error) {
+	rows, err := d.client.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get routes from postgres: %v", err)
+	}
+	defer rows.Close()
+	routes := make([]route.Config, 0)
+	keys := make([]string, 0)
+	for rows.Next() {
+		var key, val string
+		err = rows.Scan(&key, &val)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not get routes from postgres: %v", err)
+		}
+		var r route.Config
+		err = json.Unmarshal([]byte(val), &r)
+		if err != nil {
+			return nil, nil, err
+		}
+		routes = append(routes, r)
+		keys = append(keys, key)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get routes from postgres: %v", err)
+	}
+	return routes, keys, nil
+}
+
+func (d *PostgresDbStorer) GetRoute(ctx context.Context, tid tenant.Id, id string) (route.Config, error) {
+	_, span := db.CreateSpan(ctx, "getRoute", semconv.DBSystemPostgreSQL, rtsemconv.DBTable.String(d.tableName))
+	defer span.End()
+	r := route.Config{}
+	var val string
+	err := d.client.QueryRowContext(ctx, "SELECT config FROM "+d.table+" WHERE id = $1", tid.KeyWithRoute(id)).Scan(&val)
+	if err == sql.ErrNoRows {
+		return r, &route.RouteNotFoundError{TenantId: tid, RouteId: id}
+	} else if err != nil {
+		return r, fmt.Errorf("could not get route from postgres: %v", err)
+	}
+	err = json.Unmarshal([]byte(val), &r)
+	return r, err
+}
+
+func (d *PostgresDbStorer) GetAllRoutes(ctx context.Context) ([]route.Config, error) {
+	_, span := db.CreateSpan(ctx, "getRoutes", semconv.DBSystemPostgreSQL, rtsemconv.DBTable.String(d.tableName))
+	defer span.End()
+	routes, _, err := d.queryRoutes(ctx, "SELECT id, config FROM "+d.table)
+	if err != nil {
+		return make([]route.Config, 0), err
+	}
+	return routes, nil
+}
+
+func (d *PostgresDbStorer) GetAllTenantRoutes(ctx context.Context, tid tenant.Id) ([]route.Config, error) {
+	_, span := db.CreateSpan(ctx, "getTenantRoutes", semconv.DBSystemPostgreSQL, rtsemconv.DBTable.String(d.tableName))
+	defer span.End()
+	routes, _, err := d.queryRoutes(ctx, "SELECT id, config FROM "+d.table+" WHERE org_id = $1 AND app_id = $2", tid.OrgId, tid.AppId)
+	if err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
+// listBatchSize is the number of rows read at a time when listing routes
+const listBatchSize = 1000
+
+// ListRoutes pages through the routes in key order, the cursor is the key of the last route of
+// the previous page. Filters other than the tenant are applied to each batch of rows read.
+func (d *PostgresDbStorer) ListRoutes(ctx context.Context, filter route.Filter, cursor string, limit int) (route.Page, error) {
+	_, span := db.CreateSpan(ctx, "listRoutes", semconv.DBSystemPostgreSQL, rtsemconv.DBTable.String(d.tableName))
+	defer span.End()
+	page := route.Page{Routes: make([]route.Config, 0)}
+	after := ""
+	if cursor != "" {
+		var err error
+		after, err = route.DecodeCursor(cursor)
+		if err != nil {
+			return page, err
+		}
+	}
+	for {
+		var routes []route.Config
+		var keys []string
+		var err error
+		if filter.TenantId != nil {
+			routes, keys, err = d.queryRoutes(ctx, "SELECT id, config FROM "+d.table+" WHERE id > $1 AND org_id = $2 AND app_id = $3 ORDER BY id LIMIT $4",
+				after, filter.TenantId.OrgId, filter.TenantId.AppId, listBatchSize)
+		} else {
+			routes, keys, err = d.queryRoutes(ctx, "SELECT id, config FROM "+d.table+" WHERE id > $1 ORDER BY id LIMIT $2", after, listBatchSize)
+		}
+		if err != nil {
+			return page, err
+		}
+		for i := range routes {
+			if !filter.Matches(&routes[i]) {
+				continue
+			}
+			if limit > 0 && len(page.Routes) == limit {
+				last := page.Routes[limit-1]
+				page.Cursor = route.EncodeCursor(last.TenantId.KeyWithRoute(last.Id))
+				return page, nil
+			}
+			page.Routes = append(page.Routes, routes[i])
+		}
+		if len(keys) < listBatchSize {
+			return page, nil
+		}
+		after = keys[len(keys)-1]
+	}
+}
+
+// setRoute writes a route in a transaction, keeping the created timestamp of the existing route
+func (d *PostgresDbStorer) setRoute(ctx context.Context, tx *sql.Tx, r route.Config, created int64) error {
+	r.Modified = time.Now().Unix()
+	r.Created = created
+	if r.Created == 0 {
+		r.Created = r.Modified
+	}
+	val, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO "+d.table+" (id, org_id, app_id, config) VALUES ($1, $2, $3, $4) ON CONFLICT (id) DO UPDATE SET config = EXCLUDED.config",
+		r.TenantId.KeyWithRoute(r.Id), r.TenantId.OrgId, r.TenantId.AppId, string(val))
+	if err != nil {
+		return fmt.Errorf("could not insert route into postgres: %v", err)
+	}
+	return nil
+}
+
+// lockRoute reads a route and locks its row until the end of the transaction
+func (d *PostgresDbStorer) lockRoute(ctx context.Context, tx *sql.Tx, tid tenant.Id, id string) (*route.Config, error) {
+	var val string
+	err := tx.QueryRowContext(ctx, "SELECT config FROM "+d.table+" WHERE id = $1 FOR UPDATE", tid.KeyWithRoute(id)).Scan(&val)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get route from postgres: %v", err)
+	}
+	var r route.Config
+	err = json.Unmarshal([]byte(val), &r)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (d *PostgresDbStorer) SetRoute(ctx context.Context, r route.Config) error {
+	_, span := db.CreateSpan(ctx, "storeRoute", semconv.DBSystemPostgreSQL, rtsemconv.DBTable.String(d.tableName))
+	defer span.End()
+	if r.Id == "" {
+		return fmt.Errorf("no route to store in postgres")
+	}
+	return withTx(ctx, d.client, func(tx *sql.Tx) error {
+		existing, err := d.lockRoute(ctx, tx, r.TenantId, r.Id)
+		if err != nil {
+			return err
+		}
+		var created int64
+		if existing != nil {
+			created = existing.Created
+		}
+		return d.setRoute(ctx, tx, r, created)
+	})
+}
+
+func (d *PostgresDbStorer) SetRoutes(ctx context.Context, routes []route.Config) error {
+	_, span := db.CreateSpan(ctx, "storeRoutes", semconv.DBSystemPostgreSQL, rtsemconv.DBTable.String(d.tableName))
+	defer span.End()
+	if routes == nil {
+		return fmt.Errorf("no routes to store in postgres")
+	}
+	for _, r := range routes {
+		err := d.SetRoute(ctx, r)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *PostgresDbStorer) DeleteRoute(ctx context.Context, tid tenant.Id, id string) error {
+	_, span := db.CreateSpan(ctx, "deleteRoute", semconv.DBSystemPostgreSQL, rtsemconv.DBTable.String(d.tableName))
+	defer span.End()
+	if id == "" {
+		return fmt.Errorf("no route to delete in postgres")
+	}
+	_, err := d.client.ExecContext(ctx, "DELETE FROM "+d.table+" WHERE id = $1", tid.KeyWithRoute(id))
+	if err != nil {
+		return fmt.Errorf("could not delete route from postgres: %v", err)
+	}
+	return nil
+}
+
+func (d *PostgresDbStorer) DeleteRoutes(ctx context.Context, tid tenant.Id, ids []string) error {
+	_, span := db.CreateSpan(ctx, "deleteRoutes", semconv.DBSystemPostgreSQL, rtsemconv.DBTable.String(d.tableName))
+	defer span.End()
+	for _, id := range ids {
+		err := d.DeleteRoute(ctx, tid, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ifMatch runs write in a transaction if the stored route matches etag. The row of the route
+// stays locked until the write is committed, so concurrent conditional writes are serialized.
+func (d *PostgresDbStorer) ifMatch(ctx context.Context, tid tenant.Id, id string, etag string, write func(tx *sql.Tx, existing route.Config) error) error {
+	return withTx(ctx, d.client, func(tx *sql.Tx) error {
+		existing, err := d.lockRoute(ctx, tx, tid, id)
+		if err != nil {
+			return err
+		}
+		if existing == nil || !existing.MatchesETag(ctx, etag) {
+			return &route.PreconditionFailedError{TenantId: tid, RouteId: id, ETag: etag}
+		}
+		return write(tx, *existing)
+	})
+}
+
+func (d *PostgresDbStorer) SetRouteIfMatch(ctx context.Context, r route.Config, etag string) error {
+	_, span := db.CreateSpan(ctx, "storeRouteIfMatch", semconv.DBSystemPostgreSQL, rtsemconv.DBTable.String(d.tableName))
+	defer span.End()
+	if r.Id == "" {
+		return fmt.Errorf("no route to store in postgres")
+	}
+	return d.ifMatch(ctx, r.TenantId, r.Id, etag, func(tx *sql.Tx, existing route.Config) error {
+		return d.setRoute(ctx, tx, r, existing.Created)
+	})
+}
+
+func (d *PostgresDbStorer) DeleteRouteIfMatch(ctx context.Context, tid tenant.Id, id string, etag string) error {
+	_, span := db.CreateSpan(ctx, "deleteRouteIfMatch", semconv.DBSystemPostgreSQL, rtsemconv.DBTable.String(d.tableName))
+	defer span.End()
+	if id == "" {
+		return fmt.Errorf("no route to delete in postgres")
+	}
+	return d.ifMatch(ctx, tid, id, etag, func(tx *sql.Tx, existing route.Config) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM "+d.table+" WHERE id = $1", tid.KeyWithRoute(id))
+		if err != nil {
+			return fmt.Errorf("could not delete route from postgres: %v", err)
+		}
+		return nil
+	})
+}
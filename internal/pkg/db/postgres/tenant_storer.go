@@ -0,0 +1,146 @@
+// Copyright 2020 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog"
+	"github.com/xmidt-org/ears/internal/pkg/db"
+	"github.com/xmidt-org/ears/internal/pkg/rtsemconv"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"go.opentelemetry.io/otel/semconv/v1.4.0"
+	"time"
+)
+
+type TenantStorer struct {
+	client    *sql.DB
+	tableName string
+	table     string // quoted table name
+	logger    *zerolog.Logger
+}
+
+const tenantSchema = `CREATE TABLE IF NOT EXISTS %s (
+	id     TEXT PRIMARY KEY,
+	config TEXT NOT NULL
+)`
+
+func NewTenantStorer(config Config, logger *zerolog.Logger) (*TenantStorer, error) {
+	endpoint := config.GetString("ears.storage.tenant.endpoint")
+	if endpoint == "" {
+		return nil, &MissingConfigError{"ears.storage.tenant.endpoint"}
+	}
+	tableName := config.GetString("ears.storage.tenant.tableName")
+	if tableName == "" {
+		tableName = defaultTenantTableName
+	}
+	client, table, err := open(endpoint, tableName, tenantSchema)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info().Msg("connected to postgres tenant storage layer, table " + tableName)
+	return &TenantStorer{
+		client:    client,
+		tableName: tableName,
+		table:     table,
+		logger:    logger,
+	}, nil
+}
+
+func (s *TenantStorer) Close() error {
+	return s.client.Close()
+}
+
+func (s *TenantStorer) GetAllConfigs(ctx context.Context) ([]tenant.Config, error) {
+	_, span := db.CreateSpan(ctx, "getAllTenantConfigs", semconv.DBSystemPostgreSQL, rtsemconv.DBTable.String(s.tableName))
+	defer span.End()
+	rows, err := s.client.QueryContext(ctx, "SELECT config FROM "+s.table)
+	if err != nil {
+		return nil, &tenant.InternalStorageError{Wrapped: fmt.Errorf("could not get tenant configs from postgres: %v", err)}
+	}
+	defer rows.Close()
+	configs := make([]tenant.Config, 0)
+	for rows.Next() {
+		var val string
+		err = rows.Scan(&val)
+		if err != nil {
+			return nil, &tenant.InternalStorageError{Wrapped: fmt.Errorf("could not get tenant configs from postgres: %v", err)}
+		}
+		var config tenant.Config
+		err = json.Unmarshal([]byte(val), &config)
+		if err != nil {
+			return nil, &tenant.InternalStorageError{Wrapped: err}
+		}
+		configs = append(configs, config)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, &tenant.InternalStorageError{Wrapped: fmt.Errorf("could not get tenant configs from postgres: %v", err)}
+	}
+	return configs, nil
+}
+
+func (s *TenantStorer) GetConfig(ctx context.Context, id tenant.Id) (*tenant.Config, error) {
+	_, span := db.CreateSpan(ctx, "getTenantConfig", semconv.DBSystemPostgreSQL, rtsemconv.DBTable.String(s.tableName))
+	defer span.End()
+	var val string
+	err := s.client.QueryRowContext(ctx, "SELECT config FROM "+s.table+" WHERE id = $1", id.Key()).Scan(&val)
+	if err == sql.ErrNoRows {
+		return nil, &tenant.TenantNotFoundError{Tenant: id}
+	} else if err != nil {
+		return nil, &tenant.InternalStorageError{Wrapped: fmt.Errorf("could not get tenant config from postgres: %v", err)}
+	}
+	var config tenant.Config
+	err = json.Unmarshal([]byte(val), &config)
+	if err != nil {
+		return nil, &tenant.InternalStorageError{Wrapped: err}
+	}
+	return &config, nil
+}
+
+func (s *TenantStorer) SetConfig(ctx context.Context, config tenant.Config) error {
+	_, span := db.CreateSpan(ctx, "setTenantConfig", semconv.DBSystemPostgreSQL, rtsemconv.DBTable.String(s.tableName))
+	defer span.End()
+	config.Modified = time.Now().Unix()
+	val, err := json.Marshal(config)
+	if err != nil {
+		return &tenant.BadConfigError{}
+	}
+	_, err = s.client.ExecContext(ctx, "INSERT INTO "+s.table+" (id, config) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET config = EXCLUDED.config",
+		config.Tenant.Key(), string(val))
+	if err != nil {
+		return &tenant.InternalStorageError{Wrapped: fmt.Errorf("could not insert tenant config into postgres: %v", err)}
+	}
+	return nil
+}
+
+func (s *TenantStorer) DeleteConfig(ctx context.Context, id tenant.Id) error {
+	_, span := db.CreateSpan(ctx, "deleteTenantConfig", semconv.DBSystemPostgreSQL, rtsemconv.DBTable.String(s.tableName))
+	defer span.End()
+	result, err := s.client.ExecContext(ctx, "DELETE FROM "+s.table+" WHERE id = $1", id.Key())
+	if err != nil {
+		return &tenant.InternalStorageError{Wrapped: fmt.Errorf("could not delete tenant config from postgres: %v", err)}
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		return &tenant.InternalStorageError{Wrapped: err}
+	}
+	if num == 0 {
+		return &tenant.TenantNotFoundError{Tenant: id}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_test
+
+import (
+	"errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	"github.com/xmidt-org/ears/internal/pkg/db/postgres"
+	"testing"
+)
+
+func TestPostgresStorerConfig(t *testing.T) {
+	v := viper.New()
+	_, err := postgres.NewPostgresDbStorer(v, &log.Logger)
+	var missingConfig *postgres.MissingConfigError
+	if !errors.As(err, &missingConfig) {
+		t.Fatalf("expect MissingConfigError but get %v instead\n", err)
+	}
+	_, err = postgres.NewTenantStorer(v, &log.Logger)
+	if !errors.As(err, &missingConfig) {
+		t.Fatalf("expect MissingConfigError but get %v instead\n", err)
+	}
+	v.Set("ears.storage.route.endpoint", "postgres://localhost/ears")
+	v.Set("ears.storage.route.tableName", "ears.routes; DROP TABLE ears_tenants")
+	_, err = postgres.NewPostgresDbStorer(v, &log.Logger)
+	var badTableName *postgres.BadTableNameError
+	if !errors.As(err, &badTableName) {
+		t.Fatalf("expect BadTableNameError but get %v instead\n", err)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/xmidt-org/ears/pkg/route"
 	"github.com/xmidt-org/ears/pkg/tenant"
 	"go.opentelemetry.io/otel/semconv/v1.4.0"
+	"strconv"
 	"time"
 )
 
@@ -117,6 +118,63 @@ func (d *RedisDbStorer) GetAllTenantRoutes(ctx context.Context, tid tenant.Id) (
 	return filterRoutes, nil
 }
 
+// scanCount is the HSCAN count hint when listing routes without a limit
+const scanCount = 1000
+
+// ListRoutes pages through the routes hash with HSCAN. The cursor is the HSCAN cursor, so
+// pages are only approximately limit routes long and filters other than the tenant
+// (which becomes the MATCH pattern) are applied to each scanned batch.
+func (d *RedisDbStorer) ListRoutes(ctx context.Context, filter route.Filter, cursor string, limit int) (route.Page, error) {
+	_, span := db.CreateSpan(ctx, "listRoutes", semconv.DBSystemRedis,
+		semconv.DBConnectionStringKey.String(d.endpoint), rtsemconv.DBTable.String(d.tableName))
+	defer span.End()
+	page := route.Page{Routes: make([]route.Config, 0)}
+	var position uint64
+	if cursor != "" {
+		p, err := route.DecodeCursor(cursor)
+		if err != nil {
+			return page, err
+		}
+		position, err = strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return page, &route.BadCursorError{Cursor: cursor}
+		}
+	}
+	match := ""
+	if filter.TenantId != nil {
+		match = filter.TenantId.KeyWithRoute("") + "*"
+	}
+	count := int64(limit)
+	if limit <= 0 {
+		count = scanCount
+	}
+	for {
+		fields, next, err := d.client.HScan(d.tableName, position, match, count).Result()
+		if err != nil {
+			return page, fmt.Errorf("could not scan routes in redis: %v", err)
+		}
+		// HSCAN returns field value pairs
+		for i := 1; i < len(fields); i += 2 {
+			var r route.Config
+			err = json.Unmarshal([]byte(fields[i]), &r)
+			if err != nil {
+				return page, err
+			}
+			if filter.Matches(&r) {
+				page.Routes = append(page.Routes, r)
+			}
+		}
+		position = next
+		if position == 0 || (limit > 0 && len(page.Routes) >= limit) {
+			break
+		}
+	}
+	if position != 0 {
+		page.Cursor = route.EncodeCursor(strconv.FormatUint(position, 10))
+	}
+	return page, nil
+}
+
 func (d *RedisDbStorer) SetRoute(ctx context.Context, r route.Config) error {
 	_, span := db.CreateSpan(ctx, "storeRoute", semconv.DBSystemRedis,
 		semconv.DBConnectionStringKey.String(d.endpoint), rtsemconv.DBTable.String(d.tableName))
@@ -189,3 +247,71 @@ func (d *RedisDbStorer) DeleteRoutes(ctx context.Context, tid tenant.Id, ids []s
 	}
 	return nil
 }
+
+// maxTxRetries bounds how often a conditional write is retried when the routes hash
+// is modified by another writer between WATCH and EXEC
+const maxTxRetries = 10
+
+// ifMatch runs write in a MULTI/EXEC transaction if the stored route matches etag. The routes
+// hash is watched so that a concurrent modification aborts the transaction and the check is repeated.
+func (d *RedisDbStorer) ifMatch(ctx context.Context, tid tenant.Id, id string, etag string, write func(pipe redis.Pipeliner, existing route.Config) error) error {
+	key := tid.KeyWithRoute(id)
+	for i := 0; i < maxTxRetries; i++ {
+		err := d.client.Watch(func(tx *redis.Tx) error {
+			result, err := tx.HGet(d.tableName, key).Result()
+			if err == redis.Nil || (err == nil && result == "") {
+				return &route.PreconditionFailedError{TenantId: tid, RouteId: id, ETag: etag}
+			} else if err != nil {
+				return fmt.Errorf("could not get route from redis: %v", err)
+			}
+			var existing route.Config
+			err = json.Unmarshal([]byte(result), &existing)
+			if err != nil {
+				return err
+			}
+			if !existing.MatchesETag(ctx, etag) {
+				return &route.PreconditionFailedError{TenantId: tid, RouteId: id, ETag: etag}
+			}
+			_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+				return write(pipe, existing)
+			})
+			return err
+		}, d.tableName)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return fmt.Errorf("could not update route in redis: too many concurrent modifications")
+}
+
+func (d *RedisDbStorer) SetRouteIfMatch(ctx context.Context, r route.Config, etag string) error {
+	_, span := db.CreateSpan(ctx, "storeRouteIfMatch", semconv.DBSystemRedis,
+		semconv.DBConnectionStringKey.String(d.endpoint), rtsemconv.DBTable.String(d.tableName))
+	defer span.End()
+	if r.Id == "" {
+		return fmt.Errorf("no route to store in redis")
+	}
+	return d.ifMatch(ctx, r.TenantId, r.Id, etag, func(pipe redis.Pipeliner, existing route.Config) error {
+		r.Modified = time.Now().Unix()
+		r.Created = existing.Created
+		val, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		pipe.HSet(d.tableName, r.TenantId.KeyWithRoute(r.Id), val)
+		return nil
+	})
+}
+
+func (d *RedisDbStorer) DeleteRouteIfMatch(ctx context.Context, tid tenant.Id, id string, etag string) error {
+	_, span := db.CreateSpan(ctx, "deleteRouteIfMatch", semconv.DBSystemRedis,
+		semconv.DBConnectionStringKey.String(d.endpoint), rtsemconv.DBTable.String(d.tableName))
+	defer span.End()
+	if id == "" {
+		return fmt.Errorf("no route to delete in redis")
+	}
+	return d.ifMatch(ctx, tid, id, etag, func(pipe redis.Pipeliner, existing route.Config) error {
+		pipe.HDel(d.tableName, tid.KeyWithRoute(id))
+		return nil
+	})
+}
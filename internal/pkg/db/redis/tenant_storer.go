@@ -0,0 +1,122 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-redis/redis"
+	"github.com/rs/zerolog"
+	"github.com/xmidt-org/ears/internal/pkg/db"
+	"github.com/xmidt-org/ears/internal/pkg/rtsemconv"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"go.opentelemetry.io/otel/semconv/v1.4.0"
+	"time"
+)
+
+type TenantStorer struct {
+	client    *redis.Client
+	endpoint  string
+	tableName string
+	logger    *zerolog.Logger
+}
+
+func NewTenantStorer(config Config, logger *zerolog.Logger) (*TenantStorer, error) {
+	s := &TenantStorer{
+		endpoint:  config.GetString("ears.storage.tenant.endpoint"),
+		tableName: "tenants",
+		logger:    logger,
+	}
+	s.client = redis.NewClient(&redis.Options{
+		Addr:     s.endpoint,
+		Password: "",
+		DB:       0,
+	})
+	logger.Info().Msg("connected to redis tenant storage layer at " + s.endpoint)
+	return s, nil
+}
+
+func (s *TenantStorer) GetAllConfigs(ctx context.Context) ([]tenant.Config, error) {
+	_, span := db.CreateSpan(ctx, "getAllTenantConfigs", semconv.DBSystemRedis,
+		semconv.DBConnectionStringKey.String(s.endpoint), rtsemconv.DBTable.String(s.tableName))
+	defer span.End()
+	configs := make([]tenant.Config, 0)
+	results, err := s.client.HGetAll(s.tableName).Result()
+	if err != nil {
+		return nil, &tenant.InternalStorageError{Wrapped: fmt.Errorf("could not get tenant configs from redis: %v", err)}
+	}
+	for _, v := range results {
+		var config tenant.Config
+		err = json.Unmarshal([]byte(v), &config)
+		if err != nil {
+			return nil, &tenant.InternalStorageError{Wrapped: err}
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+func (s *TenantStorer) GetConfig(ctx context.Context, id tenant.Id) (*tenant.Config, error) {
+	_, span := db.CreateSpan(ctx, "getTenantConfig", semconv.DBSystemRedis,
+		semconv.DBConnectionStringKey.String(s.endpoint), rtsemconv.DBTable.String(s.tableName))
+	defer span.End()
+	result, err := s.client.HGet(s.tableName, id.Key()).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, &tenant.TenantNotFoundError{Tenant: id}
+		}
+		return nil, &tenant.InternalStorageError{Wrapped: fmt.Errorf("could not get tenant config from redis: %v", err)}
+	}
+	if result == "" {
+		return nil, &tenant.TenantNotFoundError{Tenant: id}
+	}
+	var config tenant.Config
+	err = json.Unmarshal([]byte(result), &config)
+	if err != nil {
+		return nil, &tenant.InternalStorageError{Wrapped: err}
+	}
+	return &config, nil
+}
+
+func (s *TenantStorer) SetConfig(ctx context.Context, config tenant.Config) error {
+	_, span := db.CreateSpan(ctx, "setTenantConfig", semconv.DBSystemRedis,
+		semconv.DBConnectionStringKey.String(s.endpoint), rtsemconv.DBTable.String(s.tableName))
+	defer span.End()
+	config.Modified = time.Now().Unix()
+	val, err := json.Marshal(config)
+	if err != nil {
+		return &tenant.BadConfigError{}
+	}
+	_, err = s.client.HSet(s.tableName, config.Tenant.Key(), val).Result()
+	if err != nil {
+		return &tenant.InternalStorageError{Wrapped: fmt.Errorf("could not insert tenant config into redis: %v", err)}
+	}
+	return nil
+}
+
+func (s *TenantStorer) DeleteConfig(ctx context.Context, id tenant.Id) error {
+	_, span := db.CreateSpan(ctx, "deleteTenantConfig", semconv.DBSystemRedis,
+		semconv.DBConnectionStringKey.String(s.endpoint), rtsemconv.DBTable.String(s.tableName))
+	defer span.End()
+	num, err := s.client.HDel(s.tableName, id.Key()).Result()
+	if err != nil {
+		return &tenant.InternalStorageError{Wrapped: fmt.Errorf("could not delete tenant config from redis: %v", err)}
+	}
+	if num == 0 {
+		return &tenant.TenantNotFoundError{Tenant: id}
+	}
+	return nil
+}
@@ -36,4 +36,6 @@ func TestRedisRouteStorer(t *testing.T) {
 		t.Fatalf("Error instantiate redisdb %s\n", err.Error())
 	}
 	testRouteStorer(s, t)
+	testRouteStorerIfMatch(s, t)
+	testRouteStorerListing(s, t)
 }
@@ -0,0 +1,55 @@
+// Copyright 2020 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"github.com/xmidt-org/ears/pkg/route"
+	"sort"
+)
+
+// PageRoutes returns a page of routes for storers which keep all routes in memory. Routes
+// are keyed by tenant.Id.KeyWithRoute and listed in key order, so the cursor is simply the
+// key of the last route of the previous page.
+func PageRoutes(routes map[string]route.Config, filter route.Filter, cursor string, limit int) (route.Page, error) {
+	page := route.Page{Routes: make([]route.Config, 0)}
+	after := ""
+	if cursor != "" {
+		var err error
+		after, err = route.DecodeCursor(cursor)
+		if err != nil {
+			return page, err
+		}
+	}
+	keys := make([]string, 0, len(routes))
+	for key := range routes {
+		if key > after {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		r := routes[key]
+		if !filter.Matches(&r) {
+			continue
+		}
+		if limit > 0 && len(page.Routes) == limit {
+			last := page.Routes[limit-1]
+			page.Cursor = route.EncodeCursor(last.TenantId.KeyWithRoute(last.Id))
+			break
+		}
+		page.Routes = append(page.Routes, r)
+	}
+	return page, nil
+}
@@ -397,3 +397,165 @@ func testRouteStorer(s route.RouteStorer, t *testing.T) {
 		t.Fatalf("Expect 0 routes but get %d instead\n", len(routes))
 	}
 }
+
+func testRouteStorerIfMatch(s route.RouteStorer, t *testing.T) {
+	ctx := context.Background()
+	tid := testCases[0].tenantId
+	id := "ifmatch"
+	err := s.DeleteRoute(ctx, tid, id)
+	if err != nil {
+		t.Fatalf("DeleteRoute error: %s\n", err.Error())
+	}
+	var preconditionFailed *route.PreconditionFailedError
+
+	var config route.Config
+	err = json.Unmarshal([]byte(testCases[0].routeConfig), &config)
+	if err != nil {
+		t.Fatalf("Unmarshal error: %s\n", err.Error())
+	}
+	config.Id = id
+	config.TenantId = tid
+
+	//Test Case: conditional write of a route that does not exist
+	err = s.SetRouteIfMatch(ctx, config, route.AnyETag)
+	if !errors.As(err, &preconditionFailed) {
+		t.Fatalf("SetRouteIfMatch on missing route unexpected error: %v\n", err)
+	}
+
+	err = s.SetRoute(ctx, config)
+	if err != nil {
+		t.Fatalf("SetRoute error: %s\n", err.Error())
+	}
+	r, err := s.GetRoute(ctx, tid, id)
+	if err != nil {
+		t.Fatalf("GetRoute error: %s\n", err.Error())
+	}
+	etag := r.ETag(ctx)
+
+	//Test Case: stale etag
+	config.Name = "updatedName"
+	err = s.SetRouteIfMatch(ctx, config, "stale")
+	if !errors.As(err, &preconditionFailed) {
+		t.Fatalf("SetRouteIfMatch with stale etag unexpected error: %v\n", err)
+	}
+	err = s.DeleteRouteIfMatch(ctx, tid, id, "stale")
+	if !errors.As(err, &preconditionFailed) {
+		t.Fatalf("DeleteRouteIfMatch with stale etag unexpected error: %v\n", err)
+	}
+
+	//Test Case: matching etag, created time is preserved
+	err = s.SetRouteIfMatch(ctx, config, etag)
+	if err != nil {
+		t.Fatalf("SetRouteIfMatch error: %s\n", err.Error())
+	}
+	updated, err := s.GetRoute(ctx, tid, id)
+	if err != nil {
+		t.Fatalf("GetRoute error: %s\n", err.Error())
+	}
+	if updated.Name != "updatedName" || updated.Created != r.Created {
+		t.Fatalf("Unexpected route after conditional update %s %d %d\n", updated.Name, updated.Created, r.Created)
+	}
+	if updated.ETag(ctx) == etag {
+		t.Fatalf("ETag did not change after update\n")
+	}
+
+	//Test Case: the old etag is stale now
+	err = s.DeleteRouteIfMatch(ctx, tid, id, etag)
+	if !errors.As(err, &preconditionFailed) {
+		t.Fatalf("DeleteRouteIfMatch with outdated etag unexpected error: %v\n", err)
+	}
+	err = s.DeleteRouteIfMatch(ctx, tid, id, updated.ETag(ctx))
+	if err != nil {
+		t.Fatalf("DeleteRouteIfMatch error: %s\n", err.Error())
+	}
+	_, err = s.GetRoute(ctx, tid, id)
+	var routeNotFound *route.RouteNotFoundError
+	if !errors.As(err, &routeNotFound) {
+		t.Fatalf("GetRoute after conditional delete unexpected error: %v\n", err)
+	}
+}
+
+func testRouteStorerListing(s route.RouteStorer, t *testing.T) {
+	ctx := context.Background()
+	tid1 := tenant.Id{OrgId: "listOrg", AppId: "listApp"}
+	tid2 := tenant.Id{OrgId: "listOrg", AppId: "listApp2"}
+	tid3 := tenant.Id{OrgId: "listOrg", AppId: "listApp3"}
+	routes := []route.Config{
+		{Id: "l1", TenantId: tid1, UserId: "alice", Name: "one", Origin: "flow1", Receiver: route.PluginConfig{Plugin: "kafka"}, Sender: route.PluginConfig{Plugin: "sqs"}, Senders: []route.PluginConfig{{Plugin: "http", Name: "audit"}}},
+		{Id: "l2", TenantId: tid1, UserId: "alice", Name: "two", Receiver: route.PluginConfig{Plugin: "kafka"}, Sender: route.PluginConfig{Plugin: "debug"}},
+		{Id: "l3", TenantId: tid1, UserId: "bob", Name: "three", Origin: "flow1", Receiver: route.PluginConfig{Plugin: "sqs"}, Sender: route.PluginConfig{Plugin: "debug"}},
+		{Id: "l4", TenantId: tid1, UserId: "bob", Name: "four", Receiver: route.PluginConfig{Plugin: "debug"}, Sender: route.PluginConfig{Plugin: "debug"}},
+		{Id: "l5", TenantId: tid2, UserId: "alice", Name: "five", Receiver: route.PluginConfig{Plugin: "kafka"}, Sender: route.PluginConfig{Plugin: "sqs"}},
+		{Id: "l0", TenantId: tid3, UserId: "alice", Name: "zero", Receiver: route.PluginConfig{Plugin: "kafka"}, Sender: route.PluginConfig{Plugin: "debug"}},
+	}
+	err := s.DeleteRoutes(ctx, tid1, []string{"l1", "l2", "l3", "l4"})
+	if err != nil {
+		t.Fatalf("DeleteRoutes error: %s\n", err.Error())
+	}
+	err = s.DeleteRoutes(ctx, tid2, []string{"l5"})
+	if err != nil {
+		t.Fatalf("DeleteRoutes error: %s\n", err.Error())
+	}
+	err = s.DeleteRoutes(ctx, tid3, []string{"l0"})
+	if err != nil {
+		t.Fatalf("DeleteRoutes error: %s\n", err.Error())
+	}
+	err = s.SetRoutes(ctx, routes)
+	if err != nil {
+		t.Fatalf("SetRoutes error: %s\n", err.Error())
+	}
+	defer s.DeleteRoutes(ctx, tid1, []string{"l1", "l2", "l3", "l4"})
+	defer s.DeleteRoutes(ctx, tid2, []string{"l5"})
+	defer s.DeleteRoutes(ctx, tid3, []string{"l0"})
+
+	//list all pages and return the sorted route ids
+	listAll := func(filter route.Filter, limit int) []string {
+		ids := make([]string, 0)
+		cursor := ""
+		for i := 0; i < 100; i++ {
+			page, err := s.ListRoutes(ctx, filter, cursor, limit)
+			if err != nil {
+				t.Fatalf("ListRoutes error: %s\n", err.Error())
+			}
+			for _, r := range page.Routes {
+				ids = append(ids, r.Id)
+			}
+			if page.Cursor == "" {
+				sort.Strings(ids)
+				return ids
+			}
+			cursor = page.Cursor
+		}
+		t.Fatalf("ListRoutes does not terminate\n")
+		return nil
+	}
+	expect := func(name string, ids []string, expected ...string) {
+		if len(ids) != len(expected) {
+			t.Fatalf("%s: expect routes %v but get %v instead\n", name, expected, ids)
+		}
+		for i := range ids {
+			if ids[i] != expected[i] {
+				t.Fatalf("%s: expect routes %v but get %v instead\n", name, expected, ids)
+			}
+		}
+	}
+	expect("tenant", listAll(route.Filter{TenantId: &tid1}, 0), "l1", "l2", "l3", "l4")
+	expect("tenant paginated", listAll(route.Filter{TenantId: &tid1}, 1), "l1", "l2", "l3", "l4")
+	expect("receiver", listAll(route.Filter{TenantId: &tid1, ReceiverPlugin: "kafka"}, 1), "l1", "l2")
+	expect("sender", listAll(route.Filter{TenantId: &tid1, SenderPlugin: "debug"}, 2), "l2", "l3", "l4")
+	expect("named sender", listAll(route.Filter{TenantId: &tid1, SenderPlugin: "http"}, 0), "l1")
+	expect("name", listAll(route.Filter{TenantId: &tid1, Name: "three"}, 0), "l3")
+	expect("origin", listAll(route.Filter{TenantId: &tid1, Origin: "flow1"}, 0), "l1", "l3")
+	expect("user", listAll(route.Filter{UserId: "alice", ReceiverPlugin: "kafka", SenderPlugin: "sqs"}, 0), "l1", "l5")
+	//pages ending at the last route of a tenant followed by a route of another tenant
+	expect("user paginated", listAll(route.Filter{UserId: "alice", ReceiverPlugin: "kafka"}, 1), "l0", "l1", "l2", "l5")
+	expect("user paginated by 2", listAll(route.Filter{UserId: "alice", ReceiverPlugin: "kafka"}, 2), "l0", "l1", "l2", "l5")
+	expect("modified", listAll(route.Filter{TenantId: &tid2, ModifiedSince: time.Now().Add(-time.Hour).Unix()}, 0), "l5")
+	expect("not modified", listAll(route.Filter{TenantId: &tid2, ModifiedSince: time.Now().Add(time.Hour).Unix()}, 0))
+
+	_, err = s.ListRoutes(ctx, route.Filter{}, "not a cursor!", 1)
+	var badCursor *route.BadCursorError
+	if !errors.As(err, &badCursor) {
+		t.Fatalf("ListRoutes with bad cursor unexpected error: %v\n", err)
+	}
+}
@@ -26,8 +26,10 @@ import (
 	"github.com/xmidt-org/ears/pkg/plugins/decode"
 	"github.com/xmidt-org/ears/pkg/plugins/dedup"
 	"github.com/xmidt-org/ears/pkg/plugins/encode"
+	"github.com/xmidt-org/ears/pkg/plugins/enrich"
 	"github.com/xmidt-org/ears/pkg/plugins/hash"
 	"github.com/xmidt-org/ears/pkg/plugins/http"
+	"github.com/xmidt-org/ears/pkg/plugins/jq"
 	"github.com/xmidt-org/ears/pkg/plugins/js"
 	"github.com/xmidt-org/ears/pkg/plugins/kafka"
 	"github.com/xmidt-org/ears/pkg/plugins/kinesis"
@@ -38,6 +40,7 @@ import (
 	"github.com/xmidt-org/ears/pkg/plugins/regex"
 	"github.com/xmidt-org/ears/pkg/plugins/split"
 	"github.com/xmidt-org/ears/pkg/plugins/sqs"
+	"github.com/xmidt-org/ears/pkg/plugins/switcher"
 	"github.com/xmidt-org/ears/pkg/plugins/trace"
 	"github.com/xmidt-org/ears/pkg/plugins/transform"
 	"github.com/xmidt-org/ears/pkg/plugins/ttl"
@@ -123,6 +126,10 @@ func ProvidePluginManager(in PluginIn) (PluginOut, error) {
 			name:   "dedup",
 			plugin: toArr(dedup.NewPluginVersion("dedup", "", ""))[0].(pkgplugin.Pluginer),
 		},
+		{
+			name:   "enrich",
+			plugin: toArr(enrich.NewPluginVersion("enrich", "", ""))[0].(pkgplugin.Pluginer),
+		},
 		{
 			name:   "batch",
 			plugin: toArr(batch.NewPluginVersion("batch", "", ""))[0].(pkgplugin.Pluginer),
@@ -159,6 +166,14 @@ func ProvidePluginManager(in PluginIn) (PluginOut, error) {
 			name:   "split",
 			plugin: toArr(split.NewPluginVersion("split", "", ""))[0].(pkgplugin.Pluginer),
 		},
+		{
+			name:   "jq",
+			plugin: toArr(jq.NewPluginVersion("jq", "", ""))[0].(pkgplugin.Pluginer),
+		},
+		{
+			name:   "switch",
+			plugin: toArr(switcher.NewPluginVersion("switch", "", ""))[0].(pkgplugin.Pluginer),
+		},
 		{
 			name:   "unwrap",
 			plugin: toArr(unwrap.NewPluginVersion("unwrap", "", ""))[0].(pkgplugin.Pluginer),
@@ -19,6 +19,8 @@ import (
 	"github.com/xmidt-org/ears/internal/pkg/config"
 	"github.com/xmidt-org/ears/internal/pkg/db"
 	"github.com/xmidt-org/ears/internal/pkg/db/dynamo"
+	"github.com/xmidt-org/ears/internal/pkg/db/file"
+	"github.com/xmidt-org/ears/internal/pkg/db/postgres"
 	"github.com/xmidt-org/ears/internal/pkg/db/redis"
 	"github.com/xmidt-org/ears/pkg/route"
 	"go.uber.org/fx"
@@ -59,6 +61,18 @@ func ProvideRouteStorer(in StorageIn) (StorageOut, error) {
 			return out, err
 		}
 		out.RouteStorer = routeStorer
+	case "postgres":
+		routeStorer, err := postgres.NewPostgresDbStorer(in.Config, in.Logger)
+		if err != nil {
+			return out, err
+		}
+		out.RouteStorer = routeStorer
+	case "file":
+		routeStorer, err := file.NewRouteStorer(in.Config)
+		if err != nil {
+			return out, err
+		}
+		out.RouteStorer = routeStorer
 	default:
 		return out, &UnsupportedRouteStorageError{storageType}
 	}
@@ -20,6 +20,7 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/xmidt-org/ears/internal/pkg/config"
 	"github.com/xmidt-org/ears/internal/pkg/syncer"
+	"github.com/xmidt-org/ears/internal/pkg/syncer/postgres"
 	"github.com/xmidt-org/ears/internal/pkg/syncer/redis"
 	"go.uber.org/fx"
 )
@@ -49,6 +50,8 @@ func ProvideRouteTableSyncer(in TableSyncerIn) (TableSyncerOut, error) {
 		out.RoutingTableDeltaSyncer = syncer.NewInMemoryDeltaSyncer(in.Logger, in.Config)
 	case "redis":
 		out.RoutingTableDeltaSyncer = redis.NewRedisDeltaSyncer(in.Logger, in.Config)
+	case "postgres":
+		out.RoutingTableDeltaSyncer = postgres.NewPostgresDeltaSyncer(in.Logger, in.Config)
 	default:
 		return out, errors.New("unsupported table syncer type " + tableSyncerType)
 	}
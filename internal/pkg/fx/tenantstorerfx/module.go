@@ -19,6 +19,9 @@ import (
 	"github.com/xmidt-org/ears/internal/pkg/config"
 	"github.com/xmidt-org/ears/internal/pkg/db"
 	"github.com/xmidt-org/ears/internal/pkg/db/dynamo"
+	"github.com/xmidt-org/ears/internal/pkg/db/file"
+	"github.com/xmidt-org/ears/internal/pkg/db/postgres"
+	"github.com/xmidt-org/ears/internal/pkg/db/redis"
 	"github.com/xmidt-org/ears/pkg/tenant"
 	"go.uber.org/fx"
 )
@@ -52,6 +55,24 @@ func ProvideTenantStorer(in StorageIn) (StorageOut, error) {
 			return out, err
 		}
 		out.TenantStorer = tenantStorer
+	case "redis":
+		tenantStorer, err := redis.NewTenantStorer(in.Config, in.Logger)
+		if err != nil {
+			return out, err
+		}
+		out.TenantStorer = tenantStorer
+	case "postgres":
+		tenantStorer, err := postgres.NewTenantStorer(in.Config, in.Logger)
+		if err != nil {
+			return out, err
+		}
+		out.TenantStorer = tenantStorer
+	case "file":
+		tenantStorer, err := file.NewTenantStorer(in.Config)
+		if err != nil {
+			return out, err
+		}
+		out.TenantStorer = tenantStorer
 	default:
 		return out, &UnsupportedTenantStorageError{storageType}
 	}
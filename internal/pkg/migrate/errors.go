@@ -0,0 +1,46 @@
+// Copyright 2020 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrate
+
+import (
+	"github.com/xmidt-org/ears/pkg/errs"
+)
+
+type BadTenantError struct {
+	Tenant string
+}
+
+func (e *BadTenantError) Error() string {
+	return errs.String("BadTenantError", map[string]interface{}{"tenant": e.Tenant}, nil)
+}
+
+type UnsupportedConflictPolicyError struct {
+	Policy ConflictPolicy
+}
+
+func (e *UnsupportedConflictPolicyError) Error() string {
+	return errs.String("UnsupportedConflictPolicyError", map[string]interface{}{"policy": e.Policy}, nil)
+}
+
+// ConflictError is returned by the fail conflict policy when an item exists in the
+// destination with a different configuration
+type ConflictError struct {
+	Kind string
+	Key  string
+}
+
+func (e *ConflictError) Error() string {
+	return errs.String("ConflictError", map[string]interface{}{"kind": e.Kind, "key": e.Key}, nil)
+}
@@ -0,0 +1,316 @@
+// Copyright 2020 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/rs/zerolog"
+	"github.com/xmidt-org/ears/pkg/route"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"sort"
+	"strings"
+)
+
+// ConflictPolicy decides what happens to items which already exist in the destination
+// with a different configuration. Identical items are never written again.
+type ConflictPolicy string
+
+const (
+	ConflictSkip      ConflictPolicy = "skip"      // keep the destination version
+	ConflictOverwrite ConflictPolicy = "overwrite" // replace the destination version
+	ConflictFail      ConflictPolicy = "fail"      // abort the migration
+)
+
+const (
+	kindTenant = "tenant"
+	kindRoute  = "route"
+)
+
+type Options struct {
+	DryRun   bool           // only report what would be migrated
+	Tenants  []tenant.Id    // migrate only these tenants, all tenants if empty
+	Conflict ConflictPolicy // defaults to ConflictFail
+}
+
+// Counts summarizes the migration of one kind of item
+type Counts struct {
+	Total       int      `json:"total"`       // items selected in the source
+	Copied      int      `json:"copied"`      // items new to the destination
+	Overwritten int      `json:"overwritten"` // conflicting items replaced in the destination
+	Unchanged   int      `json:"unchanged"`   // items identical in both storages
+	Skipped     int      `json:"skipped"`     // conflicting items left alone in the destination
+	Conflicts   []string `json:"conflicts,omitempty"`
+	Mismatches  []string `json:"mismatches,omitempty"` // items failing verification
+}
+
+type Report struct {
+	DryRun   bool   `json:"dryRun"`
+	Tenants  Counts `json:"tenants"`
+	Routes   Counts `json:"routes"`
+	Verified bool   `json:"verified"` // true if all written items were read back from the destination unaltered
+}
+
+// Migrator copies tenant configs and routes from one storage layer to another
+type Migrator struct {
+	srcTenants tenant.TenantStorer
+	dstTenants tenant.TenantStorer
+	srcRoutes  route.RouteStorer
+	dstRoutes  route.RouteStorer
+	logger     *zerolog.Logger
+}
+
+func NewMigrator(srcTenants tenant.TenantStorer, srcRoutes route.RouteStorer, dstTenants tenant.TenantStorer, dstRoutes route.RouteStorer, logger *zerolog.Logger) *Migrator {
+	return &Migrator{
+		srcTenants: srcTenants,
+		dstTenants: dstTenants,
+		srcRoutes:  srcRoutes,
+		dstRoutes:  dstRoutes,
+		logger:     logger,
+	}
+}
+
+// ParseTenant parses a tenant given as orgId/appId
+func ParseTenant(s string) (tenant.Id, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return tenant.Id{}, &BadTenantError{s}
+	}
+	return tenant.Id{OrgId: parts[0], AppId: parts[1]}, nil
+}
+
+// Migrate copies all selected tenant configs first and their routes second, so that the
+// destination never holds routes of unknown tenants. Written items are read back and
+// compared afterwards, routes by their Config.Hash.
+func (m *Migrator) Migrate(ctx context.Context, opts Options) (*Report, error) {
+	if opts.Conflict == "" {
+		opts.Conflict = ConflictFail
+	}
+	switch opts.Conflict {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+	default:
+		return nil, &UnsupportedConflictPolicyError{opts.Conflict}
+	}
+	report := &Report{DryRun: opts.DryRun}
+	tenants, err := m.selectTenants(ctx, opts.Tenants)
+	if err != nil {
+		return report, err
+	}
+	routes, err := m.selectRoutes(ctx, opts.Tenants)
+	if err != nil {
+		return report, err
+	}
+	report.Tenants.Total = len(tenants)
+	report.Routes.Total = len(routes)
+
+	// decide for each item before writing anything so that the fail policy leaves the destination untouched
+	tenantWrites := make([]tenant.Config, 0)
+	for _, config := range tenants {
+		existing, err := m.dstTenants.GetConfig(ctx, config.Tenant)
+		var notFound *tenant.TenantNotFoundError
+		if errors.As(err, &notFound) {
+			report.Tenants.Copied++
+			tenantWrites = append(tenantWrites, config)
+			continue
+		} else if err != nil {
+			return report, err
+		}
+		if sameTenantConfig(existing, &config) {
+			report.Tenants.Unchanged++
+			continue
+		}
+		write, err := resolve(opts.Conflict, kindTenant, tenantKey(config.Tenant), &report.Tenants)
+		if err != nil {
+			return report, err
+		}
+		if write {
+			tenantWrites = append(tenantWrites, config)
+		}
+	}
+	routeWrites := make([]route.Config, 0)
+	for _, r := range routes {
+		existing, err := m.dstRoutes.GetRoute(ctx, r.TenantId, r.Id)
+		var notFound *route.RouteNotFoundError
+		if errors.As(err, &notFound) {
+			report.Routes.Copied++
+			routeWrites = append(routeWrites, r)
+			continue
+		} else if err != nil {
+			return report, err
+		}
+		if sameRoute(ctx, &existing, &r) {
+			report.Routes.Unchanged++
+			continue
+		}
+		write, err := resolve(opts.Conflict, kindRoute, routeKey(&r), &report.Routes)
+		if err != nil {
+			return report, err
+		}
+		if write {
+			routeWrites = append(routeWrites, r)
+		}
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+
+	for _, config := range tenantWrites {
+		err = m.dstTenants.SetConfig(ctx, config)
+		if err != nil {
+			return report, err
+		}
+	}
+	for _, r := range routeWrites {
+		err = m.dstRoutes.SetRoute(ctx, r)
+		if err != nil {
+			return report, err
+		}
+	}
+	m.logger.Info().Str("op", "Migrate").Int("tenants", len(tenantWrites)).Int("routes", len(routeWrites)).Msg("items written")
+
+	err = m.verify(ctx, tenantWrites, routeWrites, report)
+	if err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// resolve applies the conflict policy to a conflicting item and tells if it should be written
+func resolve(policy ConflictPolicy, kind string, key string, counts *Counts) (bool, error) {
+	counts.Conflicts = append(counts.Conflicts, key)
+	switch policy {
+	case ConflictOverwrite:
+		counts.Overwritten++
+		return true, nil
+	case ConflictSkip:
+		counts.Skipped++
+		return false, nil
+	}
+	return false, &ConflictError{Kind: kind, Key: key}
+}
+
+func (m *Migrator) verify(ctx context.Context, tenants []tenant.Config, routes []route.Config, report *Report) error {
+	for _, config := range tenants {
+		written, err := m.dstTenants.GetConfig(ctx, config.Tenant)
+		if err != nil {
+			var notFound *tenant.TenantNotFoundError
+			if !errors.As(err, &notFound) {
+				return err
+			}
+		}
+		if written == nil || !sameTenantConfig(written, &config) {
+			report.Tenants.Mismatches = append(report.Tenants.Mismatches, tenantKey(config.Tenant))
+		}
+	}
+	for _, r := range routes {
+		written, err := m.dstRoutes.GetRoute(ctx, r.TenantId, r.Id)
+		if err != nil {
+			var notFound *route.RouteNotFoundError
+			if !errors.As(err, &notFound) {
+				return err
+			}
+		}
+		if err != nil || !sameRoute(ctx, &written, &r) {
+			report.Routes.Mismatches = append(report.Routes.Mismatches, routeKey(&r))
+		}
+	}
+	report.Verified = len(report.Tenants.Mismatches) == 0 && len(report.Routes.Mismatches) == 0
+	if !report.Verified {
+		m.logger.Error().Str("op", "Migrate").Strs("tenants", report.Tenants.Mismatches).Strs("routes", report.Routes.Mismatches).Msg("verification failed")
+	}
+	return nil
+}
+
+func (m *Migrator) selectTenants(ctx context.Context, selection []tenant.Id) ([]tenant.Config, error) {
+	configs := make([]tenant.Config, 0)
+	if len(selection) == 0 {
+		all, err := m.srcTenants.GetAllConfigs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, all...)
+	} else {
+		for _, tid := range selection {
+			config, err := m.srcTenants.GetConfig(ctx, tid)
+			if err != nil {
+				var notFound *tenant.TenantNotFoundError
+				if errors.As(err, &notFound) {
+					// a tenant may have routes without a tenant config if tenancy is not strict
+					continue
+				}
+				return nil, err
+			}
+			configs = append(configs, *config)
+		}
+	}
+	sort.SliceStable(configs, func(i, j int) bool {
+		return tenantKey(configs[i].Tenant) < tenantKey(configs[j].Tenant)
+	})
+	return configs, nil
+}
+
+func (m *Migrator) selectRoutes(ctx context.Context, selection []tenant.Id) ([]route.Config, error) {
+	routes := make([]route.Config, 0)
+	if len(selection) == 0 {
+		all, err := m.srcRoutes.GetAllRoutes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, all...)
+	} else {
+		for _, tid := range selection {
+			tenantRoutes, err := m.srcRoutes.GetAllTenantRoutes(ctx, tid)
+			if err != nil {
+				return nil, err
+			}
+			routes = append(routes, tenantRoutes...)
+		}
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routeKey(&routes[i]) < routeKey(&routes[j])
+	})
+	return routes, nil
+}
+
+func routeKey(r *route.Config) string {
+	return tenantKey(r.TenantId) + "/" + r.Id
+}
+
+// sameRoute compares routes by hash as well as the fields not covered by the hash,
+// ignoring the timestamps maintained by the storage layer
+func sameRoute(ctx context.Context, a *route.Config, b *route.Config) bool {
+	return a.Hash(ctx) == b.Hash(ctx) && a.Origin == b.Origin && a.Debug == b.Debug
+}
+
+// sameTenantConfig compares tenant configs ignoring the modified time maintained by the storage layer
+func sameTenantConfig(a *tenant.Config, b *tenant.Config) bool {
+	ac, bc := *a, *b
+	ac.Modified = 0
+	bc.Modified = 0
+	aj, err := json.Marshal(ac)
+	if err != nil {
+		return false
+	}
+	bj, err := json.Marshal(bc)
+	if err != nil {
+		return false
+	}
+	return string(aj) == string(bj)
+}
+
+func tenantKey(tid tenant.Id) string {
+	return tid.OrgId + "/" + tid.AppId
+}
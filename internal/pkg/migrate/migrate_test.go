@@ -0,0 +1,226 @@
+// Copyright 2020 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"github.com/xmidt-org/ears/internal/pkg/db"
+	"github.com/xmidt-org/ears/internal/pkg/db/file"
+	"github.com/xmidt-org/ears/internal/pkg/migrate"
+	"github.com/xmidt-org/ears/pkg/route"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"path/filepath"
+	"testing"
+)
+
+var (
+	tid1 = tenant.Id{OrgId: "myorg", AppId: "myapp"}
+	tid2 = tenant.Id{OrgId: "otherorg", AppId: "otherapp"}
+)
+
+type storage struct {
+	tenants tenant.TenantStorer
+	routes  route.RouteStorer
+}
+
+func sourceStorage(t *testing.T) storage {
+	ctx := context.Background()
+	s := storage{tenants: db.NewTenantInmemoryStorer(), routes: db.NewInMemoryRouteStorer(nil)}
+	for _, tid := range []tenant.Id{tid1, tid2} {
+		err := s.tenants.SetConfig(ctx, tenant.Config{Tenant: tid, Quota: tenant.Quota{EventsPerSec: 10}})
+		if err != nil {
+			t.Fatalf("SetConfig error: %s\n", err.Error())
+		}
+	}
+	err := s.routes.SetRoutes(ctx, []route.Config{
+		testRoute(tid1, "r1", "first"),
+		testRoute(tid1, "r2", "second"),
+		testRoute(tid2, "r3", "third"),
+	})
+	if err != nil {
+		t.Fatalf("SetRoutes error: %s\n", err.Error())
+	}
+	return s
+}
+
+func fileStorage(t *testing.T) storage {
+	v := viper.New()
+	dir := t.TempDir()
+	v.Set("ears.storage.route.path", filepath.Join(dir, "routes.log"))
+	v.Set("ears.storage.tenant.path", filepath.Join(dir, "tenants.log"))
+	rs, err := file.NewRouteStorer(v)
+	if err != nil {
+		t.Fatalf("Error instantiate file storer %s\n", err.Error())
+	}
+	t.Cleanup(func() { rs.Close() })
+	ts, err := file.NewTenantStorer(v)
+	if err != nil {
+		t.Fatalf("Error instantiate file storer %s\n", err.Error())
+	}
+	t.Cleanup(func() { ts.Close() })
+	return storage{tenants: ts, routes: rs}
+}
+
+func testRoute(tid tenant.Id, id string, name string) route.Config {
+	return route.Config{
+		Id:       id,
+		TenantId: tid,
+		Name:     name,
+		Receiver: route.PluginConfig{Plugin: "debug", Name: "receiver"},
+		Sender:   route.PluginConfig{Plugin: "debug", Name: "sender"},
+	}
+}
+
+func newMigrator(src storage, dst storage) *migrate.Migrator {
+	logger := zerolog.Nop()
+	return migrate.NewMigrator(src.tenants, src.routes, dst.tenants, dst.routes, &logger)
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	src := sourceStorage(t)
+	dst := fileStorage(t)
+	m := newMigrator(src, dst)
+
+	report, err := m.Migrate(ctx, migrate.Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("Migrate error: %s\n", err.Error())
+	}
+	if report.Routes.Copied != 3 || report.Tenants.Copied != 2 {
+		t.Fatalf("unexpected dry run report %+v\n", report)
+	}
+	routes, _ := dst.routes.GetAllRoutes(ctx)
+	if len(routes) != 0 {
+		t.Fatalf("dry run wrote %d routes\n", len(routes))
+	}
+
+	report, err = m.Migrate(ctx, migrate.Options{})
+	if err != nil {
+		t.Fatalf("Migrate error: %s\n", err.Error())
+	}
+	if !report.Verified || report.Routes.Copied != 3 || report.Tenants.Copied != 2 {
+		t.Fatalf("unexpected report %+v\n", report)
+	}
+	for _, r := range []route.Config{testRoute(tid1, "r1", "first"), testRoute(tid2, "r3", "third")} {
+		written, err := dst.routes.GetRoute(ctx, r.TenantId, r.Id)
+		if err != nil {
+			t.Fatalf("GetRoute error: %s\n", err.Error())
+		}
+		if written.Hash(ctx) != r.Hash(ctx) {
+			t.Fatalf("route %s not migrated unaltered\n", r.Id)
+		}
+	}
+
+	// migrating again finds everything in place
+	report, err = m.Migrate(ctx, migrate.Options{})
+	if err != nil {
+		t.Fatalf("Migrate error: %s\n", err.Error())
+	}
+	if report.Routes.Unchanged != 3 || report.Tenants.Unchanged != 2 || report.Routes.Copied != 0 {
+		t.Fatalf("unexpected report %+v\n", report)
+	}
+}
+
+func TestMigrateTenantSelection(t *testing.T) {
+	ctx := context.Background()
+	src := sourceStorage(t)
+	dst := fileStorage(t)
+
+	report, err := newMigrator(src, dst).Migrate(ctx, migrate.Options{Tenants: []tenant.Id{tid2}})
+	if err != nil {
+		t.Fatalf("Migrate error: %s\n", err.Error())
+	}
+	if report.Routes.Total != 1 || report.Tenants.Total != 1 {
+		t.Fatalf("unexpected report %+v\n", report)
+	}
+	routes, _ := dst.routes.GetAllRoutes(ctx)
+	if len(routes) != 1 || routes[0].Id != "r3" {
+		t.Fatalf("unexpected routes in destination %+v\n", routes)
+	}
+	_, err = dst.tenants.GetConfig(ctx, tid1)
+	var notFound *tenant.TenantNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("expected tenant %s not to be migrated\n", tid1.ToString())
+	}
+}
+
+func TestMigrateConflicts(t *testing.T) {
+	ctx := context.Background()
+	src := sourceStorage(t)
+	dst := fileStorage(t)
+	conflicting := testRoute(tid1, "r1", "changed")
+	err := dst.routes.SetRoute(ctx, conflicting)
+	if err != nil {
+		t.Fatalf("SetRoute error: %s\n", err.Error())
+	}
+	m := newMigrator(src, dst)
+
+	_, err = m.Migrate(ctx, migrate.Options{Conflict: migrate.ConflictFail})
+	var conflictErr *migrate.ConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("expected ConflictError, got %v\n", err)
+	}
+	routes, _ := dst.routes.GetAllRoutes(ctx)
+	if len(routes) != 1 {
+		t.Fatalf("failed migration wrote to the destination\n")
+	}
+
+	report, err := m.Migrate(ctx, migrate.Options{Conflict: migrate.ConflictSkip})
+	if err != nil {
+		t.Fatalf("Migrate error: %s\n", err.Error())
+	}
+	if !report.Verified || report.Routes.Skipped != 1 || report.Routes.Copied != 2 {
+		t.Fatalf("unexpected report %+v\n", report)
+	}
+	r, _ := dst.routes.GetRoute(ctx, tid1, "r1")
+	if r.Name != "changed" {
+		t.Fatalf("skipped route was overwritten\n")
+	}
+
+	report, err = m.Migrate(ctx, migrate.Options{Conflict: migrate.ConflictOverwrite})
+	if err != nil {
+		t.Fatalf("Migrate error: %s\n", err.Error())
+	}
+	if !report.Verified || report.Routes.Overwritten != 1 || report.Routes.Unchanged != 2 {
+		t.Fatalf("unexpected report %+v\n", report)
+	}
+	r, _ = dst.routes.GetRoute(ctx, tid1, "r1")
+	if r.Name != "first" {
+		t.Fatalf("route was not overwritten\n")
+	}
+
+	_, err = m.Migrate(ctx, migrate.Options{Conflict: "merge"})
+	var policyErr *migrate.UnsupportedConflictPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("expected UnsupportedConflictPolicyError, got %v\n", err)
+	}
+}
+
+func TestParseTenant(t *testing.T) {
+	tid, err := migrate.ParseTenant("myorg/myapp")
+	if err != nil || tid != tid1 {
+		t.Fatalf("unexpected tenant %+v %v\n", tid, err)
+	}
+	for _, s := range []string{"", "myorg", "myorg/", "/myapp", "a/b/c"} {
+		_, err = migrate.ParseTenant(s)
+		var badTenant *migrate.BadTenantError
+		if !errors.As(err, &badTenant) {
+			t.Fatalf("expected BadTenantError for %q\n", s)
+		}
+	}
+}
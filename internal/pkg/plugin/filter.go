@@ -77,6 +77,14 @@ func (f *filter) Config() interface{} {
 	return f.filterer.Config()
 }
 
+// SenderNames delegates to the wrapped filterer if it targets senders
+func (f *filter) SenderNames() []string {
+	if st, ok := f.filterer.(pkgfilter.SenderTargeter); ok {
+		return st.SenderNames()
+	}
+	return nil
+}
+
 func (f *filter) Name() string {
 	return f.name
 }
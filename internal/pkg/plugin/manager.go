@@ -16,6 +16,7 @@ package plugin
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -174,11 +175,16 @@ func (m *manager) RegisterReceiver(
 					//ratelimit
 					tracer := otel.Tracer(rtsemconv.EARSTracerName)
 					_, span := tracer.Start(e.Context(), "rateLimit")
-					err = m.quotaManager.Wait(e.Context(), tid)
+					err = m.quotaManager.Enforce(e.Context(), tid)
 					span.End()
 					if err != nil {
 						m.logger.Debug().Str("op", "receiverNext").Str("tenantId", tid.ToString()).Msg("Tenant Ratelimited")
-						e.Nack(err)
+						var quotaExceeded *quota.QuotaExceededError
+						if errors.As(err, &quotaExceeded) && quotaExceeded.Policy == tenant.QuotaPolicyDrop {
+							e.Ack()
+						} else {
+							e.Nack(err)
+						}
 						return
 					}
 				}
@@ -620,7 +626,8 @@ func (m *manager) UnregisterSender(ctx context.Context, ps pkgsender.Sender) err
 	key := m.mapkey(s.tid, s.name, s.hash)
 	m.Lock()
 	m.sendersCount[key]--
-	if m.sendersCount[key] <= 0 {
+	last := m.sendersCount[key] <= 0
+	if last {
 		delete(m.sendersCount, key)
 		delete(m.senders, key)
 	}
@@ -629,6 +636,10 @@ func (m *manager) UnregisterSender(ctx context.Context, ps pkgsender.Sender) err
 	s.Lock()
 	s.active = false
 	s.Unlock()
+	if last {
+		// no route uses the sender any more
+		s.sender.StopSending(ctx)
+	}
 	return nil
 
 }
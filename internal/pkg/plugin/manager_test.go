@@ -261,6 +261,9 @@ func TestSenderUnregister(t *testing.T) {
 	sMap = m.Senders()
 	a.Expect(len(sMap)).To(Equal(0))
 
+	// the sender stops once no route uses it any more
+	unwrapped := s.(interface{ Unwrap() pkgsender.Sender }).Unwrap()
+	a.Expect(len(unwrapped.(*pkgsender.SenderMock).StopSendingCalls())).To(Equal(1))
 }
 
 func TestSenderLifecycle(t *testing.T) {
@@ -572,6 +575,8 @@ func newSenderPlugin(t *testing.T) pkgplugin.Pluginer {
 				defer mock.Unlock()
 				mock.events = append(mock.events, e)
 			},
+			StopSendingFunc: func(ctx context.Context) {
+			},
 		}, nil
 	}
 
@@ -14,7 +14,10 @@
 
 package quota
 
-import "github.com/xmidt-org/ears/pkg/errs"
+import (
+	"github.com/xmidt-org/ears/pkg/errs"
+	"github.com/xmidt-org/ears/pkg/tenant"
+)
 
 type ConfigNotFoundError struct {
 	configKey string
@@ -39,3 +42,15 @@ type NoEarsInstances struct {
 func (e *NoEarsInstances) Error() string {
 	return errs.String("NoEarsInstances", nil, nil)
 }
+
+// QuotaExceededError is returned when an event is turned away because its tenant
+// is over quota. Period tells which quota is exceeded
+type QuotaExceededError struct {
+	Tenant tenant.Id
+	Policy string
+	Period string
+}
+
+func (e *QuotaExceededError) Error() string {
+	return errs.String("QuotaExceededError", map[string]interface{}{"orgId": e.Tenant.OrgId, "appId": e.Tenant.AppId, "policy": e.Policy, "period": e.Period}, nil)
+}
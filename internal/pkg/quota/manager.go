@@ -20,8 +20,14 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/xmidt-org/ears/internal/pkg/config"
+	"github.com/xmidt-org/ears/internal/pkg/rtsemconv"
 	"github.com/xmidt-org/ears/internal/pkg/syncer"
+	"github.com/xmidt-org/ears/pkg/ratelimit"
 	"github.com/xmidt-org/ears/pkg/tenant"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+	"sort"
 	"sync"
 	"time"
 )
@@ -34,16 +40,23 @@ type QuotaManager struct {
 	backendLimiterType string
 	redisAddr          string
 	logger             *zerolog.Logger
+	throttledCounter   metric.Int64Counter
 
-	ticker *time.Ticker
-	done   context.CancelFunc
-	ctx    context.Context
+	ticker      *time.Ticker
+	usageTicker *time.Ticker
+	done        context.CancelFunc
+	ctx         context.Context
 }
 
 const LimiterTypeNone = "none"
 const LimiterTypeRedis = "redis"
 const LimiterTypeInMemory = "inmemory"
 
+// every instance reports its quota usage this often. Reports older than
+// usageStaleAfter belong to instances that are gone and are ignored
+const usageReportInterval = 10 * time.Second
+const usageStaleAfter = 6 * usageReportInterval
+
 func validateLimiterType(limiterType string) bool {
 	return limiterType == LimiterTypeNone ||
 		limiterType == LimiterTypeRedis ||
@@ -65,6 +78,13 @@ func NewQuotaManager(logger *zerolog.Logger, tenantStorer tenant.TenantStorer, s
 		}
 	}
 
+	meter := global.Meter(rtsemconv.EARSMeterName)
+	throttledCounter := metric.Must(meter).
+		NewInt64Counter(
+			rtsemconv.EARSMetricEventThrottled,
+			metric.WithDescription("measures the number of events throttled by tenant quota"),
+		)
+
 	return &QuotaManager{
 		limiters:           make(map[string]*QuotaLimiter),
 		tenantStorer:       tenantStorer,
@@ -73,6 +93,7 @@ func NewQuotaManager(logger *zerolog.Logger, tenantStorer tenant.TenantStorer, s
 		backendLimiterType: backendLimiterType,
 		redisAddr:          redisAddr,
 		logger:             logger,
+		throttledCounter:   throttledCounter,
 	}, nil
 }
 
@@ -87,15 +108,18 @@ func (m *QuotaManager) Start() {
 	m.done = cancel
 	m.ctx = ctx
 	m.ticker = ticker
+	m.usageTicker = time.NewTicker(usageReportInterval)
 
 	go func() {
 		for {
-			m.logger.Info().Str("op", "PeriodicQuotaSync").Msg("sync tenant quotas")
 			select {
 			case <-m.ctx.Done():
 				return
 			case <-m.ticker.C:
+				m.logger.Info().Str("op", "PeriodicQuotaSync").Msg("sync tenant quotas")
 				m.syncAllItems()
+			case <-m.usageTicker.C:
+				m.reportAllUsage()
 			}
 		}
 	}()
@@ -106,6 +130,7 @@ func (m *QuotaManager) Stop() {
 
 	if m.ticker != nil {
 		m.ticker.Stop()
+		m.usageTicker.Stop()
 		m.done()
 	}
 }
@@ -119,6 +144,48 @@ func (m *QuotaManager) Wait(ctx context.Context, tid tenant.Id) error {
 	return limiter.Wait(ctx)
 }
 
+// Enforce applies the tenant quota policy to an event. With the wait policy it behaves
+// like Wait. With the drop and reject policies it returns a QuotaExceededError right
+// away if the tenant is over quota. Throttled events are counted per tenant and policy.
+func (m *QuotaManager) Enforce(ctx context.Context, tid tenant.Id) error {
+	limiter, err := m.getLimiter(ctx, tid)
+	if limiter == nil || err != nil {
+		return nil
+	}
+	throttled, err := limiter.Enforce(ctx)
+	if throttled {
+		period := QuotaPeriodSecond
+		var quotaExceeded *QuotaExceededError
+		if errors.As(err, &quotaExceeded) {
+			period = quotaExceeded.Period
+		}
+		m.throttledCounter.Add(ctx, 1,
+			attribute.String(rtsemconv.EARSOrgIdLabel, tid.OrgId),
+			attribute.String(rtsemconv.EARSAppIdLabel, tid.AppId),
+			attribute.String(rtsemconv.EARSQuotaPolicyLabel, limiter.Policy()),
+			attribute.String(rtsemconv.EARSQuotaPeriodLabel, period),
+		)
+	}
+	return err
+}
+
+// TenantBudget gets the remaining daily and monthly event volume budget of a tenant
+func (m *QuotaManager) TenantBudget(ctx context.Context, tid tenant.Id) (*TenantBudget, error) {
+	config, err := m.tenantStorer.GetConfig(ctx, tid)
+	if err != nil {
+		return nil, err
+	}
+	limiter, err := m.getLimiter(ctx, tid)
+	if err != nil {
+		return nil, err
+	}
+	if limiter == nil {
+		//volume is not counted without a backend limiter
+		return UnenforcedBudget(tid, config.Quota.EventsPerDay, config.Quota.EventsPerMonth), nil
+	}
+	return limiter.VolumeLimiter().Budget(ctx)
+}
+
 func (m *QuotaManager) TenantLimit(ctx context.Context, tid tenant.Id) int {
 	limiter, err := m.getLimiter(ctx, tid)
 	if err != nil {
@@ -127,6 +194,79 @@ func (m *QuotaManager) TenantLimit(ctx context.Context, tid tenant.Id) int {
 	return limiter.Limit()
 }
 
+// TenantUsage reports the configured quota of a tenant together with the adaptive limit,
+// observed throughput and throttle counts of every live ears instance
+func (m *QuotaManager) TenantUsage(ctx context.Context, tid tenant.Id) (*TenantUsage, error) {
+	config, err := m.tenantStorer.GetConfig(ctx, tid)
+	if err != nil {
+		return nil, err
+	}
+	usage := &TenantUsage{
+		Tenant:       tid,
+		EventsPerSec: config.Quota.EventsPerSec,
+		Policy:       config.Quota.EnforcementPolicy(),
+		Instances:    []ratelimit.InstanceUsage{},
+	}
+	limiter, err := m.getLimiter(ctx, tid)
+	if err != nil {
+		return nil, err
+	}
+	if limiter == nil {
+		return usage, nil
+	}
+	//report first so that this instance is always up-to-date
+	err = limiter.ReportUsage(ctx, m.syncer.GetInstanceId())
+	if err != nil {
+		log.Ctx(ctx).Error().Str("op", "TenantUsage").Str("error", err.Error()).Msg("Error reporting quota usage")
+	}
+	instances, err := limiter.GetUsage(ctx, m.syncer.GetInstanceId())
+	if err != nil {
+		return nil, err
+	}
+	staleTs := time.Now().Add(-usageStaleAfter).Unix()
+	for _, instance := range instances {
+		if instance.Reported < staleTs {
+			continue
+		}
+		usage.Events = usage.Events.Add(instance.Events)
+		usage.Throttled = usage.Throttled.Add(instance.Throttled)
+		usage.Instances = append(usage.Instances, instance)
+	}
+	sort.Slice(usage.Instances, func(i, j int) bool {
+		return usage.Instances[i].InstanceId < usage.Instances[j].InstanceId
+	})
+	usage.Throughput = NewUsageRates(usage.Events)
+	return usage, nil
+}
+
+// InstanceShares reports the part of each tenant quota allotted to this instance for all
+// tenants which have sent events through this instance
+func (m *QuotaManager) InstanceShares() []InstanceShare {
+	shares := make([]InstanceShare, 0)
+	for _, limiter := range m.copyLimiters() {
+		share := InstanceShare{
+			Tenant:             limiter.tid,
+			EventsPerSec:       limiter.AdaptiveLimit(),
+			TenantEventsPerSec: limiter.Limit(),
+		}
+		if share.EventsPerSec < 0 {
+			// nothing allotted before the first event
+			share.EventsPerSec = 0
+		}
+		if share.TenantEventsPerSec > 0 {
+			share.Share = float64(share.EventsPerSec) / float64(share.TenantEventsPerSec)
+		}
+		shares = append(shares, share)
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Tenant.OrgId != shares[j].Tenant.OrgId {
+			return shares[i].Tenant.OrgId < shares[j].Tenant.OrgId
+		}
+		return shares[i].Tenant.AppId < shares[j].Tenant.AppId
+	})
+	return shares
+}
+
 func (m *QuotaManager) SyncItem(ctx context.Context, tid tenant.Id, itemId string, add bool) error {
 	limiter, err := m.getLimiter(ctx, tid)
 	if limiter == nil {
@@ -136,16 +276,18 @@ func (m *QuotaManager) SyncItem(ctx context.Context, tid tenant.Id, itemId strin
 		return err
 	}
 	config, err := m.tenantStorer.GetConfig(ctx, tid)
-	tenantRqs := 0
+	var quota tenant.Quota
 	if err != nil {
 		var tenantNotFound *tenant.TenantNotFoundError
 		if !errors.As(err, &tenantNotFound) {
 			return err
 		}
 	} else {
-		tenantRqs = config.Quota.EventsPerSec
+		quota = config.Quota
 	}
-	return limiter.SetLimit(tenantRqs)
+	limiter.SetPolicy(quota.EnforcementPolicy())
+	limiter.VolumeLimiter().SetLimits(quota.EventsPerDay, quota.EventsPerMonth)
+	return limiter.SetLimit(quota.EventsPerSec)
 }
 
 //PublishQuota publishes tenant quota to ratelimiters in all nodes so they can sync to the new quota
@@ -173,15 +315,16 @@ func (m *QuotaManager) getLimiter(ctx context.Context, tid tenant.Id) (*QuotaLim
 	}
 
 	config, err := m.tenantStorer.GetConfig(ctx, tid)
-	tenantRqs := 0
+	var quota tenant.Quota
 	if err != nil {
 		var tenantNotFound *tenant.TenantNotFoundError
 		if !errors.As(err, &tenantNotFound) {
 			return nil, err
 		}
 	} else {
-		tenantRqs = config.Quota.EventsPerSec
+		quota = config.Quota
 	}
+	tenantRqs := quota.EventsPerSec
 
 	instanceCount := m.syncer.GetInstanceCount(ctx)
 	if instanceCount == 0 {
@@ -191,12 +334,28 @@ func (m *QuotaManager) getLimiter(ctx context.Context, tid tenant.Id) (*QuotaLim
 	initialRqs := tenantRqs / instanceCount
 
 	limiter = NewQuotaLimiter(tid, m.backendLimiterType, m.redisAddr, initialRqs, tenantRqs)
+	limiter.SetPolicy(quota.EnforcementPolicy())
+	limiter.VolumeLimiter().SetLimits(quota.EventsPerDay, quota.EventsPerMonth)
 	m.limiters[tid.Key()] = limiter
 	return limiter, nil
 }
 
+func (m *QuotaManager) reportAllUsage() {
+	for _, limiter := range m.copyLimiters() {
+		err := limiter.ReportUsage(m.ctx, m.syncer.GetInstanceId())
+		if err != nil {
+			m.logger.Error().Str("op", "ReportQuotaUsage").Str("orgId", limiter.tid.OrgId).Str("appId", limiter.tid.AppId).Str("error", err.Error()).Msg("Error reporting quota usage")
+		}
+	}
+}
+
 func (m *QuotaManager) syncAllItems() {
-	//make a copy of quota limiters
+	for _, limiter := range m.copyLimiters() {
+		m.SyncItem(m.ctx, limiter.tid, "ignored", true)
+	}
+}
+
+func (m *QuotaManager) copyLimiters() []*QuotaLimiter {
 	m.lock.Lock()
 	limiters := make([]*QuotaLimiter, len(m.limiters))
 	i := 0
@@ -205,8 +364,5 @@ func (m *QuotaManager) syncAllItems() {
 		i++
 	}
 	m.lock.Unlock()
-
-	for _, limiter := range limiters {
-		m.SyncItem(m.ctx, limiter.tid, "ignored", true)
-	}
+	return limiters
 }
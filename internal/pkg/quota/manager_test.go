@@ -110,6 +110,14 @@ func TestQuotaManagerSyncing(t *testing.T) {
 		t.Fatalf("Expect limit=20 instead, got limit=%d\n", quotaMgr2.TenantLimit(ctx, tenantConfig.Tenant))
 	}
 
+	shares := quotaMgr1.InstanceShares()
+	if len(shares) != 1 || !shares[0].Tenant.Equal(tenantConfig.Tenant) || shares[0].TenantEventsPerSec != 20 {
+		t.Fatalf("Expect one quota share of 20 events per sec, got %+v\n", shares)
+	}
+	if shares[0].EventsPerSec < 0 || shares[0].Share != float64(shares[0].EventsPerSec)/20 {
+		t.Fatalf("Unexpected quota share %+v\n", shares[0])
+	}
+
 	quotaMgr1.Stop()
 	quotaMgr2.Stop()
 }
@@ -180,3 +188,194 @@ func validateQuotaMgrRps(mgr *quota.QuotaManager, tid tenant.Id, rps int) error
 	}
 	return TestErr_FailToReachRps
 }
+
+func TestQuotaManagerPolicy(t *testing.T) {
+	policies := []string{tenant.QuotaPolicyDrop, tenant.QuotaPolicyReject}
+	for _, policy := range policies {
+		t.Run(policy, func(t *testing.T) {
+			tenantStorer := db.NewTenantInmemoryStorer()
+			ctx := context.Background()
+			tenantConfig := tenant.Config{
+				Tenant: tenant.Id{
+					OrgId: "myPolicyOrg",
+					AppId: "myApp_" + policy,
+				},
+				Quota: tenant.Quota{
+					EventsPerSec: 5,
+					Policy:       policy,
+				},
+			}
+			tenantStorer.SetConfig(ctx, tenantConfig)
+
+			quotaMgr, err := setup(tenantStorer)
+			if err != nil {
+				t.Fatalf("Fail to start quota manager %s\n", err.Error())
+			}
+			quotaMgr.Start()
+			defer quotaMgr.Stop()
+
+			//none of the calls should block, and the ones over quota should be turned away
+			start := time.Now()
+			exceeded := 0
+			for i := 0; i < 50; i++ {
+				err = quotaMgr.Enforce(ctx, tenantConfig.Tenant)
+				if err == nil {
+					continue
+				}
+				var quotaExceeded *quota.QuotaExceededError
+				if !errors.As(err, &quotaExceeded) {
+					t.Fatalf("Expect QuotaExceededError, got %s instead\n", err.Error())
+				}
+				if quotaExceeded.Policy != policy {
+					t.Fatalf("Expect policy %s, got %s instead\n", policy, quotaExceeded.Policy)
+				}
+				exceeded++
+			}
+			if time.Since(start) > time.Second {
+				t.Fatalf("Enforce blocked for %dms\n", time.Since(start).Milliseconds())
+			}
+			if exceeded == 0 {
+				t.Fatalf("Expect some events to exceed the quota\n")
+			}
+		})
+	}
+}
+
+func TestQuotaManagerUsage(t *testing.T) {
+
+	//setup tenant shared tenant storer
+	tenantStorer := db.NewTenantInmemoryStorer()
+	ctx := context.Background()
+	tenantConfig := tenant.Config{
+		Tenant: tenant.Id{
+			OrgId: "usageOrg",
+			AppId: "usageApp",
+		},
+		Quota: tenant.Quota{
+			EventsPerSec: 10,
+			Policy:       tenant.QuotaPolicyDrop,
+		},
+	}
+	tenantStorer.SetConfig(ctx, tenantConfig)
+
+	quotaMgr1, err := setup(tenantStorer)
+	if err != nil {
+		t.Fatalf("Fail to start quota manager 1 %s\n", err.Error())
+	}
+	quotaMgr2, err := setup(tenantStorer)
+	if err != nil {
+		t.Fatalf("Fail to start quota manager 2 %s\n", err.Error())
+	}
+
+	for i := 0; i < 20; i++ {
+		quotaMgr1.Enforce(ctx, tenantConfig.Tenant)
+		quotaMgr2.Enforce(ctx, tenantConfig.Tenant)
+	}
+
+	//instance 2 reports its usage before instance 1 aggregates it
+	_, err = quotaMgr2.TenantUsage(ctx, tenantConfig.Tenant)
+	if err != nil {
+		t.Fatalf("Error getting usage %s\n", err.Error())
+	}
+	usage, err := quotaMgr1.TenantUsage(ctx, tenantConfig.Tenant)
+	if err != nil {
+		t.Fatalf("Error getting usage %s\n", err.Error())
+	}
+	if usage.EventsPerSec != 10 {
+		t.Fatalf("Expect eventsPerSec=10 instead, got eventsPerSec=%d\n", usage.EventsPerSec)
+	}
+	if len(usage.Instances) != 2 {
+		t.Fatalf("Expect 2 instances instead, got %d\n", len(usage.Instances))
+	}
+	if usage.Throttled.OneMinute == 0 {
+		t.Fatalf("Expect some throttled events\n")
+	}
+	if usage.Events.OneMinute+usage.Throttled.OneMinute != 40 {
+		t.Fatalf("Expect 40 events instead, got %d\n", usage.Events.OneMinute+usage.Throttled.OneMinute)
+	}
+	if usage.Events.OneHour != usage.Events.OneMinute || usage.Throttled.FiveMinutes != usage.Throttled.OneMinute {
+		t.Fatalf("Unexpected usage windows %+v %+v\n", usage.Events, usage.Throttled)
+	}
+	if usage.Throughput.OneMinute != float64(usage.Events.OneMinute)/60 {
+		t.Fatalf("Unexpected throughput %f\n", usage.Throughput.OneMinute)
+	}
+}
+
+func TestQuotaManagerVolume(t *testing.T) {
+	tenantStorer := db.NewTenantInmemoryStorer()
+	ctx := context.Background()
+	tenantConfig := tenant.Config{
+		Tenant: tenant.Id{
+			OrgId: "volumeOrg",
+			AppId: "volumeApp",
+		},
+		Quota: tenant.Quota{
+			EventsPerSec: 100,
+			EventsPerDay: 10,
+		},
+	}
+	tenantStorer.SetConfig(ctx, tenantConfig)
+
+	quotaMgr, err := setup(tenantStorer)
+	if err != nil {
+		t.Fatalf("Fail to start quota manager %s\n", err.Error())
+	}
+
+	for i := 0; i < 10; i++ {
+		err = quotaMgr.Enforce(ctx, tenantConfig.Tenant)
+		if err != nil {
+			t.Fatalf("Fail to enforce event %d %s\n", i, err.Error())
+		}
+	}
+
+	//the wait policy cannot wait out the daily quota
+	err = quotaMgr.Enforce(ctx, tenantConfig.Tenant)
+	var quotaExceeded *quota.QuotaExceededError
+	if !errors.As(err, &quotaExceeded) {
+		t.Fatalf("Expect QuotaExceededError, got %v instead\n", err)
+	}
+	if quotaExceeded.Policy != tenant.QuotaPolicyReject || quotaExceeded.Period != quota.QuotaPeriodDay {
+		t.Fatalf("Unexpected error %s\n", err.Error())
+	}
+
+	budget, err := quotaMgr.TenantBudget(ctx, tenantConfig.Tenant)
+	if err != nil {
+		t.Fatalf("Fail to get budget %s\n", err.Error())
+	}
+	if !budget.Enforced || budget.Day.Limit != 10 || budget.Day.Used != 10 || budget.Day.Remaining != 0 || budget.Month.Remaining != -1 {
+		t.Fatalf("Unexpected budget %+v\n", budget)
+	}
+}
+
+func TestQuotaManagerVolumeNotEnforced(t *testing.T) {
+	tenantStorer := db.NewTenantInmemoryStorer()
+	ctx := context.Background()
+	tenantConfig := tenant.Config{
+		Tenant: tenant.Id{
+			OrgId: "volumeOrg",
+			AppId: "unenforcedApp",
+		},
+		Quota: tenant.Quota{
+			EventsPerSec: 100,
+			EventsPerDay: 10,
+		},
+	}
+	tenantStorer.SetConfig(ctx, tenantConfig)
+
+	testLogger := zerolog.New(os.Stdout)
+	config := testConfig()
+	config.(*viper.Viper).Set("ears.ratelimiter.type", quota.LimiterTypeNone)
+	syncer := syncer.NewInMemoryDeltaSyncer(&testLogger, config)
+	quotaMgr, err := quota.NewQuotaManager(&testLogger, tenantStorer, syncer, config)
+	if err != nil {
+		t.Fatalf("Fail to start quota manager %s\n", err.Error())
+	}
+
+	budget, err := quotaMgr.TenantBudget(ctx, tenantConfig.Tenant)
+	if err != nil {
+		t.Fatalf("Fail to get budget %s\n", err.Error())
+	}
+	if budget.Enforced || budget.Day.Limit != 10 || budget.Day.Used != 0 || budget.Day.Remaining != -1 || budget.Month.Remaining != -1 {
+		t.Fatalf("Unexpected budget %+v\n", budget)
+	}
+}
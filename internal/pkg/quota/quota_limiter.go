@@ -21,6 +21,7 @@ import (
 	"github.com/xmidt-org/ears/pkg/ratelimit"
 	"github.com/xmidt-org/ears/pkg/ratelimit/redis"
 	"github.com/xmidt-org/ears/pkg/tenant"
+	"sync"
 	"time"
 )
 
@@ -28,7 +29,12 @@ import (
 type QuotaLimiter struct {
 	tid             tenant.Id
 	adaptiveLimiter *ratelimit.AdaptiveRateLimiter
+	backendLimiter  ratelimit.RateLimiter
+	volumeLimiter   *VolumeLimiter
+	usage           *usageWindow
 	wakeup          chan bool
+	policy          string
+	lock            *sync.RWMutex
 }
 
 func NewQuotaLimiter(tid tenant.Id, backendLimiterType string, redisAddr string, initialRqs int, tenantRqs int) *QuotaLimiter {
@@ -44,19 +50,129 @@ func NewQuotaLimiter(tid tenant.Id, backendLimiterType string, redisAddr string,
 	return &QuotaLimiter{
 		tid:             tid,
 		adaptiveLimiter: limiter,
+		backendLimiter:  backendLimiter,
+		volumeLimiter:   NewVolumeLimiter(tid, backendLimiterType, redisAddr),
+		usage:           &usageWindow{},
 		wakeup:          make(chan bool),
+		policy:          tenant.QuotaPolicyWait,
+		lock:            &sync.RWMutex{},
 	}
 }
 
 func (r *QuotaLimiter) Wait(ctx context.Context) error {
+	throttled, err := r.wait(ctx)
+	r.recordUsage(throttled, err)
+	return err
+}
+
+// Enforce applies the tenant quota policy to a single event. It also reports whether
+// the event was throttled, i.e. whether it hit the limit at least once.
+// Events that pass the rate limit are then counted against the daily and monthly
+// volume quota. Waiting out a used up volume quota is not an option, so the wait
+// policy rejects those events
+func (r *QuotaLimiter) Enforce(ctx context.Context) (bool, error) {
+	throttled, err := r.enforceRate(ctx)
+	if err == nil {
+		err = r.enforceVolume(ctx)
+		throttled = err != nil
+	}
+	r.recordUsage(throttled, err)
+	return throttled, err
+}
+
+func (r *QuotaLimiter) enforceRate(ctx context.Context) (bool, error) {
+	policy := r.Policy()
+	if policy == tenant.QuotaPolicyWait {
+		return r.wait(ctx)
+	}
+	err := r.Take(ctx, 1)
+	if err == nil {
+		return false, nil
+	}
+	var limitReached *ratelimit.LimitReached
+	if !errors.As(err, &limitReached) {
+		log.Ctx(ctx).Error().Str("op", "QuotaLimiter.Enforce").Str("error", err.Error()).Msg("Error taking quota")
+	}
+	return true, &QuotaExceededError{Tenant: r.tid, Policy: policy, Period: QuotaPeriodSecond}
+}
+
+func (r *QuotaLimiter) enforceVolume(ctx context.Context) error {
+	err := r.volumeLimiter.Take(ctx)
+	if err == nil {
+		return nil
+	}
+	var quotaExceeded *QuotaExceededError
+	if !errors.As(err, &quotaExceeded) {
+		//do not hold up events when the volume counters are unavailable
+		log.Ctx(ctx).Error().Str("op", "QuotaLimiter.Enforce").Str("error", err.Error()).Msg("Error taking volume quota")
+		return nil
+	}
+	quotaExceeded.Policy = tenant.QuotaPolicyReject
+	if r.Policy() == tenant.QuotaPolicyDrop {
+		quotaExceeded.Policy = tenant.QuotaPolicyDrop
+	}
+	return quotaExceeded
+}
+
+func (r *QuotaLimiter) VolumeLimiter() *VolumeLimiter {
+	return r.volumeLimiter
+}
+
+func (r *QuotaLimiter) recordUsage(throttled bool, err error) {
+	var events, throttledEvents int64
+	if err == nil {
+		events = 1
+	}
+	if throttled {
+		throttledEvents = 1
+	}
+	r.usage.record(time.Now(), events, throttledEvents)
+}
+
+// Usage gets the quota usage of this instance
+func (r *QuotaLimiter) Usage(instanceId string) ratelimit.InstanceUsage {
+	now := time.Now()
+	events, throttled := r.usage.counts(now)
+	return ratelimit.InstanceUsage{
+		InstanceId:    instanceId,
+		AdaptiveLimit: r.AdaptiveLimit(),
+		Events:        events,
+		Throttled:     throttled,
+		Reported:      now.Unix(),
+	}
+}
+
+// ReportUsage shares the quota usage of this instance through the backend limiter.
+// It is a no-op if the backend limiter cannot share usage
+func (r *QuotaLimiter) ReportUsage(ctx context.Context, instanceId string) error {
+	reporter, ok := r.backendLimiter.(ratelimit.UsageReporter)
+	if !ok {
+		return nil
+	}
+	return reporter.ReportUsage(ctx, r.Usage(instanceId))
+}
+
+// GetUsage gets the quota usage reported by all instances through the backend limiter.
+// If the backend limiter cannot share usage, only the usage of this instance is returned
+func (r *QuotaLimiter) GetUsage(ctx context.Context, instanceId string) ([]ratelimit.InstanceUsage, error) {
+	reporter, ok := r.backendLimiter.(ratelimit.UsageReporter)
+	if !ok {
+		return []ratelimit.InstanceUsage{r.Usage(instanceId)}, nil
+	}
+	return reporter.GetUsage(ctx)
+}
+
+func (r *QuotaLimiter) wait(ctx context.Context) (bool, error) {
+	throttled := false
 	for {
 		err := r.Take(ctx, 1)
 		if err == nil {
-			return nil
+			return throttled, nil
 		}
 		sleepTO := time.Second * 5
 		var limitReached *ratelimit.LimitReached
 		if errors.As(err, &limitReached) {
+			throttled = true
 			//TODO figure out what's the optimal way of waiting
 			sleepTO = time.Millisecond * 100
 		} else {
@@ -64,7 +180,7 @@ func (r *QuotaLimiter) Wait(ctx context.Context) error {
 		}
 		select {
 		case <-ctx.Done():
-			return &ratelimit.ContextCancelled{}
+			return throttled, &ratelimit.ContextCancelled{}
 		case <-r.wakeup:
 			//keep looping
 		case <-time.After(sleepTO):
@@ -85,6 +201,21 @@ func (r *QuotaLimiter) AdaptiveLimit() int {
 	return r.adaptiveLimiter.AdaptiveLimit()
 }
 
+func (r *QuotaLimiter) Policy() string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.policy
+}
+
+func (r *QuotaLimiter) SetPolicy(policy string) {
+	if policy == "" {
+		policy = tenant.QuotaPolicyWait
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.policy = policy
+}
+
 func (r *QuotaLimiter) SetLimit(newLimit int) error {
 	if r.Limit() == newLimit {
 		//limit is not changed
@@ -0,0 +1,109 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"github.com/xmidt-org/ears/pkg/ratelimit"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"sync"
+	"time"
+)
+
+const (
+	usageBucketSize = 10 * time.Second
+	usageBuckets    = int(time.Hour / usageBucketSize)
+)
+
+// TenantUsage reports the quota usage of a tenant aggregated across all ears instances
+type TenantUsage struct {
+	Tenant       tenant.Id                 `json:"tenant"`
+	EventsPerSec int                       `json:"eventsPerSec"`
+	Policy       string                    `json:"policy"`
+	Throughput   UsageRates                `json:"throughput"`
+	Events       ratelimit.UsageCounts     `json:"events"`
+	Throttled    ratelimit.UsageCounts     `json:"throttled"`
+	Instances    []ratelimit.InstanceUsage `json:"instances"`
+}
+
+// InstanceShare is the part of a tenant quota currently allotted to one ears instance
+type InstanceShare struct {
+	Tenant             tenant.Id `json:"tenant"`
+	EventsPerSec       int       `json:"eventsPerSec"`       // adaptive limit of the instance
+	TenantEventsPerSec int       `json:"tenantEventsPerSec"` // quota of the tenant across all instances
+	Share              float64   `json:"share"`              // fraction of the tenant quota
+}
+
+// UsageRates holds the observed throughput in events per second over the 1m, 5m and 1h windows
+type UsageRates struct {
+	OneMinute   float64 `json:"1m"`
+	FiveMinutes float64 `json:"5m"`
+	OneHour     float64 `json:"1h"`
+}
+
+func NewUsageRates(counts ratelimit.UsageCounts) UsageRates {
+	return UsageRates{
+		OneMinute:   float64(counts.OneMinute) / time.Minute.Seconds(),
+		FiveMinutes: float64(counts.FiveMinutes) / (5 * time.Minute).Seconds(),
+		OneHour:     float64(counts.OneHour) / time.Hour.Seconds(),
+	}
+}
+
+type usageBucket struct {
+	slot      int64
+	events    int64
+	throttled int64
+}
+
+// usageWindow counts admitted and throttled events over the last hour in 10 second buckets
+type usageWindow struct {
+	sync.Mutex
+	buckets [usageBuckets]usageBucket
+}
+
+func (w *usageWindow) record(now time.Time, events int64, throttled int64) {
+	slot := now.UnixNano() / int64(usageBucketSize)
+	w.Lock()
+	defer w.Unlock()
+	bucket := &w.buckets[slot%int64(usageBuckets)]
+	if bucket.slot != slot {
+		*bucket = usageBucket{slot: slot}
+	}
+	bucket.events += events
+	bucket.throttled += throttled
+}
+
+func (w *usageWindow) counts(now time.Time) (ratelimit.UsageCounts, ratelimit.UsageCounts) {
+	var events, throttled ratelimit.UsageCounts
+	slot := now.UnixNano() / int64(usageBucketSize)
+	w.Lock()
+	defer w.Unlock()
+	for _, bucket := range w.buckets {
+		age := time.Duration(slot-bucket.slot) * usageBucketSize
+		if bucket.slot == 0 || age < 0 || age >= time.Hour {
+			continue
+		}
+		events.OneHour += bucket.events
+		throttled.OneHour += bucket.throttled
+		if age < 5*time.Minute {
+			events.FiveMinutes += bucket.events
+			throttled.FiveMinutes += bucket.throttled
+		}
+		if age < time.Minute {
+			events.OneMinute += bucket.events
+			throttled.OneMinute += bucket.throttled
+		}
+	}
+	return events, throttled
+}
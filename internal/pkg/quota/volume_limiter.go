@@ -0,0 +1,198 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"context"
+	"github.com/xmidt-org/ears/pkg/ratelimit"
+	"github.com/xmidt-org/ears/pkg/ratelimit/redis"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"sync"
+	"time"
+)
+
+const (
+	QuotaPeriodSecond = "second"
+	QuotaPeriodDay    = "day"
+	QuotaPeriodMonth  = "month"
+)
+
+// counters outlive their period a little so that instances with slightly skewed
+// clocks still count against the same period
+const volumeCounterGracePeriod = time.Hour
+
+// VolumeLimiter enforces the daily and monthly event volume quota of a tenant.
+// Periods are aligned to calendar days and months in UTC
+type VolumeLimiter struct {
+	tid            tenant.Id
+	counter        ratelimit.VolumeCounter
+	eventsPerDay   int
+	eventsPerMonth int
+	lock           *sync.RWMutex
+}
+
+// VolumeBudget is the event volume budget of a tenant for the current period.
+// Remaining is -1 if there is no limit for the period
+type VolumeBudget struct {
+	Limit     int   `json:"limit"`
+	Used      int64 `json:"used"`
+	Remaining int64 `json:"remaining"`
+	Resets    int64 `json:"resets"`
+}
+
+// TenantBudget is the remaining daily and monthly event volume budget of a tenant.
+// Enforced is false if there is no backend limiter counting the event volume
+type TenantBudget struct {
+	Tenant   tenant.Id    `json:"tenant"`
+	Enforced bool         `json:"enforced"`
+	Day      VolumeBudget `json:"day"`
+	Month    VolumeBudget `json:"month"`
+}
+
+type volumePeriod struct {
+	name  string
+	limit int
+	key   string
+	end   time.Time
+}
+
+func NewVolumeLimiter(tid tenant.Id, backendLimiterType string, redisAddr string) *VolumeLimiter {
+	var counter ratelimit.VolumeCounter
+	if backendLimiterType == LimiterTypeRedis {
+		counter = redis.NewRedisVolumeCounter(redisAddr)
+	} else {
+		counter = ratelimit.NewInMemoryVolumeCounter()
+	}
+	return &VolumeLimiter{
+		tid:     tid,
+		counter: counter,
+		lock:    &sync.RWMutex{},
+	}
+}
+
+func (v *VolumeLimiter) Limits() (int, int) {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+	return v.eventsPerDay, v.eventsPerMonth
+}
+
+func (v *VolumeLimiter) SetLimits(eventsPerDay int, eventsPerMonth int) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.eventsPerDay = eventsPerDay
+	v.eventsPerMonth = eventsPerMonth
+}
+
+// Take counts one event against the daily and monthly quota. It returns a
+// QuotaExceededError without counting the event if either quota is used up
+func (v *VolumeLimiter) Take(ctx context.Context) error {
+	now := time.Now().UTC()
+	taken := make([]volumePeriod, 0, 2)
+	for _, period := range v.periods(now) {
+		if period.limit == 0 {
+			continue
+		}
+		count, err := v.counter.Incr(ctx, period.key, 1, period.end.Sub(now)+volumeCounterGracePeriod)
+		if err != nil {
+			v.undo(ctx, now, taken)
+			return err
+		}
+		taken = append(taken, period)
+		if count > int64(period.limit) {
+			v.undo(ctx, now, taken)
+			return &QuotaExceededError{Tenant: v.tid, Period: period.name}
+		}
+	}
+	return nil
+}
+
+// Budget gets the used and remaining event volume of the current day and month
+func (v *VolumeLimiter) Budget(ctx context.Context) (*TenantBudget, error) {
+	budget := &TenantBudget{Tenant: v.tid, Enforced: true}
+	for _, period := range v.periods(time.Now().UTC()) {
+		used, err := v.counter.Get(ctx, period.key)
+		if err != nil {
+			return nil, err
+		}
+		periodBudget := VolumeBudget{
+			Limit:     period.limit,
+			Used:      used,
+			Remaining: -1,
+			Resets:    period.end.Unix(),
+		}
+		if period.limit > 0 {
+			periodBudget.Remaining = int64(period.limit) - used
+			if periodBudget.Remaining < 0 {
+				periodBudget.Remaining = 0
+			}
+		}
+		if period.name == QuotaPeriodDay {
+			budget.Day = periodBudget
+		} else {
+			budget.Month = periodBudget
+		}
+	}
+	return budget, nil
+}
+
+// UnenforcedBudget reports the configured limits of a tenant whose event volume is not counted.
+// Nothing is used and the remaining budget is unlimited for every period
+func UnenforcedBudget(tid tenant.Id, eventsPerDay int, eventsPerMonth int) *TenantBudget {
+	v := &VolumeLimiter{
+		tid:  tid,
+		lock: &sync.RWMutex{},
+	}
+	v.SetLimits(eventsPerDay, eventsPerMonth)
+	budget := &TenantBudget{Tenant: tid}
+	for _, period := range v.periods(time.Now().UTC()) {
+		periodBudget := VolumeBudget{
+			Limit:     period.limit,
+			Remaining: -1,
+			Resets:    period.end.Unix(),
+		}
+		if period.name == QuotaPeriodDay {
+			budget.Day = periodBudget
+		} else {
+			budget.Month = periodBudget
+		}
+	}
+	return budget
+}
+
+func (v *VolumeLimiter) undo(ctx context.Context, now time.Time, taken []volumePeriod) {
+	for _, period := range taken {
+		v.counter.Incr(ctx, period.key, -1, period.end.Sub(now)+volumeCounterGracePeriod)
+	}
+}
+
+func (v *VolumeLimiter) periods(now time.Time) []volumePeriod {
+	eventsPerDay, eventsPerMonth := v.Limits()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return []volumePeriod{
+		{
+			name:  QuotaPeriodDay,
+			limit: eventsPerDay,
+			key:   v.tid.Key() + "_volume_" + dayStart.Format("20060102"),
+			end:   dayStart.AddDate(0, 0, 1),
+		},
+		{
+			name:  QuotaPeriodMonth,
+			limit: eventsPerMonth,
+			key:   v.tid.Key() + "_volume_" + monthStart.Format("200601"),
+			end:   monthStart.AddDate(0, 1, 0),
+		},
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota_test
+
+import (
+	"context"
+	"errors"
+	"github.com/xmidt-org/ears/internal/pkg/quota"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"testing"
+	"time"
+)
+
+func TestVolumeLimiter(t *testing.T) {
+	ctx := context.Background()
+	limiter := quota.NewVolumeLimiter(tenant.Id{OrgId: "myOrg", AppId: "myVolumeApp"}, "inmemory", "")
+	limiter.SetLimits(5, 8)
+
+	for i := 0; i < 5; i++ {
+		err := limiter.Take(ctx)
+		if err != nil {
+			t.Fatalf("Fail to take event %d, error=%s\n", i, err.Error())
+		}
+	}
+	err := limiter.Take(ctx)
+	var quotaExceeded *quota.QuotaExceededError
+	if !errors.As(err, &quotaExceeded) {
+		t.Fatalf("Expect QuotaExceededError, got %v instead\n", err)
+	}
+	if quotaExceeded.Period != quota.QuotaPeriodDay {
+		t.Fatalf("Expect day quota exceeded, got %s instead\n", quotaExceeded.Period)
+	}
+
+	//the event turned away is not counted
+	budget, err := limiter.Budget(ctx)
+	if err != nil {
+		t.Fatalf("Fail to get budget, error=%s\n", err.Error())
+	}
+	if budget.Day.Used != 5 || budget.Day.Remaining != 0 || budget.Month.Used != 5 || budget.Month.Remaining != 3 {
+		t.Fatalf("Unexpected budget %+v\n", budget)
+	}
+
+	//calendar aligned resets
+	now := time.Now().UTC()
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	if budget.Day.Resets != tomorrow.Unix() {
+		t.Fatalf("Expect day budget to reset at %d, got %d instead\n", tomorrow.Unix(), budget.Day.Resets)
+	}
+	nextMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	if budget.Month.Resets != nextMonth.Unix() {
+		t.Fatalf("Expect month budget to reset at %d, got %d instead\n", nextMonth.Unix(), budget.Month.Resets)
+	}
+
+	//lift the daily limit so that the monthly limit kicks in
+	limiter.SetLimits(0, 8)
+	for i := 0; i < 3; i++ {
+		err := limiter.Take(ctx)
+		if err != nil {
+			t.Fatalf("Fail to take event %d, error=%s\n", i, err.Error())
+		}
+	}
+	err = limiter.Take(ctx)
+	if !errors.As(err, &quotaExceeded) {
+		t.Fatalf("Expect QuotaExceededError, got %v instead\n", err)
+	}
+	if quotaExceeded.Period != quota.QuotaPeriodMonth {
+		t.Fatalf("Expect month quota exceeded, got %s instead\n", quotaExceeded.Period)
+	}
+	budget, err = limiter.Budget(ctx)
+	if err != nil {
+		t.Fatalf("Fail to get budget, error=%s\n", err.Error())
+	}
+	if budget.Day.Remaining != -1 || budget.Month.Used != 8 || budget.Month.Remaining != 0 {
+		t.Fatalf("Unexpected budget %+v\n", budget)
+	}
+}
@@ -41,6 +41,9 @@ const (
 	EARSPluginTypeHttpReceiver    = "httpReceiver"
 	EARSPluginTypeRedisReceiver   = "redisReceiver"
 
+	EARSPluginTypeDedupFilter  = "dedupFilter"
+	EARSPluginTypeEnrichFilter = "enrichFilter"
+
 	EARSMetricEventSuccess        = "ears.eventSuccess"
 	EARSMetricEventFailure        = "ears.eventFailure"
 	EARSMetricEventBytes          = "ears.eventBytes"
@@ -51,6 +54,11 @@ const (
 	EARSMetricAddRouteFailure     = "ears.addRouteFailure"
 	EARSMetricRemoveRouteSuccess  = "ears.removeRouteSuccess"
 	EARSMetricRemoveRouteFailure  = "ears.removeRouteFailure"
+	EARSMetricEventThrottled      = "ears.eventThrottled"
+	EARSMetricDedupHit            = "ears.dedupHit"
+	EARSMetricDedupMiss           = "ears.dedupMiss"
+	EARSMetricEnrichHit           = "ears.enrichHit"
+	EARSMetricEnrichMiss          = "ears.enrichMiss"
 
 	EARSRouteId = attribute.Key("ears.routeId")
 
@@ -64,6 +72,9 @@ const (
 	EARSOrgIdLabel   = "ears.orgId"
 	EARSReceiverName = "ears.receiver"
 
+	EARSQuotaPolicyLabel = "ears.quotaPolicy"
+	EARSQuotaPeriodLabel = "ears.quotaPeriod"
+
 	DBTable = attribute.Key("db.table")
 
 	KafkaTopicLabel        = "kafka.topic"
@@ -83,4 +94,5 @@ var (
 	EARSAPITrace   = attribute.Key("ears.op").String("api")
 
 	DBSystemInMemory = semconv.DBSystemKey.String("inmemory")
+	DBSystemFile     = semconv.DBSystemKey.String("file")
 )
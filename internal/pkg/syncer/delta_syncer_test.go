@@ -18,6 +18,7 @@ import (
 	"context"
 	"github.com/xmidt-org/ears/internal/pkg/syncer"
 	"github.com/xmidt-org/ears/pkg/tenant"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
@@ -101,8 +102,85 @@ func testSyncers(newSyncer func() syncer.DeltaSyncer, t *testing.T) {
 	collector.ValidateCount(4, t)
 	publisherCollector.ValidateCount(0, t)
 
+	//Case 4: validate that all syncers applied the latest version
+	validateVersions(ctx, syncers, t)
+
+	//Case 5: validate that a syncer catches up with the changes it missed while not listening
+	collector.Reset()
+	publisherCollector.Reset()
+	syncers[4].StopListeningForSyncRequests()
+	syncers[0].PublishSyncRequest(ctx, tid, "test", "testId", true)
+	syncers[0].PublishSyncRequest(ctx, tid, "test", "testId", false)
+	collector.ValidateCount(4, t)
+	publisherCollector.ValidateCount(2, t)
+	syncers[4].StartListeningForSyncRequests()
+	collector.ValidateCount(6, t)
+	validateVersions(ctx, syncers, t)
+
+	//Case 6: validate that a lease is held by one syncer at a time
+	validateLease(ctx, syncers, t)
+
 	//Teardown
 	for _, syncer := range syncers {
 		syncer.StopListeningForSyncRequests()
 	}
 }
+
+func validateVersions(ctx context.Context, syncers []syncer.DeltaSyncer, t *testing.T) {
+	version, err := syncers[0].GetVersion(ctx)
+	if err != nil {
+		t.Fatalf("GetVersion error: %s\n", err.Error())
+	}
+	if version == 0 {
+		t.Fatalf("Expect a version after publishing sync requests\n")
+	}
+	for i, s := range syncers {
+		numTries := 0
+		for s.GetAppliedVersion() != version && numTries < 10 {
+			time.Sleep(time.Millisecond * 100)
+			numTries++
+		}
+		if s.GetAppliedVersion() != version {
+			t.Fatalf("Expect syncer %d to apply version %d but get %d instead\n", i, version, s.GetAppliedVersion())
+		}
+	}
+}
+
+func validateLease(ctx context.Context, syncers []syncer.DeltaSyncer, t *testing.T) {
+	key := "test_" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	// the first syncer acquires the lease, the second one cannot and the first one renews it
+	steps := []struct {
+		syncer int
+		held   bool
+	}{{0, true}, {1, false}, {0, true}}
+	for _, step := range steps {
+		held, err := syncers[step.syncer].AcquireLease(ctx, key, time.Second)
+		if err != nil {
+			t.Fatalf("AcquireLease error: %s\n", err.Error())
+		}
+		if held != step.held {
+			t.Fatalf("Expect lease held by syncer %d to be %t but get %t instead\n", step.syncer, step.held, held)
+		}
+	}
+	err := syncers[0].ReleaseLease(ctx, key)
+	if err != nil {
+		t.Fatalf("ReleaseLease error: %s\n", err.Error())
+	}
+	held, err := syncers[1].AcquireLease(ctx, key, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("AcquireLease error: %s\n", err.Error())
+	}
+	if !held {
+		t.Fatalf("Expect released lease to be acquired by another syncer\n")
+	}
+	// a lease which is not renewed expires
+	time.Sleep(200 * time.Millisecond)
+	held, err = syncers[0].AcquireLease(ctx, key, time.Second)
+	if err != nil {
+		t.Fatalf("AcquireLease error: %s\n", err.Error())
+	}
+	if !held {
+		t.Fatalf("Expect expired lease to be acquired by another syncer\n")
+	}
+	syncers[0].ReleaseLease(ctx, key)
+}
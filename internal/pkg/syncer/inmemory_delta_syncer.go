@@ -21,27 +21,49 @@ import (
 	"github.com/xmidt-org/ears/internal/pkg/config"
 	"github.com/xmidt-org/ears/pkg/tenant"
 	"os"
+	"sort"
 	"sync"
+	"time"
 )
 
+// maximum number of commands kept in the change log of a delta syncer group
+const DefaultChangeLogLength = 10000
+
 var (
 	syncerGroup = DeltaSyncerGroup{
-		syncers: make(map[string]*InmemoryDeltaSyncer),
+		syncers:    make(map[string]*InmemoryDeltaSyncer),
+		heartbeats: make(map[string]heartbeat),
+		leases:     make(map[string]lease),
 	}
 )
 
 type (
 	DeltaSyncerGroup struct {
 		sync.Mutex
-		syncers map[string]*InmemoryDeltaSyncer
+		syncers    map[string]*InmemoryDeltaSyncer
+		version    int64         // version of the latest command in the change log
+		log        []SyncCommand // most recent commands ordered by version
+		heartbeats map[string]heartbeat
+		leases     map[string]lease
+	}
+
+	heartbeat struct {
+		status  []byte
+		expires time.Time
+	}
+
+	lease struct {
+		instanceId string
+		expires    time.Time
 	}
 
 	InmemoryDeltaSyncer struct {
 		sync.Mutex
-		active       bool
-		instanceId   string
-		localSyncers map[string][]LocalSyncer
-		logger       *zerolog.Logger
+		active         bool
+		instanceId     string
+		localSyncers   map[string][]LocalSyncer
+		logger         *zerolog.Logger
+		appliedVersion int64
 	}
 )
 
@@ -58,6 +80,10 @@ func NewInMemoryDeltaSyncer(logger *zerolog.Logger, config config.Config) DeltaS
 	if !s.active {
 		logger.Info().Msg("InMemory Delta Syncer Not Activated")
 	}
+	// a new instance loads all items from storage, so it starts at the current version
+	syncerGroup.Lock()
+	s.appliedVersion = syncerGroup.version
+	syncerGroup.Unlock()
 	return s
 }
 
@@ -89,7 +115,7 @@ func (s *InmemoryDeltaSyncer) UnregisterLocalSyncer(itemType string, localSyncer
 	}
 }
 
-// PublishSyncRequest asks others to sync their routing tables
+// PublishSyncRequest appends a command to the change log and asks others to sync their routing tables
 
 func (s *InmemoryDeltaSyncer) PublishSyncRequest(ctx context.Context, tid tenant.Id, itemType string, itemId string, add bool) {
 	if !s.active {
@@ -102,39 +128,60 @@ func (s *InmemoryDeltaSyncer) PublishSyncRequest(ctx context.Context, tid tenant
 		cmd = EARS_REMOVE_ITEM_CMD
 	}
 	sid := uuid.New().String() // session id
-	numSubscribers := s.GetInstanceCount(ctx)
-	if numSubscribers <= 1 {
-		s.logger.Info().Str("op", "PublishSyncRequest").Msg("no subscribers but me - no need to publish sync")
-	} else {
 
-		syncerGroup.Lock()
-		for id, syncer := range syncerGroup.syncers {
-			if id == s.instanceId {
-				continue
-			}
-			localSyncer := syncer
-			go func() {
-				msg := SyncCommand{
-					cmd,
-					itemType,
-					itemId,
-					s.instanceId,
-					sid,
-					tid,
-				}
+	syncerGroup.Lock()
+	syncerGroup.version++
+	syncerGroup.log = append(syncerGroup.log, SyncCommand{
+		cmd,
+		itemType,
+		itemId,
+		s.instanceId,
+		sid,
+		tid,
+		syncerGroup.version,
+	})
+	if len(syncerGroup.log) > DefaultChangeLogLength {
+		syncerGroup.log = syncerGroup.log[len(syncerGroup.log)-DefaultChangeLogLength:]
+	}
+	// every listener including the publisher catches up in version order, the publisher only advances its version
+	for _, syncer := range syncerGroup.syncers {
+		go syncer.catchUp(ctx)
+	}
+	syncerGroup.Unlock()
+}
 
-				localSyncer.notify(ctx, msg)
-			}()
+// catchUp applies all commands from the change log which are newer than the applied version
+func (s *InmemoryDeltaSyncer) catchUp(ctx context.Context) {
+	s.Lock()
+	defer s.Unlock()
+	syncerGroup.Lock()
+	pending := make([]SyncCommand, 0)
+	for _, msg := range syncerGroup.log {
+		if msg.Version > s.appliedVersion {
+			pending = append(pending, msg)
+		}
+	}
+	version := syncerGroup.version
+	syncerGroup.Unlock()
+	if len(pending) > 0 && pending[0].Version > s.appliedVersion+1 {
+		s.logger.Error().Str("op", "catchUp").Str("instanceId", s.instanceId).Int64("appliedVersion", s.appliedVersion).Int64("version", pending[0].Version).Msg("changes no longer in change log")
+		ResyncAll(ctx, s.logger, s.localSyncers)
+		s.appliedVersion = version
+		return
+	}
+	for _, msg := range pending {
+		if msg.InstanceId != s.instanceId {
+			s.notify(ctx, msg)
 		}
-		syncerGroup.Unlock()
+		s.appliedVersion = msg.Version
 	}
 }
 
+// notify applies a single command to the local syncers, the caller must hold the lock
 func (s *InmemoryDeltaSyncer) notify(ctx context.Context, msg SyncCommand) {
 	if msg.Cmd == EARS_ADD_ITEM_CMD {
 		s.logger.Info().Str("op", "ListenForSyncRequests").Str("instanceId", msg.InstanceId).Str("routeId", msg.ItemId).Str("sid", msg.Sid).Msg("received message to add route")
 
-		s.Lock()
 		syncers, ok := s.localSyncers[msg.ItemType]
 		if ok {
 			for _, localSyncer := range syncers {
@@ -144,11 +191,9 @@ func (s *InmemoryDeltaSyncer) notify(ctx context.Context, msg SyncCommand) {
 				}
 			}
 		}
-		s.Unlock()
 	} else if msg.Cmd == EARS_REMOVE_ITEM_CMD {
 		s.logger.Info().Str("op", "ListenForSyncRequests").Str("instanceId", msg.InstanceId).Str("routeId", msg.ItemId).Str("sid", msg.Sid).Msg("received message to remove route")
 
-		s.Lock()
 		syncers, ok := s.localSyncers[msg.ItemType]
 		if ok {
 			for _, localSyncer := range syncers {
@@ -158,7 +203,6 @@ func (s *InmemoryDeltaSyncer) notify(ctx context.Context, msg SyncCommand) {
 				}
 			}
 		}
-		s.Unlock()
 	} else if msg.Cmd == EARS_STOP_LISTENING_CMD {
 		s.logger.Info().Str("op", "ListenForSyncRequests").Str("instanceId", msg.InstanceId).Msg("stop message ignored")
 		// already handled above
@@ -167,11 +211,14 @@ func (s *InmemoryDeltaSyncer) notify(ctx context.Context, msg SyncCommand) {
 	}
 }
 
-// ListenForSyncRequests listens for sync request
+// ListenForSyncRequests listens for sync request and catches up with changes missed while not listening
 func (s *InmemoryDeltaSyncer) StartListeningForSyncRequests() {
 	syncerGroup.Lock()
-	defer syncerGroup.Unlock()
 	syncerGroup.syncers[s.instanceId] = s
+	syncerGroup.Unlock()
+	if s.active {
+		s.catchUp(context.Background())
+	}
 }
 
 // StopListeningForSyncRequests stops listening for sync requests
@@ -187,8 +234,99 @@ func (s *InmemoryDeltaSyncer) GetInstanceCount(ctx context.Context) int {
 	if !s.active {
 		return 0
 	}
+	syncerGroup.Lock()
+	defer syncerGroup.Unlock()
+
+	return len(syncerGroup.syncers)
+}
+
+func (s *InmemoryDeltaSyncer) GetInstanceId() string {
+	return s.instanceId
+}
+
+func (s *InmemoryDeltaSyncer) GetMembers(ctx context.Context) ([]string, error) {
+	members := []string{s.instanceId}
+	if !s.active {
+		return members, nil
+	}
+	syncerGroup.Lock()
+	for id := range syncerGroup.syncers {
+		if id != s.instanceId {
+			members = append(members, id)
+		}
+	}
+	syncerGroup.Unlock()
+	sort.Strings(members)
+	return members, nil
+}
+
+func (s *InmemoryDeltaSyncer) GetVersion(ctx context.Context) (int64, error) {
+	if !s.active {
+		return 0, nil
+	}
+	syncerGroup.Lock()
+	defer syncerGroup.Unlock()
+	return syncerGroup.version, nil
+}
+
+func (s *InmemoryDeltaSyncer) GetAppliedVersion() int64 {
 	s.Lock()
 	defer s.Unlock()
+	return s.appliedVersion
+}
 
-	return len(syncerGroup.syncers)
+func (s *InmemoryDeltaSyncer) PublishHeartbeat(ctx context.Context, status []byte, ttl time.Duration) error {
+	if !s.active {
+		return nil
+	}
+	syncerGroup.Lock()
+	defer syncerGroup.Unlock()
+	syncerGroup.heartbeats[s.instanceId] = heartbeat{status, time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *InmemoryDeltaSyncer) GetHeartbeats(ctx context.Context) (map[string][]byte, error) {
+	heartbeats := make(map[string][]byte)
+	if !s.active {
+		return heartbeats, nil
+	}
+	syncerGroup.Lock()
+	defer syncerGroup.Unlock()
+	now := time.Now()
+	for id, hb := range syncerGroup.heartbeats {
+		if hb.expires.Before(now) {
+			delete(syncerGroup.heartbeats, id)
+			continue
+		}
+		heartbeats[id] = hb.status
+	}
+	return heartbeats, nil
+}
+
+func (s *InmemoryDeltaSyncer) AcquireLease(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if !s.active {
+		return true, nil
+	}
+	syncerGroup.Lock()
+	defer syncerGroup.Unlock()
+	now := time.Now()
+	l, ok := syncerGroup.leases[key]
+	if ok && l.instanceId != s.instanceId && l.expires.After(now) {
+		return false, nil
+	}
+	syncerGroup.leases[key] = lease{s.instanceId, now.Add(ttl)}
+	return true, nil
+}
+
+func (s *InmemoryDeltaSyncer) ReleaseLease(ctx context.Context, key string) error {
+	if !s.active {
+		return nil
+	}
+	syncerGroup.Lock()
+	defer syncerGroup.Unlock()
+	l, ok := syncerGroup.leases[key]
+	if ok && l.instanceId == s.instanceId {
+		delete(syncerGroup.leases, key)
+	}
+	return nil
 }
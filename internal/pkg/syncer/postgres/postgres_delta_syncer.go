@@ -0,0 +1,660 @@
+// Copyright 2020 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+	"github.com/xmidt-org/ears/internal/pkg/config"
+	"github.com/xmidt-org/ears/internal/pkg/syncer"
+	"github.com/xmidt-org/ears/pkg/logs"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+
+	// notified with the version of a new change log entry
+	EARS_POSTGRES_SYNC_CHANNEL = "ears_sync"
+
+	// used by one instance of ears to tell all others that it just finished syncing its routing table
+	EARS_POSTGRES_ACK_CHANNEL = "ears_ack"
+
+	// change log of all sync commands, replayed by instances which missed commands while disconnected
+	EARS_POSTGRES_CHANGE_LOG = "ears_sync_log"
+
+	// single row table with the version of the latest command in the change log
+	EARS_POSTGRES_VERSION_TABLE = "ears_sync_version"
+
+	// membership of all listening instances, rows expire unless refreshed
+	EARS_POSTGRES_MEMBER_TABLE = "ears_members"
+
+	EARS_POSTGRES_MEMBER_REFRESH_INTERVAL = 5 * time.Second
+	EARS_POSTGRES_MEMBER_TTL              = 3 * EARS_POSTGRES_MEMBER_REFRESH_INTERVAL
+
+	// heartbeats of all instances, rows expire with the heartbeat
+	EARS_POSTGRES_HEARTBEAT_TABLE = "ears_heartbeats"
+
+	// leases held by instances, rows expire unless renewed by the holder
+	EARS_POSTGRES_LEASE_TABLE = "ears_leases"
+
+	EARS_POSTGRES_RETRY_INTERVAL_SECONDS = 10 * time.Second
+
+	// the change log is also polled as notifications are lost while the listener reconnects
+	EARS_POSTGRES_POLL_INTERVAL = 10 * time.Second
+
+	EARS_POSTGRES_ACK_TIMEOUT = 30 * time.Second
+
+	// number of change log entries read at a time
+	EARS_POSTGRES_CHANGE_LOG_BATCH = 100
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS ` + EARS_POSTGRES_VERSION_TABLE + ` (
+	id      INT PRIMARY KEY,
+	version BIGINT NOT NULL
+);
+INSERT INTO ` + EARS_POSTGRES_VERSION_TABLE + ` (id, version) VALUES (1, 0) ON CONFLICT (id) DO NOTHING;
+CREATE TABLE IF NOT EXISTS ` + EARS_POSTGRES_CHANGE_LOG + ` (
+	version BIGINT PRIMARY KEY,
+	cmd     TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS ` + EARS_POSTGRES_MEMBER_TABLE + ` (
+	instance_id TEXT PRIMARY KEY,
+	expires     TIMESTAMPTZ NOT NULL
+);
+CREATE TABLE IF NOT EXISTS ` + EARS_POSTGRES_HEARTBEAT_TABLE + ` (
+	instance_id TEXT PRIMARY KEY,
+	status      TEXT NOT NULL,
+	expires     TIMESTAMPTZ NOT NULL
+);
+CREATE TABLE IF NOT EXISTS ` + EARS_POSTGRES_LEASE_TABLE + ` (
+	lease_key   TEXT PRIMARY KEY,
+	instance_id TEXT NOT NULL,
+	expires     TIMESTAMPTZ NOT NULL
+);
+`
+
+type (
+	PostgresDeltaSyncer struct {
+		sync.Mutex
+		endpoint     string
+		active       bool
+		client       *sql.DB
+		localSyncers map[string][]syncer.LocalSyncer
+		logger       *zerolog.Logger
+		config       config.Config
+		instanceId   string
+		// the change log is read by a single goroutine at a time
+		changeLogLock   sync.Mutex
+		changeLogLength int64
+		initialized     bool
+		appliedVersion  int64
+		// acks of pending sync requests by session id
+		acksLock sync.Mutex
+		acks     map[string]chan string
+		done     chan struct{}
+	}
+)
+
+func NewPostgresDeltaSyncer(logger *zerolog.Logger, config config.Config) syncer.DeltaSyncer {
+	s := new(PostgresDeltaSyncer)
+	s.logger = logger
+	s.config = config
+	s.endpoint = config.GetString("ears.synchronization.endpoint")
+	s.localSyncers = make(map[string][]syncer.LocalSyncer)
+	s.acks = make(map[string]chan string)
+	hostname, _ := os.Hostname()
+	s.instanceId = hostname + "_" + uuid.New().String()
+	s.active = config.GetBool("ears.synchronization.active")
+	s.changeLogLength = int64(config.GetInt("ears.synchronization.changeLogLength"))
+	if s.changeLogLength <= 0 {
+		s.changeLogLength = syncer.DefaultChangeLogLength
+	}
+	if !s.active {
+		logger.Info().Msg("Postgres Delta Syncer Not Activated")
+	} else {
+		client, err := sql.Open("postgres", s.endpoint)
+		if err != nil {
+			// sql.Open only fails for unknown drivers
+			logger.Error().Str("op", "NewPostgresDeltaSyncer").Msg(err.Error())
+			s.active = false
+		}
+		s.client = client
+	}
+	return s
+}
+
+func (s *PostgresDeltaSyncer) RegisterLocalSyncer(itemType string, localSyncer syncer.LocalSyncer) {
+	s.Lock()
+	defer s.Unlock()
+	s.localSyncers[itemType] = append(s.localSyncers[itemType], localSyncer)
+}
+
+func (s *PostgresDeltaSyncer) UnregisterLocalSyncer(itemType string, localSyncer syncer.LocalSyncer) {
+	s.Lock()
+	defer s.Unlock()
+
+	syncers, ok := s.localSyncers[itemType]
+	if !ok {
+		return
+	}
+
+	for i, syncer := range syncers {
+		if syncer == localSyncer {
+			//delete by copy the last element to the current pos and then
+			//shorten the array by one
+			syncers[i] = syncers[len(syncers)-1]
+			syncers[len(syncers)-1] = nil
+			syncers = syncers[:len(syncers)-1]
+			s.localSyncers[itemType] = syncers
+			return
+		}
+	}
+}
+
+// PublishSyncRequest appends a command to the change log and asks others to sync their routing tables
+func (s *PostgresDeltaSyncer) PublishSyncRequest(ctx context.Context, tid tenant.Id, itemType string, itemId string, add bool) {
+	if !s.active {
+		return
+	}
+	cmd := ""
+	if add {
+		cmd = syncer.EARS_ADD_ITEM_CMD
+	} else {
+		cmd = syncer.EARS_REMOVE_ITEM_CMD
+	}
+	sid := uuid.New().String() // session id
+	syncCmd := &syncer.SyncCommand{
+		Cmd:        cmd,
+		ItemType:   itemType,
+		ItemId:     itemId,
+		InstanceId: s.instanceId,
+		Sid:        sid,
+		Tenant:     tid,
+	}
+	numSubscribers := s.GetInstanceCount(ctx)
+	if numSubscribers <= 1 {
+		// the command still goes into the change log to keep the version of all instances in step
+		s.logger.Info().Str("op", "PublishSyncRequest").Msg("no subscribers but me - no need to wait for ack")
+		s.appendToChangeLog(context.Background(), syncCmd)
+		return
+	}
+	// register for acks first ...
+	acks := make(chan string, numSubscribers)
+	s.acksLock.Lock()
+	s.acks[sid] = acks
+	s.acksLock.Unlock()
+	// go func is so that PublishSyncRequest returns immediately
+	go func() {
+		defer func() {
+			s.acksLock.Lock()
+			delete(s.acks, sid)
+			s.acksLock.Unlock()
+		}()
+		// ... then request all flow apis to sync
+		_, err := s.appendToChangeLog(context.Background(), syncCmd)
+		if err != nil {
+			return
+		}
+		received := make(map[string]bool)
+		timeout := time.After(EARS_POSTGRES_ACK_TIMEOUT)
+		// wait until we received an ack from each subscriber (except the one originating the request)
+		for len(received) < numSubscribers-1 {
+			select {
+			case instanceId := <-acks:
+				received[instanceId] = true
+			case <-timeout:
+				s.logger.Info().Str("op", "PublishSyncRequest").Msg("timeout while collecting acks")
+				return
+			}
+		}
+		s.logger.Info().Str("op", "PublishSyncRequest").Msg("done collecting acks")
+	}()
+}
+
+// appendToChangeLog appends a sync command to the change log and returns its version. The version row
+// stays locked until the transaction commits, so entries become visible in version order.
+func (s *PostgresDeltaSyncer) appendToChangeLog(ctx context.Context, syncCmd *syncer.SyncCommand) (int64, error) {
+	msg, _ := json.Marshal(syncCmd)
+	version, err := s.appendTx(ctx, string(msg))
+	if err != nil {
+		s.logger.Error().Str("op", "appendToChangeLog").Msg(err.Error())
+		return 0, err
+	}
+	s.logger.Info().Str("op", "appendToChangeLog").Str("routeId", syncCmd.ItemId).Str("sid", syncCmd.Sid).Int64("version", version).Msg("appended to change log")
+	return version, nil
+}
+
+func (s *PostgresDeltaSyncer) appendTx(ctx context.Context, msg string) (int64, error) {
+	tx, err := s.client.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	var version int64
+	err = tx.QueryRowContext(ctx, "UPDATE "+EARS_POSTGRES_VERSION_TABLE+" SET version = version + 1 WHERE id = 1 RETURNING version").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO "+EARS_POSTGRES_CHANGE_LOG+" (version, cmd) VALUES ($1, $2)", version, msg)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM "+EARS_POSTGRES_CHANGE_LOG+" WHERE version <= $1", version-s.changeLogLength)
+	if err != nil {
+		return 0, err
+	}
+	// notifications are delivered when the transaction commits
+	_, err = tx.ExecContext(ctx, "SELECT pg_notify($1, $2)", EARS_POSTGRES_SYNC_CHANNEL, strconv.FormatInt(version, 10))
+	if err != nil {
+		return 0, err
+	}
+	return version, tx.Commit()
+}
+
+// StopListeningForSyncRequests stops listening for sync requests
+func (s *PostgresDeltaSyncer) StopListeningForSyncRequests() {
+	if !s.active {
+		return
+	}
+	s.Lock()
+	if s.done != nil {
+		close(s.done)
+		s.done = nil
+	}
+	s.Unlock()
+	// leave right away so that other instances take over the routes placed on this one
+	_, err := s.client.Exec("DELETE FROM "+EARS_POSTGRES_MEMBER_TABLE+" WHERE instance_id = $1", s.instanceId)
+	if err != nil {
+		s.logger.Error().Str("op", "StopListeningForSyncRequests").Msg(err.Error())
+	}
+}
+
+// ListenForSyncRequests listens for sync requests and catches up with changes missed while not listening
+func (s *PostgresDeltaSyncer) StartListeningForSyncRequests() {
+	if !s.active {
+		return
+	}
+	_, err := s.client.Exec(schema)
+	if err != nil {
+		s.logger.Error().Str("op", "StartListeningForSyncRequests").Msg("could not create sync tables: " + err.Error())
+	}
+	ctx := logs.SubLoggerCtx(context.Background(), s.logger)
+	s.changeLogLock.Lock()
+	if !s.initialized {
+		// a new instance loads all items from storage, so it starts at the current version
+		version, err := s.GetVersion(ctx)
+		if err == nil {
+			atomic.StoreInt64(&s.appliedVersion, version)
+			s.initialized = true
+		}
+	}
+	s.changeLogLock.Unlock()
+	listener := pq.NewListener(s.endpoint, time.Second, EARS_POSTGRES_RETRY_INTERVAL_SECONDS, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			s.logger.Error().Str("op", "ListenForSyncRequests").Msg(err.Error())
+		}
+	})
+	for _, channel := range []string{EARS_POSTGRES_SYNC_CHANNEL, EARS_POSTGRES_ACK_CHANNEL} {
+		err = listener.Listen(channel)
+		if err != nil {
+			// the listener keeps trying to listen while reconnecting
+			s.logger.Error().Str("op", "ListenForSyncRequests").Msg(err.Error())
+		}
+	}
+	done := make(chan struct{})
+	s.Lock()
+	s.done = done
+	s.Unlock()
+	s.refreshMember()
+	go s.refreshMembership(done)
+	go s.listen(ctx, listener, done)
+}
+
+func (s *PostgresDeltaSyncer) listen(ctx context.Context, listener *pq.Listener, done chan struct{}) {
+	defer listener.Close()
+	ticker := time.NewTicker(EARS_POSTGRES_POLL_INTERVAL)
+	defer ticker.Stop()
+	s.readChangeLog(ctx)
+	for {
+		select {
+		case <-done:
+			s.logger.Info().Str("op", "ListenForSyncRequests").Str("instanceId", s.instanceId).Msg("stopped listening")
+			return
+		case n := <-listener.Notify:
+			if n != nil && n.Channel == EARS_POSTGRES_ACK_CHANNEL {
+				s.receiveAck(n.Extra)
+			} else {
+				// a nil notification means the listener reconnected and may have missed changes
+				s.readChangeLog(ctx)
+			}
+		case <-ticker.C:
+			s.readChangeLog(ctx)
+		}
+	}
+}
+
+// refreshMembership keeps the membership row of this instance alive while listening
+func (s *PostgresDeltaSyncer) refreshMembership(done chan struct{}) {
+	ticker := time.NewTicker(EARS_POSTGRES_MEMBER_REFRESH_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			s.refreshMember()
+		}
+	}
+}
+
+func (s *PostgresDeltaSyncer) refreshMember() {
+	_, err := s.client.Exec("INSERT INTO "+EARS_POSTGRES_MEMBER_TABLE+" (instance_id, expires) VALUES ($1, now() + $2::float8 * interval '1 millisecond') "+
+		"ON CONFLICT (instance_id) DO UPDATE SET expires = EXCLUDED.expires", s.instanceId, EARS_POSTGRES_MEMBER_TTL.Milliseconds())
+	if err != nil {
+		s.logger.Error().Str("op", "refreshMembership").Msg(err.Error())
+		return
+	}
+	// unlike redis keys rows do not expire by themselves
+	for _, table := range []string{EARS_POSTGRES_MEMBER_TABLE, EARS_POSTGRES_HEARTBEAT_TABLE} {
+		_, err = s.client.Exec("DELETE FROM " + table + " WHERE expires <= now()")
+		if err != nil {
+			s.logger.Error().Str("op", "refreshMembership").Msg(err.Error())
+		}
+	}
+}
+
+// readChangeLog applies all change log entries newer than the applied version
+func (s *PostgresDeltaSyncer) readChangeLog(ctx context.Context) {
+	s.changeLogLock.Lock()
+	defer s.changeLogLock.Unlock()
+	for {
+		applied := atomic.LoadInt64(&s.appliedVersion)
+		versions, cmds, err := s.queryChangeLog(ctx, applied)
+		if err != nil {
+			s.logger.Error().Str("op", "readChangeLog").Msg(err.Error())
+			return
+		}
+		if len(versions) == 0 {
+			// all missed entries may have been trimmed from the change log, the version is read
+			// first so that entries committed in the meantime are not mistaken for trimmed ones
+			version, err := s.GetVersion(ctx)
+			if err != nil || version <= applied {
+				return
+			}
+			versions, cmds, err = s.queryChangeLog(ctx, applied)
+			if err != nil {
+				s.logger.Error().Str("op", "readChangeLog").Msg(err.Error())
+				return
+			}
+			if len(versions) == 0 {
+				s.resync(ctx, applied, version)
+				return
+			}
+		}
+		for i := range versions {
+			s.applyChange(ctx, versions[i], cmds[i])
+		}
+		if len(versions) < EARS_POSTGRES_CHANGE_LOG_BATCH {
+			return
+		}
+	}
+}
+
+func (s *PostgresDeltaSyncer) queryChangeLog(ctx context.Context, after int64) ([]int64, []string, error) {
+	rows, err := s.client.QueryContext(ctx, "SELECT version, cmd FROM "+EARS_POSTGRES_CHANGE_LOG+" WHERE version > $1 ORDER BY version LIMIT $2",
+		after, EARS_POSTGRES_CHANGE_LOG_BATCH)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	versions := make([]int64, 0)
+	cmds := make([]string, 0)
+	for rows.Next() {
+		var version int64
+		var cmd string
+		err = rows.Scan(&version, &cmd)
+		if err != nil {
+			return nil, nil, err
+		}
+		versions = append(versions, version)
+		cmds = append(cmds, cmd)
+	}
+	return versions, cmds, rows.Err()
+}
+
+// applyChange applies a single change log entry unless this instance already did
+func (s *PostgresDeltaSyncer) applyChange(ctx context.Context, version int64, payload string) {
+	applied := atomic.LoadInt64(&s.appliedVersion)
+	if version <= applied {
+		return
+	}
+	if version > applied+1 {
+		s.resync(ctx, applied, version)
+		return
+	}
+	var syncCmd syncer.SyncCommand
+	err := json.Unmarshal([]byte(payload), &syncCmd)
+	if err != nil {
+		s.logger.Error().Str("op", "applyChange").Str("error", err.Error()).Msg("bad message structure: " + payload)
+	} else if syncCmd.InstanceId != s.instanceId {
+		syncCmd.Version = version
+		s.syncLocal(ctx, &syncCmd)
+	}
+	atomic.StoreInt64(&s.appliedVersion, version)
+}
+
+// resync reloads all items when the missed entries were trimmed from the change log
+func (s *PostgresDeltaSyncer) resync(ctx context.Context, applied int64, version int64) {
+	s.logger.Error().Str("op", "applyChange").Str("instanceId", s.instanceId).Int64("appliedVersion", applied).Int64("version", version).Msg("changes no longer in change log")
+	s.Lock()
+	syncer.ResyncAll(ctx, s.logger, s.localSyncers)
+	s.Unlock()
+	atomic.StoreInt64(&s.appliedVersion, version)
+}
+
+// syncLocal applies a sync command to the local syncers and acknowledges it
+func (s *PostgresDeltaSyncer) syncLocal(ctx context.Context, syncCmd *syncer.SyncCommand) {
+	if syncCmd.Cmd != syncer.EARS_ADD_ITEM_CMD && syncCmd.Cmd != syncer.EARS_REMOVE_ITEM_CMD {
+		s.logger.Error().Str("op", "ListenForSyncRequests").Str("instanceId", s.instanceId).Str("routeId", syncCmd.ItemId).Str("sid", syncCmd.Sid).Msg("bad command " + syncCmd.Cmd)
+		return
+	}
+	add := syncCmd.Cmd == syncer.EARS_ADD_ITEM_CMD
+	s.logger.Info().Str("op", "ListenForSyncRequests").Str("instanceId", s.instanceId).Str("routeId", syncCmd.ItemId).Str("sid", syncCmd.Sid).Int64("version", syncCmd.Version).Bool("add", add).Msg("received message to sync route")
+	s.Lock()
+	syncers, ok := s.localSyncers[syncCmd.ItemType]
+	if ok {
+		for _, localSyncer := range syncers {
+			err := localSyncer.SyncItem(ctx, syncCmd.Tenant, syncCmd.ItemId, add)
+			if err != nil {
+				s.logger.Error().Str("op", "ListenForSyncRequests").Str("instanceId", s.instanceId).Str("routeId", syncCmd.ItemId).Str("sid", syncCmd.Sid).Msg("failed to sync route: " + err.Error())
+			}
+		}
+	}
+	s.Unlock()
+	s.publishAckMessage(ctx, syncCmd)
+}
+
+// publishAckMessage confirms successful syncing of routing table
+func (s *PostgresDeltaSyncer) publishAckMessage(ctx context.Context, syncCmd *syncer.SyncCommand) error {
+	ack := *syncCmd
+	ack.InstanceId = s.instanceId
+	msg, _ := json.Marshal(ack)
+	_, err := s.client.ExecContext(ctx, "SELECT pg_notify($1, $2)", EARS_POSTGRES_ACK_CHANNEL, string(msg))
+	if err != nil {
+		s.logger.Error().Str("op", "PublishAckMessage").Msg(err.Error())
+	} else {
+		s.logger.Info().Str("op", "PublishAckMessage").Msg("published ack message on " + EARS_POSTGRES_ACK_CHANNEL + ": " + string(msg))
+	}
+	return err
+}
+
+// receiveAck hands an ack to the pending sync request of its session
+func (s *PostgresDeltaSyncer) receiveAck(payload string) {
+	var syncCmd syncer.SyncCommand
+	err := json.Unmarshal([]byte(payload), &syncCmd)
+	if err != nil {
+		s.logger.Error().Str("op", "PublishSyncRequest").Str("error", err.Error()).Msg("bad ack message structure: " + payload)
+		return
+	}
+	s.acksLock.Lock()
+	defer s.acksLock.Unlock()
+	acks, ok := s.acks[syncCmd.Sid]
+	if !ok {
+		return
+	}
+	select {
+	case acks <- syncCmd.InstanceId:
+	default:
+	}
+}
+
+// GetInstanceCount gets number of live ears instances
+func (s *PostgresDeltaSyncer) GetInstanceCount(ctx context.Context) int {
+	if !s.active {
+		return 0
+	}
+	var count int
+	err := s.client.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+EARS_POSTGRES_MEMBER_TABLE+" WHERE expires > now()").Scan(&count)
+	if err != nil {
+		s.logger.Error().Str("op", "GetInstanceCount").Msg(err.Error())
+		return 0
+	}
+	s.logger.Debug().Str("op", "GetInstanceCount").Msg(fmt.Sprintf("num members is %d", count))
+	return count
+}
+
+func (s *PostgresDeltaSyncer) GetInstanceId() string {
+	return s.instanceId
+}
+
+func (s *PostgresDeltaSyncer) GetVersion(ctx context.Context) (int64, error) {
+	if !s.active {
+		return 0, nil
+	}
+	var version int64
+	err := s.client.QueryRowContext(ctx, "SELECT version FROM "+EARS_POSTGRES_VERSION_TABLE+" WHERE id = 1").Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		s.logger.Error().Str("op", "GetVersion").Msg(err.Error())
+		return 0, err
+	}
+	return version, nil
+}
+
+func (s *PostgresDeltaSyncer) GetAppliedVersion() int64 {
+	return atomic.LoadInt64(&s.appliedVersion)
+}
+
+func (s *PostgresDeltaSyncer) PublishHeartbeat(ctx context.Context, status []byte, ttl time.Duration) error {
+	if !s.active {
+		return nil
+	}
+	_, err := s.client.ExecContext(ctx, "INSERT INTO "+EARS_POSTGRES_HEARTBEAT_TABLE+" (instance_id, status, expires) VALUES ($1, $2, now() + $3::float8 * interval '1 millisecond') "+
+		"ON CONFLICT (instance_id) DO UPDATE SET status = EXCLUDED.status, expires = EXCLUDED.expires", s.instanceId, string(status), ttl.Milliseconds())
+	if err != nil {
+		s.logger.Error().Str("op", "PublishHeartbeat").Msg(err.Error())
+	}
+	return err
+}
+
+func (s *PostgresDeltaSyncer) GetHeartbeats(ctx context.Context) (map[string][]byte, error) {
+	heartbeats := make(map[string][]byte)
+	if !s.active {
+		return heartbeats, nil
+	}
+	rows, err := s.client.QueryContext(ctx, "SELECT instance_id, status FROM "+EARS_POSTGRES_HEARTBEAT_TABLE+" WHERE expires > now()")
+	if err != nil {
+		s.logger.Error().Str("op", "GetHeartbeats").Msg(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, status string
+		err = rows.Scan(&id, &status)
+		if err != nil {
+			return nil, err
+		}
+		heartbeats[id] = []byte(status)
+	}
+	return heartbeats, rows.Err()
+}
+
+func (s *PostgresDeltaSyncer) AcquireLease(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if !s.active {
+		return true, nil
+	}
+	// the update only applies to leases held by this instance or expired leases
+	res, err := s.client.ExecContext(ctx, "INSERT INTO "+EARS_POSTGRES_LEASE_TABLE+" (lease_key, instance_id, expires) VALUES ($1, $2, now() + $3::float8 * interval '1 millisecond') "+
+		"ON CONFLICT (lease_key) DO UPDATE SET instance_id = EXCLUDED.instance_id, expires = EXCLUDED.expires "+
+		"WHERE "+EARS_POSTGRES_LEASE_TABLE+".instance_id = EXCLUDED.instance_id OR "+EARS_POSTGRES_LEASE_TABLE+".expires <= now()", key, s.instanceId, ttl.Milliseconds())
+	if err != nil {
+		s.logger.Error().Str("op", "AcquireLease").Msg(err.Error())
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (s *PostgresDeltaSyncer) ReleaseLease(ctx context.Context, key string) error {
+	if !s.active {
+		return nil
+	}
+	_, err := s.client.ExecContext(ctx, "DELETE FROM "+EARS_POSTGRES_LEASE_TABLE+" WHERE lease_key = $1 AND instance_id = $2", key, s.instanceId)
+	if err != nil {
+		s.logger.Error().Str("op", "ReleaseLease").Msg(err.Error())
+	}
+	return err
+}
+
+func (s *PostgresDeltaSyncer) GetMembers(ctx context.Context) ([]string, error) {
+	members := []string{s.instanceId}
+	if !s.active {
+		return members, nil
+	}
+	rows, err := s.client.QueryContext(ctx, "SELECT instance_id FROM "+EARS_POSTGRES_MEMBER_TABLE+" WHERE expires > now()")
+	if err != nil {
+		s.logger.Error().Str("op", "GetMembers").Msg(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		if id != s.instanceId {
+			members = append(members, id)
+		}
+	}
+	sort.Strings(members)
+	return members, rows.Err()
+}
@@ -26,7 +26,11 @@ import (
 	"github.com/xmidt-org/ears/pkg/logs"
 	"github.com/xmidt-org/ears/pkg/tenant"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -38,9 +42,56 @@ const (
 	// used by one instance of ears to tell all others that it just finished syncing its routing table
 	EARS_REDIS_ACK_CHANNEL = "ears_ack"
 
+	// change log of all sync commands, replayed by instances which missed commands while disconnected
+	EARS_REDIS_CHANGE_LOG = "ears_sync_log"
+
+	// version of the latest command in the change log
+	EARS_REDIS_VERSION_KEY = "ears_sync_version"
+
+	// prefix of the membership keys of all listening instances, the keys expire unless refreshed
+	EARS_REDIS_MEMBER_PREFIX = "ears_member_"
+
+	EARS_REDIS_MEMBER_REFRESH_INTERVAL = 5 * time.Second
+	EARS_REDIS_MEMBER_TTL              = 3 * EARS_REDIS_MEMBER_REFRESH_INTERVAL
+
+	// prefix of the keys holding the heartbeats of all instances, the keys expire with the heartbeat
+	EARS_REDIS_HEARTBEAT_PREFIX = "ears_heartbeat_"
+
+	// prefix of the keys holding leases, the keys hold the id of the instance and expire unless renewed
+	EARS_REDIS_LEASE_PREFIX = "ears_lease_"
+
 	EARS_REDIS_RETRY_INTERVAL_SECONDS = 10 * time.Second
+
+	// how long a read of the change log blocks before checking whether to stop listening
+	EARS_REDIS_CHANGE_LOG_BLOCK = 1 * time.Second
 )
 
+// appendScript assigns the next version to a sync command and appends it to the change log
+// in one step, so that the change log is always ordered by version
+var appendScript = redis.NewScript(`
+local version = redis.call('INCR', KEYS[1])
+redis.call('XADD', KEYS[2], 'MAXLEN', '~', ARGV[2], '*', 'version', version, 'cmd', ARGV[1])
+return version
+`)
+
+// leaseScript takes a free lease or renews the lease of the instance in one step
+var leaseScript = redis.NewScript(`
+local holder = redis.call('GET', KEYS[1])
+if holder and holder ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1
+`)
+
+// releaseScript deletes a lease only if it is still held by the instance
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
 type (
 	RedisDeltaSyncer struct {
 		sync.Mutex
@@ -51,6 +102,12 @@ type (
 		logger        *zerolog.Logger
 		config        config.Config
 		instanceId    string
+		// the change log is read by a single goroutine at a time
+		changeLogLock   sync.Mutex
+		changeLogLength int64
+		lastId          string // id of the latest change log entry read
+		appliedVersion  int64
+		done            chan struct{}
 	}
 )
 
@@ -63,6 +120,10 @@ func NewRedisDeltaSyncer(logger *zerolog.Logger, config config.Config) syncer.De
 	hostname, _ := os.Hostname()
 	s.instanceId = hostname + "_" + uuid.New().String()
 	s.active = config.GetBool("ears.synchronization.active")
+	s.changeLogLength = int64(config.GetInt("ears.synchronization.changeLogLength"))
+	if s.changeLogLength <= 0 {
+		s.changeLogLength = syncer.DefaultChangeLogLength
+	}
 	if !s.active {
 		logger.Info().Msg("Redis Delta Syncer Not Activated")
 	} else {
@@ -105,7 +166,7 @@ func (s *RedisDeltaSyncer) UnregisterLocalSyncer(itemType string, localSyncer sy
 	}
 }
 
-// PublishSyncRequest asks others to sync their routing tables
+// PublishSyncRequest appends a command to the change log and asks others to sync their routing tables
 func (s *RedisDeltaSyncer) PublishSyncRequest(ctx context.Context, tid tenant.Id, itemType string, itemId string, add bool) {
 	if !s.active {
 		return
@@ -117,9 +178,19 @@ func (s *RedisDeltaSyncer) PublishSyncRequest(ctx context.Context, tid tenant.Id
 		cmd = syncer.EARS_REMOVE_ITEM_CMD
 	}
 	sid := uuid.New().String() // session id
+	syncCmd := &syncer.SyncCommand{
+		Cmd:        cmd,
+		ItemType:   itemType,
+		ItemId:     itemId,
+		InstanceId: s.instanceId,
+		Sid:        sid,
+		Tenant:     tid,
+	}
 	numSubscribers := s.GetInstanceCount(ctx)
 	if numSubscribers <= 1 {
+		// the command still goes into the change log to keep the version of all instances in step
 		s.logger.Info().Str("op", "PublishSyncRequest").Msg("no subscribers but me - no need to wait for ack")
+		s.appendToChangeLog(syncCmd)
 		return
 	}
 	// outer go func is so that PublishSyncRequest returns immediately
@@ -176,30 +247,25 @@ func (s *RedisDeltaSyncer) PublishSyncRequest(ctx context.Context, tid tenant.Id
 			}
 			// at this point the delta has been fully synchronized - may want to publish something about that here
 		}()
-		if numSubscribers <= 1 {
-			s.logger.Info().Str("op", "PublishSyncRequest").Msg("no subscribers but me - no need to publish sync")
-		} else {
-			// wait for listener to be ready
-			wg.Wait()
-			// ... then request all flow apis to sync
-			syncCmd := &syncer.SyncCommand{
-				Cmd:        cmd,
-				ItemType:   itemType,
-				ItemId:     itemId,
-				InstanceId: s.instanceId,
-				Sid:        sid,
-				Tenant:     tid,
-			}
-
-			msg, _ := json.Marshal(syncCmd)
-			err := s.client.Publish(EARS_REDIS_SYNC_CHANNEL, string(msg)).Err()
-			if err != nil {
-				s.logger.Error().Str("op", "PublishSyncRequest").Msg(err.Error())
-			}
-		}
+		// wait for listener to be ready
+		wg.Wait()
+		// ... then request all flow apis to sync
+		s.appendToChangeLog(syncCmd)
 	}()
 }
 
+// appendToChangeLog appends a sync command to the change log and returns its version
+func (s *RedisDeltaSyncer) appendToChangeLog(syncCmd *syncer.SyncCommand) (int64, error) {
+	msg, _ := json.Marshal(syncCmd)
+	version, err := appendScript.Run(s.client, []string{EARS_REDIS_VERSION_KEY, EARS_REDIS_CHANGE_LOG}, string(msg), s.changeLogLength).Int64()
+	if err != nil {
+		s.logger.Error().Str("op", "appendToChangeLog").Msg(err.Error())
+		return 0, err
+	}
+	s.logger.Info().Str("op", "appendToChangeLog").Str("routeId", syncCmd.ItemId).Str("sid", syncCmd.Sid).Int64("version", version).Msg("appended to change log")
+	return version, nil
+}
+
 // StopListeningForSyncRequests stops listening for sync requests
 func (s *RedisDeltaSyncer) StopListeningForSyncRequests() {
 	if !s.active {
@@ -214,6 +280,17 @@ func (s *RedisDeltaSyncer) StopListeningForSyncRequests() {
 	if err != nil {
 		s.logger.Error().Str("op", "StopListeningForSyncRequests").Msg(err.Error())
 	}
+	s.Lock()
+	if s.done != nil {
+		close(s.done)
+		s.done = nil
+	}
+	s.Unlock()
+	// leave right away so that other instances take over the routes placed on this one
+	err = s.client.Del(EARS_REDIS_MEMBER_PREFIX + s.instanceId).Err()
+	if err != nil {
+		s.logger.Error().Str("op", "StopListeningForSyncRequests").Msg(err.Error())
+	}
 }
 
 // ListenForSyncRequests listens for sync request
@@ -258,45 +335,11 @@ func (s *RedisDeltaSyncer) StartListeningForSyncRequests() {
 						return
 					}
 				}
-				// sync only whats needed
+				// add and remove commands are read from the change log, the sync channel only carries
+				// them for instances which do not write to the change log yet, e.g. during an upgrade
 				if syncCmd.InstanceId != s.instanceId {
 					s.GetInstanceCount(ctx) // just for logging
-					if syncCmd.Cmd == syncer.EARS_ADD_ITEM_CMD {
-						s.logger.Info().Str("op", "ListenForSyncRequests").Str("instanceId", s.instanceId).Str("routeId", syncCmd.ItemId).Str("sid", syncCmd.Sid).Msg("received message to add route")
-
-						s.Lock()
-						syncers, ok := s.localSyncers[syncCmd.ItemType]
-						if ok {
-							for _, localSyncer := range syncers {
-								err = localSyncer.SyncItem(ctx, syncCmd.Tenant, syncCmd.ItemId, true)
-								if err != nil {
-									s.logger.Error().Str("op", "ListenForSyncRequests").Str("instanceId", s.instanceId).Str("routeId", syncCmd.ItemId).Str("sid", syncCmd.Sid).Msg("failed to sync route: " + err.Error())
-								}
-							}
-						}
-						s.Unlock()
-						s.publishAckMessage(ctx, syncCmd.Cmd, syncCmd.ItemType, syncCmd.ItemId, syncCmd.Sid, syncCmd.Tenant)
-					} else if syncCmd.Cmd == syncer.EARS_REMOVE_ITEM_CMD {
-						s.logger.Info().Str("op", "ListenForSyncRequests").Str("instanceId", s.instanceId).Str("routeId", syncCmd.ItemId).Str("sid", syncCmd.Sid).Msg("received message to remove route")
-
-						s.Lock()
-						syncers, ok := s.localSyncers[syncCmd.ItemType]
-						if ok {
-							for _, localSyncer := range syncers {
-								err = localSyncer.SyncItem(ctx, syncCmd.Tenant, syncCmd.ItemId, false)
-								if err != nil {
-									s.logger.Error().Str("op", "ListenForSyncRequests").Str("instanceId", s.instanceId).Str("routeId", syncCmd.ItemId).Str("sid", syncCmd.Sid).Msg("failed to sync route: " + err.Error())
-								}
-							}
-						}
-						s.Unlock()
-						s.publishAckMessage(ctx, syncCmd.Cmd, syncCmd.ItemType, syncCmd.ItemId, syncCmd.Sid, syncCmd.Tenant)
-					} else if syncCmd.Cmd == syncer.EARS_STOP_LISTENING_CMD {
-						s.logger.Info().Str("op", "ListenForSyncRequests").Str("instanceId", s.instanceId).Msg("stop message ignored")
-						// already handled above
-					} else {
-						s.logger.Error().Str("op", "ListenForSyncRequests").Str("instanceId", s.instanceId).Str("routeId", syncCmd.ItemId).Str("sid", syncCmd.Sid).Msg("bad command " + syncCmd.Cmd)
-					}
+					s.syncLocal(ctx, &syncCmd)
 				} else {
 					s.logger.Info().Str("op", "ListenForSyncRequests").Str("instanceId", s.instanceId).Msg("no need to sync myself")
 				}
@@ -305,6 +348,175 @@ func (s *RedisDeltaSyncer) StartListeningForSyncRequests() {
 	}()
 
 	subscribeComplete.Wait()
+	s.startReadingChangeLog()
+}
+
+// refreshMembership keeps the membership key of this instance alive while listening
+func (s *RedisDeltaSyncer) refreshMembership(done chan struct{}) {
+	ticker := time.NewTicker(EARS_REDIS_MEMBER_REFRESH_INTERVAL)
+	defer ticker.Stop()
+	for {
+		err := s.client.Set(EARS_REDIS_MEMBER_PREFIX+s.instanceId, time.Now().Unix(), EARS_REDIS_MEMBER_TTL).Err()
+		if err != nil {
+			s.logger.Error().Str("op", "refreshMembership").Msg(err.Error())
+		}
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// startReadingChangeLog applies the change log from the latest entry read, so that an
+// instance which was disconnected catches up with the commands it missed
+func (s *RedisDeltaSyncer) startReadingChangeLog() {
+	ctx := logs.SubLoggerCtx(context.Background(), s.logger)
+	s.changeLogLock.Lock()
+	if s.lastId == "" {
+		// a new instance loads all items from storage, so it starts at the current version
+		msgs, err := s.client.XRevRangeN(EARS_REDIS_CHANGE_LOG, "+", "-", 1).Result()
+		if err != nil {
+			s.logger.Error().Str("op", "startReadingChangeLog").Msg(err.Error())
+		}
+		if len(msgs) > 0 {
+			s.lastId = msgs[0].ID
+			version, _ := parseVersion(msgs[0])
+			atomic.StoreInt64(&s.appliedVersion, version)
+		} else {
+			s.lastId = "0-0"
+			version, _ := s.GetVersion(ctx)
+			atomic.StoreInt64(&s.appliedVersion, version)
+		}
+	}
+	s.changeLogLock.Unlock()
+	done := make(chan struct{})
+	s.Lock()
+	s.done = done
+	s.Unlock()
+	go s.refreshMembership(done)
+	go func() {
+		lrc := redis.NewClient(&redis.Options{
+			Addr:     s.redisEndpoint,
+			Password: "",
+			DB:       0,
+		})
+		defer lrc.Close()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			err := s.readChangeLog(ctx, lrc)
+			if err != nil {
+				s.logger.Error().Str("op", "readChangeLog").Msg(err.Error())
+				select {
+				case <-done:
+					return
+				case <-time.After(EARS_REDIS_RETRY_INTERVAL_SECONDS):
+				}
+			}
+		}
+	}()
+}
+
+func (s *RedisDeltaSyncer) readChangeLog(ctx context.Context, lrc *redis.Client) error {
+	s.changeLogLock.Lock()
+	defer s.changeLogLock.Unlock()
+	streams, err := lrc.XRead(&redis.XReadArgs{
+		Streams: []string{EARS_REDIS_CHANGE_LOG, s.lastId},
+		Count:   100,
+		Block:   EARS_REDIS_CHANGE_LOG_BLOCK,
+	}).Result()
+	if err == redis.Nil {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			s.applyChange(ctx, msg)
+			s.lastId = msg.ID
+		}
+	}
+	return nil
+}
+
+// applyChange applies a single change log entry unless this instance already did
+func (s *RedisDeltaSyncer) applyChange(ctx context.Context, msg redis.XMessage) {
+	version, err := parseVersion(msg)
+	if err != nil {
+		s.logger.Error().Str("op", "applyChange").Str("id", msg.ID).Msg("bad change log entry: " + err.Error())
+		return
+	}
+	applied := atomic.LoadInt64(&s.appliedVersion)
+	if version <= applied {
+		return
+	}
+	if version > applied+1 {
+		// the missed entries were trimmed from the change log, only a full resync helps
+		s.logger.Error().Str("op", "applyChange").Str("instanceId", s.instanceId).Int64("appliedVersion", applied).Int64("version", version).Msg("changes no longer in change log")
+		s.Lock()
+		syncer.ResyncAll(ctx, s.logger, s.localSyncers)
+		s.Unlock()
+		atomic.StoreInt64(&s.appliedVersion, version)
+		return
+	}
+	payload, _ := msg.Values["cmd"].(string)
+	var syncCmd syncer.SyncCommand
+	err = json.Unmarshal([]byte(payload), &syncCmd)
+	if err != nil {
+		s.logger.Error().Str("op", "applyChange").Str("error", err.Error()).Msg("bad message structure: " + payload)
+	} else if syncCmd.InstanceId != s.instanceId {
+		syncCmd.Version = version
+		s.syncLocal(ctx, &syncCmd)
+	}
+	atomic.StoreInt64(&s.appliedVersion, version)
+}
+
+func parseVersion(msg redis.XMessage) (int64, error) {
+	return strconv.ParseInt(fmt.Sprint(msg.Values["version"]), 10, 64)
+}
+
+// syncLocal applies a sync command to the local syncers and acknowledges it
+func (s *RedisDeltaSyncer) syncLocal(ctx context.Context, syncCmd *syncer.SyncCommand) {
+	if syncCmd.Cmd == syncer.EARS_ADD_ITEM_CMD {
+		s.logger.Info().Str("op", "ListenForSyncRequests").Str("instanceId", s.instanceId).Str("routeId", syncCmd.ItemId).Str("sid", syncCmd.Sid).Int64("version", syncCmd.Version).Msg("received message to add route")
+
+		s.Lock()
+		syncers, ok := s.localSyncers[syncCmd.ItemType]
+		if ok {
+			for _, localSyncer := range syncers {
+				err := localSyncer.SyncItem(ctx, syncCmd.Tenant, syncCmd.ItemId, true)
+				if err != nil {
+					s.logger.Error().Str("op", "ListenForSyncRequests").Str("instanceId", s.instanceId).Str("routeId", syncCmd.ItemId).Str("sid", syncCmd.Sid).Msg("failed to sync route: " + err.Error())
+				}
+			}
+		}
+		s.Unlock()
+		s.publishAckMessage(ctx, syncCmd.Cmd, syncCmd.ItemType, syncCmd.ItemId, syncCmd.Sid, syncCmd.Tenant)
+	} else if syncCmd.Cmd == syncer.EARS_REMOVE_ITEM_CMD {
+		s.logger.Info().Str("op", "ListenForSyncRequests").Str("instanceId", s.instanceId).Str("routeId", syncCmd.ItemId).Str("sid", syncCmd.Sid).Int64("version", syncCmd.Version).Msg("received message to remove route")
+
+		s.Lock()
+		syncers, ok := s.localSyncers[syncCmd.ItemType]
+		if ok {
+			for _, localSyncer := range syncers {
+				err := localSyncer.SyncItem(ctx, syncCmd.Tenant, syncCmd.ItemId, false)
+				if err != nil {
+					s.logger.Error().Str("op", "ListenForSyncRequests").Str("instanceId", s.instanceId).Str("routeId", syncCmd.ItemId).Str("sid", syncCmd.Sid).Msg("failed to sync route: " + err.Error())
+				}
+			}
+		}
+		s.Unlock()
+		s.publishAckMessage(ctx, syncCmd.Cmd, syncCmd.ItemType, syncCmd.ItemId, syncCmd.Sid, syncCmd.Tenant)
+	} else if syncCmd.Cmd == syncer.EARS_STOP_LISTENING_CMD {
+		s.logger.Info().Str("op", "ListenForSyncRequests").Str("instanceId", s.instanceId).Msg("stop message ignored")
+		// already handled above
+	} else {
+		s.logger.Error().Str("op", "ListenForSyncRequests").Str("instanceId", s.instanceId).Str("routeId", syncCmd.ItemId).Str("sid", syncCmd.Sid).Msg("bad command " + syncCmd.Cmd)
+	}
 }
 
 // PublishAckMessage confirm successful syncing of routing table
@@ -352,3 +564,124 @@ func (s *RedisDeltaSyncer) GetInstanceCount(ctx context.Context) int {
 	s.logger.Debug().Str("op", "GetInstanceCount").Msg(fmt.Sprintf("num subscribers for channel %s is %d", EARS_REDIS_SYNC_CHANNEL, numSubscribers))
 	return int(numSubscribers)
 }
+
+func (s *RedisDeltaSyncer) GetInstanceId() string {
+	return s.instanceId
+}
+
+func (s *RedisDeltaSyncer) GetVersion(ctx context.Context) (int64, error) {
+	if !s.active {
+		return 0, nil
+	}
+	version, err := s.client.Get(EARS_REDIS_VERSION_KEY).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	} else if err != nil {
+		s.logger.Error().Str("op", "GetVersion").Msg(err.Error())
+		return 0, err
+	}
+	return version, nil
+}
+
+func (s *RedisDeltaSyncer) GetAppliedVersion() int64 {
+	return atomic.LoadInt64(&s.appliedVersion)
+}
+
+func (s *RedisDeltaSyncer) PublishHeartbeat(ctx context.Context, status []byte, ttl time.Duration) error {
+	if !s.active {
+		return nil
+	}
+	err := s.client.Set(EARS_REDIS_HEARTBEAT_PREFIX+s.instanceId, string(status), ttl).Err()
+	if err != nil {
+		s.logger.Error().Str("op", "PublishHeartbeat").Msg(err.Error())
+	}
+	return err
+}
+
+func (s *RedisDeltaSyncer) GetHeartbeats(ctx context.Context) (map[string][]byte, error) {
+	heartbeats := make(map[string][]byte)
+	if !s.active {
+		return heartbeats, nil
+	}
+	keys, err := s.scanKeys(EARS_REDIS_HEARTBEAT_PREFIX)
+	if err != nil {
+		s.logger.Error().Str("op", "GetHeartbeats").Msg(err.Error())
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return heartbeats, nil
+	}
+	values, err := s.client.MGet(keys...).Result()
+	if err != nil {
+		s.logger.Error().Str("op", "GetHeartbeats").Msg(err.Error())
+		return nil, err
+	}
+	for i, value := range values {
+		// heartbeats expiring between scan and get are nil
+		status, ok := value.(string)
+		if ok {
+			heartbeats[strings.TrimPrefix(keys[i], EARS_REDIS_HEARTBEAT_PREFIX)] = []byte(status)
+		}
+	}
+	return heartbeats, nil
+}
+
+func (s *RedisDeltaSyncer) AcquireLease(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if !s.active {
+		return true, nil
+	}
+	held, err := leaseScript.Run(s.client, []string{EARS_REDIS_LEASE_PREFIX + key}, s.instanceId, ttl.Milliseconds()).Int()
+	if err != nil {
+		s.logger.Error().Str("op", "AcquireLease").Msg(err.Error())
+		return false, err
+	}
+	return held == 1, nil
+}
+
+func (s *RedisDeltaSyncer) ReleaseLease(ctx context.Context, key string) error {
+	if !s.active {
+		return nil
+	}
+	err := releaseScript.Run(s.client, []string{EARS_REDIS_LEASE_PREFIX + key}, s.instanceId).Err()
+	if err != nil {
+		s.logger.Error().Str("op", "ReleaseLease").Msg(err.Error())
+	}
+	return err
+}
+
+func (s *RedisDeltaSyncer) GetMembers(ctx context.Context) ([]string, error) {
+	members := []string{s.instanceId}
+	if !s.active {
+		return members, nil
+	}
+	keys, err := s.scanKeys(EARS_REDIS_MEMBER_PREFIX)
+	if err != nil {
+		s.logger.Error().Str("op", "GetMembers").Msg(err.Error())
+		return nil, err
+	}
+	for _, key := range keys {
+		id := strings.TrimPrefix(key, EARS_REDIS_MEMBER_PREFIX)
+		if id != s.instanceId {
+			members = append(members, id)
+		}
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// scanKeys gets all keys with the given prefix
+func (s *RedisDeltaSyncer) scanKeys(prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	var cursor uint64
+	for {
+		page, next, err := s.client.Scan(cursor, prefix+"*", 100).Result()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, page...)
+		cursor = next
+		if cursor == 0 {
+			return keys, nil
+		}
+	}
+}
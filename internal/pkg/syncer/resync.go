@@ -0,0 +1,37 @@
+// Copyright 2020 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"context"
+	"github.com/rs/zerolog"
+)
+
+// ResyncAll asks all local syncers capable of it to synchronize all of their items,
+// the caller must hold the lock protecting the local syncers
+func ResyncAll(ctx context.Context, logger *zerolog.Logger, localSyncers map[string][]LocalSyncer) {
+	for itemType, syncers := range localSyncers {
+		for _, localSyncer := range syncers {
+			resyncer, ok := localSyncer.(LocalResyncer)
+			if !ok {
+				continue
+			}
+			err := resyncer.ResyncAllItems(ctx)
+			if err != nil {
+				logger.Error().Str("op", "ResyncAll").Str("itemType", itemType).Msg("failed to resync items: " + err.Error())
+			}
+		}
+	}
+}
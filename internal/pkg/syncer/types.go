@@ -17,6 +17,7 @@ package syncer
 import (
 	"context"
 	"github.com/xmidt-org/ears/pkg/tenant"
+	"time"
 )
 
 const (
@@ -37,6 +38,7 @@ type SyncCommand struct {
 	InstanceId string
 	Sid        string
 	Tenant     tenant.Id
+	Version    int64 // position of the command in the change log
 }
 
 type (
@@ -45,6 +47,14 @@ type (
 		SyncItem(ctx context.Context, tid tenant.Id, itemId string, add bool) error
 	}
 
+	// A LocalResyncer is a LocalSyncer which can synchronize all of its items at once.
+	// Delta syncers use it when changes can no longer be replayed from the change log.
+	LocalResyncer interface {
+		LocalSyncer
+		// ResyncAllItems
+		ResyncAllItems(ctx context.Context) error
+	}
+
 	DeltaSyncer interface {
 		// StartListeningForSyncRequests
 		StartListeningForSyncRequests() // do we need this still?
@@ -58,5 +68,22 @@ type (
 		PublishSyncRequest(ctx context.Context, tenantId tenant.Id, itemType string, itemId string, add bool)
 		// GetInstanceCount
 		GetInstanceCount(ctx context.Context) int
+		// GetInstanceId
+		GetInstanceId() string
+		// GetMembers gets the sorted ids of all live instances, always including this instance
+		GetMembers(ctx context.Context) ([]string, error)
+		// GetVersion gets the version of the latest change published by any instance, 0 if there is none
+		GetVersion(ctx context.Context) (int64, error)
+		// GetAppliedVersion gets the version of the latest change applied by this instance
+		GetAppliedVersion() int64
+		// PublishHeartbeat stores the status of this instance, the heartbeat expires after ttl
+		PublishHeartbeat(ctx context.Context, status []byte, ttl time.Duration) error
+		// GetHeartbeats gets the status of all instances with a live heartbeat by instance id
+		GetHeartbeats(ctx context.Context) (map[string][]byte, error)
+		// AcquireLease acquires a free lease or renews a lease held by this instance, it tells if this
+		// instance holds the lease. The lease expires after ttl unless renewed.
+		AcquireLease(ctx context.Context, key string, ttl time.Duration) (bool, error)
+		// ReleaseLease releases a lease if it is held by this instance
+		ReleaseLease(ctx context.Context, key string) error
 	}
 )
@@ -57,3 +57,13 @@ type InternalStorageError struct {
 func (e *InternalStorageError) Error() string {
 	return errs.String("InternalStorageError", nil, e.Wrapped)
 }
+
+type TenantPolicyError struct {
+	Plugin string
+	Name   string
+	Reason string
+}
+
+func (e *TenantPolicyError) Error() string {
+	return errs.String("TenantPolicyError", map[string]interface{}{"plugin": e.Plugin, "name": e.Name, "reason": e.Reason}, nil)
+}
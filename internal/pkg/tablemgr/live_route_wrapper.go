@@ -20,13 +20,16 @@ import (
 	"github.com/xmidt-org/ears/pkg/receiver"
 	"github.com/xmidt-org/ears/pkg/route"
 	"github.com/xmidt-org/ears/pkg/sender"
+	"github.com/xmidt-org/ears/pkg/tenant"
 	"sync"
+	"time"
 )
 
 type LiveRouteWrapper struct {
 	sync.Mutex
 	Route       *route.Route
 	Sender      sender.Sender
+	Senders     []sender.Sender // named senders
 	Receiver    receiver.Receiver
 	FilterChain *filter.Chain
 	Config      route.Config
@@ -61,44 +64,102 @@ func (lrw *LiveRouteWrapper) RemoveRouteReference() int {
 }
 
 func (lrw *LiveRouteWrapper) Unregister(ctx context.Context, r *DefaultRoutingTableManager) error {
+	_, err := lrw.Drain(ctx, r, 0)
+	return err
+}
+
+// Drain stops receiving, waits up to timeout until the events in flight on the route are acked or nacked
+// and then unregisters sender and filters
+func (lrw *LiveRouteWrapper) Drain(ctx context.Context, r *DefaultRoutingTableManager, timeout time.Duration) (route.DrainReport, error) {
 	lrw.Lock()
 	defer lrw.Unlock()
 	var e, err error
+	var report route.DrainReport
 	if lrw.Receiver != nil {
 		err = r.pluginMgr.UnregisterReceiver(ctx, lrw.Receiver)
 		if err != nil {
 			e = err
 		}
 	}
+	if lrw.Route != nil {
+		report = lrw.Route.AwaitInFlight(ctx, timeout)
+	}
 	if lrw.Sender != nil {
 		err = r.pluginMgr.UnregisterSender(ctx, lrw.Sender)
 		if err != nil {
 			e = err
 		}
 	}
+	for _, s := range lrw.Senders {
+		err = r.pluginMgr.UnregisterSender(ctx, s)
+		if err != nil {
+			e = err
+		}
+	}
+	lrw.Senders = nil
 	if lrw.FilterChain != nil {
-		for _, filter := range lrw.FilterChain.Filterers() {
-			err = r.pluginMgr.UnregisterFilter(ctx, filter)
+		err = unregisterFilters(ctx, r, lrw.FilterChain)
+		if err != nil {
+			e = err
+		}
+	}
+	return report, e
+}
+
+// unregisterFilters unregisters the filters of a chain including those in branches
+func unregisterFilters(ctx context.Context, r *DefaultRoutingTableManager, chain filter.Chainer) error {
+	var e error
+	for _, f := range chain.Filterers() {
+		var err error
+		if c, ok := f.(filter.Chainer); ok {
+			err = unregisterFilters(ctx, r, c)
+		} else {
+			err = r.pluginMgr.UnregisterFilter(ctx, f)
+		}
+		if err != nil {
+			e = err
+		}
+	}
+	return e
+}
+
+// registerFilters registers the filters of a chain config and adds them to the chain. Filters with
+// branches become filter.Branches holding a chain per branch. Filters are added as soon as they are
+// registered so that unregistering the chain after an error cleans up.
+func registerFilters(ctx context.Context, r *DefaultRoutingTableManager, chain filter.Chainer, configs []route.PluginConfig, tid tenant.Id) error {
+	for _, f := range configs {
+		if f.Branches != nil {
+			branches, err := filter.NewBranches(f.Name, f.Join)
 			if err != nil {
-				e = err
+				return err
+			}
+			chain.Add(branches)
+			for _, branch := range f.Branches {
+				sub := &filter.Chain{}
+				branches.Add(sub)
+				err = registerFilters(ctx, r, sub, branch, tid)
+				if err != nil {
+					return err
+				}
 			}
+			continue
+		}
+		filterer, err := r.pluginMgr.RegisterFilter(ctx, f.Plugin, f.Name, stringify(f.Config), tid)
+		if err != nil {
+			return err
 		}
+		chain.Add(filterer)
 	}
-	return e
+	return nil
 }
 func (lrw *LiveRouteWrapper) Register(ctx context.Context, r *DefaultRoutingTableManager) error {
 	var err error
 	lrw.FilterChain = &filter.Chain{}
 	tid := lrw.Config.TenantId
-	if lrw.Config.FilterChain != nil {
-		for _, f := range lrw.Config.FilterChain {
-			filter, err := r.pluginMgr.RegisterFilter(ctx, f.Plugin, f.Name, stringify(f.Config), tid)
-			if err != nil {
-				lrw.Unregister(ctx, r)
-				return err
-			}
-			lrw.FilterChain.Add(filter)
-		}
+	err = registerFilters(ctx, r, lrw.FilterChain, lrw.Config.FilterChain, tid)
+	if err != nil {
+		lrw.Unregister(ctx, r)
+		return err
 	}
 	// set up sender
 	lrw.Sender, err = r.pluginMgr.RegisterSender(ctx, lrw.Config.Sender.Plugin, lrw.Config.Sender.Name, stringify(lrw.Config.Sender.Config), tid)
@@ -106,6 +167,25 @@ func (lrw *LiveRouteWrapper) Register(ctx context.Context, r *DefaultRoutingTabl
 		lrw.Unregister(ctx, r)
 		return err
 	}
+	for _, sc := range lrw.Config.Senders {
+		s, err := r.pluginMgr.RegisterSender(ctx, sc.Plugin, sc.Name, stringify(sc.Config), tid)
+		if err != nil {
+			lrw.Unregister(ctx, r)
+			return err
+		}
+		lrw.Senders = append(lrw.Senders, s)
+	}
+	// filters such as switch must only target named senders of the route
+	named := make(map[string]bool, len(lrw.Config.Senders))
+	for _, sc := range lrw.Config.Senders {
+		named[sc.Name] = true
+	}
+	for _, name := range filter.SenderNames(lrw.FilterChain) {
+		if !named[name] {
+			lrw.Unregister(ctx, r)
+			return &route.UnknownSenderError{Name: name}
+		}
+	}
 	// set up receiver
 	lrw.Receiver, err = r.pluginMgr.RegisterReceiver(ctx, lrw.Config.Receiver.Plugin, lrw.Config.Receiver.Name, stringify(lrw.Config.Receiver.Config), tid)
 	if err != nil {
@@ -36,13 +36,25 @@ type DefaultRoutingTableManager struct {
 	sync.Mutex
 	pluginMgr    plugin.Manager
 	storageMgr   route.RouteStorer
+	tenantStorer tenant.TenantStorer
 	rtSyncer     syncer.DeltaSyncer
 	liveRouteMap map[string]*LiveRouteWrapper // references to live routes by route ID
 	routeHashMap map[string]*LiveRouteWrapper // references to live routes by hash
 	logger       *zerolog.Logger
 	config       config.Config
+	membersLock  sync.RWMutex
+	members      []string // live ears instances for route placement
+	syncLock     sync.RWMutex
+	synchronized bool // outcome of the last full synchronization with the persistence layer
+	leasePending bool // a route placed on this instance waits for the lease held by another instance
 }
 
+// how often the live ears instances are checked to move routes with a placement policy
+const placementCheckInterval = 5 * time.Second
+
+// how long the lease of a route with a single placement lasts unless renewed by the placement check
+const leaseTTL = 3 * placementCheckInterval
+
 func stringify(data interface{}) string {
 	if data == nil {
 		return ""
@@ -73,13 +85,15 @@ func SetupRoutingManager(lifecycle fx.Lifecycle, logger *zerolog.Logger, routing
 	return nil
 }
 
-func NewRoutingTableManager(pluginMgr plugin.Manager, storageMgr route.RouteStorer, tableSyncer syncer.DeltaSyncer, logger *zerolog.Logger, config config.Config) RoutingTableManager {
+func NewRoutingTableManager(pluginMgr plugin.Manager, storageMgr route.RouteStorer, tenantStorer tenant.TenantStorer, tableSyncer syncer.DeltaSyncer, logger *zerolog.Logger, config config.Config) RoutingTableManager {
 	rtm := &DefaultRoutingTableManager{
-		pluginMgr:  pluginMgr,
-		storageMgr: storageMgr,
-		rtSyncer:   tableSyncer,
-		logger:     logger,
-		config:     config}
+		pluginMgr:    pluginMgr,
+		storageMgr:   storageMgr,
+		tenantStorer: tenantStorer,
+		rtSyncer:     tableSyncer,
+		logger:       logger,
+		config:       config,
+		members:      []string{tableSyncer.GetInstanceId()}}
 	rtm.Lock()
 	defer rtm.Unlock()
 	rtm.liveRouteMap = make(map[string]*LiveRouteWrapper)
@@ -89,36 +103,186 @@ func NewRoutingTableManager(pluginMgr plugin.Manager, storageMgr route.RouteStor
 }
 
 func (r *DefaultRoutingTableManager) unregisterAndStopRoute(ctx context.Context, tid tenant.Id, routeId string) error {
+	_, err := r.unregisterAndDrainRoute(ctx, tid, routeId)
+	return err
+}
+
+// unregisterAndDrainRoute stops a route once its last reference is gone. The route is drained outside
+// of the routing table lock so that other routes can be managed in the meantime.
+func (r *DefaultRoutingTableManager) unregisterAndDrainRoute(ctx context.Context, tid tenant.Id, routeId string) (route.DrainReport, error) {
 	tracer := otel.Tracer(rtsemconv.EARSTracerName)
 	ctx, span := tracer.Start(ctx, "unregisterAndStopRoute")
 	defer span.End()
-	var err error
 	r.Lock()
-	defer r.Unlock()
 	liveRoute, ok := r.liveRouteMap[tid.KeyWithRoute(routeId)]
 	if !ok {
+		r.Unlock()
 		r.logger.Info().Str("op", "unregisterAndStopRoute").Str("routeId", routeId).Msg("no live route exists with this ID")
 		// no error to make this idempotent
 		//return errors.New("no live route exists with ID " + routeId)
-	} else {
-		delete(r.liveRouteMap, tid.KeyWithRoute(routeId))
-		numRefs := liveRoute.RemoveRouteReference()
-		r.logger.Info().Str("op", "unregisterAndStopRoute").Str("routeId", routeId).Int("numRefs", numRefs).Str("routeHash", liveRoute.Config.Hash(ctx)).Msg("number references")
-		if numRefs == 0 {
-			delete(r.routeHashMap, liveRoute.Config.Hash(ctx))
-			//err = liveRoute.Route.Stop(ctx)
-			//if err != nil {
-			//	r.logger.Error().Str("op", "unregisterAndStopRoute").Msg("could not stop route: " + err.Error())
-			//return err
-			//}
-			err = liveRoute.Unregister(ctx, r)
-			if err != nil {
-				return err
+		return route.DrainReport{}, nil
+	}
+	delete(r.liveRouteMap, tid.KeyWithRoute(routeId))
+	numRefs := liveRoute.RemoveRouteReference()
+	r.logger.Info().Str("op", "unregisterAndStopRoute").Str("routeId", routeId).Int("numRefs", numRefs).Str("routeHash", liveRoute.Config.Hash(ctx)).Msg("number references")
+	if numRefs > 0 {
+		r.Unlock()
+		return route.DrainReport{}, nil
+	}
+	delete(r.routeHashMap, liveRoute.Config.Hash(ctx))
+	r.Unlock()
+	report, err := liveRoute.Drain(ctx, r, r.drainTimeout())
+	if err != nil {
+		return report, err
+	}
+	// other instances may only take over the route once it has stopped
+	r.releaseLease(ctx, &liveRoute.Config)
+	r.logger.Info().Str("op", "unregisterAndStopRoute").Str("routeId", routeId).Int("drained", report.Drained).Int("abandoned", report.Abandoned).Msg("route stopped")
+	return report, nil
+}
+
+// drainTimeout bounds how long stopping a route waits for its events in flight
+func (r *DefaultRoutingTableManager) drainTimeout() time.Duration {
+	timeout := r.config.GetInt("ears.drainTimeoutMs")
+	if timeout <= 0 {
+		return route.DefaultDrainTimeout
+	}
+	return time.Duration(timeout) * time.Millisecond
+}
+
+// refreshMembers updates the live ears instances and tells if they changed
+func (r *DefaultRoutingTableManager) refreshMembers(ctx context.Context) bool {
+	members, err := r.rtSyncer.GetMembers(ctx)
+	if err != nil {
+		// keep the last known placement rather than moving routes around
+		r.logger.Error().Str("op", "refreshMembers").Msg(err.Error())
+		return false
+	}
+	r.membersLock.Lock()
+	defer r.membersLock.Unlock()
+	changed := len(members) != len(r.members)
+	for i := 0; !changed && i < len(members); i++ {
+		changed = members[i] != r.members[i]
+	}
+	r.members = members
+	return changed
+}
+
+// isPlaced tells if this instance should run the route according to its placement policy
+func (r *DefaultRoutingTableManager) isPlaced(ctx context.Context, routeConfig *route.Config) bool {
+	r.membersLock.RLock()
+	defer r.membersLock.RUnlock()
+	return routeConfig.IsPlacedOn(ctx, r.rtSyncer.GetInstanceId(), r.members)
+}
+
+// refreshPlacement makes sure the placement of a route is decided on the current ears instances,
+// other routes are moved in the background if the instances changed
+func (r *DefaultRoutingTableManager) refreshPlacement(ctx context.Context, routeConfig *route.Config) {
+	if routeConfig.Placement == nil || !r.refreshMembers(ctx) {
+		return
+	}
+	go func() {
+		_, err := r.SynchronizeAllRoutes()
+		if err != nil {
+			r.logger.Error().Str("op", "refreshPlacement").Msg(err.Error())
+		}
+	}()
+}
+
+// placedRoutes filters the routes this instance should run
+func (r *DefaultRoutingTableManager) placedRoutes(ctx context.Context, routes []route.Config) []route.Config {
+	placed := make([]route.Config, 0, len(routes))
+	for i := range routes {
+		if r.isPlaced(ctx, &routes[i]) {
+			placed = append(placed, routes[i])
+		}
+	}
+	return placed
+}
+
+// leaseKey identifies the lease of a route, identical routes share a live route and so a lease
+func leaseKey(ctx context.Context, routeConfig *route.Config) string {
+	return "route_" + routeConfig.Hash(ctx)
+}
+
+// acquireLease tells if this instance may run the route, routes with an exclusive placement
+// only run on the instance holding their lease
+func (r *DefaultRoutingTableManager) acquireLease(ctx context.Context, routeConfig *route.Config) bool {
+	if !routeConfig.Placement.Exclusive() {
+		return true
+	}
+	held, err := r.rtSyncer.AcquireLease(ctx, leaseKey(ctx, routeConfig), leaseTTL)
+	if err != nil {
+		r.logger.Error().Str("op", "acquireLease").Str("routeId", routeConfig.Id).Msg(err.Error())
+		held = false
+	}
+	if !held {
+		r.syncLock.Lock()
+		r.leasePending = true
+		r.syncLock.Unlock()
+	}
+	return held
+}
+
+// releaseLease lets other instances take over a stopped route with an exclusive placement
+func (r *DefaultRoutingTableManager) releaseLease(ctx context.Context, routeConfig *route.Config) {
+	if !routeConfig.Placement.Exclusive() {
+		return
+	}
+	err := r.rtSyncer.ReleaseLease(ctx, leaseKey(ctx, routeConfig))
+	if err != nil {
+		r.logger.Error().Str("op", "releaseLease").Str("routeId", routeConfig.Id).Msg(err.Error())
+	}
+}
+
+// takeLeasePending tells if a route waited for a lease since the last call
+func (r *DefaultRoutingTableManager) takeLeasePending() bool {
+	r.syncLock.Lock()
+	defer r.syncLock.Unlock()
+	pending := r.leasePending
+	r.leasePending = false
+	return pending
+}
+
+// renewLeases renews the leases of all live routes with an exclusive placement. Routes which lost
+// their lease to another instance are stopped.
+func (r *DefaultRoutingTableManager) renewLeases(ctx context.Context) {
+	r.Lock()
+	leased := make([]*LiveRouteWrapper, 0)
+	for _, lrw := range r.routeHashMap {
+		if lrw.Config.Placement.Exclusive() {
+			leased = append(leased, lrw)
+		}
+	}
+	r.Unlock()
+	for _, lrw := range leased {
+		held, err := r.rtSyncer.AcquireLease(ctx, leaseKey(ctx, &lrw.Config), leaseTTL)
+		if err != nil {
+			// the lease is still valid until it expires, so the route keeps running for now
+			r.logger.Error().Str("op", "renewLeases").Str("routeId", lrw.Config.Id).Msg(err.Error())
+			continue
+		}
+		if held {
+			continue
+		}
+		r.logger.Error().Str("op", "renewLeases").Str("routeId", lrw.Config.Id).Msg("route lease lost")
+		// stop the live route with all its references, the next synchronization waits for the lease again
+		r.Lock()
+		for k, v := range r.liveRouteMap {
+			if v == lrw {
+				delete(r.liveRouteMap, k)
 			}
-			r.logger.Info().Str("op", "unregisterAndStopRoute").Str("routeId", routeId).Msg("route stopped")
+		}
+		delete(r.routeHashMap, lrw.Config.Hash(ctx))
+		r.Unlock()
+		r.syncLock.Lock()
+		r.leasePending = true
+		r.syncLock.Unlock()
+		_, err = lrw.Drain(ctx, r, r.drainTimeout())
+		if err != nil {
+			r.logger.Error().Str("op", "renewLeases").Str("routeId", lrw.Config.Id).Msg(err.Error())
 		}
 	}
-	return nil
 }
 
 func (r *DefaultRoutingTableManager) registerAndRunRoute(ctx context.Context, routeConfig *route.Config) error {
@@ -130,6 +294,14 @@ func (r *DefaultRoutingTableManager) registerAndRunRoute(ctx context.Context, ro
 	r.Lock()
 	existingLiveRoute, ok := r.liveRouteMap[routeConfig.TenantId.KeyWithRoute(routeConfig.Id)]
 	r.Unlock()
+	if !r.isPlaced(ctx, routeConfig) {
+		r.logger.Info().Str("op", "registerAndRunRoute").Str("routeId", routeConfig.Id).Msg("route placed on other instances")
+		if ok {
+			// the placement of the route changed with an update
+			return r.unregisterAndStopRoute(ctx, routeConfig.TenantId, routeConfig.Id)
+		}
+		return nil
+	}
 	if ok && existingLiveRoute.Config.Hash(ctx) == routeConfig.Hash(ctx) {
 		r.logger.Info().Str("op", "registerAndRunRoute").Str("routeId", routeConfig.Id).Msg("identical route exists with same hash and same ID")
 		return nil
@@ -142,6 +314,11 @@ func (r *DefaultRoutingTableManager) registerAndRunRoute(ctx context.Context, ro
 			r.logger.Error().Str("op", "registerAndRunRoute").Str("routeId", routeConfig.Id).Msg(err.Error())
 		}
 	}
+	// a single route only runs on the instance holding its lease
+	if !r.acquireLease(ctx, routeConfig) {
+		r.logger.Info().Str("op", "registerAndRunRoute").Str("routeId", routeConfig.Id).Msg("route leased by other instance")
+		return nil
+	}
 	// An identical route already exists under a different ID.
 	// It would be ok to simply create another route here because plugin manager will ensure we share receiver and sender
 	// plugin for performance. However, simply creating another route would cause event duplication. Instead we need to
@@ -167,15 +344,16 @@ func (r *DefaultRoutingTableManager) registerAndRunRoute(ctx context.Context, ro
 	err = lrw.Register(ctx, r)
 	if err != nil {
 		r.logger.Error().Str("op", "registerAndRunRoute").Str("routeId", routeConfig.Id).Msg("failed to register new route: " + err.Error())
+		r.releaseLease(ctx, routeConfig)
 		return err
 	}
 	// create live route
-	lrw.Route = &route.Route{}
+	lrw.Route = &route.Route{Id: routeConfig.Id}
 	r.liveRouteMap[routeConfig.TenantId.KeyWithRoute(routeConfig.Id)] = lrw
 	r.routeHashMap[routeConfig.Hash(ctx)] = lrw
 	r.logger.Info().Str("op", "registerAndRunRoute").Str("routeId", routeConfig.Id).Msg("starting route")
 	go func() {
-		err = lrw.Route.Run(lrw.Receiver, lrw.FilterChain, lrw.Sender) // run is blocking
+		err = lrw.Route.Run(lrw.Receiver, lrw.FilterChain, lrw.Sender, lrw.Senders...) // run is blocking
 		if err != nil {
 			r.logger.Error().Str("op", "registerAndRunRoute").Msg(err.Error())
 		}
@@ -200,30 +378,134 @@ func (r *DefaultRoutingTableManager) RemoveRoute(ctx context.Context, tid tenant
 	return storageErr
 }
 
+// RemoveRouteIfMatch removes a route only if the stored route still matches etag. Unlike RemoveRoute
+// nothing is unregistered if the route cannot be deleted from storage.
+func (r *DefaultRoutingTableManager) RemoveRouteIfMatch(ctx context.Context, tid tenant.Id, routeId string, etag string) error {
+	if routeId == "" {
+		return errors.New("missing route ID")
+	}
+	err := r.storageMgr.DeleteRouteIfMatch(ctx, tid, routeId, etag)
+	if err != nil {
+		return err
+	}
+	r.rtSyncer.PublishSyncRequest(ctx, tid, syncer.ITEM_TYPE_ROUTE, routeId, false)
+	err = r.unregisterAndStopRoute(ctx, tid, routeId)
+	if err != nil {
+		return &RouteRegistrationError{err}
+	}
+	return nil
+}
+
+// RemoveTenantRoutes removes all routes of a tenant from the persistence layer and stops them on all ears instances
+func (r *DefaultRoutingTableManager) RemoveTenantRoutes(ctx context.Context, tid tenant.Id) error {
+	routes, err := r.storageMgr.GetAllTenantRoutes(ctx, tid)
+	if err != nil {
+		return err
+	}
+	if len(routes) == 0 {
+		return nil
+	}
+	routeIds := make([]string, len(routes))
+	for i, routeConfig := range routes {
+		routeIds[i] = routeConfig.Id
+	}
+	err = r.storageMgr.DeleteRoutes(ctx, tid, routeIds)
+	if err != nil {
+		return err
+	}
+	var registrationErr error
+	for _, routeId := range routeIds {
+		r.rtSyncer.PublishSyncRequest(ctx, tid, syncer.ITEM_TYPE_ROUTE, routeId, false)
+		err = r.unregisterAndStopRoute(ctx, tid, routeId)
+		if err != nil {
+			r.logger.Error().Str("op", "RemoveTenantRoutes").Str("routeId", routeId).Msg("could not stop route: " + err.Error())
+			registrationErr = err
+		}
+	}
+	if registrationErr != nil {
+		return &RouteRegistrationError{registrationErr}
+	}
+	return nil
+}
+
+// strictTenancy tells if routes can only be added for tenants that have a tenant config
+func (r *DefaultRoutingTableManager) strictTenancy() bool {
+	return r.config.GetBool("ears.tenancy.strict")
+}
+
 func (r *DefaultRoutingTableManager) AddRoute(ctx context.Context, routeConfig *route.Config) error {
+	return r.addRoute(ctx, routeConfig, "")
+}
+
+func (r *DefaultRoutingTableManager) AddRouteIfMatch(ctx context.Context, routeConfig *route.Config, etag string) error {
+	if etag == "" {
+		return errors.New("missing etag")
+	}
+	return r.addRoute(ctx, routeConfig, etag)
+}
+
+// addRoute stores and runs a route. If etag is not empty the route is only updated if the stored
+// route matches the etag.
+func (r *DefaultRoutingTableManager) addRoute(ctx context.Context, routeConfig *route.Config, etag string) error {
 	if routeConfig == nil {
 		return errors.New("missing route config")
 	}
+	tenantConfig, err := r.tenantStorer.GetConfig(ctx, routeConfig.TenantId)
+	if err != nil {
+		var tenantNotFound *tenant.TenantNotFoundError
+		if r.strictTenancy() || !errors.As(err, &tenantNotFound) {
+			return err
+		}
+	} else {
+		err = applyTenantPolicy(tenantConfig, routeConfig)
+		if err != nil {
+			return err
+		}
+	}
 	// use hashed ID if none is provided - this ID will be returned by the AddRoute REST API
 	routeHash := routeConfig.Hash(ctx)
 	if routeConfig.Id == "" {
 		routeConfig.Id = routeHash
 	}
-	err := routeConfig.Validate(ctx)
+	err = routeConfig.Validate(ctx)
 	if err != nil {
 		return &RouteValidationError{err}
 	}
+	if etag != "" {
+		// fail early before touching the live routing table, the conditional write below closes the race
+		existing, err := r.storageMgr.GetRoute(ctx, routeConfig.TenantId, routeConfig.Id)
+		if err != nil || !existing.MatchesETag(ctx, etag) {
+			var notFound *route.RouteNotFoundError
+			if err != nil && !errors.As(err, &notFound) {
+				return err
+			}
+			return &route.PreconditionFailedError{TenantId: routeConfig.TenantId, RouteId: routeConfig.Id, ETag: etag}
+		}
+	}
+	r.refreshPlacement(ctx, routeConfig)
 	err = r.registerAndRunRoute(ctx, routeConfig)
 	if err != nil {
 		return &RouteRegistrationError{err}
 	}
 	// currently storage layer handles created and updated timestamps
-	err = r.storageMgr.SetRoute(ctx, *routeConfig)
+	if etag != "" {
+		err = r.storageMgr.SetRouteIfMatch(ctx, *routeConfig, etag)
+	} else {
+		err = r.storageMgr.SetRoute(ctx, *routeConfig)
+	}
 	if err != nil {
 		err2 := r.unregisterAndStopRoute(ctx, routeConfig.TenantId, routeConfig.Id)
 		if err2 != nil {
 			r.logger.Error().Str("op", "addRoute").Str("routeId", routeConfig.Id).Msg("failed to stop router following storage error")
 		}
+		var preconditionFailed *route.PreconditionFailedError
+		if errors.As(err, &preconditionFailed) {
+			// another writer won the race, restore the stored version of the route locally
+			err2 = r.SyncItem(ctx, routeConfig.TenantId, routeConfig.Id, true)
+			if err2 != nil {
+				r.logger.Error().Str("op", "addRoute").Str("routeId", routeConfig.Id).Msg("failed to restore route following precondition failure: " + err2.Error())
+			}
+		}
 		return err
 	}
 	r.rtSyncer.PublishSyncRequest(ctx, routeConfig.TenantId, syncer.ITEM_TYPE_ROUTE, routeConfig.Id, true)
@@ -255,6 +537,10 @@ func (r *DefaultRoutingTableManager) GetAllRoutes(ctx context.Context) ([]route.
 	return routes, nil
 }
 
+func (r *DefaultRoutingTableManager) ListRoutes(ctx context.Context, filter route.Filter, cursor string, limit int) (route.Page, error) {
+	return r.storageMgr.ListRoutes(ctx, filter, cursor, limit)
+}
+
 func (r *DefaultRoutingTableManager) GetAllSendersStatus(ctx context.Context) (map[string]plugin.SenderStatus, error) {
 	senders := r.pluginMgr.SendersStatus()
 	return senders, nil
@@ -276,6 +562,7 @@ func (r *DefaultRoutingTableManager) SyncItem(ctx context.Context, tid tenant.Id
 		if err != nil {
 			return err
 		}
+		r.refreshPlacement(ctx, &routeConfig)
 		return r.registerAndRunRoute(ctx, &routeConfig)
 	} else {
 		return r.unregisterAndStopRoute(ctx, tid, routeId)
@@ -283,6 +570,25 @@ func (r *DefaultRoutingTableManager) SyncItem(ctx context.Context, tid tenant.Id
 }
 
 func (r *DefaultRoutingTableManager) StartGlobalSyncChecker() {
+	go func() {
+		// move routes with a placement policy when ears instances come and go and renew route leases
+		for {
+			time.Sleep(placementCheckInterval)
+			ctx := context.Background()
+			r.renewLeases(ctx)
+			changed := r.refreshMembers(ctx)
+			// routes waiting for a lease are retried until the other instance has stopped them
+			pending := r.takeLeasePending()
+			if !changed && !pending {
+				continue
+			}
+			cnt, err := r.SynchronizeAllRoutes()
+			if err != nil {
+				r.logger.Error().Str("op", "StartGlobalSyncChecker").Msg(err.Error())
+			}
+			r.logger.Info().Str("op", "StartGlobalSyncChecker").Int("count", cnt).Bool("leasePending", pending).Msg("ears instances changed, routes placed")
+		}
+	}()
 	go func() {
 		time.Sleep(5 * time.Second)
 		for {
@@ -300,12 +606,56 @@ func (r *DefaultRoutingTableManager) StartGlobalSyncChecker() {
 	}()
 }
 
+// ResyncAllItems synchronizes the whole routing table, the delta syncer calls it when it missed changes
+func (r *DefaultRoutingTableManager) ResyncAllItems(ctx context.Context) error {
+	cnt, err := r.SynchronizeAllRoutes()
+	if err != nil {
+		return err
+	}
+	r.logger.Info().Str("op", "ResyncAllItems").Int("count", cnt).Msg("routing table resynchronized")
+	return nil
+}
+
+// setSynchronized records the outcome of a full synchronization with the persistence layer
+func (r *DefaultRoutingTableManager) setSynchronized(synchronized bool) {
+	r.syncLock.Lock()
+	defer r.syncLock.Unlock()
+	r.synchronized = synchronized
+}
+
+// LastSynchronized is a cheap variant of IsSynchronized for frequent callers like the heartbeat.
+// Without published changes it reports the outcome of the last full synchronization by the
+// global sync checker instead of comparing the routing table with the persistence layer.
+func (r *DefaultRoutingTableManager) LastSynchronized() (bool, error) {
+	version, err := r.rtSyncer.GetVersion(context.Background())
+	if err != nil {
+		return true, err
+	}
+	if version > 0 {
+		return r.rtSyncer.GetAppliedVersion() >= version, nil
+	}
+	r.syncLock.RLock()
+	defer r.syncLock.RUnlock()
+	return r.synchronized, nil
+}
+
+// IsSynchronized compares the version of the latest change applied locally with the version of
+// the latest change published by any instance. Without published changes it compares the
+// routing table with the persistence layer.
 func (r *DefaultRoutingTableManager) IsSynchronized() (bool, error) {
 	ctx := context.Background()
+	version, err := r.rtSyncer.GetVersion(ctx)
+	if err != nil {
+		return true, err
+	}
+	if version > 0 {
+		return r.rtSyncer.GetAppliedVersion() >= version, nil
+	}
 	storedRoutes, err := r.storageMgr.GetAllRoutes(ctx)
 	if err != nil {
 		return true, err
 	}
+	storedRoutes = r.placedRoutes(ctx, storedRoutes)
 	r.Lock()
 	defer r.Unlock()
 	if len(storedRoutes) != len(r.liveRouteMap) {
@@ -337,12 +687,19 @@ func (r *DefaultRoutingTableManager) IsSynchronized() (bool, error) {
 	return 0, nil
 }*/
 
+// SynchronizeAllRoutes makes the routing table consistent with the routes stored in the persistence
+// layer which are placed on this instance
 func (r *DefaultRoutingTableManager) SynchronizeAllRoutes() (int, error) {
 	ctx := context.Background()
 	storedRoutes, err := r.storageMgr.GetAllRoutes(ctx)
 	if err != nil {
+		r.setSynchronized(false)
 		return 0, err
 	}
+	// routes started below may still fail to run but the next synchronization retries them
+	defer r.setSynchronized(true)
+	r.refreshMembers(ctx)
+	storedRoutes = r.placedRoutes(ctx, storedRoutes)
 	storedRouteMap := make(map[string]route.Config)
 	for _, storedRoute := range storedRoutes {
 		storedRouteMap[storedRoute.TenantId.KeyWithRoute(storedRoute.Id)] = storedRoute
@@ -386,16 +743,34 @@ func (r *DefaultRoutingTableManager) SynchronizeAllRoutes() (int, error) {
 func (r *DefaultRoutingTableManager) UnregisterAllRoutes() error {
 	ctx := context.Background()
 	r.logger.Info().Str("op", "UnregisterAllRoutes").Msg("starting to unregister all routes")
-	var err error
+	r.Lock()
+	liveRoutes := make([]route.Config, 0, len(r.liveRouteMap))
 	for _, lrw := range r.liveRouteMap {
-		r.logger.Info().Str("op", "UnregisterAllRoutes").Msg("unregistering route " + lrw.Config.Id)
-		err = r.unregisterAndStopRoute(ctx, lrw.Config.TenantId, lrw.Config.Id)
-		if err != nil {
-			// best effort strategy
-			r.logger.Error().Str("op", "UnregisterAllRoutes").Msg(err.Error())
-		}
+		liveRoutes = append(liveRoutes, lrw.Config)
 	}
-	r.logger.Info().Str("op", "UnregisterAllRoutes").Msg("done unregistering all routes")
+	r.Unlock()
+	// all routes drain at the same time so that shutdown takes at most one drain timeout
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var total route.DrainReport
+	for _, rc := range liveRoutes {
+		wg.Add(1)
+		go func(rc route.Config) {
+			defer wg.Done()
+			r.logger.Info().Str("op", "UnregisterAllRoutes").Msg("unregistering route " + rc.Id)
+			report, err := r.unregisterAndDrainRoute(ctx, rc.TenantId, rc.Id)
+			if err != nil {
+				// best effort strategy
+				r.logger.Error().Str("op", "UnregisterAllRoutes").Msg(err.Error())
+			}
+			lock.Lock()
+			total.Drained += report.Drained
+			total.Abandoned += report.Abandoned
+			lock.Unlock()
+		}(rc)
+	}
+	wg.Wait()
+	r.logger.Info().Str("op", "UnregisterAllRoutes").Int("drained", total.Drained).Int("abandoned", total.Abandoned).Msg("done unregistering all routes")
 	return nil
 }
 
@@ -407,6 +782,7 @@ func (r *DefaultRoutingTableManager) RegisterAllRoutes() error {
 	if err != nil {
 		return err
 	}
+	r.refreshMembers(ctx)
 	for _, routeConfig := range routeConfigs {
 		rc := routeConfig
 		r.logger.Info().Str("op", "RegisterAllRoutes").Msg("registering route " + routeConfig.Id)
@@ -416,6 +792,7 @@ func (r *DefaultRoutingTableManager) RegisterAllRoutes() error {
 			r.logger.Error().Str("op", "RegisterAllRoutes").Msg(err.Error())
 		}
 	}
+	r.setSynchronized(true)
 	r.logger.Info().Str("op", "RegisterAllRoutes").Msg("done registering all routes")
 	return nil
 }
@@ -0,0 +1,50 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tablemgr
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"github.com/xmidt-org/ears/internal/pkg/db"
+	"github.com/xmidt-org/ears/internal/pkg/syncer"
+)
+
+func TestLastSynchronized(t *testing.T) {
+	v := viper.New()
+	v.Set("ears.synchronization.active", false)
+	logger := zerolog.Nop()
+	rtm := NewRoutingTableManager(nil, db.NewInMemoryRouteStorer(v), db.NewTenantInmemoryStorer(), syncer.NewInMemoryDeltaSyncer(&logger, v), &logger, v)
+	// without published changes the state comes from the last full synchronization
+	synchronized, err := rtm.LastSynchronized()
+	if err != nil {
+		t.Fatalf("cannot get synchronized state: %s", err.Error())
+	}
+	if synchronized {
+		t.Fatalf("routing table reported synchronized before any synchronization")
+	}
+	_, err = rtm.SynchronizeAllRoutes()
+	if err != nil {
+		t.Fatalf("cannot synchronize routes: %s", err.Error())
+	}
+	synchronized, err = rtm.LastSynchronized()
+	if err != nil {
+		t.Fatalf("cannot get synchronized state: %s", err.Error())
+	}
+	if !synchronized {
+		t.Fatalf("routing table not reported synchronized after synchronization")
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2020 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tablemgr
+
+import (
+	"github.com/xmidt-org/ears/pkg/route"
+	"github.com/xmidt-org/ears/pkg/secret"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"strings"
+)
+
+// plugin config keys holding comma separated lists of host[:port]
+var hostConfigKeys = map[string]bool{
+	"brokers":       true,
+	"endpoint":      true,
+	"host":          true,
+	"hosts":         true,
+	"address":       true,
+	"addr":          true,
+	"redisendpoint": true,
+}
+
+// applyTenantPolicy merges the tenant plugin defaults into the plugin configs of a route
+// and checks that the route only uses plugin types, hosts and urls allowed for the tenant
+func applyTenantPolicy(tenantConfig *tenant.Config, routeConfig *route.Config) error {
+	plugins := []*route.PluginConfig{&routeConfig.Receiver, &routeConfig.Sender}
+	for i := range routeConfig.Senders {
+		plugins = append(plugins, &routeConfig.Senders[i])
+	}
+	plugins = appendFilters(plugins, routeConfig.FilterChain)
+	for _, pc := range plugins {
+		if !tenantConfig.IsPluginAllowed(pc.Plugin) {
+			return &TenantPolicyError{Plugin: pc.Plugin, Name: pc.Name, Reason: "plugin type not allowed for tenant"}
+		}
+		defaults, ok := tenantConfig.Defaults[pc.Plugin]
+		if ok {
+			pc.Config = mergeDefaults(pc.Config, defaults)
+		}
+		reason := checkEndpoints(tenantConfig, pc.Config, "")
+		if reason != "" {
+			return &TenantPolicyError{Plugin: pc.Plugin, Name: pc.Name, Reason: reason}
+		}
+	}
+	return nil
+}
+
+// appendFilters appends the filters of a chain including those in branches
+func appendFilters(plugins []*route.PluginConfig, chain []route.PluginConfig) []*route.PluginConfig {
+	for i := range chain {
+		if chain[i].Branches != nil {
+			for _, branch := range chain[i].Branches {
+				plugins = appendFilters(plugins, branch)
+			}
+			continue
+		}
+		plugins = append(plugins, &chain[i])
+	}
+	return plugins
+}
+
+// mergeDefaults deep merges defaults into config. Values in config win
+func mergeDefaults(config interface{}, defaults interface{}) interface{} {
+	if config == nil {
+		return defaults
+	}
+	configMap, ok := toStringMap(config)
+	if !ok {
+		return config
+	}
+	defaultsMap, ok := toStringMap(defaults)
+	if !ok {
+		return config
+	}
+	merged := make(map[string]interface{}, len(defaultsMap)+len(configMap))
+	for key, value := range defaultsMap {
+		merged[key] = value
+	}
+	for key, value := range configMap {
+		defaultValue, ok := merged[key]
+		if ok {
+			merged[key] = mergeDefaults(value, defaultValue)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// checkEndpoints walks a plugin config looking for urls and hosts the tenant is not
+// allowed to use. It returns the reason for the first one found or "" if there is none
+func checkEndpoints(tenantConfig *tenant.Config, value interface{}, key string) string {
+	if m, ok := toStringMap(value); ok {
+		for k, v := range m {
+			reason := checkEndpoints(tenantConfig, v, k)
+			if reason != "" {
+				return reason
+			}
+		}
+		return ""
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			reason := checkEndpoints(tenantConfig, item, key)
+			if reason != "" {
+				return reason
+			}
+		}
+	case string:
+		// secret references are resolved from the tenant's own secrets, not connected to
+		if strings.HasPrefix(v, secret.Protocol) {
+			return ""
+		}
+		lowerKey := strings.ToLower(key)
+		if strings.HasSuffix(lowerKey, "url") || strings.Contains(v, "://") {
+			if v != "" && !tenantConfig.IsUrlAllowed(v) {
+				return "url " + v + " not allowed for tenant"
+			}
+		} else if hostConfigKeys[lowerKey] {
+			for _, host := range strings.Split(v, ",") {
+				host = strings.TrimSpace(host)
+				if host != "" && !tenantConfig.IsHostAllowed(host) {
+					return "host " + host + " not allowed for tenant"
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func toStringMap(value interface{}) (map[string]interface{}, bool) {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			key, ok := k.(string)
+			if !ok {
+				return nil, false
+			}
+			converted[key] = v
+		}
+		return converted, true
+	}
+	return nil, false
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tablemgr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/xmidt-org/ears/pkg/route"
+	"github.com/xmidt-org/ears/pkg/tenant"
+)
+
+func TestTenantPolicyEndpoints(t *testing.T) {
+	tenantConfig := &tenant.Config{
+		Tenant:       tenant.Id{OrgId: "myorg", AppId: "myapp"},
+		AllowedHosts: []string{"*.example.com"},
+		AllowedUrls:  []string{"https://api.example.com/v1"},
+	}
+	testCases := []struct {
+		name    string
+		config  map[string]interface{}
+		allowed bool
+	}{
+		{name: "allowedUrl", config: map[string]interface{}{"url": "https://api.example.com/v1/events"}, allowed: true},
+		{name: "lookalikeUrl", config: map[string]interface{}{"url": "https://api.example.com.evil.net/v1/events"}, allowed: false},
+		{name: "allowedHost", config: map[string]interface{}{"brokers": "a.example.com:9092,b.example.com:9092"}, allowed: true},
+		{name: "disallowedHost", config: map[string]interface{}{"brokers": "a.example.com:9092,localhost:9092"}, allowed: false},
+		{name: "allowedRedisEndpoint", config: map[string]interface{}{"redisEndpoint": "redis.example.com:6379"}, allowed: true},
+		{name: "disallowedRedisEndpoint", config: map[string]interface{}{"redisEndpoint": "localhost:6379"}, allowed: false},
+		{name: "secretUrl", config: map[string]interface{}{"url": "secret://http.url"}, allowed: true},
+		{name: "secretHost", config: map[string]interface{}{"endpoint": "secret://redis.endpoint"}, allowed: true},
+		{name: "secretValue", config: map[string]interface{}{"password": "secret://kafka.password"}, allowed: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			routeConfig := &route.Config{
+				Receiver: route.PluginConfig{Plugin: "debug"},
+				Sender:   route.PluginConfig{Plugin: "http", Config: tc.config},
+			}
+			err := applyTenantPolicy(tenantConfig, routeConfig)
+			var policyErr *TenantPolicyError
+			if tc.allowed && err != nil {
+				t.Errorf("expected route to be allowed, got %s", err.Error())
+			}
+			if !tc.allowed && !errors.As(err, &policyErr) {
+				t.Errorf("expected TenantPolicyError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTenantPolicyFilterEndpoints(t *testing.T) {
+	tenantConfig := &tenant.Config{
+		Tenant:       tenant.Id{OrgId: "myorg", AppId: "myapp"},
+		AllowedHosts: []string{"*.example.com"},
+	}
+	enrich := func(endpoint string) []route.PluginConfig {
+		return []route.PluginConfig{{
+			Plugin: "enrich",
+			Config: map[string]interface{}{"source": "redis", "redisEndpoint": endpoint, "redisKey": "acc:{key}"},
+		}}
+	}
+	testCases := []struct {
+		name    string
+		chain   []route.PluginConfig
+		allowed bool
+	}{
+		{name: "allowedEnrich", chain: enrich("redis.example.com:6379"), allowed: true},
+		{name: "disallowedEnrich", chain: enrich("localhost:6379"), allowed: false},
+		{name: "disallowedEnrichInBranch", chain: []route.PluginConfig{{Name: "fork", Branches: [][]route.PluginConfig{enrich("localhost:6379")}}}, allowed: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			routeConfig := &route.Config{
+				Receiver:    route.PluginConfig{Plugin: "debug"},
+				FilterChain: tc.chain,
+				Sender:      route.PluginConfig{Plugin: "debug"},
+			}
+			err := applyTenantPolicy(tenantConfig, routeConfig)
+			var policyErr *TenantPolicyError
+			if tc.allowed && err != nil {
+				t.Errorf("expected route to be allowed, got %s", err.Error())
+			}
+			if !tc.allowed && !errors.As(err, &policyErr) {
+				t.Errorf("expected TenantPolicyError, got %v", err)
+			}
+		})
+	}
+}
@@ -30,14 +30,22 @@ type (
 		syncer.LocalSyncer       // to sync routing table upon receipt of an update notification for a single route
 		// AddRoute adds a route to live routing table and runs it and also stores the route in the persistence layer
 		AddRoute(ctx context.Context, route *route.Config) error
+		// AddRouteIfMatch updates an existing route like AddRoute but only if the stored route matches the given etag
+		AddRouteIfMatch(ctx context.Context, route *route.Config, etag string) error
 		// RemoveRoute removes a route from a live routing table and stops it and also removes the route from the persistence layer
 		RemoveRoute(ctx context.Context, tenantId tenant.Id, routeId string) error
+		// RemoveRouteIfMatch removes a route like RemoveRoute but only if the stored route matches the given etag
+		RemoveRouteIfMatch(ctx context.Context, tenantId tenant.Id, routeId string, etag string) error
+		// RemoveTenantRoutes removes all routes of a tenant from live routing tables and stops them and also removes them from the persistence layer
+		RemoveTenantRoutes(ctx context.Context, tenantId tenant.Id) error
 		// GetRoute gets a single route by its ID from persistence layer
 		GetRoute(ctx context.Context, tenantId tenant.Id, routeId string) (*route.Config, error)
 		// GetAllTenantRoutes gets all routes for a tenant from persistence layer
 		GetAllTenantRoutes(ctx context.Context, tenantId tenant.Id) ([]route.Config, error)
 		// GetAllRoutes gets all routes from persistence layer
 		GetAllRoutes(ctx context.Context) ([]route.Config, error)
+		// ListRoutes gets a page of routes passing a filter from persistence layer
+		ListRoutes(ctx context.Context, filter route.Filter, cursor string, limit int) (route.Page, error)
 		// GetAllSenders gets all senders currently present in the system
 		GetAllSendersStatus(ctx context.Context) (map[string]plugin.SenderStatus, error)
 		// GetAllReceivers gets all receivers currently present in the system
@@ -57,6 +65,8 @@ type (
 		SynchronizeAllRoutes() (int, error)
 		// IsSynchronized
 		IsSynchronized() (bool, error)
+		// LastSynchronized reports the synchronized state without a full comparison with the persistence layer
+		LastSynchronized() (bool, error)
 		// GetAllRegisteredRoutes gets all routes that are currently registered and running on ears instance
 		GetAllRegisteredRoutes() ([]route.Config, error)
 	}
@@ -20,6 +20,7 @@ package event
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"github.com/xmidt-org/ears/internal/pkg/ack"
@@ -189,6 +190,46 @@ func WithOtelTracing(spanName string) EventOption {
 	}
 }
 
+type routeIdKey struct{}
+
+//WithRouteId returns a context carrying the id of the route processing an event. Filter instances are
+//shared by routes, so they tell routes apart by this id.
+func WithRouteId(ctx context.Context, routeId string) context.Context {
+	return context.WithValue(ctx, routeIdKey{}, routeId)
+}
+
+//RouteId gets the id of the route processing an event, or an empty string outside of a route
+func RouteId(ctx context.Context) string {
+	routeId, _ := ctx.Value(routeIdKey{}).(string)
+	return routeId
+}
+
+//Track hands an event over to a new event with the same payload, metadata and context. Once the new
+//event and all its child events are acknowledged, the original event is acked or nacked accordingly and
+//done is called. Acknowledgements must go to the new event from then on.
+func Track(e Event, done func()) Event {
+	ctx := e.Context()
+	return &event{
+		payload:  e.Payload(),
+		metadata: e.Metadata(),
+		ctx:      ctx,
+		ack: ack.NewAckTree(ctx, func() {
+			e.Ack()
+			done()
+		}, func(err error) {
+			// the original event wraps the error in a nack error again
+			var nackErr *ack.NackError
+			if errors.As(err, &nackErr) && errors.Unwrap(nackErr) != nil {
+				err = errors.Unwrap(nackErr)
+			}
+			e.Nack(err)
+			done()
+		}),
+		tid:     e.Tenant(),
+		created: e.Created(),
+	}
+}
+
 func (e *event) Created() time.Time {
 	return e.created
 }
@@ -292,3 +292,53 @@ func TestEventTimeout(t *testing.T) {
 
 	<-done
 }
+
+func TestPayloadFormats(t *testing.T) {
+	in := []byte(`{"a":1}`)
+	testCases := []struct {
+		format  string
+		payload interface{}
+	}{
+		{"", map[string]interface{}{"a": float64(1)}},
+		{event.PayloadFormatJson, map[string]interface{}{"a": float64(1)}},
+		{event.PayloadFormatText, `{"a":1}`},
+		{event.PayloadFormatBytes, in},
+	}
+	for _, tc := range testCases {
+		payload, err := event.UnmarshalPayload(tc.format, in)
+		if err != nil {
+			t.Fatalf("%s: unmarshal error %s", tc.format, err.Error())
+		}
+		if !reflect.DeepEqual(payload, tc.payload) {
+			t.Errorf("%s: expected %v got %v", tc.format, tc.payload, payload)
+		}
+		buf, err := event.MarshalPayload(tc.format, payload)
+		if err != nil {
+			t.Fatalf("%s: marshal error %s", tc.format, err.Error())
+		}
+		if string(buf) != string(in) {
+			t.Errorf("%s: expected %s got %s", tc.format, string(in), string(buf))
+		}
+	}
+
+	bytesPayload, _ := event.UnmarshalPayload(event.PayloadFormatBytes, in)
+	in[0] = '['
+	if bytesPayload.([]byte)[0] != '{' {
+		t.Errorf("bytes payload aliases the receive buffer")
+	}
+
+	_, err := event.UnmarshalPayload(event.PayloadFormatJson, []byte("not json"))
+	if err == nil {
+		t.Errorf("expected error for invalid json")
+	}
+	_, err = event.UnmarshalPayload("xml", in)
+	if err == nil {
+		t.Errorf("expected error for unsupported format")
+	}
+
+	// text and bytes senders marshal structured payloads as json
+	buf, err := event.MarshalPayload(event.PayloadFormatText, map[string]interface{}{"b": "c"})
+	if err != nil || string(buf) != `{"b":"c"}` {
+		t.Errorf("unexpected text payload %s %v", string(buf), err)
+	}
+}
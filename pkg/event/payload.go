@@ -0,0 +1,70 @@
+// Copyright 2020 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package event
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+const (
+	//PayloadFormatJson parses received data as JSON and marshals payloads as JSON when sending
+	PayloadFormatJson = "json"
+	//PayloadFormatText carries received data as a string payload
+	PayloadFormatText = "text"
+	//PayloadFormatBytes carries received data as a []byte payload
+	PayloadFormatBytes = "bytes"
+)
+
+// UnmarshalPayload turns data received by a receiver into an event payload
+// according to the payload format. An empty format defaults to json.
+func UnmarshalPayload(format string, buf []byte) (interface{}, error) {
+	switch format {
+	case "", PayloadFormatJson:
+		var payload interface{}
+		err := json.Unmarshal(buf, &payload)
+		if err != nil {
+			return nil, err
+		}
+		return payload, nil
+	case PayloadFormatText:
+		return string(buf), nil
+	case PayloadFormatBytes:
+		//copy so that the payload does not alias buffers owned by client libraries
+		payload := make([]byte, len(buf))
+		copy(payload, buf)
+		return payload, nil
+	}
+	return nil, errors.New("unsupported payload format " + format)
+}
+
+// MarshalPayload turns an event payload into the data a sender sends according
+// to the payload format. With the text and bytes formats string and []byte
+// payloads are sent as they are while any other payload is marshalled as JSON.
+func MarshalPayload(format string, payload interface{}) ([]byte, error) {
+	switch format {
+	case "", PayloadFormatJson:
+		return json.Marshal(payload)
+	case PayloadFormatText, PayloadFormatBytes:
+		switch payload := payload.(type) {
+		case string:
+			return []byte(payload), nil
+		case []byte:
+			return payload, nil
+		}
+		return json.Marshal(payload)
+	}
+	return nil, errors.New("unsupported payload format " + format)
+}
@@ -36,6 +36,22 @@ const (
 	TENANT   = "tenant"
 )
 
+// metadata keys set by receivers
+
+const (
+	//MetadataBrokerTimestamp holds the time in epoch milliseconds at which the message broker
+	//received the message, if the receiver knows it
+	MetadataBrokerTimestamp = "brokerTimestamp"
+)
+
+// metadata keys used by routes
+
+const (
+	//MetadataSenders holds the names of the route senders an event should be sent to, the
+	//route sends the event to its default sender if not set
+	MetadataSenders = "earsSenders"
+)
+
 type Event interface {
 	//Get the event payload
 	Payload() interface{}
@@ -0,0 +1,170 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/xmidt-org/ears/internal/pkg/rtsemconv"
+	"github.com/xmidt-org/ears/pkg/event"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"go.opentelemetry.io/otel"
+)
+
+// NewBranches returns an empty set of branches joined by concatenation or merge
+func NewBranches(name string, join string) (*Branches, error) {
+	if join == "" {
+		join = JoinConcat
+	}
+	if join != JoinConcat && join != JoinMerge {
+		return nil, &InvalidArgumentError{
+			Err: fmt.Errorf("unsupported join %s", join),
+		}
+	}
+	return &Branches{name: name, join: join}, nil
+}
+
+// Add adds a branch
+func (b *Branches) Add(f Filterer) error {
+	if f == nil {
+		return &InvalidArgumentError{
+			Err: fmt.Errorf("filter cannot be nil"),
+		}
+	}
+	b.Lock()
+	defer b.Unlock()
+	b.branches = append(b.branches, f)
+	return nil
+}
+
+// Filterers returns the branches
+func (b *Branches) Filterers() []Filterer {
+	b.RLock()
+	defer b.RUnlock()
+	fs := []Filterer{}
+	fs = append(fs, b.branches...)
+	return fs
+}
+
+func (b *Branches) Filter(e event.Event) []event.Event {
+	b.RLock()
+	defer b.RUnlock()
+	if len(b.branches) == 0 {
+		return []event.Event{e}
+	}
+	// all clones must exist before the original event is acked
+	clones := make([]event.Event, len(b.branches))
+	for i := range b.branches {
+		clone, err := e.Clone(e.Context())
+		if err != nil {
+			e.Nack(err)
+			return nil
+		}
+		clones[i] = clone
+	}
+	var merged event.Event
+	if b.join == JoinMerge {
+		var err error
+		merged, err = e.Clone(e.Context())
+		if err != nil {
+			e.Nack(err)
+			return nil
+		}
+	}
+	e.Ack()
+	outputs := make([][]event.Event, len(b.branches))
+	var wg sync.WaitGroup
+	tracer := otel.Tracer(rtsemconv.EARSTracerName)
+	for i, branch := range b.branches {
+		wg.Add(1)
+		go func(i int, branch Filterer) {
+			defer wg.Done()
+			_, span := tracer.Start(clones[i].Context(), branch.Name())
+			outputs[i] = branch.Filter(clones[i])
+			span.End()
+		}(i, branch)
+	}
+	wg.Wait()
+	events := []event.Event{}
+	for _, evts := range outputs {
+		events = append(events, evts...)
+	}
+	if b.join == JoinConcat {
+		return events
+	}
+	if len(events) == 0 {
+		merged.Ack()
+		return events
+	}
+	payload := map[string]interface{}{}
+	metadata := map[string]interface{}{}
+	var err error
+	for _, evt := range events {
+		p, ok := evt.Payload().(map[string]interface{})
+		if !ok && err == nil {
+			err = errors.New("cannot merge non-object payload")
+		}
+		mergeMaps(payload, p)
+		mergeMaps(metadata, evt.Metadata())
+		evt.Ack()
+	}
+	if err != nil {
+		merged.Nack(err)
+		return []event.Event{}
+	}
+	merged.SetPayload(payload)
+	merged.SetMetadata(metadata)
+	return []event.Event{merged}
+}
+
+// mergeMaps deep merges src into dst, values of src win unless both values are objects
+func mergeMaps(dst map[string]interface{}, src map[string]interface{}) {
+	for k, v := range src {
+		sm, ok := v.(map[string]interface{})
+		if ok {
+			dm, ok := dst[k].(map[string]interface{})
+			if ok {
+				mergeMaps(dm, sm)
+				continue
+			}
+			dm = map[string]interface{}{}
+			mergeMaps(dm, sm)
+			dst[k] = dm
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+func (b *Branches) Config() interface{} {
+	return nil
+}
+
+func (b *Branches) Name() string {
+	if b.name == "" {
+		return "filter_branches"
+	}
+	return b.name
+}
+
+func (b *Branches) Plugin() string {
+	return "filter_branches"
+}
+
+func (b *Branches) Tenant() tenant.Id {
+	return tenant.Id{}
+}
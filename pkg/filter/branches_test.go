@@ -0,0 +1,136 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xmidt-org/ears/pkg/event"
+	"github.com/xmidt-org/ears/pkg/filter"
+
+	. "github.com/onsi/gomega"
+)
+
+func newSetFilterer(path string, value interface{}) filter.Filterer {
+	return &filter.FiltererMock{
+		FilterFunc: func(e event.Event) []event.Event {
+			e.SetPathValue(path, value, true)
+			return []event.Event{e}
+		},
+		NameFunc: func() string {
+			return "mockSet"
+		},
+	}
+}
+
+func newBranches(a *WithT, join string, branches ...[]filter.Filterer) *filter.Branches {
+	b, err := filter.NewBranches("branches", join)
+	a.Expect(err).To(BeNil())
+	for _, branch := range branches {
+		c := &filter.Chain{}
+		for _, f := range branch {
+			a.Expect(c.Add(f)).To(BeNil())
+		}
+		a.Expect(b.Add(c)).To(BeNil())
+	}
+	return b
+}
+
+// newTrackedEvent returns an event and a channel receiving nil on ack or the nack error
+func newTrackedEvent(a *WithT, payload interface{}) (event.Event, chan error) {
+	done := make(chan error, 1)
+	e, err := event.New(context.Background(), payload, event.WithAck(
+		func(e event.Event) {
+			done <- nil
+		},
+		func(e event.Event, err error) {
+			done <- err
+		}))
+	a.Expect(err).To(BeNil())
+	return e, done
+}
+
+func TestBranchesConcat(t *testing.T) {
+	a := NewWithT(t)
+	b := newBranches(a, filter.JoinConcat,
+		[]filter.Filterer{newSetFilterer(".branch", "a")},
+		[]filter.Filterer{newSetFilterer(".branch", "b"), newDoubleFilterer()},
+		[]filter.Filterer{newBlockFilterer()},
+	)
+	e, done := newTrackedEvent(a, map[string]interface{}{"foo": "bar"})
+	evts := b.Filter(e)
+	a.Expect(evts).To(HaveLen(3))
+	branches := []interface{}{}
+	for _, evt := range evts {
+		v, _, _ := evt.GetPathValue(".branch")
+		branches = append(branches, v)
+	}
+	a.Expect(branches).To(ConsistOf("a", "b", "b"))
+	// the original event is only acked once all branch outputs are acked
+	for _, evt := range evts {
+		a.Consistently(done, 50*time.Millisecond).ShouldNot(Receive())
+		evt.Ack()
+	}
+	a.Eventually(done).Should(Receive(BeNil()))
+}
+
+func TestBranchesMerge(t *testing.T) {
+	a := NewWithT(t)
+	b := newBranches(a, filter.JoinMerge,
+		[]filter.Filterer{newSetFilterer(".geo.country", "us")},
+		[]filter.Filterer{newSetFilterer(".geo.city", "denver"), newSetFilterer(".score", 7)},
+	)
+	e, done := newTrackedEvent(a, map[string]interface{}{"foo": "bar"})
+	evts := b.Filter(e)
+	a.Expect(evts).To(HaveLen(1))
+	a.Expect(evts[0].Payload()).To(Equal(map[string]interface{}{
+		"foo":   "bar",
+		"geo":   map[string]interface{}{"country": "us", "city": "denver"},
+		"score": 7,
+	}))
+	a.Consistently(done, 50*time.Millisecond).ShouldNot(Receive())
+	evts[0].Ack()
+	a.Eventually(done).Should(Receive(BeNil()))
+}
+
+func TestBranchesMergeNonObject(t *testing.T) {
+	a := NewWithT(t)
+	b := newBranches(a, filter.JoinMerge,
+		[]filter.Filterer{newPassFilterer()},
+		[]filter.Filterer{newPassFilterer()},
+	)
+	e, done := newTrackedEvent(a, "text")
+	a.Expect(b.Filter(e)).To(HaveLen(0))
+	a.Eventually(done).Should(Receive(HaveOccurred()))
+}
+
+func TestBranchesInChain(t *testing.T) {
+	a := NewWithT(t)
+	b := newBranches(a, filter.JoinConcat,
+		[]filter.Filterer{newPassFilterer()},
+		[]filter.Filterer{newDoubleFilterer()},
+	)
+	var c filter.Chain
+	a.Expect(c.Add(newDoubleFilterer())).To(BeNil())
+	a.Expect(c.Add(b)).To(BeNil())
+	e, err := event.New(context.Background(), "payload", event.FailOnNack(t))
+	a.Expect(err).To(BeNil())
+	a.Expect(c.Filter(e)).To(HaveLen(6))
+	a.Expect(b.Filterers()).To(HaveLen(2))
+	_, err = filter.NewBranches("branches", "zip")
+	a.Expect(err).ToNot(BeNil())
+}
@@ -107,3 +107,17 @@ func (c *Chain) Plugin() string {
 func (c *Chain) Tenant() tenant.Id {
 	return tenant.Id{}
 }
+
+// SenderNames returns the sender names targeted by a filter, walking into chains and branches
+func SenderNames(f Filterer) []string {
+	var names []string
+	if c, ok := f.(Chainer); ok {
+		for _, sub := range c.Filterers() {
+			names = append(names, SenderNames(sub)...)
+		}
+	}
+	if st, ok := f.(SenderTargeter); ok {
+		names = append(names, st.SenderNames()...)
+	}
+	return names
+}
@@ -0,0 +1,329 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package codec provides the composable byte encodings shared by the
+// encode and decode filters. An encoding is a '+' delimited list of
+// stages such as "gzip+base64url" which is applied left to right when
+// encoding and right to left when decoding.
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	None      = "none"
+	Base64    = "base64"
+	Base64Url = "base64url"
+	Hex       = "hex"
+	Url       = "url"
+	Gzip      = "gzip"
+	Zstd      = "zstd"
+)
+
+// MaxDecodedSize caps the output of a decompressing stage so that a small
+// compressed value cannot expand without bound
+const MaxDecodedSize = 16 << 20
+
+// Codec is a single reversible encoding stage
+type Codec interface {
+	Name() string
+	Encode(in []byte) ([]byte, error)
+	Decode(in []byte) ([]byte, error)
+	// Binary reports whether the encoded output may contain arbitrary,
+	// non printable bytes given whether the input may
+	Binary(input bool) bool
+}
+
+// Pipeline is an ordered list of codecs
+type Pipeline []Codec
+
+// Parse builds a pipeline from an encoding such as "gzip+base64url"
+func Parse(encoding string) (Pipeline, error) {
+	if strings.TrimSpace(encoding) == "" {
+		return nil, &UnsupportedEncodingError{Encoding: encoding}
+	}
+	var p Pipeline
+	for _, name := range strings.Split(encoding, "+") {
+		c, err := newCodec(strings.ToLower(strings.TrimSpace(name)))
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, c)
+	}
+	return p, nil
+}
+
+func newCodec(name string) (Codec, error) {
+	switch name {
+	case None:
+		return noneCodec{}, nil
+	case Base64:
+		return &base64Codec{name: name, enc: base64.StdEncoding, raw: base64.RawStdEncoding}, nil
+	case Base64Url:
+		return &base64Codec{name: name, enc: base64.URLEncoding, raw: base64.RawURLEncoding}, nil
+	case Hex:
+		return hexCodec{}, nil
+	case Url:
+		return urlCodec{}, nil
+	case Gzip:
+		return gzipCodec{}, nil
+	case Zstd:
+		return sharedZstd()
+	}
+	return nil, &UnsupportedEncodingError{Encoding: name}
+}
+
+// Encode applies all stages left to right
+func (p Pipeline) Encode(in []byte) ([]byte, error) {
+	var err error
+	for _, c := range p {
+		in, err = c.Encode(in)
+		if err != nil {
+			return nil, &CodecError{Codec: c.Name(), Err: err}
+		}
+	}
+	return in, nil
+}
+
+// Decode applies all stages right to left
+func (p Pipeline) Decode(in []byte) ([]byte, error) {
+	var err error
+	for i := len(p) - 1; i >= 0; i-- {
+		in, err = p[i].Decode(in)
+		if err != nil {
+			return nil, &CodecError{Codec: p[i].Name(), Err: err}
+		}
+	}
+	return in, nil
+}
+
+// Binary reports whether the encoded output of the pipeline may contain
+// arbitrary bytes and therefore cannot be carried as a string
+func (p Pipeline) Binary(input bool) bool {
+	for _, c := range p {
+		input = c.Binary(input)
+	}
+	return input
+}
+
+func (p Pipeline) String() string {
+	names := make([]string, len(p))
+	for i, c := range p {
+		names[i] = c.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+// noneCodec passes its input through unchanged
+type noneCodec struct{}
+
+func (noneCodec) Name() string {
+	return None
+}
+
+func (noneCodec) Encode(in []byte) ([]byte, error) {
+	return in, nil
+}
+
+func (noneCodec) Decode(in []byte) ([]byte, error) {
+	return in, nil
+}
+
+func (noneCodec) Binary(input bool) bool {
+	return input
+}
+
+type base64Codec struct {
+	name string
+	enc  *base64.Encoding
+	raw  *base64.Encoding
+}
+
+func (c *base64Codec) Name() string {
+	return c.name
+}
+
+func (c *base64Codec) Encode(in []byte) ([]byte, error) {
+	out := make([]byte, c.enc.EncodedLen(len(in)))
+	c.enc.Encode(out, in)
+	return out, nil
+}
+
+// Decode accepts both padded and unpadded input
+func (c *base64Codec) Decode(in []byte) ([]byte, error) {
+	in = bytes.TrimRight(bytes.TrimSpace(in), "=")
+	out := make([]byte, c.raw.DecodedLen(len(in)))
+	n, err := c.raw.Decode(out, in)
+	if err != nil {
+		return nil, err
+	}
+	return out[:n], nil
+}
+
+func (c *base64Codec) Binary(input bool) bool {
+	return false
+}
+
+type hexCodec struct{}
+
+func (hexCodec) Name() string {
+	return Hex
+}
+
+func (hexCodec) Encode(in []byte) ([]byte, error) {
+	out := make([]byte, hex.EncodedLen(len(in)))
+	hex.Encode(out, in)
+	return out, nil
+}
+
+func (hexCodec) Decode(in []byte) ([]byte, error) {
+	in = bytes.TrimSpace(in)
+	out := make([]byte, hex.DecodedLen(len(in)))
+	n, err := hex.Decode(out, in)
+	if err != nil {
+		return nil, err
+	}
+	return out[:n], nil
+}
+
+func (hexCodec) Binary(input bool) bool {
+	return false
+}
+
+type urlCodec struct{}
+
+func (urlCodec) Name() string {
+	return Url
+}
+
+func (urlCodec) Encode(in []byte) ([]byte, error) {
+	return []byte(url.QueryEscape(string(in))), nil
+}
+
+func (urlCodec) Decode(in []byte) ([]byte, error) {
+	out, err := url.QueryUnescape(string(in))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+func (urlCodec) Binary(input bool) bool {
+	return false
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string {
+	return Gzip
+}
+
+func (gzipCodec) Encode(in []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(in)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decode(in []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(in))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	out, err := ioutil.ReadAll(io.LimitReader(r, MaxDecodedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > MaxDecodedSize {
+		return nil, &SizeExceededError{Limit: MaxDecodedSize}
+	}
+	return out, nil
+}
+
+func (gzipCodec) Binary(input bool) bool {
+	return true
+}
+
+// zstdCodec holds a single encoder and decoder; both are safe for
+// concurrent use through EncodeAll and DecodeAll
+type zstdCodec struct {
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+var (
+	zstdOnce   sync.Once
+	zstdShared *zstdCodec
+	zstdErr    error
+)
+
+// sharedZstd returns the zstd codec shared by all pipelines. The encoder
+// and decoder run background goroutines and are never closed, so they are
+// built once rather than per pipeline
+func sharedZstd() (*zstdCodec, error) {
+	zstdOnce.Do(func() {
+		enc, err := zstd.NewWriter(nil)
+		if err != nil {
+			zstdErr = err
+			return
+		}
+		dec, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(MaxDecodedSize), zstd.WithDecoderMaxWindow(MaxDecodedSize))
+		if err != nil {
+			zstdErr = err
+			return
+		}
+		zstdShared = &zstdCodec{enc: enc, dec: dec}
+	})
+	return zstdShared, zstdErr
+}
+
+func (c *zstdCodec) Name() string {
+	return Zstd
+}
+
+func (c *zstdCodec) Encode(in []byte) ([]byte, error) {
+	return c.enc.EncodeAll(in, nil), nil
+}
+
+func (c *zstdCodec) Decode(in []byte) ([]byte, error) {
+	out, err := c.dec.DecodeAll(in, nil)
+	if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+		return nil, &SizeExceededError{Limit: MaxDecodedSize}
+	}
+	return out, err
+}
+
+func (c *zstdCodec) Binary(input bool) bool {
+	return true
+}
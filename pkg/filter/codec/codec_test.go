@@ -0,0 +1,177 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec_test
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/xmidt-org/ears/pkg/filter/codec"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRoundTrip(t *testing.T) {
+	inputs := [][]byte{
+		[]byte(""),
+		[]byte("hello world"),
+		[]byte(`{"a":"b & c","d":[1,2,3]}`),
+		{0x00, 0xff, 0x10, 0x80, 0x7f},
+	}
+	encodings := []string{
+		"none",
+		"base64",
+		"base64url",
+		"hex",
+		"url",
+		"gzip",
+		"zstd",
+		"gzip+base64url",
+		"zstd+hex",
+		"gzip+base64+url",
+		"base64url+gzip+base64",
+	}
+	for _, encoding := range encodings {
+		t.Run(encoding, func(t *testing.T) {
+			a := NewWithT(t)
+			p, err := codec.Parse(encoding)
+			a.Expect(err).To(BeNil())
+			a.Expect(p.String()).To(Equal(encoding))
+			for _, in := range inputs {
+				enc, err := p.Encode(in)
+				a.Expect(err).To(BeNil())
+				dec, err := p.Decode(enc)
+				a.Expect(err).To(BeNil())
+				a.Expect(string(dec)).To(Equal(string(in)))
+			}
+		})
+	}
+}
+
+func TestKnownValues(t *testing.T) {
+	testCases := []struct {
+		encoding string
+		decoded  string
+		encoded  string
+	}{
+		{"base64", "hello world", "aGVsbG8gd29ybGQ="},
+		{"base64url", "??>>", "Pz8-Pg=="},
+		{"hex", "hello world", "68656c6c6f20776f726c64"},
+		{"url", "a b&c=d", "a+b%26c%3Dd"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.encoding, func(t *testing.T) {
+			a := NewWithT(t)
+			p, err := codec.Parse(tc.encoding)
+			a.Expect(err).To(BeNil())
+			enc, err := p.Encode([]byte(tc.decoded))
+			a.Expect(err).To(BeNil())
+			a.Expect(string(enc)).To(Equal(tc.encoded))
+			dec, err := p.Decode([]byte(tc.encoded))
+			a.Expect(err).To(BeNil())
+			a.Expect(string(dec)).To(Equal(tc.decoded))
+		})
+	}
+}
+
+func TestUnpaddedBase64(t *testing.T) {
+	a := NewWithT(t)
+	p, err := codec.Parse("base64url")
+	a.Expect(err).To(BeNil())
+	dec, err := p.Decode([]byte("Pz8-Pg"))
+	a.Expect(err).To(BeNil())
+	a.Expect(string(dec)).To(Equal("??>>"))
+}
+
+func TestBinary(t *testing.T) {
+	testCases := []struct {
+		encoding string
+		input    bool
+		binary   bool
+	}{
+		{"gzip", false, true},
+		{"zstd", false, true},
+		{"gzip+base64url", false, false},
+		{"hex+gzip", false, true},
+		{"base64", true, false},
+		{"none", false, false},
+		{"none", true, true},
+		{"gzip+none", false, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.encoding, func(t *testing.T) {
+			a := NewWithT(t)
+			p, err := codec.Parse(tc.encoding)
+			a.Expect(err).To(BeNil())
+			a.Expect(p.Binary(tc.input)).To(Equal(tc.binary))
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	a := NewWithT(t)
+	for _, encoding := range []string{"", "base32", "gzip+", "gzip+rot13"} {
+		_, err := codec.Parse(encoding)
+		var uerr *codec.UnsupportedEncodingError
+		a.Expect(errors.As(err, &uerr)).To(BeTrue(), encoding)
+	}
+	p, err := codec.Parse("gzip+base64")
+	a.Expect(err).To(BeNil())
+	_, err = p.Decode([]byte("not base64!"))
+	var cerr *codec.CodecError
+	a.Expect(errors.As(err, &cerr)).To(BeTrue())
+	a.Expect(cerr.Codec).To(Equal("base64"))
+}
+
+func TestZstdShared(t *testing.T) {
+	a := NewWithT(t)
+	first, err := codec.Parse("zstd")
+	a.Expect(err).To(BeNil())
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		p, err := codec.Parse("zstd+base64")
+		a.Expect(err).To(BeNil())
+		a.Expect(p[0] == first[0]).To(BeTrue(), "pipelines share the zstd codec")
+	}
+	a.Expect(runtime.NumGoroutine()).To(BeNumerically("<=", before))
+}
+
+func TestDecompressionLimit(t *testing.T) {
+	for _, encoding := range []string{"gzip", "zstd"} {
+		t.Run(encoding, func(t *testing.T) {
+			a := NewWithT(t)
+			p, err := codec.Parse(encoding)
+			a.Expect(err).To(BeNil())
+			in := make([]byte, codec.MaxDecodedSize)
+			enc, err := p.Encode(in)
+			a.Expect(err).To(BeNil())
+			dec, err := p.Decode(enc)
+			a.Expect(err).To(BeNil())
+			a.Expect(bytes.Equal(dec, in)).To(BeTrue())
+			// a compression bomb, a small value which expands past the limit
+			bomb, err := p.Encode(make([]byte, 4*codec.MaxDecodedSize))
+			a.Expect(err).To(BeNil())
+			a.Expect(len(bomb)).To(BeNumerically("<", codec.MaxDecodedSize/100))
+			_, err = p.Decode(bomb)
+			var cerr *codec.CodecError
+			a.Expect(errors.As(err, &cerr)).To(BeTrue())
+			a.Expect(cerr.Codec).To(Equal(encoding))
+			var serr *codec.SizeExceededError
+			a.Expect(errors.As(err, &serr)).To(BeTrue())
+		})
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import "github.com/xmidt-org/ears/pkg/errs"
+
+// UnsupportedEncodingError is returned when an encoding stage is unknown
+type UnsupportedEncodingError struct {
+	Encoding string
+}
+
+func (e *UnsupportedEncodingError) Error() string {
+	return errs.String("UnsupportedEncodingError", map[string]interface{}{"encoding": e.Encoding}, nil)
+}
+
+// SizeExceededError is returned when a decompressing stage would exceed the decoded size limit
+type SizeExceededError struct {
+	Limit int
+}
+
+func (e *SizeExceededError) Error() string {
+	return errs.String("SizeExceededError", map[string]interface{}{"limit": e.Limit}, nil)
+}
+
+// CodecError is returned when a stage fails to encode or decode its input
+type CodecError struct {
+	Codec string
+	Err   error
+}
+
+func (e *CodecError) Error() string {
+	return errs.String("CodecError", map[string]interface{}{"codec": e.Codec}, e.Err)
+}
+
+func (e *CodecError) Unwrap() error {
+	return e.Err
+}
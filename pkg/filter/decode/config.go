@@ -15,11 +15,12 @@
 package decode
 
 import (
-	"errors"
 	"github.com/xmidt-org/ears/pkg/config"
 	pkgconfig "github.com/xmidt-org/ears/pkg/config"
 	"github.com/xmidt-org/ears/pkg/errs"
 	"github.com/xmidt-org/ears/pkg/filter"
+	"github.com/xmidt-org/ears/pkg/filter/codec"
+	"github.com/xmidt-org/ears/pkg/filter/format"
 )
 
 func NewConfig(config interface{}) (*Config, error) {
@@ -44,12 +45,24 @@ func (c Config) WithDefaults() *Config {
 	if c.Encoding == "" {
 		cfg.Encoding = DefaultConfig.Encoding
 	}
+	if c.Format == "" {
+		cfg.Format = DefaultConfig.Format
+	}
 	return &cfg
 }
 
 func (c *Config) Validate() error {
-	if c.Encoding != "base64" {
-		return errors.New("unsupported encoding " + c.Encoding)
+	_, err := codec.Parse(c.Encoding)
+	if err != nil {
+		return &filter.InvalidConfigError{
+			Err: err,
+		}
+	}
+	err = format.Validate(c.Format, c.Schema)
+	if err != nil {
+		return &filter.InvalidConfigError{
+			Err: err,
+		}
 	}
 	return nil
 }
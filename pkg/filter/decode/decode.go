@@ -15,13 +15,13 @@
 package decode
 
 import (
-	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"github.com/xmidt-org/ears/pkg/event"
 	"github.com/xmidt-org/ears/pkg/filter"
+	"github.com/xmidt-org/ears/pkg/filter/codec"
+	"github.com/xmidt-org/ears/pkg/filter/format"
 	"github.com/xmidt-org/ears/pkg/secret"
 	"github.com/xmidt-org/ears/pkg/tenant"
 )
@@ -38,11 +38,25 @@ func NewFilter(tid tenant.Id, plugin string, name string, config interface{}, se
 	if err != nil {
 		return nil, err
 	}
+	codecs, err := codec.Parse(cfg.Encoding)
+	if err != nil {
+		return nil, &filter.InvalidConfigError{
+			Err: err,
+		}
+	}
+	fmtr, err := format.New(cfg.Format, cfg.Schema)
+	if err != nil {
+		return nil, &filter.InvalidConfigError{
+			Err: err,
+		}
+	}
 	f := &Filter{
 		config: *cfg,
 		name:   name,
 		plugin: plugin,
 		tid:    tid,
+		codecs: codecs,
+		format: fmtr,
 	}
 	return f, nil
 }
@@ -59,23 +73,22 @@ func (f *Filter) Filter(evt event.Event) []event.Event {
 		evt.Nack(errors.New("nil object at path " + f.config.FromPath))
 		return []event.Event{}
 	}
-	var input string
+	var input []byte
 	switch obj := obj.(type) {
 	case string:
-		input = obj
+		input = []byte(obj)
 	case []byte:
-		input = string(obj)
+		input = obj
 	default:
 		evt.Nack(errors.New("unsupported field type at path " + f.config.FromPath))
 		return []event.Event{}
 	}
-	buf, err := base64.StdEncoding.DecodeString(input)
+	buf, err := f.codecs.Decode(input)
 	if err != nil {
 		evt.Nack(err)
 		return []event.Event{}
 	}
-	var output interface{}
-	err = json.Unmarshal(buf, &output)
+	output, err := f.format.Unmarshal(buf)
 	if err != nil {
 		evt.Nack(err)
 		return []event.Event{}
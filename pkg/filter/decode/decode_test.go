@@ -0,0 +1,183 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decode_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/xmidt-org/ears/pkg/event"
+	"github.com/xmidt-org/ears/pkg/filter"
+	"github.com/xmidt-org/ears/pkg/filter/decode"
+	"github.com/xmidt-org/ears/pkg/filter/encode"
+	"github.com/xmidt-org/ears/pkg/filter/format"
+	"github.com/xmidt-org/ears/pkg/tenant"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	testCases := []struct {
+		encoding string
+		format   string
+		value    interface{}
+	}{
+		{"", "", map[string]interface{}{"temp": 21.5}},
+		{"base64url", "json", []interface{}{"a", "b", 1.0}},
+		{"gzip+base64url", "json", map[string]interface{}{"device": "d1"}},
+		{"zstd+base64", "json", "quoted string"},
+		{"hex", "text", "hello world"},
+		{"url", "text", "a b&c=d"},
+		{"gzip", "text", "binary output"},
+		{"gzip+base64url", "text", "not json at all"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.encoding+"/"+tc.format, func(t *testing.T) {
+			a := NewWithT(t)
+			enc, err := encode.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "encode", "myencode", encode.Config{
+				FromPath: ".body",
+				ToPath:   ".encoded",
+				Encoding: tc.encoding,
+				Format:   tc.format,
+			}, nil)
+			a.Expect(err).To(BeNil())
+			dec, err := decode.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "decode", "mydecode", decode.Config{
+				FromPath: ".encoded",
+				ToPath:   ".decoded",
+				Encoding: tc.encoding,
+				Format:   tc.format,
+			}, nil)
+			a.Expect(err).To(BeNil())
+			e, err := event.New(ctx, map[string]interface{}{"body": tc.value}, event.FailOnNack(t))
+			a.Expect(err).To(BeNil())
+			evts := enc.Filter(e)
+			a.Expect(evts).To(HaveLen(1))
+			evts = dec.Filter(evts[0])
+			a.Expect(evts).To(HaveLen(1))
+			decoded, _, _ := evts[0].GetPathValue(".decoded")
+			a.Expect(decoded).To(Equal(tc.value))
+			evts[0].Ack()
+		})
+	}
+}
+
+func TestSchemaFormats(t *testing.T) {
+	ctx := context.Background()
+	avroSchema := `{"type": "record", "name": "Reading", "fields": [
+		{"name": "device", "type": "string"},
+		{"name": "value", "type": ["null", "double"]}
+	]}`
+	protoSchema := `syntax = "proto3";
+		message Reading {
+			string device = 1;
+			double value = 2;
+		}`
+	testCases := []struct {
+		encoding string
+		format   string
+		schema   *format.Schema
+		binary   bool
+	}{
+		{"base64", "avro", &format.Schema{Inline: avroSchema}, false},
+		{"none", "avro", &format.Schema{Inline: avroSchema}, true},
+		{"hex", "protobuf", &format.Schema{Inline: protoSchema}, false},
+		{"none", "msgpack", nil, true},
+		{"zstd+base64url", "msgpack", nil, false},
+	}
+	value := map[string]interface{}{"device": "d1", "value": 21.5}
+	for _, tc := range testCases {
+		t.Run(tc.format+"/"+tc.encoding, func(t *testing.T) {
+			a := NewWithT(t)
+			enc, err := encode.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "encode", "myencode", encode.Config{
+				FromPath: ".body",
+				Encoding: tc.encoding,
+				Format:   tc.format,
+				Schema:   tc.schema,
+			}, nil)
+			a.Expect(err).To(BeNil())
+			dec, err := decode.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "decode", "mydecode", decode.Config{
+				FromPath: ".body",
+				Encoding: tc.encoding,
+				Format:   tc.format,
+				Schema:   tc.schema,
+			}, nil)
+			a.Expect(err).To(BeNil())
+			e, err := event.New(ctx, map[string]interface{}{"body": value}, event.FailOnNack(t))
+			a.Expect(err).To(BeNil())
+			evts := enc.Filter(e)
+			a.Expect(evts).To(HaveLen(1))
+			encoded, _, _ := evts[0].GetPathValue(".body")
+			_, binary := encoded.([]byte)
+			a.Expect(binary).To(Equal(tc.binary), fmt.Sprintf("encoded type %T", encoded))
+			evts = dec.Filter(evts[0])
+			a.Expect(evts).To(HaveLen(1))
+			decoded, _, _ := evts[0].GetPathValue(".body")
+			a.Expect(decoded).To(Equal(value))
+			evts[0].Ack()
+		})
+	}
+}
+
+func TestDecodeTelemetry(t *testing.T) {
+	a := NewWithT(t)
+	// gzip compressed and unpadded base64url encoded json
+	dec, err := decode.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "decode", "mydecode", decode.Config{
+		FromPath: ".data",
+		Encoding: "gzip+base64url",
+	}, nil)
+	a.Expect(err).To(BeNil())
+	e, err := event.New(context.Background(), map[string]interface{}{"data": "H4sIAAAAAAACA6tWSkkty0xOVbJSSjFU0lEqSc0tULIyMtQzrQUAyDpDYhsAAAA"}, event.FailOnNack(t))
+	a.Expect(err).To(BeNil())
+	evts := dec.Filter(e)
+	a.Expect(evts).To(HaveLen(1))
+	data, _, _ := evts[0].GetPathValue(".data")
+	a.Expect(data).To(Equal(map[string]interface{}{"device": "d1", "temp": 21.5}))
+	evts[0].Ack()
+}
+
+func TestDecodeErrors(t *testing.T) {
+	a := NewWithT(t)
+	for _, config := range []decode.Config{
+		{Encoding: "base32"},
+		{Encoding: "gzip+"},
+		{Format: "xml"},
+		{Format: "avro"},
+		{Format: "protobuf", Schema: &format.Schema{Inline: "not a proto"}},
+	} {
+		var cerr *filter.InvalidConfigError
+		_, err := decode.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "decode", "mydecode", config, nil)
+		a.Expect(errors.As(err, &cerr)).To(BeTrue(), fmt.Sprintf("decode config %v", config))
+		_, err = encode.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "encode", "myencode", encode.Config(config), nil)
+		a.Expect(errors.As(err, &cerr)).To(BeTrue(), fmt.Sprintf("encode config %v", config))
+	}
+	dec, err := decode.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "decode", "mydecode", decode.Config{
+		FromPath: ".data",
+		Encoding: "hex",
+	}, nil)
+	a.Expect(err).To(BeNil())
+	nacked := make(chan error, 1)
+	e, err := event.New(context.Background(), map[string]interface{}{"data": "zz"}, event.WithAck(
+		func(e event.Event) {},
+		func(e event.Event, err error) {
+			nacked <- err
+		}))
+	a.Expect(err).To(BeNil())
+	a.Expect(dec.Filter(e)).To(HaveLen(0))
+	a.Eventually(nacked, time.Second).Should(Receive())
+}
@@ -41,6 +41,15 @@ func (c Config) WithDefaults() *Config {
 	if c.Path == "" {
 		cfg.Path = DefaultConfig.Path
 	}
+	if c.Backend == "" {
+		cfg.Backend = DefaultConfig.Backend
+	}
+	if c.Window == nil {
+		cfg.Window = DefaultConfig.Window
+		if cfg.Backend == BackendRedis {
+			cfg.Window = &DefaultRedisWindow
+		}
+	}
 	return &cfg
 }
 
@@ -48,6 +57,21 @@ func (c *Config) Validate() error {
 	if c.CacheSize == nil || *c.CacheSize < 0 || *c.CacheSize > 10000 {
 		return errors.New("cache size must be between 0 and 10000")
 	}
+	if c.Window == nil || *c.Window < 0 {
+		return errors.New("window must not be negative")
+	}
+	switch c.Backend {
+	case BackendMemory:
+	case BackendRedis:
+		if *c.Window == 0 {
+			return errors.New("redis backend requires a window")
+		}
+		if c.RedisEndpoint == "" {
+			return errors.New("redis backend requires a redis endpoint")
+		}
+	default:
+		return errors.New("unsupported backend " + c.Backend)
+	}
 	return nil
 }
 
@@ -19,12 +19,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	lru "github.com/hashicorp/golang-lru"
+	"time"
+
 	"github.com/rs/zerolog/log"
+	"github.com/xmidt-org/ears/internal/pkg/rtsemconv"
 	"github.com/xmidt-org/ears/pkg/event"
 	"github.com/xmidt-org/ears/pkg/filter"
 	"github.com/xmidt-org/ears/pkg/secret"
 	"github.com/xmidt-org/ears/pkg/tenant"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
 )
 
 func NewFilter(tid tenant.Id, plugin string, name string, config interface{}, secrets secret.Vault) (*Filter, error) {
@@ -45,10 +50,34 @@ func NewFilter(tid tenant.Id, plugin string, name string, config interface{}, se
 		plugin: plugin,
 		tid:    tid,
 	}
-	f.lruCache, err = lru.New(*cfg.CacheSize)
-	if err != nil {
-		return nil, err
+	window := time.Duration(*cfg.Window) * time.Millisecond
+	switch cfg.Backend {
+	case BackendRedis:
+		f.store = newRedisStore(cfg.RedisEndpoint, window)
+	default:
+		f.store, err = newMemoryStore(*cfg.CacheSize, window)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// metric recorders
+	meter := global.Meter(rtsemconv.EARSMeterName)
+	f.labels = []attribute.KeyValue{
+		attribute.String(rtsemconv.EARSPluginTypeLabel, rtsemconv.EARSPluginTypeDedupFilter),
+		attribute.String(rtsemconv.EARSPluginNameLabel, f.Name()),
+		attribute.String(rtsemconv.EARSAppIdLabel, f.tid.AppId),
+		attribute.String(rtsemconv.EARSOrgIdLabel, f.tid.OrgId),
 	}
+	f.hitCounter = metric.Must(meter).
+		NewInt64Counter(
+			rtsemconv.EARSMetricDedupHit,
+			metric.WithDescription("measures the number of duplicate events filtered"),
+		)
+	f.missCounter = metric.Must(meter).
+		NewInt64Counter(
+			rtsemconv.EARSMetricDedupMiss,
+			metric.WithDescription("measures the number of unique events passed"),
+		)
 	return f, nil
 }
 
@@ -70,18 +99,32 @@ func (f *Filter) Filter(evt event.Event) []event.Event {
 		return []event.Event{}
 	}
 	evtHash := fmt.Sprintf("%x", md5.Sum(buf))
-	_, ok := f.lruCache.Get(evtHash)
-	if !ok {
-		f.lruCache.Add(evtHash, struct{}{})
+	// keys are scoped to the tenant and filter so that routes sharing a redis do not dedup each other
+	seen, err := f.store.seen(evt.Context(), f.tid.Key()+"_dedup_"+f.name+"_"+evtHash)
+	if err != nil {
+		log.Ctx(evt.Context()).Error().Str("op", "filter").Str("filterType", "dedup").Str("name", f.Name()).Msg("dedup store error: " + err.Error())
+		evt.Nack(err)
+		return []event.Event{}
+	}
+	if !seen {
+		f.missCounter.Add(evt.Context(), 1, f.metricLabels(evt)...)
 		log.Ctx(evt.Context()).Debug().Str("op", "filter").Str("filterType", "dedup").Str("name", f.Name()).Int("eventCount", 1).Msg("dedup")
 		return []event.Event{evt}
 	} else {
+		f.hitCounter.Add(evt.Context(), 1, f.metricLabels(evt)...)
 		evt.Ack()
 		log.Ctx(evt.Context()).Debug().Str("op", "filter").Str("filterType", "dedup").Str("name", f.Name()).Int("eventCount", 0).Msg("dedup")
 		return []event.Event{}
 	}
 }
 
+// metricLabels adds the id of the route processing the event to the labels of the filter
+func (f *Filter) metricLabels(evt event.Event) []attribute.KeyValue {
+	labels := make([]attribute.KeyValue, 0, len(f.labels)+1)
+	labels = append(labels, f.labels...)
+	return append(labels, rtsemconv.EARSRouteId.String(event.RouteId(evt.Context())))
+}
+
 func (f *Filter) Config() interface{} {
 	if f == nil {
 		return Config{}
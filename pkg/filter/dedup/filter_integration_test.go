@@ -0,0 +1,59 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build integration
+
+package dedup_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xmidt-org/ears/pkg/event"
+	"github.com/xmidt-org/ears/pkg/filter/dedup"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"github.com/xorcare/pointer"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDedupRedis(t *testing.T) {
+	ctx := context.Background()
+	a := NewWithT(t)
+	config := dedup.Config{
+		Backend:       dedup.BackendRedis,
+		Window:        pointer.Int(1000),
+		RedisEndpoint: "127.0.0.1:6379",
+	}
+	// two filters with the same name behave like the same route on two ears instances
+	f1, err := dedup.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "dedup", "redisdedup", config, nil)
+	a.Expect(err).To(BeNil())
+	f2, err := dedup.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "dedup", "redisdedup", config, nil)
+	a.Expect(err).To(BeNil())
+	payload := map[string]interface{}{"id": time.Now().String()}
+
+	e, err := event.New(ctx, payload, event.FailOnNack(t))
+	a.Expect(err).To(BeNil())
+	a.Expect(f1.Filter(e)).To(HaveLen(1), "first event passes")
+
+	e, err = event.New(ctx, payload, event.FailOnNack(t))
+	a.Expect(err).To(BeNil())
+	a.Expect(f2.Filter(e)).To(HaveLen(0), "duplicate on other instance is filtered")
+
+	time.Sleep(1100 * time.Millisecond)
+	e, err = event.New(ctx, payload, event.FailOnNack(t))
+	a.Expect(err).To(BeNil())
+	a.Expect(f2.Filter(e)).To(HaveLen(1), "event passes after window elapsed")
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedup_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/xmidt-org/ears/pkg/event"
+	"github.com/xmidt-org/ears/pkg/filter/dedup"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"github.com/xorcare/pointer"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDedupMemory(t *testing.T) {
+	a := NewWithT(t)
+	f, err := dedup.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "dedup", "mydedup", dedup.Config{CacheSize: pointer.Int(2), Path: ".id"}, nil)
+	a.Expect(err).To(BeNil())
+	steps := []struct {
+		id   string
+		pass bool
+	}{
+		{id: "a", pass: true},
+		{id: "a", pass: false},
+		{id: "b", pass: true},
+		{id: "c", pass: true},
+		// a was evicted by c
+		{id: "a", pass: true},
+		{id: "c", pass: false},
+	}
+	for i, s := range steps {
+		e, err := event.New(context.Background(), map[string]interface{}{"id": s.id, "seq": i}, event.FailOnNack(t))
+		a.Expect(err).To(BeNil())
+		a.Expect(len(f.Filter(e)) == 1).To(Equal(s.pass), fmt.Sprintf("step %d id %s", i, s.id))
+	}
+}
+
+func TestDedupMemoryWindow(t *testing.T) {
+	ctx := context.Background()
+	a := NewWithT(t)
+	f, err := dedup.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "dedup", "mydedup", dedup.Config{Window: pointer.Int(50)}, nil)
+	a.Expect(err).To(BeNil())
+	payload := map[string]interface{}{"id": "a"}
+
+	e, err := event.New(ctx, payload, event.FailOnNack(t))
+	a.Expect(err).To(BeNil())
+	a.Expect(f.Filter(e)).To(HaveLen(1), "first event passes")
+
+	e, err = event.New(ctx, payload, event.FailOnNack(t))
+	a.Expect(err).To(BeNil())
+	a.Expect(f.Filter(e)).To(HaveLen(0), "duplicate within window is filtered")
+
+	time.Sleep(60 * time.Millisecond)
+	e, err = event.New(ctx, payload, event.FailOnNack(t))
+	a.Expect(err).To(BeNil())
+	a.Expect(f.Filter(e)).To(HaveLen(1), "event passes after window elapsed")
+}
+
+func TestDedupConfig(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config dedup.Config
+		valid  bool
+	}{
+		{name: "default", config: dedup.Config{}, valid: true},
+		{name: "redis", config: dedup.Config{Backend: dedup.BackendRedis, RedisEndpoint: "localhost:6379"}, valid: true},
+		{name: "redisNoEndpoint", config: dedup.Config{Backend: dedup.BackendRedis}, valid: false},
+		{name: "redisNoWindow", config: dedup.Config{Backend: dedup.BackendRedis, RedisEndpoint: "localhost:6379", Window: pointer.Int(0)}, valid: false},
+		{name: "negativeWindow", config: dedup.Config{Window: pointer.Int(-1)}, valid: false},
+		{name: "unknownBackend", config: dedup.Config{Backend: "memcached"}, valid: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewWithT(t)
+			f, err := dedup.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "dedup", "mydedup", tc.config, nil)
+			if tc.valid {
+				a.Expect(err).To(BeNil())
+				a.Expect(f).ToNot(BeNil())
+			} else {
+				a.Expect(err).ToNot(BeNil())
+				a.Expect(f).To(BeNil())
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedup
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/xmidt-org/ears/pkg/filter/redisclient"
+)
+
+// store records keys and reports whether a key was already seen within the window
+type store interface {
+	seen(ctx context.Context, key string) (bool, error)
+}
+
+type memoryStore struct {
+	sync.Mutex
+	cache  *lru.Cache
+	window time.Duration
+}
+
+func newMemoryStore(size int, window time.Duration) (*memoryStore, error) {
+	cache, err := lru.New(size)
+	if err != nil {
+		return nil, err
+	}
+	return &memoryStore{cache: cache, window: window}, nil
+}
+
+func (s *memoryStore) seen(ctx context.Context, key string) (bool, error) {
+	// lock so that concurrent events with the same key yield a single miss
+	s.Lock()
+	defer s.Unlock()
+	now := time.Now()
+	if v, ok := s.cache.Get(key); ok {
+		if s.window == 0 || now.Sub(v.(time.Time)) < s.window {
+			return true, nil
+		}
+	}
+	s.cache.Add(key, now)
+	return false, nil
+}
+
+// redisStore shares the dedup state between ears instances, SETNX only
+// succeeds for the first instance seeing a key within the window
+type redisStore struct {
+	client *redis.Client
+	window time.Duration
+}
+
+func newRedisStore(endpoint string, window time.Duration) *redisStore {
+	return &redisStore{
+		client: redisclient.Client(endpoint),
+		window: window,
+	}
+}
+
+func (s *redisStore) seen(ctx context.Context, key string) (bool, error) {
+	set, err := s.client.SetNX(ctx, key, 1, s.window).Result()
+	if err != nil {
+		return false, err
+	}
+	return !set, nil
+}
@@ -15,27 +15,45 @@
 package dedup
 
 import (
-	lru "github.com/hashicorp/golang-lru"
 	"github.com/xmidt-org/ears/pkg/tenant"
 	"github.com/xorcare/pointer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 )
 
 // Config can be passed into NewFilter() in order to configure
 // the behavior of the sender.
 type Config struct {
-	CacheSize *int   `json:"cacheSize,omitempty"`
-	Path      string `json:"path,omitempty"`
+	CacheSize     *int   `json:"cacheSize,omitempty"`     // max number of entries of the memory backend
+	Path          string `json:"path,omitempty"`          // path of the value to dedup on
+	Backend       string `json:"backend,omitempty"`       // memory or redis
+	Window        *int   `json:"window,omitempty"`        // dedup window in ms, 0 keeps memory entries until evicted
+	RedisEndpoint string `json:"redisEndpoint,omitempty"` // redis address of the redis backend
 }
 
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
 var DefaultConfig = Config{
 	CacheSize: pointer.Int(1000),
 	Path:      "",
+	Backend:   BackendMemory,
+	Window:    pointer.Int(0),
 }
 
+// DefaultRedisWindow is used when the redis backend is configured without a
+// window because redis keys must expire
+var DefaultRedisWindow = 1000 * 60 * 5 // 5 min in ms
+
 type Filter struct {
-	config   Config
-	name     string
-	plugin   string
-	tid      tenant.Id
-	lruCache *lru.Cache
+	config      Config
+	name        string
+	plugin      string
+	tid         tenant.Id
+	store       store
+	hitCounter  metric.Int64Counter
+	missCounter metric.Int64Counter
+	labels      []attribute.KeyValue
 }
@@ -19,6 +19,8 @@ import (
 	pkgconfig "github.com/xmidt-org/ears/pkg/config"
 	"github.com/xmidt-org/ears/pkg/errs"
 	"github.com/xmidt-org/ears/pkg/filter"
+	"github.com/xmidt-org/ears/pkg/filter/codec"
+	"github.com/xmidt-org/ears/pkg/filter/format"
 )
 
 func NewConfig(config interface{}) (*Config, error) {
@@ -43,10 +45,25 @@ func (c Config) WithDefaults() *Config {
 	if c.Encoding == "" {
 		cfg.Encoding = DefaultConfig.Encoding
 	}
+	if c.Format == "" {
+		cfg.Format = DefaultConfig.Format
+	}
 	return &cfg
 }
 
 func (c *Config) Validate() error {
+	_, err := codec.Parse(c.Encoding)
+	if err != nil {
+		return &filter.InvalidConfigError{
+			Err: err,
+		}
+	}
+	err = format.Validate(c.Format, c.Schema)
+	if err != nil {
+		return &filter.InvalidConfigError{
+			Err: err,
+		}
+	}
 	return nil
 }
 
@@ -15,13 +15,13 @@
 package encode
 
 import (
-	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"github.com/xmidt-org/ears/pkg/event"
 	"github.com/xmidt-org/ears/pkg/filter"
+	"github.com/xmidt-org/ears/pkg/filter/codec"
+	"github.com/xmidt-org/ears/pkg/filter/format"
 	"github.com/xmidt-org/ears/pkg/secret"
 	"github.com/xmidt-org/ears/pkg/tenant"
 )
@@ -38,11 +38,25 @@ func NewFilter(tid tenant.Id, plugin string, name string, config interface{}, se
 	if err != nil {
 		return nil, err
 	}
+	codecs, err := codec.Parse(cfg.Encoding)
+	if err != nil {
+		return nil, &filter.InvalidConfigError{
+			Err: err,
+		}
+	}
+	fmtr, err := format.New(cfg.Format, cfg.Schema)
+	if err != nil {
+		return nil, &filter.InvalidConfigError{
+			Err: err,
+		}
+	}
 	f := &Filter{
 		config: *cfg,
 		name:   name,
 		plugin: plugin,
 		tid:    tid,
+		codecs: codecs,
+		format: fmtr,
 	}
 	return f, nil
 }
@@ -59,12 +73,21 @@ func (f *Filter) Filter(evt event.Event) []event.Event {
 		evt.Nack(errors.New("nil object at path " + f.config.FromPath))
 		return []event.Event{}
 	}
-	buf, err := json.Marshal(obj)
+	buf, err := f.format.Marshal(obj)
 	if err != nil {
 		evt.Nack(err)
 		return []event.Event{}
 	}
-	output := base64.StdEncoding.EncodeToString(buf)
+	encoded, err := f.codecs.Encode(buf)
+	if err != nil {
+		evt.Nack(err)
+		return []event.Event{}
+	}
+	// binary output such as bare gzip or avro cannot be carried as a string
+	var output interface{} = string(encoded)
+	if f.codecs.Binary(f.format.Binary()) {
+		output = encoded
+	}
 	path := f.config.FromPath
 	if f.config.ToPath != "" {
 		path = f.config.ToPath
@@ -14,20 +14,32 @@
 
 package encode
 
-import "github.com/xmidt-org/ears/pkg/tenant"
+import (
+	"github.com/xmidt-org/ears/pkg/filter/codec"
+	"github.com/xmidt-org/ears/pkg/filter/format"
+	"github.com/xmidt-org/ears/pkg/tenant"
+)
 
 // Config can be passed into NewFilter() in order to configure
 // the behavior of the sender.
 type Config struct {
 	FromPath string `json:"fromPath,omitempty"`
 	ToPath   string `json:"toPath,omitempty"`
+	// Encoding is a '+' delimited list of encodings such as gzip+base64url,
+	// supported are none, base64, base64url, hex, url, gzip and zstd
 	Encoding string `json:"encoding,omitempty"`
+	// Format selects how the value at FromPath is turned into bytes before encoding,
+	// one of json, text, avro, protobuf or msgpack
+	Format string `json:"format,omitempty"`
+	// Schema is required by the avro and protobuf formats
+	Schema *format.Schema `json:"schema,omitempty"`
 }
 
 var DefaultConfig = Config{
 	FromPath: "",
 	ToPath:   "",
 	Encoding: "base64",
+	Format:   format.Json,
 }
 
 type Filter struct {
@@ -35,4 +47,6 @@ type Filter struct {
 	name   string
 	plugin string
 	tid    tenant.Id
+	codecs codec.Pipeline
+	format format.Format
 }
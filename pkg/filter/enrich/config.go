@@ -0,0 +1,131 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enrich
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/xmidt-org/ears/pkg/config"
+	pkgconfig "github.com/xmidt-org/ears/pkg/config"
+	"github.com/xmidt-org/ears/pkg/errs"
+	"github.com/xmidt-org/ears/pkg/filter"
+)
+
+func NewConfig(config interface{}) (*Config, error) {
+	var cfg Config
+	err := pkgconfig.NewConfig(config, &cfg)
+	if err != nil {
+		return nil, &filter.InvalidConfigError{
+			Err: err,
+		}
+	}
+	return &cfg, nil
+}
+
+func (c Config) WithDefaults() *Config {
+	cfg := c
+	if c.Source == "" {
+		cfg.Source = DefaultConfig.Source
+	}
+	if c.CacheSize == nil {
+		cfg.CacheSize = DefaultConfig.CacheSize
+	}
+	if c.CacheTTL == nil {
+		cfg.CacheTTL = DefaultConfig.CacheTTL
+	}
+	if c.OnMiss == "" {
+		cfg.OnMiss = DefaultConfig.OnMiss
+	}
+	return &cfg
+}
+
+func (c *Config) Validate() error {
+	if c.FromPath == "" {
+		return errors.New("fromPath is required")
+	}
+	if c.ToPath == "" {
+		return errors.New("toPath is required")
+	}
+	switch c.Source {
+	case SourceInline:
+		if c.Table == nil {
+			return errors.New("inline source requires a table")
+		}
+	case SourceFile:
+		if c.File == "" {
+			return errors.New("file source requires a file")
+		}
+		switch strings.ToLower(filepath.Ext(c.File)) {
+		case ".csv", ".json":
+		default:
+			return errors.New("file must be a .csv or .json file")
+		}
+	case SourceRedis:
+		if c.RedisEndpoint == "" {
+			return errors.New("redis source requires a redis endpoint")
+		}
+		if (c.RedisKey == "") == (c.RedisHash == "") {
+			return errors.New("redis source requires either a redisKey or a redisHash")
+		}
+		if c.RedisKey != "" && !strings.Contains(c.RedisKey, KeyPlaceholder) {
+			return errors.New("redisKey must contain " + KeyPlaceholder)
+		}
+	default:
+		return errors.New("unsupported source " + c.Source)
+	}
+	if c.CacheSize == nil || *c.CacheSize < 0 || *c.CacheSize > 10000 {
+		return errors.New("cache size must be between 0 and 10000")
+	}
+	if c.CacheTTL == nil || *c.CacheTTL < 0 {
+		return errors.New("cache ttl must not be negative")
+	}
+	switch c.OnMiss {
+	case OnMissPass, OnMissDrop, OnMissNack:
+	case OnMissDefault:
+		if c.Default == nil {
+			return errors.New("onMiss default requires a default value")
+		}
+	default:
+		return errors.New("unsupported onMiss " + c.OnMiss)
+	}
+	return nil
+}
+
+func (c *Config) String() string {
+	s, err := c.YAML()
+	if err != nil {
+		return errs.String("error", nil, err)
+	}
+
+	return s
+}
+
+func (c *Config) YAML() (string, error) {
+	return config.ToYAML(c)
+}
+
+func (c *Config) FromYAML(in string) error {
+	return config.FromYAML(in, c)
+}
+
+func (c *Config) JSON() (string, error) {
+	return config.ToJSON(c)
+}
+
+func (c *Config) FromJSON(in string) error {
+	return config.FromJSON(in, c)
+}
@@ -0,0 +1,185 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enrich
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mohae/deepcopy"
+	"github.com/rs/zerolog/log"
+	"github.com/xmidt-org/ears/internal/pkg/rtsemconv"
+	"github.com/xmidt-org/ears/pkg/event"
+	"github.com/xmidt-org/ears/pkg/filter"
+	"github.com/xmidt-org/ears/pkg/secret"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+)
+
+func NewFilter(tid tenant.Id, plugin string, name string, config interface{}, secrets secret.Vault) (*Filter, error) {
+	cfg, err := NewConfig(config)
+	if err != nil {
+		return nil, &filter.InvalidConfigError{
+			Err: err,
+		}
+	}
+	cfg = cfg.WithDefaults()
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	f := &Filter{
+		config: *cfg,
+		name:   name,
+		plugin: plugin,
+		tid:    tid,
+	}
+	switch cfg.Source {
+	case SourceFile:
+		path, err := filter.ResolveFile(cfg.File)
+		if err != nil {
+			return nil, &filter.InvalidConfigError{
+				Err: err,
+			}
+		}
+		f.source, err = loadFile(path, cfg.KeyColumn, cfg.ValueColumn)
+		if err != nil {
+			return nil, &filter.InvalidConfigError{
+				Err: err,
+			}
+		}
+	case SourceRedis:
+		f.source = newRedisSource(tid, cfg.RedisEndpoint, cfg.RedisKey, cfg.RedisHash)
+		// static tables live in memory already, only remote lookups are cached
+		if *cfg.CacheSize > 0 && *cfg.CacheTTL > 0 {
+			f.source, err = newCachedSource(f.source, *cfg.CacheSize, time.Duration(*cfg.CacheTTL)*time.Millisecond)
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		f.source = tableSource(cfg.Table)
+	}
+	// metric recorders
+	meter := global.Meter(rtsemconv.EARSMeterName)
+	f.labels = []attribute.KeyValue{
+		attribute.String(rtsemconv.EARSPluginTypeLabel, rtsemconv.EARSPluginTypeEnrichFilter),
+		attribute.String(rtsemconv.EARSPluginNameLabel, f.Name()),
+		attribute.String(rtsemconv.EARSAppIdLabel, f.tid.AppId),
+		attribute.String(rtsemconv.EARSOrgIdLabel, f.tid.OrgId),
+	}
+	f.hitCounter = metric.Must(meter).
+		NewInt64Counter(
+			rtsemconv.EARSMetricEnrichHit,
+			metric.WithDescription("measures the number of events with a lookup key found in the source"),
+		)
+	f.missCounter = metric.Must(meter).
+		NewInt64Counter(
+			rtsemconv.EARSMetricEnrichMiss,
+			metric.WithDescription("measures the number of events with a lookup key not found in the source"),
+		)
+	return f, nil
+}
+
+func (f *Filter) Filter(evt event.Event) []event.Event {
+	if f == nil {
+		evt.Nack(&filter.InvalidConfigError{
+			Err: fmt.Errorf("<nil> pointer filter"),
+		})
+		return nil
+	}
+	var value interface{}
+	found := false
+	obj, _, _ := evt.GetPathValue(f.config.FromPath)
+	key, err := lookupKey(obj)
+	if err != nil {
+		evt.Nack(err)
+		return []event.Event{}
+	}
+	if obj != nil {
+		value, found, err = f.source.lookup(evt.Context(), key)
+		if err != nil {
+			log.Ctx(evt.Context()).Error().Str("op", "filter").Str("filterType", "enrich").Str("name", f.Name()).Msg("enrich source error: " + err.Error())
+			evt.Nack(err)
+			return []event.Event{}
+		}
+	}
+	if found {
+		f.hitCounter.Add(evt.Context(), 1, f.metricLabels(evt)...)
+		// values are shared by all events looked up from the same source
+		evt.SetPathValue(f.config.ToPath, deepcopy.Copy(value), true)
+		log.Ctx(evt.Context()).Debug().Str("op", "filter").Str("filterType", "enrich").Str("name", f.Name()).Int("eventCount", 1).Msg("enrich")
+		return []event.Event{evt}
+	}
+	f.missCounter.Add(evt.Context(), 1, f.metricLabels(evt)...)
+	switch f.config.OnMiss {
+	case OnMissDrop:
+		evt.Ack()
+		log.Ctx(evt.Context()).Debug().Str("op", "filter").Str("filterType", "enrich").Str("name", f.Name()).Int("eventCount", 0).Msg("enrich miss")
+		return []event.Event{}
+	case OnMissNack:
+		evt.Nack(&MissError{Key: key})
+		return []event.Event{}
+	case OnMissDefault:
+		evt.SetPathValue(f.config.ToPath, deepcopy.Copy(f.config.Default), true)
+	}
+	log.Ctx(evt.Context()).Debug().Str("op", "filter").Str("filterType", "enrich").Str("name", f.Name()).Int("eventCount", 1).Msg("enrich miss")
+	return []event.Event{evt}
+}
+
+// lookupKey turns the value at fromPath into a lookup key, strings are used as is
+// and anything else by its json representation, so that 42 looks up "42"
+func lookupKey(obj interface{}) (string, error) {
+	switch v := obj.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+	buf, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// metricLabels adds the id of the route processing the event to the labels of the filter
+func (f *Filter) metricLabels(evt event.Event) []attribute.KeyValue {
+	labels := make([]attribute.KeyValue, 0, len(f.labels)+1)
+	labels = append(labels, f.labels...)
+	return append(labels, rtsemconv.EARSRouteId.String(event.RouteId(evt.Context())))
+}
+
+func (f *Filter) Config() interface{} {
+	if f == nil {
+		return Config{}
+	}
+	return f.config
+}
+
+func (f *Filter) Name() string {
+	return f.name
+}
+
+func (f *Filter) Plugin() string {
+	return f.plugin
+}
+
+func (f *Filter) Tenant() tenant.Id {
+	return f.tid
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build integration
+
+package enrich_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/xmidt-org/ears/pkg/event"
+	"github.com/xmidt-org/ears/pkg/filter/enrich"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"github.com/xorcare/pointer"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestEnrichRedis(t *testing.T) {
+	ctx := context.Background()
+	a := NewWithT(t)
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	defer client.Close()
+	// keys of the redis source are prefixed with the tenant key
+	prefix := tenant.Id{AppId: "myapp", OrgId: "myorg"}.Key() + "_"
+	name := "enrichtest_" + time.Now().Format(time.RFC3339Nano) + "_"
+	a.Expect(client.Set(ctx, prefix+name+"dev1", `{"account":"acc1"}`, time.Minute).Err()).To(BeNil())
+	a.Expect(client.HSet(ctx, prefix+name+"hash", "dev1", "acc1").Err()).To(BeNil())
+	defer client.Del(ctx, prefix+name+"dev1", prefix+name+"hash")
+	testCases := []struct {
+		name     string
+		config   enrich.Config
+		expected interface{}
+	}{
+		{
+			name:     "key",
+			config:   enrich.Config{RedisKey: name + "{key}"},
+			expected: map[string]interface{}{"account": "acc1"},
+		},
+		{
+			name:     "hash",
+			config:   enrich.Config{RedisHash: name + "hash"},
+			expected: "acc1",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewWithT(t)
+			tc.config.Source = enrich.SourceRedis
+			tc.config.RedisEndpoint = "127.0.0.1:6379"
+			tc.config.FromPath = ".device"
+			tc.config.ToPath = ".account"
+			tc.config.OnMiss = enrich.OnMissDrop
+			f, err := enrich.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "enrich", "myenrich", tc.config, nil)
+			a.Expect(err).To(BeNil())
+			e, err := event.New(ctx, map[string]interface{}{"device": "dev1"}, event.FailOnNack(t))
+			a.Expect(err).To(BeNil())
+			evts := f.Filter(e)
+			a.Expect(evts).To(HaveLen(1))
+			v, _, _ := evts[0].GetPathValue(".account")
+			a.Expect(v).To(Equal(tc.expected))
+			e, err = event.New(ctx, map[string]interface{}{"device": "dev2"}, event.FailOnNack(t))
+			a.Expect(err).To(BeNil())
+			a.Expect(f.Filter(e)).To(HaveLen(0))
+
+			// another tenant using the same config reads its own keys only
+			f, err = enrich.NewFilter(tenant.Id{AppId: "yourapp", OrgId: "yourorg"}, "enrich", "myenrich", tc.config, nil)
+			a.Expect(err).To(BeNil())
+			e, err = event.New(ctx, map[string]interface{}{"device": "dev1"}, event.FailOnNack(t))
+			a.Expect(err).To(BeNil())
+			a.Expect(f.Filter(e)).To(HaveLen(0))
+		})
+	}
+}
+
+func TestEnrichRedisCache(t *testing.T) {
+	ctx := context.Background()
+	a := NewWithT(t)
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	defer client.Close()
+	prefix := tenant.Id{AppId: "myapp", OrgId: "myorg"}.Key() + "_"
+	key := "enrichtest_" + time.Now().Format(time.RFC3339Nano)
+	defer client.Del(ctx, prefix+key)
+	f, err := enrich.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "enrich", "myenrich", enrich.Config{
+		Source:        enrich.SourceRedis,
+		RedisEndpoint: "127.0.0.1:6379",
+		RedisKey:      "{key}",
+		CacheTTL:      pointer.Int(100),
+		FromPath:      ".device",
+		ToPath:        ".account",
+		OnMiss:        enrich.OnMissDrop,
+	}, nil)
+	a.Expect(err).To(BeNil())
+	payload := map[string]interface{}{"device": key}
+	e, err := event.New(ctx, payload, event.FailOnNack(t))
+	a.Expect(err).To(BeNil())
+	a.Expect(f.Filter(e)).To(HaveLen(0))
+	a.Expect(client.Set(ctx, prefix+key, "acc1", time.Minute).Err()).To(BeNil())
+	// the miss is cached until the ttl elapses
+	e, err = event.New(ctx, payload, event.FailOnNack(t))
+	a.Expect(err).To(BeNil())
+	a.Expect(f.Filter(e)).To(HaveLen(0))
+	time.Sleep(150 * time.Millisecond)
+	e, err = event.New(ctx, payload, event.FailOnNack(t))
+	a.Expect(err).To(BeNil())
+	a.Expect(f.Filter(e)).To(HaveLen(1))
+}
@@ -0,0 +1,222 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enrich_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xmidt-org/ears/pkg/event"
+	"github.com/xmidt-org/ears/pkg/filter"
+	"github.com/xmidt-org/ears/pkg/filter/enrich"
+	"github.com/xmidt-org/ears/pkg/tenant"
+
+	. "github.com/onsi/gomega"
+)
+
+var accounts = map[string]interface{}{
+	"dev1": "acc1",
+	"42":   map[string]interface{}{"account": "acc42", "region": "us-east"},
+}
+
+func TestEnrichInline(t *testing.T) {
+	ctx := context.Background()
+	a := NewWithT(t)
+	f, err := enrich.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "enrich", "myenrich", enrich.Config{
+		FromPath: ".device",
+		ToPath:   ".account",
+		Table:    accounts,
+	}, nil)
+	a.Expect(err).To(BeNil())
+	testCases := []struct {
+		name     string
+		payload  map[string]interface{}
+		expected interface{}
+	}{
+		{name: "string", payload: map[string]interface{}{"device": "dev1"}, expected: "acc1"},
+		{name: "number", payload: map[string]interface{}{"device": 42.0}, expected: accounts["42"]},
+		{name: "miss", payload: map[string]interface{}{"device": "dev2"}, expected: nil},
+		{name: "nopath", payload: map[string]interface{}{"other": "dev1"}, expected: nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewWithT(t)
+			e, err := event.New(ctx, tc.payload, event.FailOnNack(t))
+			a.Expect(err).To(BeNil())
+			evts := f.Filter(e)
+			a.Expect(evts).To(HaveLen(1))
+			v, _, _ := evts[0].GetPathValue(".account")
+			if tc.expected == nil {
+				a.Expect(v).To(BeNil())
+			} else {
+				a.Expect(v).To(Equal(tc.expected))
+			}
+		})
+	}
+	// enrichment values are copied into the event
+	e, err := event.New(ctx, map[string]interface{}{"device": "42"}, event.FailOnNack(t))
+	a.Expect(err).To(BeNil())
+	evts := f.Filter(e)
+	a.Expect(evts).To(HaveLen(1))
+	evts[0].SetPathValue(".account.region", "eu-west", false)
+	a.Expect(accounts["42"].(map[string]interface{})["region"]).To(Equal("us-east"))
+}
+
+func TestEnrichOnMiss(t *testing.T) {
+	ctx := context.Background()
+	a := NewWithT(t)
+	config := enrich.Config{
+		FromPath: ".device",
+		ToPath:   ".account",
+		Table:    accounts,
+	}
+
+	config.OnMiss = enrich.OnMissDrop
+	f, err := enrich.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "enrich", "myenrich", config, nil)
+	a.Expect(err).To(BeNil())
+	e, err := event.New(ctx, map[string]interface{}{"device": "dev2"}, event.FailOnNack(t))
+	a.Expect(err).To(BeNil())
+	a.Expect(f.Filter(e)).To(HaveLen(0))
+
+	config.OnMiss = enrich.OnMissNack
+	f, err = enrich.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "enrich", "myenrich", config, nil)
+	a.Expect(err).To(BeNil())
+	e, err = event.New(ctx, map[string]interface{}{"device": "dev2"}, event.FailOnAck(t))
+	a.Expect(err).To(BeNil())
+	a.Expect(f.Filter(e)).To(HaveLen(0))
+
+	config.OnMiss = enrich.OnMissDefault
+	config.Default = "unknown"
+	f, err = enrich.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "enrich", "myenrich", config, nil)
+	a.Expect(err).To(BeNil())
+	e, err = event.New(ctx, map[string]interface{}{"device": "dev2"}, event.FailOnNack(t))
+	a.Expect(err).To(BeNil())
+	evts := f.Filter(e)
+	a.Expect(evts).To(HaveLen(1))
+	v, _, _ := evts[0].GetPathValue(".account")
+	a.Expect(v).To(Equal("unknown"))
+}
+
+func TestEnrichFile(t *testing.T) {
+	ctx := context.Background()
+	a := NewWithT(t)
+	defer filter.SetFileDir(filter.FileDir())
+	dir := t.TempDir()
+	filter.SetFileDir(filepath.Join(dir, "tables"))
+	a.Expect(os.Mkdir(filepath.Join(dir, "tables"), 0755)).To(BeNil())
+	err := os.WriteFile(filepath.Join(dir, "tables", "accounts.csv"), []byte("device,account,region\ndev1,acc1,us-east\ndev2,acc2,eu-west\n"), 0644)
+	a.Expect(err).To(BeNil())
+	err = os.WriteFile(filepath.Join(dir, "tables", "accounts.json"), []byte(`{"dev1":{"account":"acc1"},"dev2":"acc2"}`), 0644)
+	a.Expect(err).To(BeNil())
+	err = os.WriteFile(filepath.Join(dir, "secret.csv"), []byte("device,account\ndev1,acc1\n"), 0644)
+	a.Expect(err).To(BeNil())
+
+	testCases := []struct {
+		name     string
+		config   enrich.Config
+		device   string
+		expected interface{}
+	}{
+		{
+			name:     "csvRow",
+			config:   enrich.Config{File: "accounts.csv"},
+			device:   "dev2",
+			expected: map[string]interface{}{"account": "acc2", "region": "eu-west"},
+		},
+		{
+			name:     "csvColumns",
+			config:   enrich.Config{File: "accounts.csv", KeyColumn: "account", ValueColumn: "region"},
+			device:   "acc1",
+			expected: "us-east",
+		},
+		{
+			name:     "json",
+			config:   enrich.Config{File: "accounts.json"},
+			device:   "dev1",
+			expected: map[string]interface{}{"account": "acc1"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewWithT(t)
+			tc.config.Source = enrich.SourceFile
+			tc.config.FromPath = ".device"
+			tc.config.ToPath = ".account"
+			f, err := enrich.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "enrich", "myenrich", tc.config, nil)
+			a.Expect(err).To(BeNil())
+			e, err := event.New(ctx, map[string]interface{}{"device": tc.device}, event.FailOnNack(t))
+			a.Expect(err).To(BeNil())
+			evts := f.Filter(e)
+			a.Expect(evts).To(HaveLen(1))
+			v, _, _ := evts[0].GetPathValue(".account")
+			a.Expect(v).To(Equal(tc.expected))
+		})
+	}
+
+	// files outside of the filter file directory cannot be read
+	for _, file := range []string{filepath.Join(dir, "secret.csv"), "../secret.csv", "tables/../../secret.csv"} {
+		t.Run(file, func(t *testing.T) {
+			a := NewWithT(t)
+			_, err := enrich.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "enrich", "myenrich", enrich.Config{
+				Source:   enrich.SourceFile,
+				File:     file,
+				FromPath: ".device",
+				ToPath:   ".account",
+			}, nil)
+			var notAllowed *filter.FileNotAllowedError
+			a.Expect(errors.As(err, &notAllowed)).To(BeTrue())
+		})
+	}
+
+	_, err = enrich.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "enrich", "myenrich", enrich.Config{
+		Source:    enrich.SourceFile,
+		File:      "accounts.csv",
+		KeyColumn: "serial",
+		FromPath:  ".device",
+		ToPath:    ".account",
+	}, nil)
+	a.Expect(err).ToNot(BeNil())
+}
+
+func TestEnrichBadConfig(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config enrich.Config
+	}{
+		{name: "noFromPath", config: enrich.Config{ToPath: ".a", Table: accounts}},
+		{name: "noToPath", config: enrich.Config{FromPath: ".a", Table: accounts}},
+		{name: "noTable", config: enrich.Config{FromPath: ".a", ToPath: ".b"}},
+		{name: "badSource", config: enrich.Config{FromPath: ".a", ToPath: ".b", Source: "ldap"}},
+		{name: "badFileType", config: enrich.Config{FromPath: ".a", ToPath: ".b", Source: enrich.SourceFile, File: "a.txt"}},
+		{name: "missingFile", config: enrich.Config{FromPath: ".a", ToPath: ".b", Source: enrich.SourceFile, File: "missing.csv"}},
+		{name: "noRedisEndpoint", config: enrich.Config{FromPath: ".a", ToPath: ".b", Source: enrich.SourceRedis, RedisKey: "acc:{key}"}},
+		{name: "noRedisKey", config: enrich.Config{FromPath: ".a", ToPath: ".b", Source: enrich.SourceRedis, RedisEndpoint: "localhost:6379"}},
+		{name: "redisKeyAndHash", config: enrich.Config{FromPath: ".a", ToPath: ".b", Source: enrich.SourceRedis, RedisEndpoint: "localhost:6379", RedisKey: "acc:{key}", RedisHash: "accounts"}},
+		{name: "redisKeyNoPlaceholder", config: enrich.Config{FromPath: ".a", ToPath: ".b", Source: enrich.SourceRedis, RedisEndpoint: "localhost:6379", RedisKey: "acc"}},
+		{name: "badOnMiss", config: enrich.Config{FromPath: ".a", ToPath: ".b", Table: accounts, OnMiss: "retry"}},
+		{name: "noDefault", config: enrich.Config{FromPath: ".a", ToPath: ".b", Table: accounts, OnMiss: enrich.OnMissDefault}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewWithT(t)
+			f, err := enrich.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "enrich", "myenrich", tc.config, nil)
+			a.Expect(err).ToNot(BeNil())
+			a.Expect(f).To(BeNil())
+		})
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enrich
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/xmidt-org/ears/pkg/filter/redisclient"
+	"github.com/xmidt-org/ears/pkg/tenant"
+)
+
+// source looks up the enrichment value of a key
+type source interface {
+	lookup(ctx context.Context, key string) (interface{}, bool, error)
+}
+
+type tableSource map[string]interface{}
+
+func (s tableSource) lookup(ctx context.Context, key string) (interface{}, bool, error) {
+	v, ok := s[key]
+	return v, ok, nil
+}
+
+// loadFile reads a json object or a csv file with a header row into a table
+func loadFile(path string, keyColumn string, valueColumn string) (tableSource, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		var table map[string]interface{}
+		err = json.Unmarshal(buf, &table)
+		if err != nil {
+			return nil, err
+		}
+		return table, nil
+	}
+	records, err := csv.NewReader(strings.NewReader(string(buf))).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("csv file " + path + " has no header row")
+	}
+	header := records[0]
+	keyIdx, valueIdx := 0, -1
+	if keyColumn != "" {
+		keyIdx = indexOf(header, keyColumn)
+		if keyIdx < 0 {
+			return nil, errors.New("csv file " + path + " has no column " + keyColumn)
+		}
+	}
+	if valueColumn != "" {
+		valueIdx = indexOf(header, valueColumn)
+		if valueIdx < 0 {
+			return nil, errors.New("csv file " + path + " has no column " + valueColumn)
+		}
+	}
+	table := make(tableSource, len(records)-1)
+	for _, record := range records[1:] {
+		if valueIdx >= 0 {
+			table[record[keyIdx]] = record[valueIdx]
+			continue
+		}
+		row := make(map[string]interface{}, len(header)-1)
+		for i, column := range header {
+			if i != keyIdx {
+				row[column] = record[i]
+			}
+		}
+		table[record[keyIdx]] = row
+	}
+	return table, nil
+}
+
+func indexOf(columns []string, column string) int {
+	for i, c := range columns {
+		if c == column {
+			return i
+		}
+	}
+	return -1
+}
+
+// redisSource looks up keys either with GET on a key pattern or with HGET on a hash,
+// values holding json are decoded, anything else is returned as a string. Keys and
+// hashes are prefixed with the tenant key so that a tenant can only read its own data
+type redisSource struct {
+	client     *redis.Client
+	prefix     string
+	keyPattern string
+	hash       string
+}
+
+func newRedisSource(tid tenant.Id, endpoint string, keyPattern string, hash string) *redisSource {
+	return &redisSource{
+		client:     redisclient.Client(endpoint),
+		prefix:     tid.Key() + "_",
+		keyPattern: keyPattern,
+		hash:       hash,
+	}
+}
+
+func (s *redisSource) lookup(ctx context.Context, key string) (interface{}, bool, error) {
+	var val string
+	var err error
+	if s.hash != "" {
+		val, err = s.client.HGet(ctx, s.prefix+s.hash, key).Result()
+	} else {
+		val, err = s.client.Get(ctx, s.prefix+strings.ReplaceAll(s.keyPattern, KeyPlaceholder, key)).Result()
+	}
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var v interface{}
+	if json.Unmarshal([]byte(val), &v) != nil {
+		return val, true, nil
+	}
+	return v, true, nil
+}
+
+type cacheEntry struct {
+	value   interface{}
+	found   bool
+	expires time.Time
+}
+
+// cachedSource keeps lookup results, including misses, for the duration of the ttl
+type cachedSource struct {
+	source source
+	cache  *lru.Cache
+	ttl    time.Duration
+}
+
+func newCachedSource(s source, size int, ttl time.Duration) (*cachedSource, error) {
+	cache, err := lru.New(size)
+	if err != nil {
+		return nil, err
+	}
+	return &cachedSource{source: s, cache: cache, ttl: ttl}, nil
+}
+
+func (s *cachedSource) lookup(ctx context.Context, key string) (interface{}, bool, error) {
+	now := time.Now()
+	if v, ok := s.cache.Get(key); ok {
+		entry := v.(cacheEntry)
+		if now.Before(entry.expires) {
+			return entry.value, entry.found, nil
+		}
+	}
+	value, found, err := s.source.lookup(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	s.cache.Add(key, cacheEntry{value: value, found: found, expires: now.Add(s.ttl)})
+	return value, found, nil
+}
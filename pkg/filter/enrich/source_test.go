@@ -0,0 +1,76 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enrich
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/xmidt-org/ears/pkg/tenant"
+
+	. "github.com/onsi/gomega"
+)
+
+var errSkipped = errors.New("skipped")
+
+// keyRecorder records the keys of redis commands and skips sending them
+type keyRecorder struct {
+	keys []string
+}
+
+func (r *keyRecorder) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	r.keys = append(r.keys, cmd.Args()[1].(string))
+	return ctx, errSkipped
+}
+
+func (r *keyRecorder) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (r *keyRecorder) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, errSkipped
+}
+
+func (r *keyRecorder) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+func TestRedisSourceTenantPrefix(t *testing.T) {
+	a := NewWithT(t)
+	ctx := context.Background()
+	mine := tenant.Id{AppId: "myapp", OrgId: "myorg"}
+	yours := tenant.Id{AppId: "yourapp", OrgId: "yourorg"}
+	recorder := &keyRecorder{}
+
+	s := newRedisSource(mine, "enrich.recorder:6379", "acc:{key}", "")
+	s.client.AddHook(recorder)
+	_, _, err := s.lookup(ctx, "dev1")
+	a.Expect(err).To(Equal(errSkipped))
+	s = newRedisSource(mine, "enrich.recorder:6379", "", "accounts")
+	_, _, err = s.lookup(ctx, "dev1")
+	a.Expect(err).To(Equal(errSkipped))
+	// a key pattern naming another tenant's key stays below the own tenant key
+	s = newRedisSource(mine, "enrich.recorder:6379", yours.Key()+"_acc:{key}", "")
+	_, _, err = s.lookup(ctx, "dev1")
+	a.Expect(err).To(Equal(errSkipped))
+
+	a.Expect(recorder.keys).To(Equal([]string{
+		mine.Key() + "_acc:dev1",
+		mine.Key() + "_accounts",
+		mine.Key() + "_" + yours.Key() + "_acc:dev1",
+	}))
+}
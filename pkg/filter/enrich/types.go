@@ -0,0 +1,77 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enrich
+
+import (
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"github.com/xorcare/pointer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Config can be passed into NewFilter() in order to configure
+// the behavior of the filter.
+type Config struct {
+	FromPath      string                 `json:"fromPath,omitempty"`      // path of the lookup key
+	ToPath        string                 `json:"toPath,omitempty"`        // path the lookup result is written to
+	Source        string                 `json:"source,omitempty"`        // inline, file or redis
+	Table         map[string]interface{} `json:"table,omitempty"`         // lookup table of the inline source
+	File          string                 `json:"file,omitempty"`          // csv or json file of the file source, relative to the filter file directory
+	KeyColumn     string                 `json:"keyColumn,omitempty"`     // csv column holding the key, defaults to the first column
+	ValueColumn   string                 `json:"valueColumn,omitempty"`   // csv column holding the value, defaults to an object of all other columns
+	RedisEndpoint string                 `json:"redisEndpoint,omitempty"` // redis address of the redis source
+	RedisKey      string                 `json:"redisKey,omitempty"`      // redis key pattern, {key} is replaced by the lookup key, prefixed with the tenant key
+	RedisHash     string                 `json:"redisHash,omitempty"`     // redis hash whose fields are the lookup keys, prefixed with the tenant key
+	CacheSize     *int                   `json:"cacheSize,omitempty"`     // max number of cached redis lookups
+	CacheTTL      *int                   `json:"cacheTTL,omitempty"`      // cache ttl in ms, 0 disables the cache
+	OnMiss        string                 `json:"onMiss,omitempty"`        // pass, drop, nack or default
+	Default       interface{}            `json:"default,omitempty"`       // value written to toPath on a miss if onMiss is default
+}
+
+const (
+	SourceInline = "inline"
+	SourceFile   = "file"
+	SourceRedis  = "redis"
+)
+
+const (
+	OnMissPass    = "pass"
+	OnMissDrop    = "drop"
+	OnMissNack    = "nack"
+	OnMissDefault = "default"
+)
+
+// KeyPlaceholder is replaced by the lookup key in the redis key pattern
+const KeyPlaceholder = "{key}"
+
+var DefaultConfig = Config{
+	FromPath:  "",
+	ToPath:    "",
+	Source:    SourceInline,
+	CacheSize: pointer.Int(1000),
+	CacheTTL:  pointer.Int(60000),
+	OnMiss:    OnMissPass,
+}
+
+type Filter struct {
+	config      Config
+	name        string
+	plugin      string
+	tid         tenant.Id
+	source      source
+	hitCounter  metric.Int64Counter
+	missCounter metric.Int64Counter
+	labels      []attribute.KeyValue
+}
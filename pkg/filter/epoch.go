@@ -0,0 +1,40 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"math"
+	"time"
+)
+
+// EpochFactor returns the nanoseconds per unit of an epoch timestamp, picking
+// seconds, milliseconds, microseconds or nanoseconds based on its magnitude
+// which works for any date between 1973 and 5138
+func EpochFactor(ts float64) float64 {
+	switch abs := math.Abs(ts); {
+	case abs < 1e11:
+		return 1e9
+	case abs < 1e14:
+		return 1e6
+	case abs < 1e17:
+		return 1e3
+	}
+	return 1
+}
+
+// FromEpoch converts an epoch timestamp of unknown unit, see EpochFactor
+func FromEpoch(ts float64) time.Time {
+	return time.Unix(0, int64(ts*EpochFactor(ts)))
+}
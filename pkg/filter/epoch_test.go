@@ -0,0 +1,33 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xmidt-org/ears/pkg/filter"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestFromEpoch(t *testing.T) {
+	a := NewWithT(t)
+	expected := time.Date(2021, 6, 1, 12, 30, 15, 0, time.UTC)
+	for _, ts := range []float64{1622550615, 1622550615000, 1622550615000000, 1622550615000000000} {
+		a.Expect(filter.FromEpoch(ts).Equal(expected)).To(BeTrue(), "%f", ts)
+	}
+	a.Expect(filter.FromEpoch(-1622550615).Equal(time.Unix(-1622550615, 0))).To(BeTrue())
+}
@@ -14,7 +14,11 @@
 
 package filter
 
-import "github.com/xmidt-org/ears/pkg/errs"
+import (
+	"errors"
+
+	"github.com/xmidt-org/ears/pkg/errs"
+)
 
 func (e *InvalidConfigError) Unwrap() error {
 	return e.Err
@@ -31,3 +35,7 @@ func (e *InvalidArgumentError) Unwrap() error {
 func (e *InvalidArgumentError) Error() string {
 	return errs.String("InvalidArgumentError", nil, e.Err)
 }
+
+func (e *FileNotAllowedError) Error() string {
+	return errs.String("FileNotAllowedError", map[string]interface{}{"file": e.File}, errors.New(e.Reason))
+}
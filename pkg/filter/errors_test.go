@@ -41,6 +41,11 @@ func TestErrorMessage(t *testing.T) {
 			name: "InvalidArgumentError_Err",
 			err:  &filter.InvalidArgumentError{Err: fmt.Errorf("wrapped error")},
 		},
+
+		{
+			name: "FileNotAllowedError",
+			err:  &filter.FileNotAllowedError{File: "../secrets.json", Reason: "path leaves the filter file directory"},
+		},
 	}
 
 	for _, tc := range testCases {
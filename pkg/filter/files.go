@@ -0,0 +1,80 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	fileDirLock sync.RWMutex
+	fileDir     string
+)
+
+// SetFileDir sets the directory filters may read files such as schemas or
+// lookup tables from. Files are disabled if the directory is empty
+func SetFileDir(dir string) {
+	fileDirLock.Lock()
+	defer fileDirLock.Unlock()
+	if dir != "" {
+		dir = filepath.Clean(dir)
+	}
+	fileDir = dir
+}
+
+// FileDir returns the directory filters may read files from
+func FileDir() string {
+	fileDirLock.RLock()
+	defer fileDirLock.RUnlock()
+	return fileDir
+}
+
+// ResolveFile turns a file name from a filter config into a path below the
+// filter file directory. Absolute names and names leaving the directory are
+// rejected, so that tenants cannot read arbitrary files of the ears host
+func ResolveFile(name string) (string, error) {
+	dir := FileDir()
+	if dir == "" {
+		return "", &FileNotAllowedError{File: name, Reason: "filter files are disabled"}
+	}
+	if filepath.IsAbs(name) {
+		return "", &FileNotAllowedError{File: name, Reason: "absolute paths are not allowed"}
+	}
+	clean := filepath.Clean(name)
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(os.PathSeparator)) {
+		return "", &FileNotAllowedError{File: name, Reason: "path leaves the filter file directory"}
+	}
+	path := filepath.Join(dir, clean)
+	if !IsFileAllowed(path) {
+		return "", &FileNotAllowedError{File: name, Reason: "path leaves the filter file directory"}
+	}
+	return path, nil
+}
+
+// IsFileAllowed tells if a path is below the filter file directory
+func IsFileAllowed(path string) bool {
+	dir := FileDir()
+	if dir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(dir, filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+}
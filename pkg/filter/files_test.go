@@ -0,0 +1,63 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/xmidt-org/ears/pkg/filter"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestResolveFile(t *testing.T) {
+	a := NewWithT(t)
+	defer filter.SetFileDir(filter.FileDir())
+
+	filter.SetFileDir("")
+	_, err := filter.ResolveFile("table.csv")
+	var notAllowed *filter.FileNotAllowedError
+	a.Expect(errors.As(err, &notAllowed)).To(BeTrue())
+
+	dir := t.TempDir()
+	filter.SetFileDir(dir)
+	testCases := []struct {
+		name    string
+		file    string
+		allowed bool
+	}{
+		{name: "file", file: "table.csv", allowed: true},
+		{name: "subdir", file: "tables/table.csv", allowed: true},
+		{name: "inner dots", file: "tables/../table.csv", allowed: true},
+		{name: "absolute", file: "/etc/passwd", allowed: false},
+		{name: "parent", file: "../table.csv", allowed: false},
+		{name: "nested parent", file: "tables/../../table.csv", allowed: false},
+		{name: "dir", file: ".", allowed: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewWithT(t)
+			path, err := filter.ResolveFile(tc.file)
+			if tc.allowed {
+				a.Expect(err).To(BeNil())
+				a.Expect(path).To(Equal(filepath.Join(dir, tc.file)))
+			} else {
+				a.Expect(errors.As(err, &notAllowed)).To(BeTrue())
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// avroSchema converts between avro binary and plain JSON values. Unions are
+// accepted as plain values when encoding and unwrapped when decoding, so
+// ["null","string"] fields read and write as a string or null rather than
+// goavro's {"string": "..."} representation.
+type avroSchema struct {
+	// encoder accepts plain JSON unions, the decoder reports union
+	// branches by type name
+	encoder *goavro.Codec
+	decoder *goavro.Codec
+	schema  interface{}
+	named   map[string]map[string]interface{}
+}
+
+func compileAvro(text string) (*avroSchema, error) {
+	encoder, err := goavro.NewCodecForStandardJSON(text)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := goavro.NewCodec(text)
+	if err != nil {
+		return nil, err
+	}
+	var schema interface{}
+	err = json.Unmarshal([]byte(text), &schema)
+	if err != nil {
+		// a bare primitive type name such as long
+		schema = text
+	}
+	s := &avroSchema{
+		encoder: encoder,
+		decoder: decoder,
+		schema:  schema,
+		named:   make(map[string]map[string]interface{}),
+	}
+	s.register(schema, "")
+	return s, nil
+}
+
+func (s *avroSchema) marshal(v interface{}) ([]byte, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	native, _, err := s.encoder.NativeFromTextual(buf)
+	if err != nil {
+		return nil, err
+	}
+	return s.encoder.BinaryFromNative(nil, native)
+}
+
+func (s *avroSchema) unmarshal(buf []byte, indexes []int) (interface{}, error) {
+	native, _, err := s.decoder.NativeFromBinary(buf)
+	if err != nil {
+		return nil, err
+	}
+	return normalize(s.unwrap(s.schema, "", native))
+}
+
+func (s *avroSchema) indexes() []int {
+	return nil
+}
+
+// register records all named types so that references can be resolved
+func (s *avroSchema) register(schema interface{}, ns string) {
+	switch schema := schema.(type) {
+	case []interface{}:
+		for _, branch := range schema {
+			s.register(branch, ns)
+		}
+	case map[string]interface{}:
+		switch t := schema["type"].(type) {
+		case string:
+			switch t {
+			case "record", "error", "enum", "fixed":
+				name := s.fullName(schema, ns)
+				s.named[name] = schema
+				if fields, ok := schema["fields"].([]interface{}); ok {
+					for _, field := range fields {
+						if field, ok := field.(map[string]interface{}); ok {
+							s.register(field["type"], namespaceOf(name))
+						}
+					}
+				}
+			case "array":
+				s.register(schema["items"], ns)
+			case "map":
+				s.register(schema["values"], ns)
+			}
+		default:
+			s.register(t, ns)
+		}
+	}
+}
+
+func (s *avroSchema) unwrap(schema interface{}, ns string, v interface{}) interface{} {
+	switch schema := schema.(type) {
+	case string:
+		if named, ok := s.lookup(schema, ns); ok {
+			return s.unwrap(named, ns, v)
+		}
+	case []interface{}:
+		m, ok := v.(map[string]interface{})
+		if !ok || len(m) != 1 {
+			return v
+		}
+		for key, inner := range m {
+			for _, branch := range schema {
+				if s.unionKey(branch, ns) == key {
+					return s.unwrap(branch, ns, inner)
+				}
+			}
+		}
+	case map[string]interface{}:
+		switch t := schema["type"].(type) {
+		case string:
+			switch t {
+			case "record", "error":
+				rns := namespaceOf(s.fullName(schema, ns))
+				record, ok := v.(map[string]interface{})
+				fields, _ := schema["fields"].([]interface{})
+				if !ok {
+					return v
+				}
+				for _, field := range fields {
+					field, ok := field.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					name, _ := field["name"].(string)
+					if value, ok := record[name]; ok {
+						record[name] = s.unwrap(field["type"], rns, value)
+					}
+				}
+				return record
+			case "array":
+				items, ok := v.([]interface{})
+				if !ok {
+					return v
+				}
+				for i := range items {
+					items[i] = s.unwrap(schema["items"], ns, items[i])
+				}
+				return items
+			case "map":
+				values, ok := v.(map[string]interface{})
+				if !ok {
+					return v
+				}
+				for k := range values {
+					values[k] = s.unwrap(schema["values"], ns, values[k])
+				}
+				return values
+			}
+		default:
+			return s.unwrap(t, ns, v)
+		}
+	}
+	return v
+}
+
+// unionKey returns the key goavro uses for a union branch
+func (s *avroSchema) unionKey(branch interface{}, ns string) string {
+	switch branch := branch.(type) {
+	case string:
+		if _, ok := s.lookup(branch, ns); ok {
+			return qualify(branch, ns)
+		}
+		return branch
+	case map[string]interface{}:
+		t, _ := branch["type"].(string)
+		switch t {
+		case "record", "error", "enum", "fixed":
+			return s.fullName(branch, ns)
+		}
+		if logicalType, ok := branch["logicalType"].(string); ok {
+			return t + "." + logicalType
+		}
+		return t
+	}
+	return ""
+}
+
+func (s *avroSchema) lookup(name string, ns string) (map[string]interface{}, bool) {
+	if named, ok := s.named[qualify(name, ns)]; ok {
+		return named, true
+	}
+	named, ok := s.named[name]
+	return named, ok
+}
+
+func (s *avroSchema) fullName(schema map[string]interface{}, ns string) string {
+	name, _ := schema["name"].(string)
+	if namespace, ok := schema["namespace"].(string); ok && !strings.Contains(name, ".") {
+		ns = namespace
+	}
+	return qualify(name, ns)
+}
+
+func qualify(name string, ns string) string {
+	if ns == "" || strings.Contains(name, ".") {
+		return name
+	}
+	return ns + "." + name
+}
+
+func namespaceOf(fullName string) string {
+	idx := strings.LastIndex(fullName, ".")
+	if idx < 0 {
+		return ""
+	}
+	return fullName[:idx]
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import "github.com/xmidt-org/ears/pkg/errs"
+
+// UnsupportedFormatError is returned for unknown formats
+type UnsupportedFormatError struct {
+	Format string
+}
+
+func (e *UnsupportedFormatError) Error() string {
+	return errs.String("UnsupportedFormatError", map[string]interface{}{"format": e.Format}, nil)
+}
+
+// MissingSchemaError is returned when a schema based format has no schema
+type MissingSchemaError struct {
+	Format string
+}
+
+func (e *MissingSchemaError) Error() string {
+	return errs.String("MissingSchemaError", map[string]interface{}{"format": e.Format}, nil)
+}
+
+// InvalidSchemaError is returned when a schema cannot be loaded or compiled
+type InvalidSchemaError struct {
+	Format string
+	Err    error
+}
+
+func (e *InvalidSchemaError) Error() string {
+	return errs.String("InvalidSchemaError", map[string]interface{}{"format": e.Format}, e.Err)
+}
+
+func (e *InvalidSchemaError) Unwrap() error {
+	return e.Err
+}
+
+// RegistryError is returned when the schema registry request fails
+type RegistryError struct {
+	Url        string
+	StatusCode int
+	Err        error
+}
+
+func (e *RegistryError) Error() string {
+	return errs.String("RegistryError", map[string]interface{}{"url": e.Url, "statusCode": e.StatusCode}, e.Err)
+}
+
+func (e *RegistryError) Unwrap() error {
+	return e.Err
+}
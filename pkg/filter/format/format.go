@@ -0,0 +1,168 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package format converts between the bytes carried in an event and the
+// generic map[string]interface{} representation used by EARS payloads.
+// Besides json and text it supports the schema based avro and protobuf
+// formats and the schema-less msgpack format.
+package format
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	Json     = "json"
+	Text     = "text"
+	Avro     = "avro"
+	Protobuf = "protobuf"
+	MsgPack  = "msgpack"
+)
+
+// Format serializes EARS values to bytes and back
+type Format interface {
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(buf []byte) (interface{}, error)
+	// Binary reports whether marshalled values may contain arbitrary,
+	// non printable bytes
+	Binary() bool
+}
+
+// New returns the named format; avro and protobuf require a schema
+func New(name string, schema *Schema) (Format, error) {
+	switch name {
+	case Json:
+		return jsonFormat{}, nil
+	case Text:
+		return textFormat{}, nil
+	case MsgPack:
+		return msgpackFormat{}, nil
+	case Avro, Protobuf:
+		if schema == nil {
+			return nil, &MissingSchemaError{Format: name}
+		}
+		return newSchemaFormat(name, schema)
+	}
+	return nil, &UnsupportedFormatError{Format: name}
+}
+
+type jsonFormat struct{}
+
+func (jsonFormat) Name() string {
+	return Json
+}
+
+func (jsonFormat) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonFormat) Unmarshal(buf []byte) (interface{}, error) {
+	var v interface{}
+	err := json.Unmarshal(buf, &v)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (jsonFormat) Binary() bool {
+	return false
+}
+
+// textFormat carries strings as they are
+type textFormat struct{}
+
+func (textFormat) Name() string {
+	return Text
+}
+
+func (textFormat) Marshal(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	}
+	return nil, errors.New("text format requires a string value")
+}
+
+func (textFormat) Unmarshal(buf []byte) (interface{}, error) {
+	return string(buf), nil
+}
+
+func (textFormat) Binary() bool {
+	return false
+}
+
+type msgpackFormat struct{}
+
+func (msgpackFormat) Name() string {
+	return MsgPack
+}
+
+func (msgpackFormat) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpackFormat) Unmarshal(buf []byte) (interface{}, error) {
+	var v interface{}
+	err := msgpack.Unmarshal(buf, &v)
+	if err != nil {
+		return nil, err
+	}
+	return normalize(v)
+}
+
+func (msgpackFormat) Binary() bool {
+	return true
+}
+
+// normalize converts decoded values into the types produced by
+// json.Unmarshal (float64 numbers, base64 strings for bytes etc.) so that
+// downstream filters see the same representation regardless of format
+func normalize(v interface{}) (interface{}, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(buf, &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Validate checks a format name and its schema without compiling or
+// fetching the schema
+func Validate(name string, schema *Schema) error {
+	switch name {
+	case Json, Text, MsgPack:
+		return nil
+	case Avro, Protobuf:
+		if schema == nil {
+			return &MissingSchemaError{Format: name}
+		}
+		err := schema.Validate()
+		if err != nil {
+			return &InvalidSchemaError{Format: name, Err: err}
+		}
+		return nil
+	}
+	return &UnsupportedFormatError{Format: name}
+}
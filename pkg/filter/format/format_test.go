@@ -0,0 +1,357 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xmidt-org/ears/pkg/filter"
+	"github.com/xmidt-org/ears/pkg/filter/format"
+
+	. "github.com/onsi/gomega"
+)
+
+const avroSchema = `{
+	"type": "record",
+	"name": "Telemetry",
+	"namespace": "com.example",
+	"fields": [
+		{"name": "device", "type": "string"},
+		{"name": "seq", "type": "long"},
+		{"name": "note", "type": ["null", "string"], "default": null},
+		{"name": "readings", "type": {"type": "array", "items": {
+			"type": "record",
+			"name": "Reading",
+			"fields": [
+				{"name": "sensor", "type": "string"},
+				{"name": "value", "type": ["null", "double"]}
+			]
+		}}},
+		{"name": "tags", "type": {"type": "map", "values": "string"}},
+		{"name": "last", "type": ["null", "Reading"], "default": null}
+	]
+}`
+
+const protoSchema = `syntax = "proto3";
+package example;
+
+message Envelope {
+	message Header {
+		string id = 1;
+	}
+	Header header = 1;
+	Telemetry body = 2;
+}
+
+message Telemetry {
+	string device = 1;
+	int32 seq = 2;
+	repeated double readings = 3;
+	map<string, string> tags = 4;
+}
+`
+
+func TestAvro(t *testing.T) {
+	a := NewWithT(t)
+	f, err := format.New(format.Avro, &format.Schema{Inline: avroSchema})
+	a.Expect(err).To(BeNil())
+	for _, js := range []string{`{
+		"device": "d1",
+		"seq": 42,
+		"note": "hello",
+		"readings": [{"sensor": "temp", "value": 21.5}, {"sensor": "hum", "value": null}],
+		"tags": {"site": "a"},
+		"last": {"sensor": "temp", "value": 21.5}
+	}`, `{
+		"device": "d2",
+		"seq": 1,
+		"note": null,
+		"readings": [],
+		"tags": {},
+		"last": null
+	}`} {
+		var in interface{}
+		a.Expect(json.Unmarshal([]byte(js), &in)).To(BeNil())
+		buf, err := f.Marshal(in)
+		a.Expect(err).To(BeNil())
+		out, err := f.Unmarshal(buf)
+		a.Expect(err).To(BeNil())
+		a.Expect(out).To(Equal(in))
+	}
+	_, err = f.Marshal(map[string]interface{}{"device": 1.0})
+	a.Expect(err).ToNot(BeNil())
+}
+
+func TestAvroInlineObject(t *testing.T) {
+	a := NewWithT(t)
+	var schema interface{}
+	a.Expect(json.Unmarshal([]byte(avroSchema), &schema)).To(BeNil())
+	f, err := format.New(format.Avro, &format.Schema{Inline: schema})
+	a.Expect(err).To(BeNil())
+	var in interface{}
+	a.Expect(json.Unmarshal([]byte(`{"device": "d1", "seq": 1, "note": null, "readings": [], "tags": {}, "last": null}`), &in)).To(BeNil())
+	buf, err := f.Marshal(in)
+	a.Expect(err).To(BeNil())
+	out, err := f.Unmarshal(buf)
+	a.Expect(err).To(BeNil())
+	a.Expect(out).To(Equal(in))
+}
+
+func TestProtobuf(t *testing.T) {
+	testCases := []struct {
+		name        string
+		messageType string
+		in          string
+	}{
+		{name: "messageType", messageType: "Telemetry", in: `{"device": "d1", "seq": 7, "readings": [1.5, 2], "tags": {"site": "a"}}`},
+		{name: "firstMessage", in: `{"header": {"id": "x"}, "body": {"device": "d1", "seq": 7}}`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewWithT(t)
+			f, err := format.New(format.Protobuf, &format.Schema{Inline: protoSchema, MessageType: tc.messageType})
+			a.Expect(err).To(BeNil())
+			var in interface{}
+			a.Expect(json.Unmarshal([]byte(tc.in), &in)).To(BeNil())
+			buf, err := f.Marshal(in)
+			a.Expect(err).To(BeNil())
+			out, err := f.Unmarshal(buf)
+			a.Expect(err).To(BeNil())
+			a.Expect(out).To(Equal(in))
+		})
+	}
+	a := NewWithT(t)
+	_, err := format.New(format.Protobuf, &format.Schema{Inline: protoSchema, MessageType: "Unknown"})
+	var serr *format.InvalidSchemaError
+	a.Expect(errors.As(err, &serr)).To(BeTrue())
+}
+
+func TestProtobufFile(t *testing.T) {
+	a := NewWithT(t)
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "common.proto"), []byte(`syntax = "proto3";
+package common;
+message Header {
+	string id = 1;
+}
+`), 0644)
+	a.Expect(err).To(BeNil())
+	err = ioutil.WriteFile(filepath.Join(dir, "event.proto"), []byte(`syntax = "proto3";
+package events;
+import "common.proto";
+message Event {
+	common.Header header = 1;
+	string name = 2;
+}
+`), 0644)
+	a.Expect(err).To(BeNil())
+	defer filter.SetFileDir(filter.FileDir())
+	filter.SetFileDir(dir)
+	f, err := format.New(format.Protobuf, &format.Schema{File: "event.proto", MessageType: "events.Event"})
+	a.Expect(err).To(BeNil())
+	in := map[string]interface{}{"header": map[string]interface{}{"id": "x"}, "name": "boot"}
+	buf, err := f.Marshal(in)
+	a.Expect(err).To(BeNil())
+	out, err := f.Unmarshal(buf)
+	a.Expect(err).To(BeNil())
+	a.Expect(out).To(Equal(in))
+
+	// files and their imports must stay inside the filter file directory
+	a.Expect(os.Mkdir(filepath.Join(dir, "schemas"), 0755)).To(BeNil())
+	err = ioutil.WriteFile(filepath.Join(dir, "schemas", "escape.proto"), []byte(`syntax = "proto3";
+import "../../common.proto";
+message Escape {
+	string id = 1;
+}
+`), 0644)
+	a.Expect(err).To(BeNil())
+	filter.SetFileDir(filepath.Join(dir, "schemas"))
+	for _, file := range []string{filepath.Join(dir, "event.proto"), "../event.proto", "escape.proto"} {
+		_, err = format.New(format.Protobuf, &format.Schema{File: file})
+		var serr *format.InvalidSchemaError
+		a.Expect(errors.As(err, &serr)).To(BeTrue(), file)
+	}
+}
+
+func TestMsgPack(t *testing.T) {
+	a := NewWithT(t)
+	f, err := format.New(format.MsgPack, nil)
+	a.Expect(err).To(BeNil())
+	var obj interface{}
+	a.Expect(json.Unmarshal([]byte(`{"a": 1, "b": [true, null, "c", 2.5], "d": {"e": -3}}`), &obj)).To(BeNil())
+	for _, in := range []interface{}{obj, "plain string"} {
+		buf, err := f.Marshal(in)
+		a.Expect(err).To(BeNil())
+		out, err := f.Unmarshal(buf)
+		a.Expect(err).To(BeNil())
+		a.Expect(out).To(Equal(in))
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	a := NewWithT(t)
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		schema, _ := json.Marshal(avroSchema)
+		switch r.URL.Path {
+		case "/subjects/telemetry-value/versions/latest", "/schemas/ids/7":
+			fmt.Fprintf(w, `{"subject": "telemetry-value", "version": 3, "id": 7, "schema": %s}`, string(schema))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error_code": 40401, "message": "not found"}`)
+		}
+	}))
+	defer server.Close()
+
+	f, err := format.New(format.Avro, &format.Schema{
+		Registry: &format.Registry{
+			Url:     server.URL,
+			Subject: "telemetry-value",
+		},
+		ConfluentHeader: true,
+	})
+	a.Expect(err).To(BeNil())
+	var in interface{}
+	a.Expect(json.Unmarshal([]byte(`{"device": "d1", "seq": 1, "note": null, "readings": [], "tags": {}, "last": null}`), &in)).To(BeNil())
+	for i := 0; i < 3; i++ {
+		buf, err := f.Marshal(in)
+		a.Expect(err).To(BeNil())
+		a.Expect(buf[:5]).To(Equal([]byte{0, 0, 0, 0, 7}))
+		out, err := f.Unmarshal(buf)
+		a.Expect(err).To(BeNil())
+		a.Expect(out).To(Equal(in))
+	}
+	// the subject lookup also caches the schema by its id
+	a.Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+	buf, err := f.Marshal(in)
+	a.Expect(err).To(BeNil())
+	buf[4] = 9
+	var rerr *format.RegistryError
+	for i := 0; i < 3; i++ {
+		_, err = f.Unmarshal(buf)
+		a.Expect(errors.As(err, &rerr)).To(BeTrue())
+		a.Expect(rerr.StatusCode).To(Equal(http.StatusNotFound))
+	}
+	// failed lookups are cached as well
+	a.Expect(atomic.LoadInt32(&requests)).To(Equal(int32(2)))
+
+	f, err = format.New(format.Avro, &format.Schema{
+		Registry: &format.Registry{
+			Url: server.URL,
+			Id:  8,
+		},
+	})
+	a.Expect(err).To(BeNil())
+	_, err = f.Marshal(in)
+	a.Expect(errors.As(err, &rerr)).To(BeTrue())
+	a.Expect(rerr.StatusCode).To(Equal(http.StatusNotFound))
+}
+
+func TestProtobufConfluentHeader(t *testing.T) {
+	a := NewWithT(t)
+	schema, _ := json.Marshal(protoSchema)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"id": 3, "schema": %s}`, string(schema))
+	}))
+	defer server.Close()
+	f, err := format.New(format.Protobuf, &format.Schema{
+		Registry:        &format.Registry{Url: server.URL, Id: 3},
+		MessageType:     "example.Envelope.Header",
+		ConfluentHeader: true,
+	})
+	a.Expect(err).To(BeNil())
+	in := map[string]interface{}{"id": "abc"}
+	buf, err := f.Marshal(in)
+	a.Expect(err).To(BeNil())
+	// message indexes [0, 0]: count 2 followed by two zeros as zigzag varints
+	a.Expect(buf[4:8]).To(Equal([]byte{3, 4, 0, 0}))
+	// a reader without a message type resolves the message from the indexes
+	reader, err := format.New(format.Protobuf, &format.Schema{
+		Registry:        &format.Registry{Url: server.URL, Id: 3},
+		ConfluentHeader: true,
+	})
+	a.Expect(err).To(BeNil())
+	out, err := reader.Unmarshal(buf)
+	a.Expect(err).To(BeNil())
+	a.Expect(out).To(Equal(in))
+}
+
+func TestProtobufMalformedHeader(t *testing.T) {
+	a := NewWithT(t)
+	schema, _ := json.Marshal(protoSchema)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"id": 1, "schema": %s}`, string(schema))
+	}))
+	defer server.Close()
+	f, err := format.New(format.Protobuf, &format.Schema{
+		Registry:        &format.Registry{Url: server.URL, Id: 1},
+		ConfluentHeader: true,
+	})
+	a.Expect(err).To(BeNil())
+	for _, buf := range [][]byte{
+		{0x00},
+		{0x00, 0x00, 0x00, 0x00, 0x01},
+		// a huge message index count
+		{0x00, 0x00, 0x00, 0x00, 0x01, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f},
+		// more indexes than bytes left
+		{0x00, 0x00, 0x00, 0x00, 0x01, 0x08, 0x00},
+		// a negative message index count
+		{0x00, 0x00, 0x00, 0x00, 0x01, 0x01},
+	} {
+		_, err = f.Unmarshal(buf)
+		a.Expect(err).ToNot(BeNil(), fmt.Sprintf("header %v", buf))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		format string
+		schema *format.Schema
+		valid  bool
+	}{
+		{"json", format.Json, nil, true},
+		{"msgpack", format.MsgPack, nil, true},
+		{"unknownFormat", "xml", nil, false},
+		{"noSchema", format.Avro, nil, false},
+		{"emptySchema", format.Avro, &format.Schema{}, false},
+		{"twoSources", format.Avro, &format.Schema{Inline: "x", File: "y"}, false},
+		{"noRegistryUrl", format.Avro, &format.Schema{Registry: &format.Registry{Subject: "s"}}, false},
+		{"noRegistrySubject", format.Avro, &format.Schema{Registry: &format.Registry{Url: "http://localhost"}}, false},
+		{"inlineConfluentHeader", format.Avro, &format.Schema{Inline: avroSchema, ConfluentHeader: true}, false},
+		{"registryId", format.Protobuf, &format.Schema{Registry: &format.Registry{Url: "http://localhost", Id: 1}}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewWithT(t)
+			err := format.Validate(tc.format, tc.schema)
+			if tc.valid {
+				a.Expect(err).To(BeNil())
+			} else {
+				a.Expect(err).ToNot(BeNil())
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/xmidt-org/ears/pkg/filter"
+)
+
+// protobufSchema converts between protobuf binary and values following the
+// proto3 JSON mapping, with the original (not camel cased) field names
+type protobufSchema struct {
+	file    *desc.FileDescriptor
+	message *desc.MessageDescriptor
+	// messageSet is true when a message type was configured explicitly,
+	// in which case message indexes in confluent headers are ignored
+	messageSet bool
+}
+
+// compileProtobuf parses .proto source. A schema read from a file is parsed
+// from its directory so that it may import neighbouring files.
+func compileProtobuf(text string, file string, messageType string) (*protobufSchema, error) {
+	var parser protoparse.Parser
+	name := protobufDefaultFileName
+	if file != "" {
+		name = filepath.Base(file)
+		parser.ImportPaths = []string{filepath.Dir(file)}
+		// imports must not leave the filter file directory either
+		parser.Accessor = func(path string) (io.ReadCloser, error) {
+			if !filter.IsFileAllowed(path) {
+				return nil, &filter.FileNotAllowedError{File: path, Reason: "path leaves the filter file directory"}
+			}
+			return os.Open(path)
+		}
+	} else {
+		parser.Accessor = protoparse.FileContentsFromMap(map[string]string{name: text})
+	}
+	fds, err := parser.ParseFiles(name)
+	if err != nil {
+		return nil, err
+	}
+	fd := fds[0]
+	s := &protobufSchema{
+		file:       fd,
+		messageSet: messageType != "",
+	}
+	if messageType != "" {
+		s.message = fd.FindMessage(messageType)
+		if s.message == nil && fd.GetPackage() != "" {
+			s.message = fd.FindMessage(fd.GetPackage() + "." + messageType)
+		}
+		if s.message == nil {
+			return nil, errors.New("unknown message type " + messageType)
+		}
+	} else {
+		if len(fd.GetMessageTypes()) == 0 {
+			return nil, errors.New("schema defines no messages")
+		}
+		s.message = fd.GetMessageTypes()[0]
+	}
+	return s, nil
+}
+
+func (s *protobufSchema) marshal(v interface{}) ([]byte, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	msg := dynamic.NewMessage(s.message)
+	err = msg.UnmarshalJSONPB(&jsonpb.Unmarshaler{}, buf)
+	if err != nil {
+		return nil, err
+	}
+	return msg.Marshal()
+}
+
+func (s *protobufSchema) unmarshal(buf []byte, indexes []int) (interface{}, error) {
+	md := s.message
+	if indexes != nil && !s.messageSet {
+		var err error
+		md, err = s.messageAt(indexes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	msg := dynamic.NewMessage(md)
+	err := msg.Unmarshal(buf)
+	if err != nil {
+		return nil, err
+	}
+	js, err := msg.MarshalJSONPB(&jsonpb.Marshaler{OrigName: true})
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	err = json.Unmarshal(js, &v)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// indexes returns the path of the message within the file, e.g. [1, 0]
+// for the first nested message of the second top level message
+func (s *protobufSchema) indexes() []int {
+	var path []int
+	var d desc.Descriptor = s.message
+	for {
+		md, ok := d.(*desc.MessageDescriptor)
+		if !ok {
+			break
+		}
+		var siblings []*desc.MessageDescriptor
+		parent := md.GetParent()
+		if pmd, ok := parent.(*desc.MessageDescriptor); ok {
+			siblings = pmd.GetNestedMessageTypes()
+		} else {
+			siblings = s.file.GetMessageTypes()
+		}
+		for i, sibling := range siblings {
+			if sibling == md {
+				path = append([]int{i}, path...)
+				break
+			}
+		}
+		d = parent
+	}
+	return path
+}
+
+func (s *protobufSchema) messageAt(indexes []int) (*desc.MessageDescriptor, error) {
+	siblings := s.file.GetMessageTypes()
+	var md *desc.MessageDescriptor
+	for _, idx := range indexes {
+		if idx >= len(siblings) {
+			return nil, errors.New("message index out of range")
+		}
+		md = siblings[idx]
+		siblings = md.GetNestedMessageTypes()
+	}
+	if md == nil {
+		return nil, errors.New("empty message indexes")
+	}
+	return md, nil
+}
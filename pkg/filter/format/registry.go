@@ -0,0 +1,84 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const registryTimeout = 10 * time.Second
+
+// registryClient talks to a schema registry compatible HTTP endpoint
+type registryClient struct {
+	url    string
+	client *http.Client
+}
+
+type registrySchema struct {
+	Id     int    `json:"id"`
+	Schema string `json:"schema"`
+}
+
+func newRegistryClient(registryUrl string) *registryClient {
+	return &registryClient{
+		url: strings.TrimRight(registryUrl, "/"),
+		client: &http.Client{
+			Timeout: registryTimeout,
+		},
+	}
+}
+
+func (r *registryClient) schemaById(id int) (string, error) {
+	s, err := r.get(fmt.Sprintf("%s/schemas/ids/%d", r.url, id))
+	if err != nil {
+		return "", err
+	}
+	return s.Schema, nil
+}
+
+func (r *registryClient) schemaBySubject(subject string, version string) (int, string, error) {
+	s, err := r.get(fmt.Sprintf("%s/subjects/%s/versions/%s", r.url, url.PathEscape(subject), url.PathEscape(version)))
+	if err != nil {
+		return 0, "", err
+	}
+	return s.Id, s.Schema, nil
+}
+
+func (r *registryClient) get(u string) (*registrySchema, error) {
+	resp, err := r.client.Get(u)
+	if err != nil {
+		return nil, &RegistryError{Url: u, Err: err}
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &RegistryError{Url: u, StatusCode: resp.StatusCode, Err: err}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &RegistryError{Url: u, StatusCode: resp.StatusCode, Err: fmt.Errorf("%s", string(body))}
+	}
+	var s registrySchema
+	err = json.Unmarshal(body, &s)
+	if err != nil {
+		return nil, &RegistryError{Url: u, StatusCode: resp.StatusCode, Err: err}
+	}
+	return &s, nil
+}
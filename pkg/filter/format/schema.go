@@ -0,0 +1,364 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/xmidt-org/ears/pkg/filter"
+)
+
+// Schema describes where the schema of an avro or protobuf format comes
+// from. Exactly one of Inline, File or Registry must be set.
+type Schema struct {
+	// Inline is the schema itself, an avro schema may also be given as
+	// a JSON object rather than a string
+	Inline interface{} `json:"inline,omitempty"`
+	// File is the path of a schema file (.avsc or .proto) relative to
+	// the filter file directory
+	File     string    `json:"file,omitempty"`
+	Registry *Registry `json:"registry,omitempty"`
+	// MessageType is the fully qualified name of the protobuf message,
+	// defaults to the first message of the schema
+	MessageType string `json:"messageType,omitempty"`
+	// ConfluentHeader prefixes encoded values with the magic byte and
+	// schema id used by schema registry aware producers and consumers
+	ConfluentHeader bool `json:"confluentHeader,omitempty"`
+}
+
+// Registry is a schema registry compatible HTTP endpoint. Either a subject
+// or a schema id must be given.
+type Registry struct {
+	Url     string `json:"url,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	// Version of the subject, defaults to latest
+	Version string `json:"version,omitempty"`
+	Id      int    `json:"id,omitempty"`
+	// CacheTtlSeconds controls how often the latest version of a subject
+	// is looked up again, schemas by id are immutable and cached forever
+	CacheTtlSeconds *int `json:"cacheTtlSeconds,omitempty"`
+}
+
+const (
+	DefaultRegistryVersion = "latest"
+	DefaultCacheTtlSeconds = 300
+	// MaxCachedSchemas caps the number of registry schemas cached by id
+	MaxCachedSchemas = 100
+	// failedLookupTtl is how long a failed registry lookup by id is
+	// remembered, so that messages with unknown ids do not hit the
+	// registry one by one
+	failedLookupTtl         = 10 * time.Second
+	confluentMagicByte      = byte(0)
+	confluentHeaderLength   = 5
+	protobufDefaultFileName = "schema.proto"
+)
+
+func (s *Schema) Validate() error {
+	sources := 0
+	if s.Inline != nil {
+		sources++
+	}
+	if s.File != "" {
+		sources++
+	}
+	if s.Registry != nil {
+		sources++
+		if s.Registry.Url == "" {
+			return errors.New("schema registry requires a url")
+		}
+		if s.Registry.Subject == "" && s.Registry.Id == 0 {
+			return errors.New("schema registry requires a subject or an id")
+		}
+	}
+	if sources != 1 {
+		return errors.New("schema requires exactly one of inline, file or registry")
+	}
+	if s.ConfluentHeader && s.Registry == nil {
+		return errors.New("confluentHeader requires a schema registry")
+	}
+	return nil
+}
+
+// compiledSchema is a schema ready to convert values
+type compiledSchema interface {
+	marshal(v interface{}) ([]byte, error)
+	// unmarshal decodes buf, indexes are the protobuf message indexes
+	// of a confluent header, if any
+	unmarshal(buf []byte, indexes []int) (interface{}, error)
+	// indexes returns the protobuf message indexes for a confluent header
+	indexes() []int
+}
+
+// schemaFormat compiles schemas once and caches registry lookups for the
+// lifetime of the filter it belongs to. Registry requests are made without
+// holding the lock, the caches are safe for concurrent use
+type schemaFormat struct {
+	sync.Mutex
+	name     string
+	schema   Schema
+	registry *registryClient
+	ttl      time.Duration
+	static   compiledSchema
+	byId     *lru.Cache // schema id -> compiledSchema
+	failed   *lru.Cache // schema id -> failedLookup
+	latest   compiledSchema
+	latestId int
+	latestAt time.Time
+}
+
+type failedLookup struct {
+	err   error
+	until time.Time
+}
+
+func newSchemaFormat(name string, schema *Schema) (*schemaFormat, error) {
+	err := schema.Validate()
+	if err != nil {
+		return nil, &InvalidSchemaError{Format: name, Err: err}
+	}
+	f := &schemaFormat{
+		name:   name,
+		schema: *schema,
+	}
+	if schema.Registry != nil {
+		f.byId, err = lru.New(MaxCachedSchemas)
+		if err != nil {
+			return nil, err
+		}
+		f.failed, err = lru.New(MaxCachedSchemas)
+		if err != nil {
+			return nil, err
+		}
+		f.registry = newRegistryClient(schema.Registry.Url)
+		ttl := DefaultCacheTtlSeconds
+		if schema.Registry.CacheTtlSeconds != nil {
+			ttl = *schema.Registry.CacheTtlSeconds
+		}
+		f.ttl = time.Duration(ttl) * time.Second
+		return f, nil
+	}
+	var text string
+	switch inline := schema.Inline.(type) {
+	case nil:
+		path, err := filter.ResolveFile(schema.File)
+		if err != nil {
+			return nil, &InvalidSchemaError{Format: name, Err: err}
+		}
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, &InvalidSchemaError{Format: name, Err: err}
+		}
+		f.schema.File = path
+		text = string(buf)
+	case string:
+		text = inline
+	default:
+		buf, err := json.Marshal(inline)
+		if err != nil {
+			return nil, &InvalidSchemaError{Format: name, Err: err}
+		}
+		text = string(buf)
+	}
+	f.static, err = f.compile(text)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *schemaFormat) compile(text string) (compiledSchema, error) {
+	var c compiledSchema
+	var err error
+	if f.name == Avro {
+		c, err = compileAvro(text)
+	} else {
+		c, err = compileProtobuf(text, f.schema.File, f.schema.MessageType)
+	}
+	if err != nil {
+		return nil, &InvalidSchemaError{Format: f.name, Err: err}
+	}
+	return c, nil
+}
+
+func (f *schemaFormat) Name() string {
+	return f.name
+}
+
+func (f *schemaFormat) Binary() bool {
+	return true
+}
+
+func (f *schemaFormat) Marshal(v interface{}) ([]byte, error) {
+	c, id, err := f.current()
+	if err != nil {
+		return nil, err
+	}
+	buf, err := c.marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if !f.schema.ConfluentHeader {
+		return buf, nil
+	}
+	header := make([]byte, confluentHeaderLength, confluentHeaderLength+len(buf)+8)
+	header[0] = confluentMagicByte
+	binary.BigEndian.PutUint32(header[1:], uint32(id))
+	if f.name == Protobuf {
+		header = appendMessageIndexes(header, c.indexes())
+	}
+	return append(header, buf...), nil
+}
+
+func (f *schemaFormat) Unmarshal(buf []byte) (interface{}, error) {
+	if !f.schema.ConfluentHeader {
+		c, _, err := f.current()
+		if err != nil {
+			return nil, err
+		}
+		return c.unmarshal(buf, nil)
+	}
+	if len(buf) < confluentHeaderLength || buf[0] != confluentMagicByte {
+		return nil, errors.New("missing confluent header")
+	}
+	id := int(binary.BigEndian.Uint32(buf[1:confluentHeaderLength]))
+	buf = buf[confluentHeaderLength:]
+	var indexes []int
+	if f.name == Protobuf {
+		var err error
+		indexes, buf, err = readMessageIndexes(buf)
+		if err != nil {
+			return nil, err
+		}
+	}
+	c, err := f.getById(id)
+	if err != nil {
+		return nil, err
+	}
+	return c.unmarshal(buf, indexes)
+}
+
+// current returns the schema used for encoding and its registry id
+func (f *schemaFormat) current() (compiledSchema, int, error) {
+	if f.static != nil {
+		return f.static, 0, nil
+	}
+	if f.schema.Registry.Id != 0 {
+		c, err := f.getById(f.schema.Registry.Id)
+		return c, f.schema.Registry.Id, err
+	}
+	version := f.schema.Registry.Version
+	if version == "" {
+		version = DefaultRegistryVersion
+	}
+	f.Lock()
+	latest, latestId, latestAt := f.latest, f.latestId, f.latestAt
+	f.Unlock()
+	if latest != nil && (version != DefaultRegistryVersion || time.Since(latestAt) < f.ttl) {
+		return latest, latestId, nil
+	}
+	id, text, err := f.registry.schemaBySubject(f.schema.Registry.Subject, version)
+	if err != nil {
+		return nil, 0, err
+	}
+	c, err := f.cached(id, func() (string, error) { return text, nil })
+	if err != nil {
+		return nil, 0, err
+	}
+	f.Lock()
+	f.latest = c
+	f.latestId = id
+	f.latestAt = time.Now()
+	f.Unlock()
+	return c, id, nil
+}
+
+// getById returns the schema of a registry id, ids come from messages so
+// failed lookups are remembered for a while
+func (f *schemaFormat) getById(id int) (compiledSchema, error) {
+	return f.cached(id, func() (string, error) {
+		return f.registry.schemaById(id)
+	})
+}
+
+func (f *schemaFormat) cached(id int, fetch func() (string, error)) (compiledSchema, error) {
+	if c, ok := f.byId.Get(id); ok {
+		return c.(compiledSchema), nil
+	}
+	if v, ok := f.failed.Get(id); ok {
+		failed := v.(failedLookup)
+		if time.Now().Before(failed.until) {
+			return nil, failed.err
+		}
+		f.failed.Remove(id)
+	}
+	text, err := fetch()
+	var c compiledSchema
+	if err == nil {
+		c, err = f.compile(text)
+	}
+	if err != nil {
+		f.failed.Add(id, failedLookup{err: err, until: time.Now().Add(failedLookupTtl)})
+		return nil, err
+	}
+	f.byId.Add(id, c)
+	return c, nil
+}
+
+// appendMessageIndexes writes the protobuf message indexes of a confluent
+// header as zigzag varints, the common case [0] is written as a single 0
+func appendMessageIndexes(buf []byte, indexes []int) []byte {
+	if len(indexes) == 1 && indexes[0] == 0 {
+		return append(buf, 0)
+	}
+	tmp := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutVarint(tmp, int64(len(indexes)))
+	buf = append(buf, tmp[:n]...)
+	for _, idx := range indexes {
+		n = binary.PutVarint(tmp, int64(idx))
+		buf = append(buf, tmp[:n]...)
+	}
+	return buf
+}
+
+func readMessageIndexes(buf []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(buf)
+	if n <= 0 || count < 0 {
+		return nil, nil, errors.New("invalid message indexes")
+	}
+	buf = buf[n:]
+	if count == 0 {
+		return []int{0}, buf, nil
+	}
+	// every index takes at least one byte
+	if count > int64(len(buf)) {
+		return nil, nil, errors.New("invalid message indexes")
+	}
+	indexes := make([]int, 0, count)
+	for i := int64(0); i < count; i++ {
+		idx, n := binary.Varint(buf)
+		if n <= 0 || idx < 0 {
+			return nil, nil, errors.New("invalid message indexes")
+		}
+		indexes = append(indexes, int(idx))
+		buf = buf[n:]
+	}
+	return indexes, buf, nil
+}
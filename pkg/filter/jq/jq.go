@@ -0,0 +1,213 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/itchyny/gojq"
+	"github.com/rs/zerolog/log"
+	"github.com/xmidt-org/ears/pkg/event"
+	"github.com/xmidt-org/ears/pkg/filter"
+	"github.com/xmidt-org/ears/pkg/secret"
+	"github.com/xmidt-org/ears/pkg/tenant"
+)
+
+// compiled expressions are shared by all filter instances so that routes
+// using the same expression, or a route being updated, compile it only once
+var codeCache, _ = lru.New(1000)
+
+func compile(expression string) (*gojq.Code, error) {
+	if code, ok := codeCache.Get(expression); ok {
+		return code.(*gojq.Code), nil
+	}
+	query, err := gojq.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, err
+	}
+	codeCache.Add(expression, code)
+	return code, nil
+}
+
+func NewFilter(tid tenant.Id, plugin string, name string, config interface{}, secrets secret.Vault) (*Filter, error) {
+	cfg, err := NewConfig(config)
+	if err != nil {
+		return nil, &filter.InvalidConfigError{
+			Err: err,
+		}
+	}
+	cfg = cfg.WithDefaults()
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	code, err := compile(cfg.Expression)
+	if err != nil {
+		return nil, &filter.InvalidConfigError{
+			Err: err,
+		}
+	}
+	f := &Filter{
+		config: *cfg,
+		name:   name,
+		plugin: plugin,
+		tid:    tid,
+		code:   code,
+	}
+	return f, nil
+}
+
+// Filter evaluates the jq expression and stores the result at toPath. An
+// expression yielding no result, such as select(false), filters the event.
+func (f *Filter) Filter(evt event.Event) []event.Event {
+	if f == nil {
+		evt.Nack(&filter.InvalidConfigError{
+			Err: fmt.Errorf("<nil> pointer filter"),
+		})
+		return nil
+	}
+	traceId, _, _ := evt.GetPathValue(event.TRACE + ".id")
+	input := map[string]interface{}{
+		event.PAYLOAD:  normalize(evt.Payload()),
+		event.METADATA: normalize(evt.Metadata()),
+		event.TENANT: map[string]interface{}{
+			"orgId": evt.Tenant().OrgId,
+			"appId": evt.Tenant().AppId,
+		},
+		event.TRACE: map[string]interface{}{
+			"id": traceId,
+		},
+	}
+	results := make([]interface{}, 0)
+	// the deadline stops expressions which run away, such as an endless range
+	ctx, cancel := context.WithTimeout(evt.Context(), time.Duration(*f.config.Timeout)*time.Millisecond)
+	defer cancel()
+	iter := f.code.RunWithContext(ctx, input)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			evt.Nack(err)
+			return []event.Event{}
+		}
+		if len(results) == *f.config.MaxEvents {
+			evt.Nack(fmt.Errorf("jq expression yields more than %d events", *f.config.MaxEvents))
+			return []event.Event{}
+		}
+		results = append(results, normalize(v))
+		if !*f.config.MultipleEvents {
+			break
+		}
+	}
+	log.Ctx(evt.Context()).Debug().Str("op", "filter").Str("filterType", "jq").Str("name", f.Name()).Int("resultCount", len(results)).Msg("jq")
+	if len(results) == 0 {
+		evt.Ack()
+		return []event.Event{}
+	}
+	if len(results) == 1 {
+		evt.SetPathValue(f.config.ToPath, results[0], true)
+		return []event.Event{evt}
+	}
+	events := make([]event.Event, 0, len(results))
+	for _, result := range results {
+		nevt, err := evt.Clone(evt.Context())
+		if err != nil {
+			evt.Nack(err)
+			return nil
+		}
+		nevt.SetPathValue(f.config.ToPath, result, true)
+		events = append(events, nevt)
+	}
+	evt.Ack()
+	return events
+}
+
+// normalize copies a value into the types understood by gojq (maps, slices,
+// strings, bools, nil and float64 numbers), falling back to a JSON round
+// trip for anything else such as structs stored in metadata
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, bool, string, float64:
+		return v
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = normalize(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = normalize(e)
+		}
+		return a
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f
+	case []byte:
+		return string(v)
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var out interface{}
+	if json.Unmarshal(buf, &out) != nil {
+		return nil
+	}
+	return out
+}
+
+func (f *Filter) Config() interface{} {
+	if f == nil {
+		return Config{}
+	}
+	return f.config
+}
+
+func (f *Filter) Name() string {
+	return f.name
+}
+
+func (f *Filter) Plugin() string {
+	return f.plugin
+}
+
+func (f *Filter) Tenant() tenant.Id {
+	return f.tid
+}
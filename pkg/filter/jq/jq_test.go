@@ -0,0 +1,165 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jq_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xmidt-org/ears/pkg/event"
+	"github.com/xmidt-org/ears/pkg/filter"
+	"github.com/xmidt-org/ears/pkg/filter/jq"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"github.com/xorcare/pointer"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestJqTransform(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   jq.Config
+		payload  interface{}
+		expected []interface{}
+	}{
+		{
+			name:     "reshape",
+			config:   jq.Config{Expression: "{id: .payload.device.id, temp: (.payload.temp * 2)}"},
+			payload:  map[string]interface{}{"device": map[string]interface{}{"id": "d1"}, "temp": 10},
+			expected: []interface{}{map[string]interface{}{"id": "d1", "temp": float64(20)}},
+		},
+		{
+			name:     "context",
+			config:   jq.Config{Expression: "[.tenant.orgId, .metadata.topic]", ToPath: ".ctx"},
+			payload:  map[string]interface{}{"a": "b"},
+			expected: []interface{}{map[string]interface{}{"a": "b", "ctx": []interface{}{"myorg", "devices"}}},
+		},
+		{
+			name:     "firstResult",
+			config:   jq.Config{Expression: ".payload.items[]"},
+			payload:  map[string]interface{}{"items": []interface{}{"x", "y"}},
+			expected: []interface{}{"x"},
+		},
+		{
+			name:     "multipleEvents",
+			config:   jq.Config{Expression: ".payload.items[]", MultipleEvents: pointer.Bool(true)},
+			payload:  map[string]interface{}{"items": []interface{}{"x", "y", "z"}},
+			expected: []interface{}{"x", "y", "z"},
+		},
+		{
+			name:     "select",
+			config:   jq.Config{Expression: "select(.payload.temp > 50)"},
+			payload:  map[string]interface{}{"temp": 10},
+			expected: []interface{}{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewWithT(t)
+			f, err := jq.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "jq", "myjq", tc.config, nil)
+			a.Expect(err).To(BeNil())
+			acked := make(chan error, 1)
+			e, err := event.New(context.Background(), tc.payload,
+				event.WithTenant(tenant.Id{AppId: "myapp", OrgId: "myorg"}),
+				event.WithMetadata(map[string]interface{}{"topic": "devices"}),
+				event.WithAck(
+					func(e event.Event) {
+						acked <- nil
+					}, func(e event.Event, err error) {
+						acked <- err
+					}))
+			a.Expect(err).To(BeNil())
+			evts := f.Filter(e)
+			a.Expect(evts).To(HaveLen(len(tc.expected)))
+			for i, evt := range evts {
+				a.Expect(evt.Payload()).To(Equal(tc.expected[i]))
+				evt.Ack()
+			}
+			a.Eventually(acked, time.Second).Should(Receive(BeNil()))
+		})
+	}
+}
+
+func TestJqRuntimeError(t *testing.T) {
+	a := NewWithT(t)
+	f, err := jq.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "jq", "myjq", jq.Config{Expression: "error(\"bad event\")"}, nil)
+	a.Expect(err).To(BeNil())
+	acked := make(chan error, 1)
+	e, err := event.New(context.Background(), map[string]interface{}{}, event.WithAck(
+		func(e event.Event) {
+			acked <- nil
+		}, func(e event.Event, err error) {
+			acked <- err
+		}))
+	a.Expect(err).To(BeNil())
+	a.Expect(f.Filter(e)).To(HaveLen(0))
+	a.Eventually(acked, time.Second).Should(Receive(HaveOccurred()))
+}
+
+func TestJqLimits(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config jq.Config
+	}{
+		{
+			name:   "maxEvents",
+			config: jq.Config{Expression: "range(10)", MultipleEvents: pointer.Bool(true), MaxEvents: pointer.Int(5)},
+		},
+		{
+			name:   "unboundedEvents",
+			config: jq.Config{Expression: "range(infinite)", MultipleEvents: pointer.Bool(true)},
+		},
+		{
+			name:   "timeout",
+			config: jq.Config{Expression: "last(range(infinite))", Timeout: pointer.Int(50)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewWithT(t)
+			f, err := jq.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "jq", "myjq", tc.config, nil)
+			a.Expect(err).To(BeNil())
+			acked := make(chan error, 1)
+			e, err := event.New(context.Background(), map[string]interface{}{}, event.WithAck(
+				func(e event.Event) {
+					acked <- nil
+				}, func(e event.Event, err error) {
+					acked <- err
+				}))
+			a.Expect(err).To(BeNil())
+			a.Expect(f.Filter(e)).To(HaveLen(0))
+			a.Eventually(acked, time.Second).Should(Receive(HaveOccurred()))
+		})
+	}
+}
+
+func TestJqInvalidConfig(t *testing.T) {
+	a := NewWithT(t)
+	for _, config := range []jq.Config{{MaxEvents: pointer.Int(0)}, {Timeout: pointer.Int(-1)}} {
+		_, err := jq.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "jq", "myjq", config, nil)
+		var cfgErr *filter.InvalidConfigError
+		a.Expect(errors.As(err, &cfgErr)).To(BeTrue())
+	}
+}
+
+func TestJqInvalidExpression(t *testing.T) {
+	a := NewWithT(t)
+	f, err := jq.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "jq", "myjq", jq.Config{Expression: ".payload | {"}, nil)
+	var cfgErr *filter.InvalidConfigError
+	a.Expect(errors.As(err, &cfgErr)).To(BeTrue())
+	a.Expect(f).To(BeNil())
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jq
+
+import (
+	"github.com/itchyny/gojq"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"github.com/xorcare/pointer"
+)
+
+// Config can be passed into NewFilter() in order to configure
+// the behavior of the filter.
+type Config struct {
+	// Expression is a jq expression evaluated against an object with the
+	// fields payload, metadata, tenant and trace
+	Expression string `json:"expression,omitempty"`
+	// ToPath is where the result is stored, defaults to the entire payload
+	ToPath string `json:"toPath,omitempty"`
+	// MultipleEvents emits one event per result when the expression yields
+	// a stream, otherwise only the first result is used
+	MultipleEvents *bool `json:"multipleEvents,omitempty"`
+	// MaxEvents is the maximum number of results with multipleEvents, events
+	// whose expression yields more results are nacked
+	MaxEvents *int `json:"maxEvents,omitempty"`
+	// Timeout in ms bounds the evaluation of the expression, events taking
+	// longer are nacked
+	Timeout *int `json:"timeout,omitempty"`
+}
+
+var DefaultConfig = Config{
+	Expression:     ".payload",
+	ToPath:         "",
+	MultipleEvents: pointer.Bool(false),
+	MaxEvents:      pointer.Int(100),
+	Timeout:        pointer.Int(1000),
+}
+
+type Filter struct {
+	config Config
+	name   string
+	plugin string
+	tid    tenant.Id
+	code   *gojq.Code
+}
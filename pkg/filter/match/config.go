@@ -41,7 +41,8 @@ func (c Config) WithDefaults() *Config {
 	if c.Matcher == MatcherUnknown {
 		cfg.Matcher = DefaultConfig.Matcher
 	}
-	if c.Pattern == nil {
+	// an expression has no sensible default, leave it to validation
+	if c.Pattern == nil && cfg.Matcher != MatcherExpression {
 		cfg.Pattern = DefaultConfig.Pattern
 	}
 	if c.ExactArrayMatch == nil {
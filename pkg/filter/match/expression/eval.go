@@ -0,0 +1,504 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expression
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xmidt-org/ears/pkg/event"
+	"github.com/xmidt-org/ears/pkg/filter"
+)
+
+type node interface {
+	typ() valueType
+	eval(evt event.Event) interface{}
+}
+
+type literalNode struct {
+	v interface{}
+	t valueType
+}
+
+func (n *literalNode) typ() valueType {
+	return n.t
+}
+
+func (n *literalNode) eval(evt event.Event) interface{} {
+	return n.v
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) typ() valueType {
+	return typeList
+}
+
+func (n *listNode) eval(evt event.Event) interface{} {
+	list := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		list[i] = item.eval(evt)
+	}
+	return list
+}
+
+type pathNode struct {
+	path     string
+	segments []string
+}
+
+func (n *pathNode) typ() valueType {
+	return typeAny
+}
+
+func (n *pathNode) eval(evt event.Event) interface{} {
+	v, _ := n.lookup(evt)
+	return normalize(v)
+}
+
+// lookup walks maps and arrays without assuming the shape of the event, the
+// second return value reports whether the path exists
+func (n *pathNode) lookup(evt event.Event) (interface{}, bool) {
+	var obj interface{}
+	switch n.segments[0] {
+	case event.PAYLOAD:
+		obj = evt.Payload()
+	case event.METADATA:
+		obj = evt.Metadata()
+	default:
+		v, _, _ := evt.GetPathValue(n.path)
+		return v, v != nil
+	}
+	for _, s := range n.segments[1:] {
+		switch o := obj.(type) {
+		case map[string]interface{}:
+			v, ok := o[s]
+			if !ok {
+				return nil, false
+			}
+			obj = v
+		case []interface{}:
+			i, err := strconv.Atoi(s)
+			if err != nil || i < 0 || i >= len(o) {
+				return nil, false
+			}
+			obj = o[i]
+		default:
+			return nil, false
+		}
+	}
+	return obj, true
+}
+
+type notNode struct {
+	x node
+}
+
+func (n *notNode) typ() valueType {
+	return typeBool
+}
+
+func (n *notNode) eval(evt event.Event) interface{} {
+	return !truthy(n.x.eval(evt))
+}
+
+type logicalNode struct {
+	or   bool
+	l, r node
+}
+
+func (n *logicalNode) typ() valueType {
+	return typeBool
+}
+
+func (n *logicalNode) eval(evt event.Event) interface{} {
+	if n.or {
+		return truthy(n.l.eval(evt)) || truthy(n.r.eval(evt))
+	}
+	return truthy(n.l.eval(evt)) && truthy(n.r.eval(evt))
+}
+
+type compareNode struct {
+	op   string
+	l, r node
+}
+
+func (n *compareNode) typ() valueType {
+	return typeBool
+}
+
+func (n *compareNode) eval(evt event.Event) interface{} {
+	l := n.l.eval(evt)
+	r := n.r.eval(evt)
+	switch n.op {
+	case "==":
+		return equal(l, r)
+	case "!=":
+		return !equal(l, r)
+	}
+	c, ok := compare(l, r)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+type inNode struct {
+	l, r node
+}
+
+func (n *inNode) typ() valueType {
+	return typeBool
+}
+
+func (n *inNode) eval(evt event.Event) interface{} {
+	return contains(n.r.eval(evt), n.l.eval(evt))
+}
+
+type regexNode struct {
+	x  node
+	re *regexp.Regexp
+}
+
+func (n *regexNode) typ() valueType {
+	return typeBool
+}
+
+func (n *regexNode) eval(evt event.Event) interface{} {
+	s, ok := n.x.eval(evt).(string)
+	return ok && n.re.MatchString(s)
+}
+
+type function struct {
+	args []valueType
+	ret  valueType
+	call func(args []interface{}) interface{}
+}
+
+var functions = map[string]*function{
+	"exists": {
+		args: []valueType{typeAny},
+		ret:  typeBool,
+	},
+	"len": {
+		args: []valueType{typeAny},
+		ret:  typeNumber,
+		call: func(args []interface{}) interface{} {
+			switch v := args[0].(type) {
+			case string:
+				return float64(len(v))
+			case []interface{}:
+				return float64(len(v))
+			case map[string]interface{}:
+				return float64(len(v))
+			}
+			return nil
+		},
+	},
+	"lower": {
+		args: []valueType{typeString},
+		ret:  typeString,
+		call: stringFunction(strings.ToLower),
+	},
+	"upper": {
+		args: []valueType{typeString},
+		ret:  typeString,
+		call: stringFunction(strings.ToUpper),
+	},
+	"contains": {
+		args: []valueType{typeAny, typeAny},
+		ret:  typeBool,
+		call: func(args []interface{}) interface{} {
+			return contains(args[0], args[1])
+		},
+	},
+	"startsWith": {
+		args: []valueType{typeString, typeString},
+		ret:  typeBool,
+		call: stringPredicate(strings.HasPrefix),
+	},
+	"endsWith": {
+		args: []valueType{typeString, typeString},
+		ret:  typeBool,
+		call: stringPredicate(strings.HasSuffix),
+	},
+	"number": {
+		args: []valueType{typeAny},
+		ret:  typeNumber,
+		call: func(args []interface{}) interface{} {
+			switch v := args[0].(type) {
+			case float64:
+				return v
+			case string:
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return nil
+				}
+				return f
+			}
+			return nil
+		},
+	},
+	"string": {
+		args: []valueType{typeAny},
+		ret:  typeString,
+		call: func(args []interface{}) interface{} {
+			switch v := args[0].(type) {
+			case nil:
+				return nil
+			case string:
+				return v
+			case float64:
+				return strconv.FormatFloat(v, 'f', -1, 64)
+			}
+			return fmt.Sprint(args[0])
+		},
+	},
+	"time": {
+		args: []valueType{typeAny},
+		ret:  typeTime,
+		call: func(args []interface{}) interface{} {
+			t, ok := toTime(args[0])
+			if !ok {
+				return nil
+			}
+			return t
+		},
+	},
+	"now": {
+		args: []valueType{},
+		ret:  typeTime,
+		call: func(args []interface{}) interface{} {
+			return time.Now()
+		},
+	},
+	"age": {
+		args: []valueType{typeAny},
+		ret:  typeNumber,
+		call: func(args []interface{}) interface{} {
+			t, ok := toTime(args[0])
+			if !ok {
+				return nil
+			}
+			return time.Since(t).Seconds()
+		},
+	},
+	"duration": {
+		args: []valueType{typeString},
+		ret:  typeNumber,
+		call: func(args []interface{}) interface{} {
+			s, ok := args[0].(string)
+			if !ok {
+				return nil
+			}
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return nil
+			}
+			return d.Seconds()
+		},
+	},
+}
+
+func stringFunction(fn func(string) string) func(args []interface{}) interface{} {
+	return func(args []interface{}) interface{} {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil
+		}
+		return fn(s)
+	}
+}
+
+func stringPredicate(fn func(string, string) bool) func(args []interface{}) interface{} {
+	return func(args []interface{}) interface{} {
+		s, ok := args[0].(string)
+		if !ok {
+			return false
+		}
+		t, ok := args[1].(string)
+		return ok && fn(s, t)
+	}
+}
+
+type callNode struct {
+	name string
+	fn   *function
+	args []node
+}
+
+func (n *callNode) typ() valueType {
+	return n.fn.ret
+}
+
+func (n *callNode) eval(evt event.Event) interface{} {
+	if n.name == "exists" {
+		_, ok := n.args[0].(*pathNode).lookup(evt)
+		return ok
+	}
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.eval(evt)
+	}
+	return n.fn.call(args)
+}
+
+// normalize converts the numeric types found in events to float64
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return v.String()
+		}
+		return f
+	case []byte:
+		return string(v)
+	}
+	return v
+}
+
+func truthy(v interface{}) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
+
+func equal(l, r interface{}) bool {
+	if _, ok := l.(time.Time); ok {
+		c, ok := compare(l, r)
+		return ok && c == 0
+	}
+	if _, ok := r.(time.Time); ok {
+		c, ok := compare(l, r)
+		return ok && c == 0
+	}
+	return reflect.DeepEqual(normalize(l), normalize(r))
+}
+
+// compare orders two numbers, strings or times, converting the other operand
+// when one of them is a time. The second return value is false when the
+// operands cannot be ordered.
+func compare(l, r interface{}) (int, bool) {
+	l = normalize(l)
+	r = normalize(r)
+	_, lt := l.(time.Time)
+	_, rt := r.(time.Time)
+	if lt || rt {
+		a, ok := toTime(l)
+		if !ok {
+			return 0, false
+		}
+		b, ok := toTime(r)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case a.Before(b):
+			return -1, true
+		case a.After(b):
+			return 1, true
+		}
+		return 0, true
+	}
+	switch a := l.(type) {
+	case float64:
+		b, ok := r.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case a < b:
+			return -1, true
+		case a > b:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		b, ok := r.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(a, b), true
+	}
+	return 0, false
+}
+
+// contains reports whether the list contains the value or the string
+// contains the substring
+func contains(container interface{}, v interface{}) bool {
+	switch c := container.(type) {
+	case []interface{}:
+		for _, e := range c {
+			if equal(e, v) {
+				return true
+			}
+		}
+	case string:
+		s, ok := v.(string)
+		return ok && strings.Contains(c, s)
+	}
+	return false
+}
+
+// toTime accepts RFC3339 strings and epoch numbers in seconds, milliseconds,
+// microseconds or nanoseconds, the unit being inferred from the magnitude
+func toTime(v interface{}) (time.Time, bool) {
+	switch v := normalize(v).(type) {
+	case time.Time:
+		return v, true
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err == nil {
+			return t, true
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return filter.FromEpoch(f), true
+	case float64:
+		return filter.FromEpoch(v), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expression
+
+import (
+	"errors"
+
+	"github.com/xmidt-org/ears/pkg/event"
+)
+
+// Matcher evaluates a boolean expression such as payload.temp > 30 && metadata.region in ["us", "ca"]
+// against an event. Paths are rooted at payload, metadata, trace or tenant.
+type Matcher struct {
+	expression string
+	root       node
+}
+
+// NewMatcher parses and type checks the expression so that errors are
+// reported when the filter is created
+func NewMatcher(expression interface{}) (*Matcher, error) {
+	e, ok := expression.(string)
+	if !ok {
+		ee, ok := expression.(*string)
+		if !ok {
+			return nil, errors.New("expression is not a string")
+		}
+		e = *ee
+	}
+	root, err := parse(e)
+	if err != nil {
+		return nil, &InvalidExpressionError{Expression: e, Err: err}
+	}
+	return &Matcher{expression: e, root: root}, nil
+}
+
+func (m *Matcher) Match(evt event.Event) bool {
+	if m == nil || m.root == nil || evt == nil {
+		return false
+	}
+	return truthy(m.root.eval(evt))
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expression_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xmidt-org/ears/pkg/event"
+	"github.com/xmidt-org/ears/pkg/tenant"
+
+	"github.com/xmidt-org/ears/pkg/filter/match/expression"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestExpressionMatch(t *testing.T) {
+	ctx := context.Background()
+	a := NewWithT(t)
+	payload := map[string]interface{}{
+		"temp":   32.5,
+		"count":  7,
+		"name":   "Sensor-12",
+		"active": true,
+		"tags":   []interface{}{"a", "b"},
+		"items":  []interface{}{map[string]interface{}{"id": "x1"}},
+		"ts":     time.Now().Add(-2 * time.Minute).Format(time.RFC3339),
+		"epoch":  1609459200000,
+		"nested": map[string]interface{}{"level": map[string]interface{}{"deep": "yes"}},
+	}
+	metadata := map[string]interface{}{"region": "us"}
+	evt, err := event.New(ctx, payload, event.WithMetadata(metadata), event.WithTenant(tenant.Id{OrgId: "myorg", AppId: "myapp"}))
+	a.Expect(err).To(BeNil())
+
+	testCases := []struct {
+		expression string
+		match      bool
+	}{
+		{expression: `payload.temp > 30 && metadata.region in ["us", "ca"]`, match: true},
+		{expression: `payload.temp > 30 and metadata.region in ["eu"]`, match: false},
+		{expression: `.temp >= 32.5 || false`, match: true},
+		{expression: `payload.count == 7`, match: true},
+		{expression: `payload.count < -1`, match: false},
+		{expression: `not (payload.count != 7)`, match: true},
+		{expression: `!payload.active`, match: false},
+		{expression: `payload.active`, match: true},
+		{expression: `payload.name =~ "^Sensor-[0-9]+$"`, match: true},
+		{expression: `lower(payload.name) == 'sensor-12'`, match: true},
+		{expression: `startsWith(payload.name, "Sen") && endsWith(payload.name, "12")`, match: true},
+		{expression: `contains(payload.tags, "b") && len(payload.tags) == 2`, match: true},
+		{expression: `"c" in payload.tags`, match: false},
+		{expression: `payload.items.0.id == "x1"`, match: true},
+		{expression: `exists(payload.nested.level.deep) && !exists(payload.missing)`, match: true},
+		{expression: `payload.missing > 3`, match: false},
+		{expression: `payload.missing == null`, match: true},
+		{expression: `payload.name.sub == "x"`, match: false},
+		{expression: `age(payload.ts) < duration("5m")`, match: true},
+		{expression: `time(payload.epoch) == time("2021-01-01T00:00:00Z")`, match: true},
+		{expression: `time(payload.ts) > "2021-01-01T00:00:00Z" && time(payload.ts) < now()`, match: true},
+		{expression: `number("12") > 11 && string(payload.count) == "7"`, match: true},
+		{expression: `tenant.orgId == "myorg" && tenant.appId != "other"`, match: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			a := NewWithT(t)
+			m, err := expression.NewMatcher(tc.expression)
+			a.Expect(err).To(BeNil())
+			a.Expect(m.Match(evt)).To(Equal(tc.match))
+		})
+	}
+}
+
+func TestExpressionTypeCheck(t *testing.T) {
+	testCases := []string{
+		`payload.temp >`,
+		`payload.temp > 30 &&`,
+		`(payload.temp > 30`,
+		`body.temp > 30`,
+		`payload.temp > "abc" && 1 > "a"`,
+		`1 < true`,
+		`"a" in "abc"`,
+		`payload.name =~ payload.pattern`,
+		`payload.name =~ "[a"`,
+		`unknown(payload.name)`,
+		`lower(1) == "a"`,
+		`startsWith(payload.name)`,
+		`exists("a")`,
+		`payload.count + 1`,
+		`"abc"`,
+		`1 && payload.active`,
+		`payload.name == 'open`,
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc, func(t *testing.T) {
+			a := NewWithT(t)
+			m, err := expression.NewMatcher(tc)
+			a.Expect(m).To(BeNil())
+			a.Expect(err).ToNot(BeNil())
+		})
+	}
+}
@@ -0,0 +1,396 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expression
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xmidt-org/ears/pkg/event"
+)
+
+// valueType is the static type of an expression node. Values read from the
+// event are typeAny because their type is only known at runtime.
+type valueType int
+
+const (
+	typeAny valueType = iota
+	typeBool
+	typeNumber
+	typeString
+	typeTime
+	typeList
+	typeNull
+)
+
+func (t valueType) String() string {
+	return [...]string{"any", "bool", "number", "string", "time", "list", "null"}[t]
+}
+
+var roots = map[string]bool{
+	event.PAYLOAD:  true,
+	event.METADATA: true,
+	event.TRACE:    true,
+	event.TENANT:   true,
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse turns the expression into a type checked syntax tree
+func parse(input string) (node, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, p.errorf("unexpected %q", p.peek().text)
+	}
+	if n.typ() != typeBool && n.typ() != typeAny {
+		return nil, fmt.Errorf("expression must be boolean but is %s", n.typ())
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the given operators or keywords
+func (p *parser) accept(texts ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOp && t.kind != tokenIdent {
+		return "", false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			p.pos++
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		return p.errorf("expected %q", text)
+	}
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(format+" at position %d", append(args, p.peek().pos)...)
+}
+
+func checkBool(n node, op string) error {
+	if n.typ() != typeBool && n.typ() != typeAny {
+		return fmt.Errorf("operand of %s must be boolean but is %s", op, n.typ())
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||", "or"); !ok {
+			return l, nil
+		}
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkBool(l, "or"); err != nil {
+			return nil, err
+		}
+		if err := checkBool(r, "or"); err != nil {
+			return nil, err
+		}
+		l = &logicalNode{or: true, l: l, r: r}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	l, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&", "and"); !ok {
+			return l, nil
+		}
+		r, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkBool(l, "and"); err != nil {
+			return nil, err
+		}
+		if err := checkBool(r, "and"); err != nil {
+			return nil, err
+		}
+		l = &logicalNode{l: l, r: r}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.accept("!", "not"); ok {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkBool(x, "not"); err != nil {
+			return nil, err
+		}
+		return &notNode{x: x}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	l, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<", "<=", ">", ">=", "=~", "in")
+	if !ok {
+		return l, nil
+	}
+	r, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "in":
+		if r.typ() != typeList && r.typ() != typeAny {
+			return nil, fmt.Errorf("right operand of in must be a list but is %s", r.typ())
+		}
+		return &inNode{l: l, r: r}, nil
+	case "=~":
+		if l.typ() != typeString && l.typ() != typeAny {
+			return nil, fmt.Errorf("left operand of =~ must be a string but is %s", l.typ())
+		}
+		lit, ok := r.(*literalNode)
+		if !ok || lit.typ() != typeString {
+			return nil, fmt.Errorf("right operand of =~ must be a string literal")
+		}
+		re, err := regexp.Compile(lit.v.(string))
+		if err != nil {
+			return nil, err
+		}
+		return &regexNode{x: l, re: re}, nil
+	}
+	if !comparable(l.typ(), r.typ(), op) {
+		return nil, fmt.Errorf("cannot compare %s and %s with %s", l.typ(), r.typ(), op)
+	}
+	return &compareNode{op: op, l: l, r: r}, nil
+}
+
+// comparable reports whether two statically typed operands can be compared.
+// Times may be compared with strings and numbers which are converted at runtime.
+func comparable(l, r valueType, op string) bool {
+	if l == typeAny || r == typeAny {
+		return true
+	}
+	if l == typeTime && (r == typeString || r == typeNumber) || r == typeTime && (l == typeString || l == typeNumber) {
+		return true
+	}
+	if op == "==" || op == "!=" {
+		return l == r || l == typeNull || r == typeNull
+	}
+	return l == r && (l == typeNumber || l == typeString || l == typeTime)
+}
+
+func (p *parser) parseOperand() (node, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenNumber:
+		p.next()
+		return numberLiteral(t.text, false)
+	case tokenString:
+		p.next()
+		return &literalNode{v: t.text, t: typeString}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true", "false":
+			p.next()
+			return &literalNode{v: t.text == "true", t: typeBool}, nil
+		case "null":
+			p.next()
+			return &literalNode{v: nil, t: typeNull}, nil
+		}
+		if p.tokens[p.pos+1].kind == tokenOp && p.tokens[p.pos+1].text == "(" {
+			return p.parseCall()
+		}
+		return p.parsePath()
+	case tokenOp:
+		switch t.text {
+		case "-":
+			p.next()
+			n := p.next()
+			if n.kind != tokenNumber {
+				return nil, fmt.Errorf("expected number at position %d", n.pos)
+			}
+			return numberLiteral(n.text, true)
+		case ".":
+			return p.parsePath()
+		case "(":
+			p.next()
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		case "[":
+			return p.parseList()
+		}
+	}
+	if t.kind == tokenEOF {
+		return nil, p.errorf("unexpected end of expression")
+	}
+	return nil, p.errorf("unexpected %q", t.text)
+}
+
+func numberLiteral(text string, negative bool) (node, error) {
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, err
+	}
+	if negative {
+		f = -f
+	}
+	return &literalNode{v: f, t: typeNumber}, nil
+}
+
+// parsePath reads a dotted path such as payload.items.0.id, where a leading
+// dot is shorthand for the payload
+func (p *parser) parsePath() (node, error) {
+	segments := make([]string, 0)
+	if _, ok := p.accept("."); ok {
+		segments = append(segments, event.PAYLOAD)
+		if err := p.parseSegment(&segments); err != nil {
+			return nil, err
+		}
+	} else {
+		segments = append(segments, p.next().text)
+		if !roots[segments[0]] {
+			return nil, fmt.Errorf("unknown path root %s, must be one of payload, metadata, trace or tenant", segments[0])
+		}
+	}
+	for p.peekDot() {
+		p.next()
+		if err := p.parseSegment(&segments); err != nil {
+			return nil, err
+		}
+	}
+	return &pathNode{path: strings.Join(segments, "."), segments: segments}, nil
+}
+
+func (p *parser) parseSegment(segments *[]string) error {
+	t := p.next()
+	if t.kind != tokenIdent && t.kind != tokenNumber {
+		return fmt.Errorf("invalid path segment at position %d", t.pos)
+	}
+	// the lexer reads 0.1 as a number, in a path it is two array indexes
+	*segments = append(*segments, strings.Split(t.text, ".")...)
+	return nil
+}
+
+func (p *parser) peekDot() bool {
+	t := p.peek()
+	return t.kind == tokenOp && t.text == "."
+}
+
+func (p *parser) parseList() (node, error) {
+	p.next()
+	items := make([]node, 0)
+	if _, ok := p.accept("]"); ok {
+		return &listNode{items: items}, nil
+	}
+	for {
+		item, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if _, ok := p.accept("]"); ok {
+			return &listNode{items: items}, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseCall() (node, error) {
+	name := p.next()
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s at position %d", name.text, name.pos)
+	}
+	p.next()
+	args := make([]node, 0)
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(")"); ok {
+				break
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(args) != len(fn.args) {
+		return nil, fmt.Errorf("function %s expects %d arguments but got %d", name.text, len(fn.args), len(args))
+	}
+	for i, arg := range args {
+		if fn.args[i] != typeAny && arg.typ() != typeAny && arg.typ() != fn.args[i] {
+			return nil, fmt.Errorf("argument %d of %s must be %s but is %s", i+1, name.text, fn.args[i], arg.typ())
+		}
+	}
+	if name.text == "exists" {
+		if _, ok := args[0].(*pathNode); !ok {
+			return nil, fmt.Errorf("argument of exists must be a path")
+		}
+	}
+	return &callNode{name: name.text, fn: fn, args: args}, nil
+}
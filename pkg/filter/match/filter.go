@@ -19,6 +19,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/xmidt-org/ears/pkg/event"
 	"github.com/xmidt-org/ears/pkg/filter"
+	"github.com/xmidt-org/ears/pkg/filter/match/expression"
 	"github.com/xmidt-org/ears/pkg/filter/match/pattern"
 	"github.com/xmidt-org/ears/pkg/filter/match/patternregex"
 	"github.com/xmidt-org/ears/pkg/filter/match/regex"
@@ -41,42 +42,42 @@ func NewFilter(tid tenant.Id, plugin string, name string, config interface{}, se
 	if err != nil {
 		return nil, err
 	}
+	matcher, err := NewMatcher(cfg)
+	if err != nil {
+		return nil, err
+	}
+	f := &Filter{
+		config:  *cfg,
+		name:    name,
+		plugin:  plugin,
+		tid:     tid,
+		matcher: matcher,
+	}
+	return f, nil
+}
+
+// NewMatcher returns the matcher for a validated config with defaults applied
+func NewMatcher(cfg *Config) (Matcher, error) {
 	var matcher Matcher
+	var err error
 	switch cfg.Matcher {
 	case MatcherRegex:
 		matcher, err = regex.NewMatcher(cfg.Pattern)
-		if err != nil {
-			return nil, &filter.InvalidConfigError{
-				Err: err,
-			}
-		}
 	case MatcherPattern:
 		matcher, err = pattern.NewMatcher(cfg.Pattern, *cfg.ExactArrayMatch)
-		if err != nil {
-			return nil, &filter.InvalidConfigError{
-				Err: err,
-			}
-		}
 	case MatcherPatternRegex:
 		matcher, err = patternregex.NewMatcher(cfg.Pattern, *cfg.ExactArrayMatch)
-		if err != nil {
-			return nil, &filter.InvalidConfigError{
-				Err: err,
-			}
-		}
+	case MatcherExpression:
+		matcher, err = expression.NewMatcher(cfg.Pattern)
 	default:
+		err = fmt.Errorf("unsupported matcher type: %s", cfg.Matcher.String())
+	}
+	if err != nil {
 		return nil, &filter.InvalidConfigError{
-			Err: fmt.Errorf("unsupported matcher type: %s", cfg.Matcher.String()),
+			Err: err,
 		}
 	}
-	f := &Filter{
-		config:  *cfg,
-		name:    name,
-		plugin:  plugin,
-		tid:     tid,
-		matcher: matcher,
-	}
-	return f, nil
+	return matcher, nil
 }
 
 func (f *Filter) Filter(evt event.Event) []event.Event {
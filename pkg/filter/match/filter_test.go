@@ -287,6 +287,25 @@ func TestNewFilterBadConfig(t *testing.T) {
 		a.Expect(f).To(BeNil())
 	}
 
+	{
+		f, err := match.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "match", "mymatch", match.Config{
+			Mode:    match.ModeAllow,
+			Matcher: match.MatcherExpression,
+			Pattern: pointer.String("payload.temp > 'hot' && 1 > 'a'"),
+		}, nil)
+		a.Expect(err).ToNot(BeNil())
+		a.Expect(f).To(BeNil())
+	}
+
+	{
+		f, err := match.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "match", "mymatch", match.Config{
+			Mode:    match.ModeAllow,
+			Matcher: match.MatcherExpression,
+		}, nil)
+		a.Expect(err).ToNot(BeNil())
+		a.Expect(f).To(BeNil())
+	}
+
 }
 
 func TestNilFilter(t *testing.T) {
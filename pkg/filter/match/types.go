@@ -43,6 +43,7 @@ const (
 	MatcherRegex                           // regex
 	MatcherPattern                         // pattern
 	MatcherPatternRegex                    // patternregex
+	MatcherExpression                      // expression
 )
 
 // Config can be passed into NewFilter() in order to configure
@@ -0,0 +1,45 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package redisclient shares redis clients between filter instances. Filters
+// have no life cycle hook to close a client when a route goes away, so filters
+// connecting to the same endpoint use one client per endpoint instead.
+package redisclient
+
+import (
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	lock    sync.Mutex
+	clients = make(map[string]*redis.Client)
+)
+
+// Client returns the shared client of a redis endpoint
+func Client(endpoint string) *redis.Client {
+	lock.Lock()
+	defer lock.Unlock()
+	client, ok := clients[endpoint]
+	if !ok {
+		client = redis.NewClient(&redis.Options{
+			Addr:     endpoint,
+			Password: "",
+			DB:       0,
+		})
+		clients[endpoint] = client
+	}
+	return client
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package switcher
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/xmidt-org/ears/pkg/config"
+	pkgconfig "github.com/xmidt-org/ears/pkg/config"
+	"github.com/xmidt-org/ears/pkg/errs"
+	"github.com/xmidt-org/ears/pkg/filter"
+	"github.com/xmidt-org/ears/pkg/filter/match"
+)
+
+var validSenderName = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9]*$`)
+
+func NewConfig(config interface{}) (*Config, error) {
+	var cfg Config
+	err := pkgconfig.NewConfig(config, &cfg)
+	if err != nil {
+		return nil, &filter.InvalidConfigError{
+			Err: err,
+		}
+	}
+	return &cfg, nil
+}
+
+// WithDefaults will set default values
+func (c Config) WithDefaults() *Config {
+	cfg := c
+	cfg.Cases = make([]Case, len(c.Cases))
+	for i, cs := range c.Cases {
+		if cs.Matcher == match.MatcherUnknown {
+			cs.Matcher = match.MatcherExpression
+		}
+		cfg.Cases[i] = cs
+	}
+	if c.Default == nil {
+		cfg.Default = DefaultConfig.Default
+	}
+	if c.Mode == "" {
+		cfg.Mode = DefaultConfig.Mode
+	}
+	return &cfg
+}
+
+func (c *Config) Validate() error {
+	if len(c.Cases) == 0 {
+		return &filter.InvalidConfigError{
+			Err: errors.New("switch requires at least one case"),
+		}
+	}
+	if c.Mode != ModeFirst && c.Mode != ModeAll {
+		return &filter.InvalidConfigError{
+			Err: errors.New("mode must be first or all"),
+		}
+	}
+	for _, cs := range c.Cases {
+		if len(cs.Senders) == 0 {
+			return &filter.InvalidConfigError{
+				Err: errors.New("case without senders"),
+			}
+		}
+		err := validateSenders(cs.Senders)
+		if err != nil {
+			return err
+		}
+	}
+	return validateSenders(c.Default)
+}
+
+func validateSenders(names []string) error {
+	for _, name := range names {
+		if !validSenderName.MatchString(name) {
+			return &filter.InvalidConfigError{
+				Err: errors.New("invalid sender name " + name),
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) String() string {
+	s, err := c.YAML()
+	if err != nil {
+		return errs.String("error", nil, err)
+	}
+	return s
+}
+
+func (c *Config) YAML() (string, error) {
+	return config.ToYAML(c)
+}
+
+func (c *Config) FromYAML(in string) error {
+	return config.FromYAML(in, c)
+}
+
+func (c *Config) JSON() (string, error) {
+	return config.ToJSON(c)
+}
+
+func (c *Config) FromJSON(in string) error {
+	return config.FromJSON(in, c)
+}
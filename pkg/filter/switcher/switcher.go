@@ -0,0 +1,134 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package switcher
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/xmidt-org/ears/pkg/event"
+	"github.com/xmidt-org/ears/pkg/filter"
+	"github.com/xmidt-org/ears/pkg/filter/match"
+	"github.com/xmidt-org/ears/pkg/secret"
+	"github.com/xmidt-org/ears/pkg/tenant"
+)
+
+func NewFilter(tid tenant.Id, plugin string, name string, config interface{}, secrets secret.Vault) (*Filter, error) {
+	cfg, err := NewConfig(config)
+	if err != nil {
+		return nil, &filter.InvalidConfigError{
+			Err: err,
+		}
+	}
+	cfg = cfg.WithDefaults()
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	f := &Filter{
+		config: *cfg,
+		name:   name,
+		plugin: plugin,
+		tid:    tid,
+	}
+	for _, cs := range cfg.Cases {
+		mcfg := match.Config{
+			Mode:            match.ModeAllow,
+			Matcher:         cs.Matcher,
+			Pattern:         cs.Pattern,
+			ExactArrayMatch: cs.ExactArrayMatch,
+		}.WithDefaults()
+		err = mcfg.Validate()
+		if err != nil {
+			return nil, &filter.InvalidConfigError{
+				Err: err,
+			}
+		}
+		matcher, err := match.NewMatcher(mcfg)
+		if err != nil {
+			return nil, err
+		}
+		f.matchers = append(f.matchers, matcher)
+	}
+	return f, nil
+}
+
+// Filter tags the event with the senders of the matching cases, or the default senders if no
+// case matches. The route then sends the event to these senders. The filter never drops events.
+func (f *Filter) Filter(evt event.Event) []event.Event {
+	if f == nil {
+		evt.Nack(&filter.InvalidConfigError{
+			Err: fmt.Errorf("<nil> pointer filter"),
+		})
+		return nil
+	}
+	senders := make([]interface{}, 0)
+	seen := make(map[string]bool)
+	for i, m := range f.matchers {
+		if !m.Match(evt) {
+			continue
+		}
+		for _, name := range f.config.Cases[i].Senders {
+			if !seen[name] {
+				seen[name] = true
+				senders = append(senders, name)
+			}
+		}
+		if f.config.Mode == ModeFirst {
+			break
+		}
+	}
+	if len(senders) == 0 {
+		for _, name := range f.config.Default {
+			senders = append(senders, name)
+		}
+	}
+	if len(senders) > 0 {
+		evt.SetPathValue(event.METADATA+"."+event.MetadataSenders, senders, true)
+	}
+	log.Ctx(evt.Context()).Debug().Str("op", "filter").Str("filterType", "switch").Str("name", f.Name()).Int("senderCount", len(senders)).Msg("switch")
+	return []event.Event{evt}
+}
+
+// SenderNames returns the sender names of all cases and the default
+func (f *Filter) SenderNames() []string {
+	if f == nil {
+		return nil
+	}
+	names := make([]string, 0)
+	for _, cs := range f.config.Cases {
+		names = append(names, cs.Senders...)
+	}
+	return append(names, f.config.Default...)
+}
+
+func (f *Filter) Config() interface{} {
+	if f == nil {
+		return Config{}
+	}
+	return f.config
+}
+
+func (f *Filter) Name() string {
+	return f.name
+}
+
+func (f *Filter) Plugin() string {
+	return f.plugin
+}
+
+func (f *Filter) Tenant() tenant.Id {
+	return f.tid
+}
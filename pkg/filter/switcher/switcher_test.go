@@ -0,0 +1,133 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package switcher_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xmidt-org/ears/pkg/event"
+	"github.com/xmidt-org/ears/pkg/filter"
+	"github.com/xmidt-org/ears/pkg/filter/match"
+	"github.com/xmidt-org/ears/pkg/filter/switcher"
+	"github.com/xmidt-org/ears/pkg/tenant"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestSwitch(t *testing.T) {
+	cases := []switcher.Case{
+		{Pattern: "payload.priority == 'high'", Senders: []string{"kafka"}},
+		{Pattern: "payload.region in ['us', 'ca']", Senders: []string{"sqs", "kafka"}},
+		{Matcher: match.MatcherPattern, Pattern: map[string]interface{}{"audit": true}, Senders: []string{"archive"}},
+	}
+	testCases := []struct {
+		name     string
+		config   switcher.Config
+		payload  map[string]interface{}
+		expected interface{}
+	}{
+		{
+			name:     "first",
+			config:   switcher.Config{Cases: cases},
+			payload:  map[string]interface{}{"priority": "high", "region": "us"},
+			expected: []interface{}{"kafka"},
+		},
+		{
+			name:     "all",
+			config:   switcher.Config{Cases: cases, Mode: switcher.ModeAll},
+			payload:  map[string]interface{}{"priority": "high", "region": "us", "audit": true},
+			expected: []interface{}{"kafka", "sqs", "archive"},
+		},
+		{
+			name:     "pattern",
+			config:   switcher.Config{Cases: cases},
+			payload:  map[string]interface{}{"audit": true},
+			expected: []interface{}{"archive"},
+		},
+		{
+			name:     "default",
+			config:   switcher.Config{Cases: cases, Default: []string{"sqs"}},
+			payload:  map[string]interface{}{"priority": "low"},
+			expected: []interface{}{"sqs"},
+		},
+		{
+			name:     "routeDefault",
+			config:   switcher.Config{Cases: cases},
+			payload:  map[string]interface{}{"priority": "low"},
+			expected: nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewWithT(t)
+			f, err := switcher.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "switch", "myswitch", tc.config, nil)
+			a.Expect(err).To(BeNil())
+			e, err := event.New(context.Background(), tc.payload, event.FailOnNack(t))
+			a.Expect(err).To(BeNil())
+			evts := f.Filter(e)
+			a.Expect(evts).To(HaveLen(1))
+			senders, ok := evts[0].Metadata()[event.MetadataSenders]
+			if tc.expected == nil {
+				a.Expect(ok).To(BeFalse())
+			} else {
+				a.Expect(senders).To(Equal(tc.expected))
+			}
+		})
+	}
+}
+
+func TestSwitchSenderNames(t *testing.T) {
+	a := NewWithT(t)
+	f, err := switcher.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "switch", "myswitch", switcher.Config{
+		Cases: []switcher.Case{
+			{Pattern: "payload.priority == 'high'", Senders: []string{"kafka"}},
+			{Pattern: "payload.region == 'us'", Senders: []string{"sqs", "kafka"}},
+		},
+		Default: []string{"archive"},
+	}, nil)
+	a.Expect(err).To(BeNil())
+	a.Expect(f.SenderNames()).To(Equal([]string{"kafka", "sqs", "kafka", "archive"}))
+	// names are collected from nested chains and branches
+	branches, err := filter.NewBranches("fork", filter.JoinConcat)
+	a.Expect(err).To(BeNil())
+	a.Expect(branches.Add(&filter.Chain{})).To(BeNil())
+	a.Expect(branches.Add(f)).To(BeNil())
+	chain := &filter.Chain{}
+	a.Expect(chain.Add(branches)).To(BeNil())
+	a.Expect(filter.SenderNames(chain)).To(ConsistOf("kafka", "sqs", "kafka", "archive"))
+}
+
+func TestSwitchConfig(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config switcher.Config
+	}{
+		{name: "noCases", config: switcher.Config{}},
+		{name: "noSenders", config: switcher.Config{Cases: []switcher.Case{{Pattern: "payload.a == 1"}}}},
+		{name: "badSender", config: switcher.Config{Cases: []switcher.Case{{Pattern: "payload.a == 1", Senders: []string{"my-sender"}}}}},
+		{name: "badMode", config: switcher.Config{Mode: "some", Cases: []switcher.Case{{Pattern: "payload.a == 1", Senders: []string{"a"}}}}},
+		{name: "badExpression", config: switcher.Config{Cases: []switcher.Case{{Pattern: "payload.a ==", Senders: []string{"a"}}}}},
+		{name: "noPattern", config: switcher.Config{Cases: []switcher.Case{{Senders: []string{"a"}}}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewWithT(t)
+			f, err := switcher.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "switch", "myswitch", tc.config, nil)
+			a.Expect(err).ToNot(BeNil())
+			a.Expect(f).To(BeNil())
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package switcher
+
+import (
+	"github.com/xmidt-org/ears/pkg/filter/match"
+	"github.com/xmidt-org/ears/pkg/tenant"
+)
+
+// Case tags events matching the pattern with the names of the route senders they go to
+type Case struct {
+	Matcher         match.MatcherType `json:"matcher,omitempty"`         // any match filter matcher, expression by default
+	Pattern         interface{}       `json:"pattern,omitempty"`         // pattern of the matcher
+	ExactArrayMatch *bool             `json:"exactArrayMatch,omitempty"` // see match filter
+	Senders         []string          `json:"senders,omitempty"`         // names of route senders
+}
+
+// Config can be passed into NewFilter() in order to configure
+// the behavior of the filter.
+type Config struct {
+	Cases   []Case   `json:"cases,omitempty"`
+	Default []string `json:"default,omitempty"` // senders of events matching no case, the route's default sender if empty
+	Mode    string   `json:"mode,omitempty"`    // first: only the first matching case applies, all: all matching cases apply
+}
+
+const (
+	ModeFirst = "first"
+	ModeAll   = "all"
+)
+
+var DefaultConfig = Config{
+	Cases:   []Case{},
+	Default: []string{},
+	Mode:    ModeFirst,
+}
+
+type Filter struct {
+	config   Config
+	name     string
+	plugin   string
+	tid      tenant.Id
+	matchers []match.Matcher
+}
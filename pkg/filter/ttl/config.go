@@ -15,6 +15,7 @@
 package ttl
 
 import (
+	"errors"
 	"github.com/xmidt-org/ears/pkg/config"
 	pkgconfig "github.com/xmidt-org/ears/pkg/config"
 	"github.com/xmidt-org/ears/pkg/errs"
@@ -40,13 +41,40 @@ func (c Config) WithDefaults() *Config {
 	if c.Ttl == nil {
 		cfg.Ttl = DefaultConfig.Ttl
 	}
-	if c.NanoFactor == nil {
-		cfg.NanoFactor = DefaultConfig.NanoFactor
+	if c.Unit == "" {
+		cfg.Unit = DefaultConfig.Unit
+	}
+	if c.ExpiredAction == "" {
+		cfg.ExpiredAction = DefaultConfig.ExpiredAction
 	}
 	return &cfg
 }
 
 func (c *Config) Validate() error {
+	if c.Ttl != nil && *c.Ttl < 0 {
+		return &filter.InvalidConfigError{
+			Err: errors.New("ttl must not be negative"),
+		}
+	}
+	if c.NanoFactor != nil && *c.NanoFactor <= 0 {
+		return &filter.InvalidConfigError{
+			Err: errors.New("nanoFactor must be positive"),
+		}
+	}
+	switch c.Unit {
+	case UnitAuto, UnitSeconds, UnitMilliseconds, UnitMicroseconds, UnitNanoseconds:
+	default:
+		return &filter.InvalidConfigError{
+			Err: errors.New("unsupported unit " + c.Unit),
+		}
+	}
+	switch c.ExpiredAction {
+	case ExpiredActionDrop, ExpiredActionNack:
+	default:
+		return &filter.InvalidConfigError{
+			Err: errors.New("unsupported expiredAction " + c.ExpiredAction),
+		}
+	}
 	return nil
 }
 
@@ -15,6 +15,7 @@
 package ttl
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/rs/zerolog/log"
@@ -22,6 +23,7 @@ import (
 	"github.com/xmidt-org/ears/pkg/filter"
 	"github.com/xmidt-org/ears/pkg/secret"
 	"github.com/xmidt-org/ears/pkg/tenant"
+	"strconv"
 	"time"
 )
 
@@ -54,27 +56,107 @@ func (f *Filter) Filter(evt event.Event) []event.Event {
 		return nil
 	}
 	log.Ctx(evt.Context()).Debug().Str("op", "filter").Str("filterType", "ttl").Str("name", f.Name()).Msg("ttl")
-	obj, _, _ := evt.GetPathValue(f.config.Path)
-	if obj == nil {
-		evt.Nack(errors.New("nil object at path " + f.config.Path))
+	evtTs, err := f.timestamp(evt, f.config.Path)
+	if err != nil {
+		evt.Nack(err)
 		return []event.Event{}
 	}
-	evtTs, ok := obj.(uint64)
-	if !ok {
-		evt.Nack(errors.New("not a uint64 at path " + f.config.Path))
-		return []event.Event{}
+	now := time.Now()
+	if f.config.ReferencePath != "" {
+		now, err = f.timestamp(evt, f.config.ReferencePath)
+		if err != nil {
+			evt.Nack(err)
+			return []event.Event{}
+		}
 	}
-	nowNanos := time.Now().UnixNano()
-	if int64(evtTs) > nowNanos {
+	if evtTs.After(now) {
 		evt.Nack(errors.New("event from the future at path " + f.config.Path))
 		return []event.Event{}
 	}
-	if nowNanos-int64(evtTs) >= int64(*f.config.Ttl*(*f.config.NanoFactor)) {
-		evt.Ack()
+	if now.Sub(evtTs) >= time.Duration(*f.config.Ttl)*time.Millisecond {
+		log.Ctx(evt.Context()).Debug().Str("op", "filter").Str("filterType", "ttl").Str("name", f.Name()).Str("action", f.config.ExpiredAction).Msg("event expired")
+		if f.config.ExpiredAction == ExpiredActionNack {
+			evt.Nack(errors.New("event expired at path " + f.config.Path))
+		} else {
+			evt.Ack()
+		}
 		return []event.Event{}
 	}
 	return []event.Event{evt}
 }
+
+// timestamp reads the time at path which may be an epoch number, a numeric
+// string or a string in the configured layout
+func (f *Filter) timestamp(evt event.Event, path string) (time.Time, error) {
+	obj, _, _ := evt.GetPathValue(path)
+	if obj == nil {
+		return time.Time{}, errors.New("nil object at path " + path)
+	}
+	switch v := obj.(type) {
+	case time.Time:
+		return v, nil
+	case float64:
+		return f.fromEpoch(v), nil
+	case float32:
+		return f.fromEpoch(float64(v)), nil
+	case int:
+		return f.fromEpoch(float64(v)), nil
+	case int32:
+		return f.fromEpoch(float64(v)), nil
+	case int64:
+		return f.fromEpoch(float64(v)), nil
+	case uint32:
+		return f.fromEpoch(float64(v)), nil
+	case uint64:
+		return f.fromEpoch(float64(v)), nil
+	case json.Number:
+		n, err := v.Float64()
+		if err != nil {
+			return time.Time{}, errors.New("invalid timestamp at path " + path + ": " + err.Error())
+		}
+		return f.fromEpoch(n), nil
+	case string:
+		if f.config.Layout != "" {
+			t, err := time.Parse(f.config.Layout, v)
+			if err != nil {
+				return time.Time{}, errors.New("invalid timestamp at path " + path + ": " + err.Error())
+			}
+			return t, nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err == nil {
+			return t, nil
+		}
+		n, nerr := strconv.ParseFloat(v, 64)
+		if nerr != nil {
+			return time.Time{}, errors.New("invalid timestamp at path " + path + ": " + err.Error())
+		}
+		return f.fromEpoch(n), nil
+	}
+	return time.Time{}, fmt.Errorf("unsupported timestamp type %T at path %s", obj, path)
+}
+
+// fromEpoch converts an epoch timestamp using nanoFactor or unit, the auto
+// unit guesses the unit from the magnitude of the timestamp
+func (f *Filter) fromEpoch(ts float64) time.Time {
+	factor := 1.0
+	if f.config.NanoFactor != nil {
+		factor = float64(*f.config.NanoFactor)
+	} else {
+		switch f.config.Unit {
+		case UnitAuto:
+			return filter.FromEpoch(ts)
+		case UnitSeconds:
+			factor = 1e9
+		case UnitMilliseconds:
+			factor = 1e6
+		case UnitMicroseconds:
+			factor = 1e3
+		}
+	}
+	return time.Unix(0, int64(ts*factor))
+}
+
 func (f *Filter) Config() interface{} {
 	if f == nil {
 		return Config{}
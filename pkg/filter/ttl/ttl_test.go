@@ -0,0 +1,189 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ttl_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/xmidt-org/ears/pkg/event"
+	"github.com/xmidt-org/ears/pkg/filter"
+	"github.com/xmidt-org/ears/pkg/filter/ttl"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"github.com/xorcare/pointer"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestTimestampFormats(t *testing.T) {
+	fresh := time.Now().Add(-time.Minute)
+	stale := time.Now().Add(-time.Hour)
+	testCases := []struct {
+		name   string
+		config ttl.Config
+		fresh  interface{}
+		stale  interface{}
+	}{
+		{"seconds float", ttl.Config{}, float64(fresh.Unix()), float64(stale.Unix())},
+		{"fractional seconds", ttl.Config{}, float64(fresh.UnixNano()) / 1e9, float64(stale.UnixNano()) / 1e9},
+		{"milliseconds float", ttl.Config{}, float64(fresh.UnixNano() / 1e6), float64(stale.UnixNano() / 1e6)},
+		{"microseconds int64", ttl.Config{}, fresh.UnixNano() / 1e3, stale.UnixNano() / 1e3},
+		{"nanoseconds uint64", ttl.Config{}, uint64(fresh.UnixNano()), uint64(stale.UnixNano())},
+		{"nanoseconds int", ttl.Config{}, int(fresh.UnixNano()), int(stale.UnixNano())},
+		{"configured unit", ttl.Config{Unit: ttl.UnitMilliseconds}, fresh.UnixNano() / 1e6, stale.UnixNano() / 1e6},
+		{"nano factor", ttl.Config{NanoFactor: pointer.Int(1000)}, fresh.UnixNano() / 1e3, stale.UnixNano() / 1e3},
+		{"numeric string", ttl.Config{}, strconv.FormatInt(fresh.UnixNano()/1e6, 10), strconv.FormatInt(stale.UnixNano()/1e6, 10)},
+		{"rfc3339", ttl.Config{}, fresh.Format(time.RFC3339), stale.Format(time.RFC3339)},
+		{"rfc3339 nano", ttl.Config{}, fresh.Format(time.RFC3339Nano), stale.Format(time.RFC3339Nano)},
+		{"custom layout", ttl.Config{Layout: time.RFC1123Z}, fresh.Format(time.RFC1123Z), stale.Format(time.RFC1123Z)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewWithT(t)
+			tc.config.Path = ".ts"
+			f, err := ttl.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "ttl", "myttl", tc.config, nil)
+			a.Expect(err).To(BeNil())
+			e, err := event.New(context.Background(), map[string]interface{}{"ts": tc.fresh}, event.FailOnNack(t))
+			a.Expect(err).To(BeNil())
+			evts := f.Filter(e)
+			a.Expect(evts).To(HaveLen(1), "fresh event passes")
+			evts[0].Ack()
+			acked := make(chan error, 1)
+			e, err = event.New(context.Background(), map[string]interface{}{"ts": tc.stale}, event.WithAck(
+				func(e event.Event) {
+					acked <- nil
+				}, func(e event.Event, err error) {
+					acked <- err
+				}))
+			a.Expect(err).To(BeNil())
+			a.Expect(f.Filter(e)).To(HaveLen(0), "stale event is dropped")
+			a.Eventually(acked, time.Second).Should(Receive(BeNil()))
+		})
+	}
+}
+
+func TestExpiredAction(t *testing.T) {
+	a := NewWithT(t)
+	stale := map[string]interface{}{"ts": time.Now().Add(-time.Hour).Format(time.RFC3339)}
+	acked := make(chan error, 1)
+	newEvent := func() event.Event {
+		e, err := event.New(context.Background(), stale, event.WithAck(
+			func(e event.Event) {
+				acked <- nil
+			}, func(e event.Event, err error) {
+				acked <- err
+			}))
+		a.Expect(err).To(BeNil())
+		return e
+	}
+
+	f, err := ttl.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "ttl", "myttl", ttl.Config{
+		Path:          ".ts",
+		ExpiredAction: ttl.ExpiredActionNack,
+	}, nil)
+	a.Expect(err).To(BeNil())
+	a.Expect(f.Filter(newEvent())).To(HaveLen(0))
+	a.Eventually(acked, time.Second).Should(Receive(HaveOccurred()))
+
+	f, err = ttl.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "ttl", "myttl", ttl.Config{
+		Path: ".ts",
+		Ttl:  pointer.Int(2 * 60 * 60 * 1000),
+	}, nil)
+	a.Expect(err).To(BeNil())
+	evts := f.Filter(newEvent())
+	a.Expect(evts).To(HaveLen(1))
+	evts[0].Ack()
+	a.Eventually(acked, time.Second).Should(Receive(BeNil()))
+}
+
+func TestMetadataTimestamps(t *testing.T) {
+	a := NewWithT(t)
+	now := time.Now()
+	acked := make(chan error, 1)
+	newEvent := func(payload map[string]interface{}) event.Event {
+		metadata := map[string]interface{}{event.MetadataBrokerTimestamp: now.Add(-time.Hour).UnixNano() / 1e6}
+		e, err := event.New(context.Background(), payload, event.WithMetadata(metadata), event.WithAck(
+			func(e event.Event) {
+				acked <- nil
+			}, func(e event.Event, err error) {
+				acked <- err
+			}))
+		a.Expect(err).To(BeNil())
+		return e
+	}
+
+	// age determined by the broker timestamp
+	f, err := ttl.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "ttl", "myttl", ttl.Config{
+		Path: "metadata." + event.MetadataBrokerTimestamp,
+	}, nil)
+	a.Expect(err).To(BeNil())
+	a.Expect(f.Filter(newEvent(map[string]interface{}{}))).To(HaveLen(0))
+	a.Eventually(acked, time.Second).Should(Receive(BeNil()))
+
+	// age at the time the broker received the event
+	f, err = ttl.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "ttl", "myttl", ttl.Config{
+		Path:          ".ts",
+		ReferencePath: "metadata." + event.MetadataBrokerTimestamp,
+	}, nil)
+	a.Expect(err).To(BeNil())
+	evts := f.Filter(newEvent(map[string]interface{}{"ts": float64(now.Add(-time.Hour - time.Minute).Unix())}))
+	a.Expect(evts).To(HaveLen(1), "event fresh at the broker passes")
+	evts[0].Ack()
+	a.Eventually(acked, time.Second).Should(Receive(BeNil()))
+	a.Expect(f.Filter(newEvent(map[string]interface{}{"ts": float64(now.Add(-2 * time.Hour).Unix())}))).To(HaveLen(0), "event stale at the broker is dropped")
+	a.Eventually(acked, time.Second).Should(Receive(BeNil()))
+}
+
+func TestInvalidTimestamps(t *testing.T) {
+	a := NewWithT(t)
+	f, err := ttl.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "ttl", "myttl", ttl.Config{Path: ".ts"}, nil)
+	a.Expect(err).To(BeNil())
+	for _, payload := range []map[string]interface{}{
+		{},
+		{"ts": "yesterday"},
+		{"ts": true},
+		{"ts": float64(time.Now().Add(time.Hour).Unix())},
+	} {
+		acked := make(chan error, 1)
+		e, err := event.New(context.Background(), payload, event.WithAck(
+			func(e event.Event) {
+				acked <- nil
+			}, func(e event.Event, err error) {
+				acked <- err
+			}))
+		a.Expect(err).To(BeNil())
+		a.Expect(f.Filter(e)).To(HaveLen(0), fmt.Sprintf("payload %v", payload))
+		a.Eventually(acked, time.Second).Should(Receive(HaveOccurred()), fmt.Sprintf("payload %v", payload))
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	a := NewWithT(t)
+	for _, config := range []ttl.Config{
+		{Unit: "minutes"},
+		{ExpiredAction: "retry"},
+		{Ttl: pointer.Int(-1)},
+		{NanoFactor: pointer.Int(0)},
+	} {
+		f, err := ttl.NewFilter(tenant.Id{AppId: "myapp", OrgId: "myorg"}, "ttl", "myttl", config, nil)
+		var cerr *filter.InvalidConfigError
+		a.Expect(errors.As(err, &cerr)).To(BeTrue(), fmt.Sprintf("config %v", config))
+		a.Expect(f).To(BeNil())
+	}
+}
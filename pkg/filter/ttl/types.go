@@ -22,15 +22,31 @@ import (
 // Config can be passed into NewFilter() in order to configure
 // the behavior of the sender.
 type Config struct {
-	Path       string `json:"path,omitempty"`
-	Ttl        *int   `json:"ttl,omitempty"`        // ttl in MS
-	NanoFactor *int   `json:"nanoFactor,omitempty"` // factor to convert timestamp to nano seconds
+	Path          string `json:"path,omitempty"`
+	Ttl           *int   `json:"ttl,omitempty"`           // ttl in MS
+	NanoFactor    *int   `json:"nanoFactor,omitempty"`    // factor to convert timestamp to nano seconds, takes precedence over unit
+	Unit          string `json:"unit,omitempty"`          // unit of numeric timestamps: auto, s, ms, us or ns
+	Layout        string `json:"layout,omitempty"`        // go time layout of string timestamps, RFC3339 if not set
+	ReferencePath string `json:"referencePath,omitempty"` // optional path of the timestamp to compare against instead of the current time
+	ExpiredAction string `json:"expiredAction,omitempty"` // drop or nack expired events
 }
 
+const (
+	UnitAuto         = "auto"
+	UnitSeconds      = "s"
+	UnitMilliseconds = "ms"
+	UnitMicroseconds = "us"
+	UnitNanoseconds  = "ns"
+
+	ExpiredActionDrop = "drop"
+	ExpiredActionNack = "nack"
+)
+
 var DefaultConfig = Config{
-	Path:       "",
-	Ttl:        pointer.Int(1000 * 60 * 5), // 5 min in ms
-	NanoFactor: pointer.Int(1000 * 1000),
+	Path:          "",
+	Ttl:           pointer.Int(1000 * 60 * 5), // 5 min in ms
+	Unit:          UnitAuto,
+	ExpiredAction: ExpiredActionDrop,
 }
 
 type Filter struct {
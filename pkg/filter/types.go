@@ -38,6 +38,13 @@ type InvalidArgumentError struct {
 	Err error
 }
 
+// FileNotAllowedError is returned when a filter config names a file
+// outside of the filter file directory
+type FileNotAllowedError struct {
+	File   string
+	Reason string
+}
+
 // Hasher defines the hashing interface that a receiver
 // needs to implement
 type Hasher interface {
@@ -76,6 +83,13 @@ type Chainer interface {
 	Filterers() []Filterer
 }
 
+// SenderTargeter is implemented by filters that tag events with the names of
+// route senders, such as the switch filter, so that the names can be checked
+// against the route when it is registered
+type SenderTargeter interface {
+	SenderNames() []string
+}
+
 var _ Chainer = (*Chain)(nil)
 
 type Chain struct {
@@ -83,3 +97,20 @@ type Chain struct {
 
 	filterers []Filterer
 }
+
+var _ Chainer = (*Branches)(nil)
+
+const (
+	JoinConcat = "concat" // branch outputs are passed on as separate events
+	JoinMerge  = "merge"  // branch outputs are merged into one event
+)
+
+// Branches passes a clone of each event through every branch, usually a Chain, and
+// joins the branch outputs. The original event is acked once all branches are done.
+type Branches struct {
+	sync.RWMutex
+
+	name     string
+	join     string
+	branches []Filterer
+}
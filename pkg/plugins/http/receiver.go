@@ -16,7 +16,6 @@ package http
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"github.com/goccy/go-yaml"
 	"github.com/rs/zerolog/log"
@@ -108,8 +107,7 @@ func (h *Receiver) Receive(next receiver.NextFn) error {
 			h.logger.Error().Str("error", err.Error()).Msg("error reading body")
 			return
 		}
-		var body interface{}
-		err = json.Unmarshal(b, &body)
+		body, err := event.UnmarshalPayload(h.config.PayloadFormat, b)
 		if err != nil {
 			h.logger.Error().Str("error", err.Error()).Msg("error unmarshalling body")
 			return
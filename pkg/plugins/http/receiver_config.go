@@ -42,6 +42,10 @@ const receiverSchema = `
             "type": "object",
             "additionalProperties": false,
             "properties": {
+                "payloadFormat": {
+                    "type": "string",
+                    "enum": ["json", "text", "bytes"]
+                },
                 "path": {
                     "type": "string"
                 },
@@ -17,7 +17,6 @@ package http
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"github.com/goccy/go-yaml"
 	"github.com/xmidt-org/ears/internal/pkg/rtsemconv"
 	"github.com/xmidt-org/ears/pkg/event"
@@ -114,44 +113,45 @@ func (s *Sender) SetTraceId(r *http.Request, traceId string) {
 	r.Header.Set("traceId", traceId)
 }
 
-func (s *Sender) Send(event event.Event) {
-	payload := event.Payload()
-	body, err := json.Marshal(payload)
+func (s *Sender) Send(evt event.Event) {
+	payload := evt.Payload()
+	body, err := event.MarshalPayload(s.config.PayloadFormat, payload)
 	if err != nil {
-		s.eventFailureCounter.Add(event.Context(), 1)
-		event.Nack(err)
+		s.eventFailureCounter.Add(evt.Context(), 1)
+		evt.Nack(err)
 		return
 	}
-	s.eventBytesCounter.Add(event.Context(), int64(len(body)))
-	s.eventProcessingTime.Record(event.Context(), time.Since(event.Created()).Milliseconds())
+	s.eventBytesCounter.Add(evt.Context(), int64(len(body)))
+	s.eventProcessingTime.Record(evt.Context(), time.Since(evt.Created()).Milliseconds())
 	req, err := http.NewRequest(s.config.Method, s.config.Url, bytes.NewReader(body))
 	if err != nil {
-		s.eventFailureCounter.Add(event.Context(), 1)
-		event.Nack(err)
+		s.eventFailureCounter.Add(evt.Context(), 1)
+		evt.Nack(err)
 		return
 	}
-	ctx := event.Context()
+	req.Header.Set("Content-Type", contentType(s.config.PayloadFormat))
+	ctx := evt.Context()
 	traceId := ctx.Value("traceId")
 	if traceId != nil {
 		s.SetTraceId(req, traceId.(string))
 	}
 	start := time.Now()
 	resp, err := s.client.Do(req)
-	s.eventSendOutTime.Record(event.Context(), time.Since(start).Milliseconds())
+	s.eventSendOutTime.Record(evt.Context(), time.Since(start).Milliseconds())
 	if err != nil {
-		s.eventFailureCounter.Add(event.Context(), 1)
-		event.Nack(err)
+		s.eventFailureCounter.Add(evt.Context(), 1)
+		evt.Nack(err)
 		return
 	}
 	io.Copy(ioutil.Discard, resp.Body)
 	defer resp.Body.Close()
 	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
-		s.eventFailureCounter.Add(event.Context(), 1)
-		event.Nack(&BadHttpStatusError{resp.StatusCode})
+		s.eventFailureCounter.Add(evt.Context(), 1)
+		evt.Nack(&BadHttpStatusError{resp.StatusCode})
 		return
 	}
-	s.eventSuccessCounter.Add(event.Context(), 1)
-	event.Ack()
+	s.eventSuccessCounter.Add(evt.Context(), 1)
+	evt.Ack()
 }
 
 func (s *Sender) Unwrap() sender.Sender {
@@ -181,3 +181,13 @@ func (s *Sender) Plugin() string {
 func (s *Sender) Tenant() tenant.Id {
 	return s.tid
 }
+
+func contentType(payloadFormat string) string {
+	switch payloadFormat {
+	case event.PayloadFormatText:
+		return "text/plain"
+	case event.PayloadFormatBytes:
+		return "application/octet-stream"
+	}
+	return "application/json"
+}
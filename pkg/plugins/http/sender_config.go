@@ -42,6 +42,10 @@ const senderSchema = `
             "type": "object",
             "additionalProperties": false,
             "properties": {
+                "payloadFormat": {
+                    "type": "string",
+                    "enum": ["json", "text", "bytes"]
+                },
                 "url": {
                     "type": "string"
                 },
@@ -53,6 +53,7 @@ type ReceiverConfig struct {
 	Method             string `json:"method"`
 	Port               *int   `json:"port"`
 	TracePayloadOnNack *bool  `json:"tracePayloadOnNack,omitempty"`
+	PayloadFormat      string `json:"payloadFormat,omitempty"`
 }
 
 type Receiver struct {
@@ -68,8 +69,9 @@ type Receiver struct {
 }
 
 type SenderConfig struct {
-	Url    string `json:"url"`
-	Method string `json:"method"`
+	Url           string `json:"url"`
+	Method        string `json:"method"`
+	PayloadFormat string `json:"payloadFormat,omitempty"`
 }
 
 type Sender struct {
@@ -0,0 +1,37 @@
+// Copyright 2020 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/xmidt-org/ears/pkg/plugins/jq"
+)
+
+func main() {
+	// required for `go build` to not fail
+}
+
+//go:generate ../../../../script/build-plugin.sh
+
+var (
+	Name       = "jq"
+	GitVersion = "v0.0.0"
+	GitCommit  = ""
+)
+
+var Plugin, PluginErr = jq.NewPluginVersion(Name, GitVersion, GitCommit)
+
+// for golangci-lint
+var _ = Plugin
+var _ = PluginErr
@@ -21,7 +21,6 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
-	"encoding/json"
 	"fmt"
 	"github.com/Shopify/sarama"
 	"github.com/rs/zerolog/log"
@@ -298,8 +297,7 @@ func (r *Receiver) Receive(next receiver.NextFn) error {
 			r.Lock()
 			r.count++
 			r.Unlock()
-			var pl interface{}
-			err := json.Unmarshal(msg.Value, &pl)
+			pl, err := event.UnmarshalPayload(r.config.PayloadFormat, msg.Value)
 			if err != nil {
 				r.logger.Error().Str("op", "kafka.Receive").Msg("cannot parse payload: " + err.Error())
 				return false
@@ -310,7 +308,11 @@ func (r *Receiver) Receive(next receiver.NextFn) error {
 			ctx = otel.GetTextMapPropagator().Extract(ctx, otelsarama.NewConsumerMessageCarrier(msg))
 
 			r.eventBytesCounter.Add(ctx, int64(len(msg.Value)))
-			e, err := event.New(ctx, pl, event.WithAck(
+			var metadata map[string]interface{}
+			if !msg.Timestamp.IsZero() {
+				metadata = map[string]interface{}{event.MetadataBrokerTimestamp: msg.Timestamp.UnixNano() / int64(time.Millisecond)}
+			}
+			e, err := event.New(ctx, pl, event.WithMetadata(metadata), event.WithAck(
 				func(e event.Event) {
 					log.Ctx(e.Context()).Debug().Str("op", "kafka.Receive").Str("name", r.Name()).Str("tid", r.Tenant().ToString()).Msg("processed message from kafka topic")
 					r.eventSuccessCounter.Add(ctx, 1)
@@ -46,6 +46,9 @@ func (sc SenderConfig) WithDefaults() SenderConfig {
 	if cfg.DynamicMetricLabels == nil {
 		cfg.DynamicMetricLabels = DefaultSenderConfig.DynamicMetricLabels
 	}
+	if cfg.PayloadFormat == "" {
+		cfg.PayloadFormat = DefaultSenderConfig.PayloadFormat
+	}
 	return cfg
 }
 
@@ -72,6 +75,10 @@ const senderSchema = `
             "type": "object",
             "additionalProperties": false,
             "properties": {
+                "payloadFormat": {
+                    "type": "string",
+                    "enum": ["json", "text", "bytes"]
+                },
                 "brokers": {
                     "type": "string"
                 },
@@ -18,6 +18,7 @@ import (
 	"context"
 	"github.com/Shopify/sarama"
 	"github.com/rs/zerolog"
+	"github.com/xmidt-org/ears/pkg/event"
 	"github.com/xmidt-org/ears/pkg/secret"
 	"github.com/xmidt-org/ears/pkg/tenant"
 	"github.com/xorcare/pointer"
@@ -67,6 +68,7 @@ var DefaultReceiverConfig = ReceiverConfig{
 	CommitInterval:     pointer.Int(1),
 	ChannelBufferSize:  pointer.Int(0),
 	TracePayloadOnNack: pointer.Bool(false),
+	PayloadFormat:      event.PayloadFormatJson,
 }
 
 type ReceiverConfig struct {
@@ -84,6 +86,7 @@ type ReceiverConfig struct {
 	ConsumeByPartitions bool   `json:"consumeByPartitions,omitempty"`
 	TLSEnable           bool   `json:"tlsEnable,omitempty"`
 	TracePayloadOnNack  *bool  `json:"tracePayloadOnNack,omitempty"`
+	PayloadFormat       string `json:"payloadFormat,omitempty"`
 }
 
 type Receiver struct {
@@ -126,6 +129,7 @@ var DefaultSenderConfig = SenderConfig{
 	SenderPoolSize:      pointer.Int(1),
 	PartitionPath:       "",
 	DynamicMetricLabels: make([]DynamicMetricLabel, 0),
+	PayloadFormat:       event.PayloadFormatJson,
 }
 
 // SenderConfig can be passed into NewSender() in order to configure
@@ -145,6 +149,7 @@ type SenderConfig struct {
 	TLSEnable           bool                 `json:"tlsEnable,omitempty"`
 	SenderPoolSize      *int                 `json:"senderPoolSize,omitempty"`
 	DynamicMetricLabels []DynamicMetricLabel `json:"dynamicMetricLabel,omitempty"`
+	PayloadFormat       string               `json:"payloadFormat,omitempty"`
 }
 
 type DynamicMetricLabel struct {
@@ -16,7 +16,6 @@ package kinesis
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
@@ -155,15 +154,18 @@ func (r *Receiver) startReceiveWorker(svc *kinesis.Kinesis, n int) {
 						r.Lock()
 						r.receiveCount++
 						r.Unlock()
-						var payload interface{}
-						err = json.Unmarshal(msg.Data, &payload)
+						payload, err := event.UnmarshalPayload(r.config.PayloadFormat, msg.Data)
 						if err != nil {
 							r.logger.Error().Str("op", "Kinesis.receiveWorker").Str("name", r.Name()).Str("tid", r.Tenant().ToString()).Int("workerNum", n).Msg("cannot parse message " + (*msg.SequenceNumber) + ": " + err.Error())
 							continue
 						}
 						ctx, cancel := context.WithTimeout(context.Background(), time.Duration(*r.config.AcknowledgeTimeout)*time.Second)
 						r.eventBytesCounter.Add(ctx, int64(len(msg.Data)))
-						e, err := event.New(ctx, payload, event.WithMetadataKeyValue("kinesisMessage", *msg), event.WithAck(
+						metadata := map[string]interface{}{"kinesisMessage": *msg}
+						if msg.ApproximateArrivalTimestamp != nil {
+							metadata[event.MetadataBrokerTimestamp] = msg.ApproximateArrivalTimestamp.UnixNano() / int64(time.Millisecond)
+						}
+						e, err := event.New(ctx, payload, event.WithMetadata(metadata), event.WithAck(
 							func(e event.Event) {
 								r.eventSuccessCounter.Add(ctx, 1)
 								cancel()
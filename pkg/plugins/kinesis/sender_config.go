@@ -28,6 +28,9 @@ func (sc SenderConfig) WithDefaults() SenderConfig {
 	if cfg.SendTimeout == nil {
 		cfg.SendTimeout = DefaultSenderConfig.SendTimeout
 	}
+	if cfg.PayloadFormat == "" {
+		cfg.PayloadFormat = DefaultSenderConfig.PayloadFormat
+	}
 	return cfg
 }
 
@@ -54,6 +57,10 @@ const senderSchema = `
             "type": "object",
             "additionalProperties": false,
             "properties": {
+                "payloadFormat": {
+                    "type": "string",
+                    "enum": ["json", "text", "bytes"]
+                },
                 "streamName": {
                     "type": "string"
                 },
@@ -59,6 +59,7 @@ var DefaultReceiverConfig = ReceiverConfig{
 	AcknowledgeTimeout: pointer.Int(5),
 	ShardIteratorType:  "LATEST",
 	TracePayloadOnNack: pointer.Bool(false),
+	PayloadFormat:      event.PayloadFormatJson,
 }
 
 type ReceiverConfig struct {
@@ -67,6 +68,7 @@ type ReceiverConfig struct {
 	AcknowledgeTimeout *int   `json:"acknowledgeTimeout,omitempty"`
 	ShardIteratorType  string `json:"shardIteratorType,omitempty"`
 	TracePayloadOnNack *bool  `json:"tracePayloadOnNack,omitempty"`
+	PayloadFormat      string `json:"payloadFormat,omitempty"`
 }
 
 type Receiver struct {
@@ -91,12 +93,14 @@ var DefaultSenderConfig = SenderConfig{
 	StreamName:          "",
 	MaxNumberOfMessages: pointer.Int(1),
 	SendTimeout:         pointer.Int(1),
+	PayloadFormat:       event.PayloadFormatJson,
 }
 
 type SenderConfig struct {
 	StreamName          string `json:"streamName,omitempty"`
 	MaxNumberOfMessages *int   `json:"maxNumberOfMessages,omitempty"`
 	SendTimeout         *int   `json:"sendTimeout,omitempty"`
+	PayloadFormat       string `json:"payloadFormat,omitempty"`
 }
 
 type Sender struct {
@@ -16,7 +16,6 @@ package redis
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"github.com/go-redis/redis"
 	"github.com/rs/zerolog/log"
@@ -134,8 +133,7 @@ func (r *Receiver) Receive(next receiver.NextFn) error {
 				r.logger.Info().Str("op", "redis.Receive").Msg("stopping receive loop")
 				return
 			}
-			var pl interface{}
-			err := json.Unmarshal([]byte(msg.Payload), &pl)
+			pl, err := event.UnmarshalPayload(r.config.PayloadFormat, []byte(msg.Payload))
 			if err != nil {
 				r.logger.Error().Str("op", "redis.Receive").Msg("cannot parse payload: " + err.Error())
 				continue
@@ -33,6 +33,9 @@ func (rc *ReceiverConfig) WithDefaults() ReceiverConfig {
 	if cfg.TracePayloadOnNack == nil {
 		cfg.TracePayloadOnNack = DefaultReceiverConfig.TracePayloadOnNack
 	}
+	if cfg.PayloadFormat == "" {
+		cfg.PayloadFormat = DefaultReceiverConfig.PayloadFormat
+	}
 	return cfg
 }
 
@@ -59,6 +62,10 @@ const receiverSchema = `
             "type": "object",
             "additionalProperties": false,
             "properties": {
+                "payloadFormat": {
+                    "type": "string",
+                    "enum": ["json", "text", "bytes"]
+                },
                 "endpoint": {
                     "type": "string"
                 },
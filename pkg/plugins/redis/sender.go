@@ -16,7 +16,6 @@ package redis
 
 import (
 	"context"
-	"encoding/json"
 	"github.com/go-redis/redis"
 	"github.com/goccy/go-yaml"
 	"github.com/rs/zerolog/log"
@@ -133,7 +132,7 @@ func (s *Sender) StopSending(ctx context.Context) {
 }
 
 func (s *Sender) Send(e event.Event) {
-	buf, err := json.Marshal(e.Payload())
+	buf, err := event.MarshalPayload(s.config.PayloadFormat, e.Payload())
 	if err != nil {
 		log.Ctx(e.Context()).Error().Str("op", "redis.Send").Msg("failed to marshal message: " + err.Error())
 		s.eventFailureCounter.Add(e.Context(), 1)
@@ -17,6 +17,7 @@ package redis
 import (
 	"github.com/go-redis/redis"
 	"github.com/rs/zerolog"
+	"github.com/xmidt-org/ears/pkg/event"
 	"github.com/xmidt-org/ears/pkg/tenant"
 	"github.com/xorcare/pointer"
 	"go.opentelemetry.io/otel/metric"
@@ -56,12 +57,14 @@ var DefaultReceiverConfig = ReceiverConfig{
 	Endpoint:           "localhost:6379",
 	Channel:            "ears",
 	TracePayloadOnNack: pointer.Bool(false),
+	PayloadFormat:      event.PayloadFormatJson,
 }
 
 type ReceiverConfig struct {
 	Endpoint           string `json:"endpoint,omitempty"`
 	Channel            string `json:"channel,omitempty"`
 	TracePayloadOnNack *bool  `json:"tracePayloadOnNack,omitempty"`
+	PayloadFormat      string `json:"payloadFormat,omitempty"`
 }
 
 type Receiver struct {
@@ -84,15 +87,17 @@ type Receiver struct {
 }
 
 var DefaultSenderConfig = SenderConfig{
-	Endpoint: "localhost:6379",
-	Channel:  "ears",
+	Endpoint:      "localhost:6379",
+	Channel:       "ears",
+	PayloadFormat: event.PayloadFormatJson,
 }
 
 // SenderConfig can be passed into NewSender() in order to configure
 // the behavior of the sender.
 type SenderConfig struct {
-	Endpoint string `json:"endpoint,omitempty"`
-	Channel  string `json:"channel,omitempty"`
+	Endpoint      string `json:"endpoint,omitempty"`
+	Channel       string `json:"channel,omitempty"`
+	PayloadFormat string `json:"payloadFormat,omitempty"`
 }
 
 type Sender struct {
@@ -16,7 +16,6 @@ package sqs
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
@@ -111,6 +110,7 @@ func NewReceiver(tid tenant.Id, plugin string, name string, config interface{},
 
 const (
 	approximateReceiveCount     = "ApproximateReceiveCount"
+	sentTimestamp               = "SentTimestamp"
 	approximateNumberOfMessages = "ApproximateNumberOfMessages"
 	attributeNames              = "All"
 )
@@ -216,7 +216,7 @@ func (r *Receiver) startReceiveWorker(svc *sqs.SQS, n int) {
 				MaxNumberOfMessages:   aws.Int64(int64(*r.config.MaxNumberOfMessages)),
 				VisibilityTimeout:     aws.Int64(int64(*r.config.VisibilityTimeout)),
 				WaitTimeSeconds:       aws.Int64(int64(*r.config.WaitTimeSeconds)),
-				AttributeNames:        []*string{aws.String(approximateReceiveCount)},
+				AttributeNames:        []*string{aws.String(approximateReceiveCount), aws.String(sentTimestamp)},
 				MessageAttributeNames: []*string{aws.String(attributeNames)},
 			}
 			sqsResp, err := svc.ReceiveMessage(sqsParams)
@@ -269,8 +269,7 @@ func (r *Receiver) startReceiveWorker(svc *sqs.SQS, n int) {
 					cancel()
 					continue
 				}
-				var payload interface{}
-				err = json.Unmarshal([]byte(*message.Body), &payload)
+				payload, err := event.UnmarshalPayload(r.config.PayloadFormat, []byte(*message.Body))
 				if err != nil {
 					r.logger.Error().Str("op", "SQS.receiveWorker").Str(rtsemconv.EarsLogTraceIdKey, traceId).Str("name", r.Name()).Str("tid", r.Tenant().ToString()).Int("workerNum", n).Msg("cannot parse message " + (*message.MessageId) + ": " + err.Error())
 					entry := sqs.DeleteMessageBatchRequestEntry{Id: message.MessageId, ReceiptHandle: message.ReceiptHandle}
@@ -280,7 +279,14 @@ func (r *Receiver) startReceiveWorker(svc *sqs.SQS, n int) {
 				}
 
 				r.eventBytesCounter.Add(ctx, int64(len(*message.Body)))
-				e, err := event.New(ctx, payload, event.WithMetadataKeyValue("sqsMessage", *message), event.WithAck(
+				metadata := map[string]interface{}{"sqsMessage": *message}
+				if message.Attributes[sentTimestamp] != nil {
+					ts, err := strconv.ParseInt(*message.Attributes[sentTimestamp], 10, 64)
+					if err == nil {
+						metadata[event.MetadataBrokerTimestamp] = ts
+					}
+				}
+				e, err := event.New(ctx, payload, event.WithMetadata(metadata), event.WithAck(
 					func(e event.Event) {
 						msg, ok := e.Metadata()["sqsMessage"].(sqs.Message) // get metadata associated with this event
 						//log.Ctx(e.Context()).Debug().Str("op", "SQS.receiveWorker").Int("batchSize", len(sqsResp.Messages)).Int("workerNum", n).Msg("processed message " + (*msg.MessageId))
@@ -16,7 +16,6 @@ package sqs
 
 import (
 	"context"
-	"encoding/json"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -183,7 +182,7 @@ func (s *Sender) send(events []event.Event) {
 		if idx == 0 {
 			log.Ctx(evt.Context()).Debug().Str("op", "SQS.sendWorker").Str("name", s.Name()).Str("tid", s.Tenant().ToString()).Int("eventIdx", idx).Int("batchSize", len(events)).Int("sendCount", s.count).Msg("send message batch")
 		}
-		buf, err := json.Marshal(evt.Payload())
+		buf, err := event.MarshalPayload(s.config.PayloadFormat, evt.Payload())
 		if err != nil {
 			continue
 		}
@@ -31,6 +31,9 @@ func (sc SenderConfig) WithDefaults() SenderConfig {
 	if cfg.DelaySeconds == nil {
 		cfg.DelaySeconds = DefaultSenderConfig.DelaySeconds
 	}
+	if cfg.PayloadFormat == "" {
+		cfg.PayloadFormat = DefaultSenderConfig.PayloadFormat
+	}
 	return cfg
 }
 
@@ -57,6 +60,10 @@ const senderSchema = `
             "type": "object",
             "additionalProperties": false,
             "properties": {
+                "payloadFormat": {
+                    "type": "string",
+                    "enum": ["json", "text", "bytes"]
+                },
                 "queueUrl": {
                     "type": "string"
                 },
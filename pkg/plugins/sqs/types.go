@@ -64,6 +64,7 @@ var DefaultReceiverConfig = ReceiverConfig{
 	ReceiverPoolSize:    pointer.Int(1),
 	NeverDelete:         pointer.Bool(false),
 	TracePayloadOnNack:  pointer.Bool(false),
+	PayloadFormat:       event.PayloadFormatJson,
 }
 
 type ReceiverConfig struct {
@@ -77,6 +78,7 @@ type ReceiverConfig struct {
 	ReceiverPoolSize    *int   `json:"receiverPoolSize,omitempty"`
 	NeverDelete         *bool  `json:"neverDelete,omitempty"`
 	TracePayloadOnNack  *bool  `json:"tracePayloadOnNack,omitempty"`
+	PayloadFormat       string `json:"payloadFormat,omitempty"`
 }
 
 type Receiver struct {
@@ -103,6 +105,7 @@ var DefaultSenderConfig = SenderConfig{
 	MaxNumberOfMessages: pointer.Int(10),
 	SendTimeout:         pointer.Int(1),
 	DelaySeconds:        pointer.Int(0),
+	PayloadFormat:       event.PayloadFormatJson,
 }
 
 // SenderConfig can be passed into NewSender() in order to configure
@@ -112,6 +115,7 @@ type SenderConfig struct {
 	MaxNumberOfMessages *int   `json:"maxNumberOfMessages,omitempty"`
 	SendTimeout         *int   `json:"sendTimeout,omitempty"`
 	DelaySeconds        *int   `json:"delaySeconds,omitempty"`
+	PayloadFormat       string `json:"payloadFormat,omitempty"`
 }
 
 type Sender struct {
@@ -102,3 +102,34 @@ func testBackendLimiter(r ratelimit.RateLimiter, t *testing.T) {
 		t.Fatalf("Expect LimitReached, got %s instead", err.Error())
 	}
 }
+
+func testUsageReporter(r ratelimit.UsageReporter, t *testing.T) {
+	ctx := context.Background()
+	usages := []ratelimit.InstanceUsage{
+		{InstanceId: "instance1", AdaptiveLimit: 2, Events: ratelimit.UsageCounts{OneMinute: 1, FiveMinutes: 2, OneHour: 3}},
+		{InstanceId: "instance2", AdaptiveLimit: 3, Throttled: ratelimit.UsageCounts{OneMinute: 4, FiveMinutes: 5, OneHour: 6}},
+		{InstanceId: "instance1", AdaptiveLimit: 4, Events: ratelimit.UsageCounts{OneMinute: 7, FiveMinutes: 8, OneHour: 9}},
+	}
+	for _, usage := range usages {
+		err := r.ReportUsage(ctx, usage)
+		if err != nil {
+			t.Fatalf("Fail to report usage %s\n", err.Error())
+		}
+	}
+	reported, err := r.GetUsage(ctx)
+	if err != nil {
+		t.Fatalf("Fail to get usage %s\n", err.Error())
+	}
+	if len(reported) != 2 {
+		t.Fatalf("Expect 2 reports, got %d instead", len(reported))
+	}
+	for _, usage := range reported {
+		expected := usages[1]
+		if usage.InstanceId == "instance1" {
+			expected = usages[2]
+		}
+		if usage != expected {
+			t.Fatalf("Expect usage %+v, got %+v instead", expected, usage)
+		}
+	}
+}
@@ -32,6 +32,8 @@ type InMemoryBackendLimiter struct {
 	last time.Time // last time we were polled/asked
 
 	allowance float64
+
+	usage map[string]InstanceUsage
 }
 
 func NewInMemoryBackendLimiter(tid tenant.Id, rqs int) *InMemoryBackendLimiter {
@@ -43,7 +45,7 @@ func NewInMemoryBackendLimiter(tid tenant.Id, rqs int) *InMemoryBackendLimiter {
 		return limiter
 	}
 
-	limiter = &InMemoryBackendLimiter{rqs: rqs, last: time.Now()}
+	limiter = &InMemoryBackendLimiter{rqs: rqs, last: time.Now(), usage: make(map[string]InstanceUsage)}
 	limiter.allowance = float64(rqs)
 	globalLimiters[tid.Key()] = limiter
 
@@ -86,3 +88,20 @@ func (r *InMemoryBackendLimiter) SetLimit(newLimit int) error {
 	r.rqs = newLimit
 	return nil
 }
+
+func (r *InMemoryBackendLimiter) ReportUsage(ctx context.Context, usage InstanceUsage) error {
+	r.Lock()
+	defer r.Unlock()
+	r.usage[usage.InstanceId] = usage
+	return nil
+}
+
+func (r *InMemoryBackendLimiter) GetUsage(ctx context.Context) ([]InstanceUsage, error) {
+	r.Lock()
+	defer r.Unlock()
+	usages := make([]InstanceUsage, 0, len(r.usage))
+	for _, usage := range r.usage {
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
@@ -25,3 +25,9 @@ func TestInMemoryBackendLimiter(t *testing.T) {
 
 	testBackendLimiter(limiter, t)
 }
+
+func TestInMemoryUsageReporter(t *testing.T) {
+	limiter := ratelimit.NewInMemoryBackendLimiter(tenant.Id{OrgId: "myOrg", AppId: "myUsageApp"}, 0)
+
+	testUsageReporter(limiter, t)
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+var globalVolumeCounter = &InMemoryVolumeCounter{counters: make(map[string]*volumeCount)}
+
+type volumeCount struct {
+	count   int64
+	expires time.Time
+}
+
+// InMemoryVolumeCounter is a VolumeCounter for single node deployments. All
+// in-memory volume counters in a process share the same counts
+type InMemoryVolumeCounter struct {
+	sync.Mutex
+	counters map[string]*volumeCount
+}
+
+func NewInMemoryVolumeCounter() *InMemoryVolumeCounter {
+	return globalVolumeCounter
+}
+
+func (c *InMemoryVolumeCounter) Incr(ctx context.Context, key string, unit int, expiration time.Duration) (int64, error) {
+	c.Lock()
+	defer c.Unlock()
+	now := time.Now()
+	counter, ok := c.counters[key]
+	if !ok || now.After(counter.expires) {
+		c.evictExpired(now)
+		counter = &volumeCount{expires: now.Add(expiration)}
+		c.counters[key] = counter
+	}
+	counter.count += int64(unit)
+	return counter.count, nil
+}
+
+func (c *InMemoryVolumeCounter) Get(ctx context.Context, key string) (int64, error) {
+	c.Lock()
+	defer c.Unlock()
+	counter, ok := c.counters[key]
+	if !ok || time.Now().After(counter.expires) {
+		return 0, nil
+	}
+	return counter.count, nil
+}
+
+func (c *InMemoryVolumeCounter) evictExpired(now time.Time) {
+	for key, counter := range c.counters {
+		if now.After(counter.expires) {
+			delete(c.counters, key)
+		}
+	}
+}
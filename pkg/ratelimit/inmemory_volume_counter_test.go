@@ -0,0 +1,65 @@
+// Copyright 2021 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit_test
+
+import (
+	"context"
+	"github.com/xmidt-org/ears/pkg/ratelimit"
+	"testing"
+	"time"
+)
+
+func TestInMemoryVolumeCounter(t *testing.T) {
+	testVolumeCounter(ratelimit.NewInMemoryVolumeCounter(), t)
+}
+
+func testVolumeCounter(c ratelimit.VolumeCounter, t *testing.T) {
+	ctx := context.Background()
+	key := "myOrg_myVolumeApp_" + time.Now().Format(time.RFC3339Nano)
+
+	count, err := c.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Fail to get count %s\n", err.Error())
+	}
+	if count != 0 {
+		t.Fatalf("Expect count=0, got %d instead\n", count)
+	}
+	for i := 1; i <= 3; i++ {
+		count, err = c.Incr(ctx, key, 2, time.Second)
+		if err != nil {
+			t.Fatalf("Fail to increment count %s\n", err.Error())
+		}
+		if count != int64(i*2) {
+			t.Fatalf("Expect count=%d, got %d instead\n", i*2, count)
+		}
+	}
+	count, err = c.Incr(ctx, key, -1, time.Second)
+	if err != nil {
+		t.Fatalf("Fail to decrement count %s\n", err.Error())
+	}
+	if count != 5 {
+		t.Fatalf("Expect count=5, got %d instead\n", count)
+	}
+
+	//counter expires
+	time.Sleep(1100 * time.Millisecond)
+	count, err = c.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Fail to get count %s\n", err.Error())
+	}
+	if count != 0 {
+		t.Fatalf("Expect count=0 after expiration, got %d instead\n", count)
+	}
+}
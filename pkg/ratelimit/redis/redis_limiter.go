@@ -16,6 +16,7 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/go-redis/redis/v8"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -34,6 +35,9 @@ type RedisRateLimiter struct {
 
 const NUM_REDIS_RETRY = 3
 
+// usage reports of instances that stopped reporting expire after this duration
+const USAGE_REPORT_TTL = time.Hour
+
 func NewRedisRateLimiter(tid tenant.Id, addr string, rqs int) *RedisRateLimiter {
 	return &RedisRateLimiter{
 		client: redis.NewClient(&redis.Options{
@@ -154,3 +158,82 @@ func (r *RedisRateLimiter) take(ctx context.Context, unit int) error {
 	}
 	return nil
 }
+
+func (r *RedisRateLimiter) ReportUsage(ctx context.Context, usage ratelimit.InstanceUsage) error {
+	buf, err := json.Marshal(usage)
+	if err != nil {
+		return err
+	}
+	usageKey := r.tid.Key() + "_usage"
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, usageKey, usage.InstanceId, string(buf))
+		pipe.Expire(ctx, usageKey, USAGE_REPORT_TTL)
+		return nil
+	})
+	return err
+}
+
+func (r *RedisRateLimiter) GetUsage(ctx context.Context) ([]ratelimit.InstanceUsage, error) {
+	results, err := r.client.HGetAll(ctx, r.tid.Key()+"_usage").Result()
+	if err != nil {
+		return nil, err
+	}
+	usages := make([]ratelimit.InstanceUsage, 0, len(results))
+	for instanceId, result := range results {
+		var usage ratelimit.InstanceUsage
+		err = json.Unmarshal([]byte(result), &usage)
+		if err != nil {
+			log.Ctx(ctx).Error().Str("op", "RedisRateLimiter.GetUsage").Str("instanceId", instanceId).Str("error", err.Error()).Msg("Error parsing usage report")
+			continue
+		}
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
+
+// RedisVolumeCounter is a VolumeCounter shared by all ears instances
+type RedisVolumeCounter struct {
+	client *redis.Client
+}
+
+func NewRedisVolumeCounter(addr string) *RedisVolumeCounter {
+	return &RedisVolumeCounter{
+		client: redis.NewClient(&redis.Options{
+			Addr:     addr,
+			Password: "", // no password set
+			DB:       0,  // use default DB
+		}),
+	}
+}
+
+func (c *RedisVolumeCounter) Incr(ctx context.Context, key string, unit int, expiration time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	var ttl *redis.DurationCmd
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.IncrBy(ctx, key, int64(unit))
+		ttl = pipe.TTL(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return 0, &ratelimit.BackendError{Source: err}
+	}
+	//a new counter has no expiration yet
+	if ttl.Val() < 0 {
+		err = c.client.Expire(ctx, key, expiration).Err()
+		if err != nil {
+			return 0, &ratelimit.BackendError{Source: err}
+		}
+	}
+	return incr.Val(), nil
+}
+
+func (c *RedisVolumeCounter) Get(ctx context.Context, key string) (int64, error) {
+	count, err := c.client.Get(ctx, key).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return 0, &ratelimit.BackendError{Source: err}
+	}
+	return count, nil
+}
@@ -32,3 +32,17 @@ func TestRedisBackendLimiter(t *testing.T) {
 
 	testBackendLimiter(limiter, t)
 }
+
+func TestRedisUsageReporter(t *testing.T) {
+	limiter := redis.NewRedisRateLimiter(
+		tenant.Id{"myOrg", "myUsageUnitTestApp"},
+		"localhost:6379",
+		0,
+	)
+
+	testUsageReporter(limiter, t)
+}
+
+func TestRedisVolumeCounter(t *testing.T) {
+	testVolumeCounter(redis.NewRedisVolumeCounter("localhost:6379"), t)
+}
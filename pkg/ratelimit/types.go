@@ -16,6 +16,7 @@ package ratelimit
 
 import (
 	"context"
+	"time"
 )
 
 type RateLimiter interface {
@@ -32,3 +33,50 @@ type RateLimiter interface {
 	//Returns InvalidUnitError if newLimit < 0
 	SetLimit(newLimit int) error
 }
+
+// UsageReporter is implemented by backend limiters that can share per-instance
+// quota usage so that it can be aggregated across all ears instances
+type UsageReporter interface {
+
+	// ReportUsage stores the usage of the instance, replacing its previous report
+	ReportUsage(ctx context.Context, usage InstanceUsage) error
+
+	// GetUsage gets the latest usage reported by every instance
+	GetUsage(ctx context.Context) ([]InstanceUsage, error)
+}
+
+// InstanceUsage is a snapshot of how much of a tenant quota a single ears instance uses
+type InstanceUsage struct {
+	InstanceId    string      `json:"instanceId"`
+	AdaptiveLimit int         `json:"adaptiveLimit"`
+	Events        UsageCounts `json:"events"`
+	Throttled     UsageCounts `json:"throttled"`
+	Reported      int64       `json:"reported"`
+}
+
+// UsageCounts holds event counts over the 1m, 5m and 1h windows
+type UsageCounts struct {
+	OneMinute   int64 `json:"1m"`
+	FiveMinutes int64 `json:"5m"`
+	OneHour     int64 `json:"1h"`
+}
+
+func (c UsageCounts) Add(other UsageCounts) UsageCounts {
+	return UsageCounts{
+		OneMinute:   c.OneMinute + other.OneMinute,
+		FiveMinutes: c.FiveMinutes + other.FiveMinutes,
+		OneHour:     c.OneHour + other.OneHour,
+	}
+}
+
+// VolumeCounter counts units under keys that expire. It backs event volume quotas
+// that reset on calendar boundaries
+type VolumeCounter interface {
+
+	// Incr adds unit (which may be negative) to the counter under key and returns the new count.
+	// A new counter expires after the given expiration
+	Incr(ctx context.Context, key string, unit int, expiration time.Duration) (int64, error)
+
+	// Get gets the count under key. A missing counter counts as 0
+	Get(ctx context.Context, key string) (int64, error)
+}
@@ -35,3 +35,41 @@ type RouteNotFoundError struct {
 func (e *RouteNotFoundError) Error() string {
 	return errs.String("RouteNotFoundError", map[string]interface{}{"routeId": e.RouteId, "orgId": e.TenantId.OrgId, "appId": e.TenantId.AppId}, nil)
 }
+
+// PreconditionFailedError is returned by conditional writes when the stored route
+// does not exist or its ETag does not match the expected one
+type PreconditionFailedError struct {
+	TenantId tenant.Id
+	RouteId  string
+	ETag     string
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return errs.String("PreconditionFailedError", map[string]interface{}{"routeId": e.RouteId, "orgId": e.TenantId.OrgId, "appId": e.TenantId.AppId, "etag": e.ETag}, nil)
+}
+
+// BadCursorError is returned by paginated route listings for cursors they did not issue
+type BadCursorError struct {
+	Cursor string
+}
+
+func (e *BadCursorError) Error() string {
+	return errs.String("BadCursorError", map[string]interface{}{"cursor": e.Cursor}, nil)
+}
+
+// RouteStoppedError nacks events which reach a route after it was drained
+type RouteStoppedError struct {
+}
+
+func (e *RouteStoppedError) Error() string {
+	return errs.String("RouteStoppedError", nil, nil)
+}
+
+// UnknownSenderError nacks events tagged with a sender name the route does not have
+type UnknownSenderError struct {
+	Name string
+}
+
+func (e *UnknownSenderError) Error() string {
+	return errs.String("UnknownSenderError", map[string]interface{}{"name": e.Name}, nil)
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package route
+
+import (
+	"encoding/base64"
+	"github.com/xmidt-org/ears/pkg/tenant"
+)
+
+// Filter selects routes in paginated route listings. Empty fields match any route.
+type Filter struct {
+	TenantId       *tenant.Id // only routes of this tenant
+	ReceiverPlugin string     // receiver plugin type
+	SenderPlugin   string     // sender plugin type
+	Name           string
+	Origin         string
+	UserId         string
+	ModifiedSince  int64 // only routes modified at or after this unix timestamp in seconds
+}
+
+// Page is a page of routes in a paginated route listing. Cursor is empty on the last page
+// and otherwise must be passed to the next call to continue the listing.
+type Page struct {
+	Routes []Config `json:"routes"`
+	Cursor string   `json:"cursor,omitempty"`
+}
+
+// Matches tells if a route config passes the filter
+func (f *Filter) Matches(r *Config) bool {
+	if f.TenantId != nil && !r.TenantId.Equal(*f.TenantId) {
+		return false
+	}
+	if f.ReceiverPlugin != "" && r.Receiver.Plugin != f.ReceiverPlugin {
+		return false
+	}
+	if f.SenderPlugin != "" && !r.HasSenderPlugin(f.SenderPlugin) {
+		return false
+	}
+	if f.Name != "" && r.Name != f.Name {
+		return false
+	}
+	if f.Origin != "" && r.Origin != f.Origin {
+		return false
+	}
+	if f.UserId != "" && r.UserId != f.UserId {
+		return false
+	}
+	if f.ModifiedSince > 0 && r.Modified < f.ModifiedSince {
+		return false
+	}
+	return true
+}
+
+// EncodeCursor turns a storage specific position into an opaque, url safe cursor
+func EncodeCursor(position string) string {
+	if position == "" {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(position))
+}
+
+// DecodeCursor returns the storage specific position of a cursor created by EncodeCursor
+func DecodeCursor(cursor string) (string, error) {
+	position, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", &BadCursorError{Cursor: cursor}
+	}
+	return string(position), nil
+}
+
+// HasSenderPlugin tells if the default sender or one of the named senders is of the plugin type
+func (r *Config) HasSenderPlugin(plugin string) bool {
+	if r.Sender.Plugin == plugin {
+		return true
+	}
+	for _, s := range r.Senders {
+		if s.Plugin == plugin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package route
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+const (
+	PlacementAll    = "all"    // every ears instance runs the route
+	PlacementSingle = "single" // exactly one ears instance runs the route
+	PlacementN      = "n"      // a fixed number of ears instances run the route
+)
+
+// Placement decides which ears instances run a route. Instances are assigned by rendezvous
+// hashing over the route hash and the live instances, so every instance comes to the same
+// assignment on its own and only routes of a departed instance move when the cluster changes.
+// While instances disagree about the live instances an n route may briefly run on more than
+// n instances. A single route additionally requires a lease so that it never runs twice.
+type Placement struct {
+	Policy    string `json:"policy,omitempty"`    // all, single or n. Defaults to all
+	Instances int    `json:"instances,omitempty"` // number of instances running the route with the n policy
+}
+
+func (p *Placement) policy() string {
+	if p == nil || p.Policy == "" {
+		return PlacementAll
+	}
+	return p.Policy
+}
+
+// Exclusive tells if the route may only run on an instance holding its lease
+func (p *Placement) Exclusive() bool {
+	return p.policy() == PlacementSingle
+}
+
+// Validate returns an error if the placement is invalid and nil otherwise
+func (p *Placement) Validate(ctx context.Context) error {
+	switch p.policy() {
+	case PlacementAll, PlacementSingle:
+		return nil
+	case PlacementN:
+		if p.Instances < 1 {
+			return errors.New("placement policy n requires at least one instance")
+		}
+		return nil
+	}
+	return errors.New("invalid placement policy " + p.Policy)
+}
+
+// Hash returns an empty string for the all policy so that routes without placement keep their hash
+func (p *Placement) Hash(ctx context.Context) string {
+	switch p.policy() {
+	case PlacementSingle:
+		return PlacementSingle
+	case PlacementN:
+		return PlacementN + strconv.Itoa(p.Instances)
+	}
+	return ""
+}
+
+// replicas returns the number of instances running a route in a cluster of the given size
+func (p *Placement) replicas(members int) int {
+	switch p.policy() {
+	case PlacementSingle:
+		return 1
+	case PlacementN:
+		if p.Instances < members {
+			return p.Instances
+		}
+	}
+	return members
+}
+
+// IsPlacedOn tells if the route is assigned to the instance given all live instances. The
+// instance itself always counts as live. Identical routes are placed on the same instances
+// so that they can share a live route.
+func (pc *Config) IsPlacedOn(ctx context.Context, instanceId string, members []string) bool {
+	if pc.Placement.policy() == PlacementAll {
+		return true
+	}
+	candidates := make([]string, 0, len(members)+1)
+	candidates = append(candidates, instanceId)
+	for _, member := range members {
+		if member != instanceId {
+			candidates = append(candidates, member)
+		}
+	}
+	replicas := pc.Placement.replicas(len(candidates))
+	if replicas >= len(candidates) {
+		return true
+	}
+	key := pc.Hash(ctx)
+	scores := make(map[string]uint64, len(candidates))
+	for _, member := range candidates {
+		h := fnv.New64a()
+		h.Write([]byte(key + "/" + member))
+		scores[member] = h.Sum64()
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if scores[candidates[i]] != scores[candidates[j]] {
+			return scores[candidates[i]] > scores[candidates[j]]
+		}
+		return candidates[i] < candidates[j]
+	})
+	for _, member := range candidates[:replicas] {
+		if member == instanceId {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 Comcast Cable Communications Management, LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package route_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/xmidt-org/ears/pkg/route"
+	"github.com/xmidt-org/ears/pkg/tenant"
+	"testing"
+)
+
+func placementRoute(id string, placement *route.Placement) route.Config {
+	return route.Config{
+		Id:        id,
+		TenantId:  tenant.Id{OrgId: "myorg", AppId: "myapp"},
+		Receiver:  route.PluginConfig{Plugin: "debug"},
+		Sender:    route.PluginConfig{Plugin: "debug"},
+		Name:      id,
+		Placement: placement,
+	}
+}
+
+func placedOn(ctx context.Context, r route.Config, members []string) []string {
+	placed := make([]string, 0)
+	for _, member := range members {
+		if r.IsPlacedOn(ctx, member, members) {
+			placed = append(placed, member)
+		}
+	}
+	return placed
+}
+
+func TestPlacement(t *testing.T) {
+	ctx := context.Background()
+	members := []string{"i1", "i2", "i3", "i4", "i5"}
+	testCases := []struct {
+		placement *route.Placement
+		replicas  int
+	}{
+		{nil, 5},
+		{&route.Placement{Policy: route.PlacementAll}, 5},
+		{&route.Placement{Policy: route.PlacementSingle}, 1},
+		{&route.Placement{Policy: route.PlacementN, Instances: 3}, 3},
+		{&route.Placement{Policy: route.PlacementN, Instances: 7}, 5},
+	}
+	for i, tc := range testCases {
+		for j := 0; j < 20; j++ {
+			r := placementRoute(fmt.Sprintf("r%d", j), tc.placement)
+			placed := placedOn(ctx, r, members)
+			if len(placed) != tc.replicas {
+				t.Fatalf("case %d: expected route on %d instances, got %v\n", i, tc.replicas, placed)
+			}
+		}
+	}
+}
+
+func TestPlacementFailover(t *testing.T) {
+	ctx := context.Background()
+	members := []string{"i1", "i2", "i3", "i4"}
+	moved := 0
+	for j := 0; j < 50; j++ {
+		r := placementRoute(fmt.Sprintf("r%d", j), &route.Placement{Policy: route.PlacementSingle})
+		placed := placedOn(ctx, r, members)
+		remaining := make([]string, 0)
+		for _, member := range members {
+			if member != placed[0] {
+				remaining = append(remaining, member)
+			}
+		}
+		// the route moves to exactly one of the remaining instances when its instance disappears
+		failover := placedOn(ctx, r, remaining)
+		if len(failover) != 1 || failover[0] == placed[0] {
+			t.Fatalf("expected failover to one other instance, got %v\n", failover)
+		}
+		// routes of other instances stay where they are when an instance disappears
+		other := placementRoute(fmt.Sprintf("o%d", j), &route.Placement{Policy: route.PlacementSingle})
+		before := placedOn(ctx, other, members)
+		if before[0] != placed[0] {
+			after := placedOn(ctx, other, remaining)
+			if after[0] != before[0] {
+				moved++
+			}
+		}
+	}
+	if moved > 0 {
+		t.Fatalf("%d routes moved although their instance is still live\n", moved)
+	}
+}
+
+func TestPlacementValidation(t *testing.T) {
+	ctx := context.Background()
+	for _, p := range []*route.Placement{{Policy: "some"}, {Policy: route.PlacementN}} {
+		r := placementRoute("r1", p)
+		if r.Validate(ctx) == nil {
+			t.Fatalf("expected validation error for placement %+v\n", p)
+		}
+	}
+	r := placementRoute("r1", nil)
+	all := placementRoute("r1", &route.Placement{Policy: route.PlacementAll})
+	single := placementRoute("r1", &route.Placement{Policy: route.PlacementSingle})
+	if r.Hash(ctx) != all.Hash(ctx) || r.Hash(ctx) == single.Hash(ctx) {
+		t.Fatalf("unexpected placement hashes\n")
+	}
+}
+
+func TestPlacementExclusive(t *testing.T) {
+	testCases := []struct {
+		placement *route.Placement
+		exclusive bool
+	}{
+		{nil, false},
+		{&route.Placement{Policy: route.PlacementAll}, false},
+		{&route.Placement{Policy: route.PlacementSingle}, true},
+		{&route.Placement{Policy: route.PlacementN, Instances: 1}, false},
+	}
+	for i, tc := range testCases {
+		if tc.placement.Exclusive() != tc.exclusive {
+			t.Fatalf("case %d: expected exclusive to be %t\n", i, tc.exclusive)
+		}
+	}
+}
@@ -16,6 +16,7 @@ package route
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"github.com/xmidt-org/ears/internal/pkg/rtsemconv"
@@ -25,9 +26,12 @@ import (
 	"github.com/xmidt-org/ears/pkg/receiver"
 	"github.com/xmidt-org/ears/pkg/sender"
 	"go.opentelemetry.io/otel"
+	"time"
 )
 
-func (rte *Route) Run(r receiver.Receiver, f filter.Filterer, s sender.Sender) error {
+// Run receives events from r, passes them through the filter chain f and sends them to s. Events
+// tagged with event.MetadataSenders go to the named senders instead, which are looked up by name.
+func (rte *Route) Run(r receiver.Receiver, f filter.Filterer, s sender.Sender, senders ...sender.Sender) error {
 	if r == nil {
 		return &InvalidRouteError{
 			Err: fmt.Errorf("receiver cannot be nil"),
@@ -38,72 +42,236 @@ func (rte *Route) Run(r receiver.Receiver, f filter.Filterer, s sender.Sender) e
 			Err: fmt.Errorf("sender cannot be nil"),
 		}
 	}
+	named := make(map[string]sender.Sender, len(senders))
+	for _, ns := range senders {
+		if ns == nil {
+			return &InvalidRouteError{
+				Err: fmt.Errorf("sender cannot be nil"),
+			}
+		}
+		if _, ok := named[ns.Name()]; ok {
+			return &InvalidRouteError{
+				Err: fmt.Errorf("duplicate sender name %s", ns.Name()),
+			}
+		}
+		named[ns.Name()] = ns
+	}
 	rte.Lock()
 	rte.r = r
 	rte.f = f
 	rte.s = s
+	rte.senders = named
 	rte.Unlock()
-	var next receiver.NextFn
-	if f == nil {
-		next = func(e event.Event) {
+	var process receiver.NextFn
+	if f == nil && len(named) == 0 {
+		process = func(e event.Event) {
 			tracer := otel.Tracer(rtsemconv.EARSTracerName)
 			_, span := tracer.Start(e.Context(), s.Name())
 			s.Send(e)
 			span.End()
 		}
 	} else {
-		next = func(e event.Event) {
-			events := f.Filter(e)
-			err := fanOut(events, s.Send, s.Name())
+		process = func(e event.Event) {
+			events := []event.Event{e}
+			if f != nil {
+				events = f.Filter(e)
+			}
+			err := fanOut(events, rte.targets)
 			if err != nil {
 				e.Nack(err)
 			}
 		}
 	}
+	next := func(e event.Event) {
+		if !rte.enter() {
+			e.Nack(&RouteStoppedError{})
+			return
+		}
+		tracked := event.Track(e, rte.leave)
+		if rte.Id != "" {
+			tracked.SetContext(event.WithRouteId(tracked.Context(), rte.Id))
+		}
+		process(tracked)
+	}
 	//TODO: deal with errors properly
 	return rte.r.Receive(next)
 
 }
 
+// targets returns the senders an event is tagged with and removes the tag, or the default sender
+// if the event is not tagged
+func (rte *Route) targets(e event.Event) ([]sender.Sender, error) {
+	metadata := e.Metadata()
+	tag, ok := metadata[event.MetadataSenders]
+	if !ok {
+		return []sender.Sender{rte.s}, nil
+	}
+	delete(metadata, event.MetadataSenders)
+	var names []string
+	switch tag := tag.(type) {
+	case string:
+		names = []string{tag}
+	case []string:
+		names = tag
+	case []interface{}:
+		for _, n := range tag {
+			name, ok := n.(string)
+			if !ok {
+				return nil, &InvalidRouteError{
+					Err: fmt.Errorf("sender name %v is not a string", n),
+				}
+			}
+			names = append(names, name)
+		}
+	default:
+		return nil, &InvalidRouteError{
+			Err: fmt.Errorf("invalid %s metadata %v", event.MetadataSenders, tag),
+		}
+	}
+	if len(names) == 0 {
+		return []sender.Sender{rte.s}, nil
+	}
+	senders := make([]sender.Sender, 0, len(names))
+	for _, name := range names {
+		s, ok := rte.senders[name]
+		if !ok {
+			return nil, &UnknownSenderError{Name: name}
+		}
+		senders = append(senders, s)
+	}
+	return senders, nil
+}
+
+// DefaultDrainTimeout bounds how long stopping a route waits for events in flight
+const DefaultDrainTimeout = 5 * time.Second
+
 func (rte *Route) Stop(ctx context.Context) error {
+	_, err := rte.Drain(ctx, DefaultDrainTimeout)
+	return err
+}
+
+// Drain stops receiving, waits until all events in flight are acked or nacked, at most until the
+// timeout or the context is done, and then stops sending
+func (rte *Route) Drain(ctx context.Context, timeout time.Duration) (DrainReport, error) {
 	rte.Lock()
 	defer rte.Unlock()
 	if rte.r == nil {
-		return nil
+		return DrainReport{}, nil
 	}
 	//TODO: should this only be done if plugin reference count is zero
 	//log.Ctx(ctx).Info().Str("op", "StopRoute").Msg("stop receiving")
 	err := rte.r.StopReceiving(ctx)
+	report := rte.AwaitInFlight(ctx, timeout)
 	//log.Ctx(ctx).Info().Str("op", "StopRoute").Msg("stop sending")
 	rte.s.StopSending(ctx)
+	for _, s := range rte.senders {
+		s.StopSending(ctx)
+	}
 	//log.Ctx(ctx).Info().Str("op", "StopRoute").Msg("all stopped")
-	return err
+	return report, err
 }
 
-func fanOut(events []event.Event, next receiver.NextFn, senderName string) error {
-	if next == nil {
-		return &InvalidRouteError{
-			Err: fmt.Errorf("next cannot be nil"),
+// AwaitInFlight waits until all events in flight are acked or nacked, at most until the timeout or the
+// context is done. Intake must already be stopped, the route takes no more events afterwards.
+func (rte *Route) AwaitInFlight(ctx context.Context, timeout time.Duration) DrainReport {
+	rte.flightLock.Lock()
+	completed := rte.completed
+	var idle chan struct{}
+	if rte.inFlight > 0 {
+		idle = make(chan struct{})
+		rte.idle = idle
+	}
+	rte.flightLock.Unlock()
+	if idle != nil {
+		timer := time.NewTimer(timeout)
+		select {
+		case <-idle:
+		case <-timer.C:
+		case <-ctx.Done():
 		}
+		timer.Stop()
 	}
-	if len(events) == 0 {
-		return nil
+	rte.flightLock.Lock()
+	defer rte.flightLock.Unlock()
+	rte.closed = true
+	rte.idle = nil
+	return DrainReport{Drained: rte.completed - completed, Abandoned: rte.inFlight}
+}
+
+// enter counts an event in flight unless the route was drained
+func (rte *Route) enter() bool {
+	rte.flightLock.Lock()
+	defer rte.flightLock.Unlock()
+	if rte.closed {
+		return false
+	}
+	rte.inFlight++
+	return true
+}
+
+// leave is called when an event in flight and all its clones are acked or nacked
+func (rte *Route) leave() {
+	rte.flightLock.Lock()
+	defer rte.flightLock.Unlock()
+	rte.inFlight--
+	rte.completed++
+	if rte.inFlight == 0 && rte.idle != nil {
+		close(rte.idle)
+		rte.idle = nil
+	}
+}
+
+// fanOut sends each event to its senders. An event going to several senders is cloned for each of
+// them and acked once all clones are acked.
+func fanOut(events []event.Event, targets func(e event.Event) ([]sender.Sender, error)) error {
+	if targets == nil {
+		return &InvalidRouteError{
+			Err: fmt.Errorf("targets cannot be nil"),
+		}
 	}
 	for _, e := range events {
-		go func(evt event.Event) {
-			defer func() {
-				p := recover()
-				if p != nil {
-					panicErr := panics.ToError(p)
-					log.Ctx(evt.Context()).Error().Str("op", "fanOutToSender").Str("error", panicErr.Error()).
-						Str("stackTrace", panicErr.StackTrace()).Msg("A panic has occurred")
-				}
-			}()
-			tracer := otel.Tracer(rtsemconv.EARSTracerName)
-			_, span := tracer.Start(evt.Context(), senderName)
-			next(evt)
-			span.End()
-		}(e)
+		senders, err := targets(e)
+		if err != nil {
+			e.Nack(err)
+			continue
+		}
+		if len(senders) == 1 {
+			send(e, senders[0])
+			continue
+		}
+		clones := make([]event.Event, 0, len(senders))
+		for range senders {
+			clone, err := e.Clone(e.Context())
+			if err != nil {
+				break
+			}
+			clones = append(clones, clone)
+		}
+		if len(clones) < len(senders) {
+			e.Nack(errors.New("failed to clone event"))
+			continue
+		}
+		for i, s := range senders {
+			send(clones[i], s)
+		}
+		e.Ack()
 	}
 	return nil
 }
+
+func send(e event.Event, s sender.Sender) {
+	go func(evt event.Event) {
+		defer func() {
+			p := recover()
+			if p != nil {
+				panicErr := panics.ToError(p)
+				log.Ctx(evt.Context()).Error().Str("op", "fanOutToSender").Str("error", panicErr.Error()).
+					Str("stackTrace", panicErr.StackTrace()).Msg("A panic has occurred")
+			}
+		}()
+		tracer := otel.Tracer(rtsemconv.EARSTracerName)
+		_, span := tracer.Start(evt.Context(), s.Name())
+		s.Send(evt)
+		span.End()
+	}(e)
+}
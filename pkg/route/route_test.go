@@ -16,12 +16,17 @@ package route_test
 
 import (
 	"context"
+	"errors"
+	"github.com/xmidt-org/ears/pkg/event"
 	"github.com/xmidt-org/ears/pkg/filter"
 	"github.com/xmidt-org/ears/pkg/receiver"
 	"github.com/xmidt-org/ears/pkg/route"
 	"github.com/xmidt-org/ears/pkg/sender"
+	"github.com/xmidt-org/ears/pkg/tenant"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 )
@@ -111,6 +116,276 @@ func TestRoutes(t *testing.T) {
 	}
 }
 
+func TestDrain(t *testing.T) {
+	a := NewWithT(t)
+	var lock sync.Mutex
+	var held []event.Event
+	sendingStopped := false
+	nextCh := make(chan receiver.NextFn, 1)
+	done := make(chan struct{})
+	r := &receiver.ReceiverMock{
+		ReceiveFunc: func(next receiver.NextFn) error {
+			nextCh <- next
+			<-done
+			return nil
+		},
+		StopReceivingFunc: func(ctx context.Context) error {
+			close(done)
+			return nil
+		},
+	}
+	s := &sender.SenderMock{
+		NameFunc: func() string {
+			return "mock"
+		},
+		SendFunc: func(e event.Event) {
+			// hold on to the events to keep them in flight
+			lock.Lock()
+			held = append(held, e)
+			lock.Unlock()
+		},
+		StopSendingFunc: func(ctx context.Context) {
+			lock.Lock()
+			sendingStopped = true
+			lock.Unlock()
+		},
+	}
+	rte := &route.Route{}
+	go rte.Run(r, nil, s)
+	next := <-nextCh
+	acked := make(chan error, 3)
+	newEvent := func() event.Event {
+		e, err := event.New(context.Background(), map[string]interface{}{"foo": "bar"}, event.WithAck(
+			func(e event.Event) {
+				acked <- nil
+			}, func(e event.Event, err error) {
+				acked <- err
+			}))
+		a.Expect(err).To(BeNil())
+		return e
+	}
+	next(newEvent())
+	next(newEvent())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		lock.Lock()
+		defer lock.Unlock()
+		// sending must not stop while events are in flight
+		a.Expect(sendingStopped).To(BeFalse())
+		for _, e := range held {
+			e.Ack()
+		}
+	}()
+	report, err := rte.Drain(context.Background(), 5*time.Second)
+	a.Expect(err).To(BeNil())
+	a.Expect(report).To(Equal(route.DrainReport{Drained: 2, Abandoned: 0}))
+	a.Expect(sendingStopped).To(BeTrue())
+	a.Expect(<-acked).To(BeNil())
+	a.Expect(<-acked).To(BeNil())
+	// events reaching the drained route are nacked
+	next(newEvent())
+	var stopped *route.RouteStoppedError
+	a.Expect(errors.As(<-acked, &stopped)).To(BeTrue())
+}
+
+func TestDrainDeadline(t *testing.T) {
+	a := NewWithT(t)
+	nextCh := make(chan receiver.NextFn, 1)
+	done := make(chan struct{})
+	r := &receiver.ReceiverMock{
+		ReceiveFunc: func(next receiver.NextFn) error {
+			nextCh <- next
+			<-done
+			return nil
+		},
+		StopReceivingFunc: func(ctx context.Context) error {
+			close(done)
+			return nil
+		},
+	}
+	s := &sender.SenderMock{
+		NameFunc: func() string {
+			return "mock"
+		},
+		SendFunc: func(e event.Event) {
+			// never acknowledge the event
+		},
+		StopSendingFunc: func(ctx context.Context) {
+		},
+	}
+	rte := &route.Route{}
+	go rte.Run(r, nil, s)
+	next := <-nextCh
+	e, err := event.New(context.Background(), map[string]interface{}{"foo": "bar"})
+	a.Expect(err).To(BeNil())
+	next(e)
+	start := time.Now()
+	report, err := rte.Drain(context.Background(), 100*time.Millisecond)
+	a.Expect(err).To(BeNil())
+	a.Expect(report).To(Equal(route.DrainReport{Drained: 0, Abandoned: 1}))
+	a.Expect(time.Since(start) < time.Second).To(BeTrue())
+}
+
+func TestNamedSenders(t *testing.T) {
+	a := NewWithT(t)
+	nextCh := make(chan receiver.NextFn, 1)
+	done := make(chan struct{})
+	r := &receiver.ReceiverMock{
+		ReceiveFunc: func(next receiver.NextFn) error {
+			nextCh <- next
+			<-done
+			return nil
+		},
+		StopReceivingFunc: func(ctx context.Context) error {
+			close(done)
+			return nil
+		},
+	}
+	sent := make(chan string, 10)
+	newSender := func(name string) *sender.SenderMock {
+		return &sender.SenderMock{
+			NameFunc: func() string {
+				return name
+			},
+			SendFunc: func(e event.Event) {
+				_, tagged := e.Metadata()[event.MetadataSenders]
+				a.Expect(tagged).To(BeFalse())
+				sent <- name
+				e.Ack()
+			},
+			StopSendingFunc: func(ctx context.Context) {
+			},
+		}
+	}
+	f := &filter.FiltererMock{
+		FilterFunc: func(e event.Event) []event.Event {
+			return []event.Event{e}
+		},
+	}
+	rte := &route.Route{}
+	go rte.Run(r, f, newSender("default"), newSender("kafka"), newSender("sqs"))
+	next := <-nextCh
+	send := func(senders interface{}) error {
+		metadata := map[string]interface{}{}
+		if senders != nil {
+			metadata[event.MetadataSenders] = senders
+		}
+		result := make(chan error, 1)
+		e, err := event.New(context.Background(), map[string]interface{}{"foo": "bar"}, event.WithMetadata(metadata), event.WithAck(
+			func(e event.Event) {
+				result <- nil
+			}, func(e event.Event, err error) {
+				result <- err
+			}))
+		a.Expect(err).To(BeNil())
+		next(e)
+		select {
+		case err = <-result:
+			return err
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for ack")
+		}
+		return nil
+	}
+	received := func(n int) []string {
+		names := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			names = append(names, <-sent)
+		}
+		return names
+	}
+
+	a.Expect(send(nil)).To(BeNil())
+	a.Expect(received(1)).To(Equal([]string{"default"}))
+
+	a.Expect(send([]interface{}{"kafka"})).To(BeNil())
+	a.Expect(received(1)).To(Equal([]string{"kafka"}))
+
+	a.Expect(send([]interface{}{"kafka", "sqs"})).To(BeNil())
+	a.Expect(received(2)).To(ConsistOf("kafka", "sqs"))
+
+	var unknown *route.UnknownSenderError
+	a.Expect(errors.As(send("kinesis"), &unknown)).To(BeTrue())
+	a.Expect(unknown.Name).To(Equal("kinesis"))
+
+	a.Expect(rte.Stop(context.Background())).To(BeNil())
+}
+
+func TestSendersValidation(t *testing.T) {
+	ctx := context.Background()
+	config := func(senders ...route.PluginConfig) route.Config {
+		return route.Config{
+			Id:       "r1",
+			UserId:   "me",
+			TenantId: tenant.Id{OrgId: "myorg", AppId: "myapp"},
+			Receiver: route.PluginConfig{Plugin: "debug"},
+			Sender:   route.PluginConfig{Plugin: "debug"},
+			Senders:  senders,
+		}
+	}
+	valid := config(route.PluginConfig{Plugin: "kafka", Name: "high"}, route.PluginConfig{Plugin: "sqs", Name: "low"})
+	if err := valid.Validate(ctx); err != nil {
+		t.Fatalf("unexpected validation error %s\n", err.Error())
+	}
+	if !valid.HasSenderPlugin("sqs") || valid.HasSenderPlugin("http") {
+		t.Fatalf("unexpected sender plugins\n")
+	}
+	for _, senders := range [][]route.PluginConfig{
+		{{Plugin: "kafka"}},
+		{{Plugin: "kafka", Name: "high"}, {Plugin: "sqs", Name: "high"}},
+	} {
+		r := config(senders...)
+		if r.Validate(ctx) == nil {
+			t.Fatalf("expected validation error for senders %+v\n", senders)
+		}
+	}
+	none := config()
+	if valid.Hash(ctx) == none.Hash(ctx) {
+		t.Fatalf("expected senders to be part of the hash\n")
+	}
+}
+
+func TestBranchesValidation(t *testing.T) {
+	ctx := context.Background()
+	config := func(chain ...route.PluginConfig) route.Config {
+		return route.Config{
+			Id:          "r1",
+			UserId:      "me",
+			TenantId:    tenant.Id{OrgId: "myorg", AppId: "myapp"},
+			Receiver:    route.PluginConfig{Plugin: "debug"},
+			Sender:      route.PluginConfig{Plugin: "debug"},
+			FilterChain: chain,
+		}
+	}
+	branches := route.PluginConfig{
+		Join: "merge",
+		Branches: [][]route.PluginConfig{
+			{{Plugin: "js"}},
+			{{Plugin: "jq"}, {Plugin: "match"}},
+		},
+	}
+	valid := config(route.PluginConfig{Plugin: "decode"}, branches)
+	if err := valid.Validate(ctx); err != nil {
+		t.Fatalf("unexpected validation error %s\n", err.Error())
+	}
+	for _, f := range []route.PluginConfig{
+		{Plugin: "js", Branches: [][]route.PluginConfig{{{Plugin: "js"}}}},
+		{Join: "zip", Branches: [][]route.PluginConfig{{{Plugin: "js"}}}},
+		{Branches: [][]route.PluginConfig{{{Name: "noPlugin"}}}},
+	} {
+		r := config(f)
+		if r.Validate(ctx) == nil {
+			t.Fatalf("expected validation error for filter %+v\n", f)
+		}
+	}
+	concat := branches
+	concat.Join = "concat"
+	other := config(route.PluginConfig{Plugin: "decode"}, concat)
+	if valid.Hash(ctx) == other.Hash(ctx) {
+		t.Fatalf("expected join to be part of the hash\n")
+	}
+}
+
 // =========================================================================
 
 func errTypeToString(err error) string {
@@ -120,3 +395,52 @@ func errTypeToString(err error) string {
 
 	return reflect.TypeOf(err).String()
 }
+
+func TestRouteIdContext(t *testing.T) {
+	a := NewWithT(t)
+	nextCh := make(chan receiver.NextFn, 1)
+	done := make(chan struct{})
+	r := &receiver.ReceiverMock{
+		ReceiveFunc: func(next receiver.NextFn) error {
+			nextCh <- next
+			<-done
+			return nil
+		},
+		StopReceivingFunc: func(ctx context.Context) error {
+			close(done)
+			return nil
+		},
+	}
+	s := &sender.SenderMock{
+		NameFunc: func() string {
+			return "default"
+		},
+		SendFunc: func(e event.Event) {
+			e.Ack()
+		},
+		StopSendingFunc: func(ctx context.Context) {
+		},
+	}
+	routeIds := make(chan string, 1)
+	f := &filter.FiltererMock{
+		FilterFunc: func(e event.Event) []event.Event {
+			routeIds <- event.RouteId(e.Context())
+			return []event.Event{e}
+		},
+	}
+	rte := &route.Route{Id: "r1"}
+	go rte.Run(r, f, s)
+	next := <-nextCh
+	acked := make(chan error, 1)
+	e, err := event.New(context.Background(), map[string]interface{}{"foo": "bar"}, event.WithAck(
+		func(e event.Event) {
+			acked <- nil
+		}, func(e event.Event, err error) {
+			acked <- err
+		}))
+	a.Expect(err).To(BeNil())
+	next(e)
+	a.Eventually(routeIds, time.Second).Should(Receive(Equal("r1")))
+	a.Eventually(acked, time.Second).Should(Receive(BeNil()))
+	a.Expect(rte.Stop(context.Background())).To(BeNil())
+}
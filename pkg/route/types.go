@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"errors"
 	"regexp"
+	"strconv"
 	"sync"
 
 	"github.com/xmidt-org/ears/pkg/filter"
@@ -29,16 +30,33 @@ import (
 )
 
 type Router interface {
-	Run(r receiver.Receiver, f filter.Filterer, s sender.Sender) error
+	Run(r receiver.Receiver, f filter.Filterer, s sender.Sender, senders ...sender.Sender) error
 	Stop(ctx context.Context) error
 }
 
 type Route struct {
 	sync.Mutex
 
-	r receiver.Receiver
-	f filter.Filterer
-	s sender.Sender
+	Id string // route id, passed to the filters with the event context
+
+	r       receiver.Receiver
+	f       filter.Filterer
+	s       sender.Sender            // default sender
+	senders map[string]sender.Sender // named senders selected by event.MetadataSenders
+
+	// events in flight, guarded by flightLock rather than the route lock which is held while stopping
+	flightLock sync.Mutex
+	inFlight   int
+	completed  int
+	closed     bool          // the route takes no more events after a drain
+	idle       chan struct{} // closed when the last event in flight completes during a drain
+}
+
+// DrainReport tells how many events in flight completed while draining a route and how many
+// were abandoned at the deadline
+type DrainReport struct {
+	Drained   int `json:"drained"`
+	Abandoned int `json:"abandoned"`
 }
 
 type InvalidRouteError struct {
@@ -46,9 +64,11 @@ type InvalidRouteError struct {
 }
 
 type PluginConfig struct {
-	Plugin string      `json:"plugin,omitempty"` // plugin or filter type, e.g. kafka, kds, sqs, webhook, filter
-	Name   string      `json:"name,omitempty"`   // plugin label to allow multiple instances of otherwise identical plugin configurations
-	Config interface{} `json:"config,omitempty"` // plugin specific configuration parameters
+	Plugin   string           `json:"plugin,omitempty"`   // plugin or filter type, e.g. kafka, kds, sqs, webhook, filter
+	Name     string           `json:"name,omitempty"`     // plugin label to allow multiple instances of otherwise identical plugin configurations
+	Config   interface{}      `json:"config,omitempty"`   // plugin specific configuration parameters
+	Branches [][]PluginConfig `json:"branches,omitempty"` // filter chain only: sub-chains each event is passed through in parallel instead of a plugin
+	Join     string           `json:"join,omitempty"`     // how branch outputs are joined: concat (default) or merge
 }
 
 type Config struct {
@@ -59,9 +79,11 @@ type Config struct {
 	Origin       string         `json:"origin,omitempty"`       // optional reference to route owner, e.g. Flow ID in case of Gears
 	Receiver     PluginConfig   `json:"receiver,omitempty"`     // source plugin configuration
 	Sender       PluginConfig   `json:"sender,omitempty"`       // destination plugin configuration
+	Senders      []PluginConfig `json:"senders,omitempty"`      // additional named destinations selected per event, e.g. by a switch filter
 	FilterChain  []PluginConfig `json:"filterChain,omitempty"`  // filter chain configuration
 	DeliveryMode string         `json:"deliveryMode,omitempty"` // possible values: fire_and_forget, at_least_once, exactly_once
 	Debug        bool           `json:"debug,omitempty"`        // if true generate debug logs and metrics for events taking this route
+	Placement    *Placement     `json:"placement,omitempty"`    // ears instances running the route, all instances if not set
 	Created      int64          `json:"created,omitempty"`      // time on when route was created, in unix timestamp seconds
 	Modified     int64          `json:"modified,omitempty"`     // last time when route was modified, in unix timestamp seconds
 }
@@ -91,12 +113,31 @@ func (rc *Config) Validate(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	if rc.FilterChain != nil {
-		for _, f := range rc.FilterChain {
-			err = f.Validate(ctx)
-			if err != nil {
-				return err
-			}
+	names := make(map[string]bool)
+	for _, s := range rc.Senders {
+		err = s.Validate(ctx)
+		if err != nil {
+			return err
+		}
+		if s.Name == "" {
+			return errors.New("missing name for sender " + s.Plugin)
+		}
+		if names[s.Name] {
+			return errors.New("duplicate sender name " + s.Name)
+		}
+		names[s.Name] = true
+	}
+	if rc.Receiver.Branches != nil || rc.Sender.Branches != nil {
+		return errors.New("branches are only supported in the filter chain")
+	}
+	err = validateFilterChain(ctx, rc.FilterChain)
+	if err != nil {
+		return err
+	}
+	if rc.Placement != nil {
+		err = rc.Placement.Validate(ctx)
+		if err != nil {
+			return err
 		}
 	}
 	if rc.Id == "" {
@@ -120,6 +161,32 @@ func (rc *Config) Validate(ctx context.Context) error {
 	return nil
 }
 
+// validateFilterChain validates the filters of a chain and the chains of their branches
+func validateFilterChain(ctx context.Context, chain []PluginConfig) error {
+	for _, f := range chain {
+		if f.Branches == nil {
+			err := f.Validate(ctx)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if f.Plugin != "" {
+			return errors.New("filter with branches cannot have a plugin type")
+		}
+		if f.Join != "" && f.Join != filter.JoinConcat && f.Join != filter.JoinMerge {
+			return errors.New("invalid join " + f.Join)
+		}
+		for _, branch := range f.Branches {
+			err := validateFilterChain(ctx, branch)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 //Hash returns the md5 hash of the plugin config
 func (pc *PluginConfig) Hash(ctx context.Context) string {
 	cfg := ""
@@ -130,6 +197,15 @@ func (pc *PluginConfig) Hash(ctx context.Context) string {
 		}
 	}
 	str := pc.Name + pc.Plugin + cfg
+	if pc.Branches != nil {
+		str += pc.Join
+		for _, branch := range pc.Branches {
+			str += "branch"
+			for _, f := range branch {
+				str += f.Hash(ctx)
+			}
+		}
+	}
 	hash := hasher.String(str)
 	return hash
 }
@@ -140,15 +216,34 @@ func (pc *Config) Hash(ctx context.Context) string {
 	str := pc.TenantId.OrgId + pc.TenantId.AppId + pc.Name + pc.DeliveryMode + pc.UserId
 	str += pc.Receiver.Hash(ctx)
 	str += pc.Sender.Hash(ctx)
+	for _, s := range pc.Senders {
+		str += s.Hash(ctx)
+	}
 	if pc.FilterChain != nil {
 		for _, f := range pc.FilterChain {
 			str += f.Hash(ctx)
 		}
 	}
+	str += pc.Placement.Hash(ctx)
 	hash := hasher.String(str)
 	return hash
 }
 
+// AnyETag matches any existing route in conditional writes
+const AnyETag = "*"
+
+// ETag returns an entity tag for this revision of the route config. Unlike Hash it
+// covers all route settings as well as the modified time, so it changes with every update.
+func (pc *Config) ETag(ctx context.Context) string {
+	str := pc.Hash(ctx) + pc.Origin + strconv.FormatBool(pc.Debug) + strconv.FormatInt(pc.Modified, 10)
+	return hasher.String(str)
+}
+
+// MatchesETag tells if the route config matches the given entity tag
+func (pc *Config) MatchesETag(ctx context.Context, etag string) bool {
+	return etag == AnyETag || etag == pc.ETag(ctx)
+}
+
 //All route operations are synchronous. The storer should respect the cancellation
 //from the context and cancel its operation gracefully when desired.
 type RouteStorer interface {
@@ -160,6 +255,13 @@ type RouteStorer interface {
 
 	GetAllTenantRoutes(ctx context.Context, id tenant.Id) ([]Config, error)
 
+	//ListRoutes returns a page of routes passing the filter, starting at the
+	//cursor returned with the previous page (empty for the first page). Limit
+	//is a hint for the page size, 0 means no limit. A page may hold fewer routes
+	//than the limit even if more routes follow. An invalid cursor should
+	//result in a BadCursorError.
+	ListRoutes(ctx context.Context, filter Filter, cursor string, limit int) (Page, error)
+
 	//SetRoute will add the route if it new or update the route if
 	//it is an existing one. It will also update the create time and
 	//modified time of the route where appropriate.
@@ -167,7 +269,17 @@ type RouteStorer interface {
 
 	SetRoutes(context.Context, []Config) error
 
+	//SetRouteIfMatch behaves like SetRoute but only updates the route if it
+	//exists and its current ETag matches the given etag (or etag is AnyETag).
+	//Otherwise, the function should return a PreconditionFailedError. The
+	//check and the write must be atomic.
+	SetRouteIfMatch(ctx context.Context, r Config, etag string) error
+
 	DeleteRoute(context.Context, tenant.Id, string) error
 
 	DeleteRoutes(context.Context, tenant.Id, []string) error
+
+	//DeleteRouteIfMatch behaves like DeleteRoute with the same conditions
+	//as SetRouteIfMatch
+	DeleteRouteIfMatch(ctx context.Context, tid tenant.Id, id string, etag string) error
 }
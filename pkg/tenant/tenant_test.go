@@ -38,3 +38,138 @@ func TestTenantId(t *testing.T) {
 	g.Assert(t, "string", []byte(id1.ToString()))
 	g.Assert(t, "keyRoute", []byte(id1.KeyWithRoute("routeId")))
 }
+
+func TestQuotaValidate(t *testing.T) {
+	validQuotas := []tenant.Quota{
+		{EventsPerSec: 10},
+		{EventsPerSec: 10, Policy: tenant.QuotaPolicyWait},
+		{EventsPerSec: 10, Policy: tenant.QuotaPolicyDrop},
+		{EventsPerSec: 10, Policy: tenant.QuotaPolicyReject},
+		{EventsPerSec: 10, EventsPerDay: 1000, EventsPerMonth: 20000},
+	}
+	for _, q := range validQuotas {
+		if err := q.Validate(); err != nil {
+			t.Errorf("Expect quota %v to be valid, got %s\n", q, err.Error())
+		}
+	}
+
+	invalidQuotas := []tenant.Quota{
+		{EventsPerSec: -1},
+		{EventsPerSec: 10, Policy: "block"},
+		{EventsPerSec: 10, EventsPerDay: -1},
+		{EventsPerSec: 10, EventsPerMonth: -1},
+	}
+	for _, q := range invalidQuotas {
+		if err := q.Validate(); err == nil {
+			t.Errorf("Expect quota %v to be invalid\n", q)
+		}
+	}
+
+	if (tenant.Quota{}).EnforcementPolicy() != tenant.QuotaPolicyWait {
+		t.Errorf("Expect default policy to be wait")
+	}
+}
+
+func TestConfigPolicy(t *testing.T) {
+	config := tenant.Config{
+		AllowedPlugins: []string{"debug", "http", "kafka"},
+		DeniedPlugins:  []string{"kafka"},
+		AllowedHosts:   []string{"*.example.com", "localhost:8080"},
+		AllowedUrls:    []string{"https://api.example.com/v1/"},
+	}
+
+	plugins := map[string]bool{"debug": true, "http": true, "kafka": false, "sqs": false}
+	for plugin, allowed := range plugins {
+		if config.IsPluginAllowed(plugin) != allowed {
+			t.Errorf("Expect plugin %s allowed=%t\n", plugin, allowed)
+		}
+	}
+
+	hosts := map[string]bool{
+		"broker.example.com":      true,
+		"broker.example.com:9092": true,
+		"BROKER.EXAMPLE.COM":      true,
+		"example.com":             false,
+		"notexample.com":          false,
+		"localhost:8080":          true,
+		"localhost:8081":          false,
+		"localhost":               false,
+	}
+	for host, allowed := range hosts {
+		if config.IsHostAllowed(host) != allowed {
+			t.Errorf("Expect host %s allowed=%t\n", host, allowed)
+		}
+	}
+
+	urls := map[string]bool{
+		"https://api.example.com/v1/events": true,
+		"https://api.example.com/v2/events": false,
+		"https://evil.com/v1/events":        false,
+	}
+	for u, allowed := range urls {
+		if config.IsUrlAllowed(u) != allowed {
+			t.Errorf("Expect url %s allowed=%t\n", u, allowed)
+		}
+	}
+
+	// url prefixes match scheme and host exactly and paths on segment boundaries
+	config = tenant.Config{
+		AllowedUrls: []string{"https://api.example.com", "https://hooks.example.com/v1"},
+	}
+	urls = map[string]bool{
+		"https://api.example.com":                   true,
+		"https://api.example.com/events":            true,
+		"https://API.example.com/events":            true,
+		"http://api.example.com/events":             false,
+		"https://api.example.com.evil.net/events":   false,
+		"https://api.example.com:8443/events":       false,
+		"https://hooks.example.com/v1":              true,
+		"https://hooks.example.com/v1/events":       true,
+		"https://hooks.example.com/v10/events":      false,
+		"https://hooks.example.com/v1/../admin":     false,
+		"https://hooks.example.com.evil.net/v1/abc": false,
+	}
+	for u, allowed := range urls {
+		if config.IsUrlAllowed(u) != allowed {
+			t.Errorf("Expect url %s allowed=%t\n", u, allowed)
+		}
+	}
+
+	if !(tenant.Config{}).IsHostAllowed("anything:1234") || !(tenant.Config{}).IsUrlAllowed("http://anything") {
+		t.Errorf("Expect everything to be allowed without allow lists\n")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	validConfigs := []tenant.Config{
+		{Quota: tenant.Quota{EventsPerSec: 10}},
+		{
+			Quota:          tenant.Quota{EventsPerSec: 10},
+			AllowedPlugins: []string{"debug"},
+			DeniedPlugins:  []string{"http"},
+			AllowedHosts:   []string{"*.example.com"},
+			AllowedUrls:    []string{"https://api.example.com/"},
+			Defaults:       map[string]interface{}{"kafka": map[string]interface{}{"brokers": "broker.example.com:9092"}},
+		},
+	}
+	for _, c := range validConfigs {
+		if err := c.Validate(); err != nil {
+			t.Errorf("Expect config %v to be valid, got %s\n", c, err.Error())
+		}
+	}
+
+	invalidConfigs := []tenant.Config{
+		{Quota: tenant.Quota{EventsPerSec: -1}},
+		{AllowedPlugins: []string{"http"}, DeniedPlugins: []string{"http"}},
+		{AllowedPlugins: []string{""}},
+		{DeniedPlugins: []string{""}},
+		{AllowedHosts: []string{""}},
+		{AllowedUrls: []string{"example.com"}},
+		{Defaults: map[string]interface{}{"kafka": "brokers"}},
+	}
+	for _, c := range invalidConfigs {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Expect config %v to be invalid\n", c)
+		}
+	}
+}
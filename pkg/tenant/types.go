@@ -17,6 +17,11 @@ package tenant
 import (
 	"context"
 	"encoding/base64"
+	"errors"
+	"net"
+	"net/url"
+	"path"
+	"strings"
 )
 
 const delimiter = "."
@@ -48,13 +53,176 @@ func (id Id) ToString() string {
 }
 
 type Config struct {
-	Tenant   Id    `json:"tenant"`             //tenant id
-	Quota    Quota `json:"quota"`              // tenant quota
-	Modified int64 `json:"modified,omitempty"` // last time when the tenant config is modified
+	Tenant         Id                     `json:"tenant"`                   //tenant id
+	Quota          Quota                  `json:"quota"`                    // tenant quota
+	AllowedPlugins []string               `json:"allowedPlugins,omitempty"` // plugin types routes may use. Empty allows all plugin types
+	DeniedPlugins  []string               `json:"deniedPlugins,omitempty"`  // plugin types routes must not use
+	AllowedHosts   []string               `json:"allowedHosts,omitempty"`   // hosts plugins may connect to, e.g. broker.example.com:9092 or *.example.com. Empty allows all hosts
+	AllowedUrls    []string               `json:"allowedUrls,omitempty"`    // url prefixes plugins may connect to. Empty allows all urls
+	Defaults       map[string]interface{} `json:"defaults,omitempty"`       // plugin config defaults by plugin type, merged into the plugin configs of new routes
+	Modified       int64                  `json:"modified,omitempty"`       // last time when the tenant config is modified
+}
+
+// Quota policies decide what happens to an event received while the tenant
+// is over its quota
+const (
+	QuotaPolicyWait   = "wait"   // block the receiver until the event is allowed through (default)
+	QuotaPolicyDrop   = "drop"   // ack the event without processing it
+	QuotaPolicyReject = "reject" // nack the event with a QuotaExceededError
+)
+
+func (c Config) Validate() error {
+	err := c.Quota.Validate()
+	if err != nil {
+		return err
+	}
+	for _, plugin := range c.AllowedPlugins {
+		if plugin == "" {
+			return errors.New("allowedPlugins cannot contain empty plugin type")
+		}
+		if contains(c.DeniedPlugins, plugin) {
+			return errors.New("plugin type " + plugin + " is both allowed and denied")
+		}
+	}
+	for _, plugin := range c.DeniedPlugins {
+		if plugin == "" {
+			return errors.New("deniedPlugins cannot contain empty plugin type")
+		}
+	}
+	for _, host := range c.AllowedHosts {
+		if host == "" {
+			return errors.New("allowedHosts cannot contain empty host")
+		}
+	}
+	for _, allowedUrl := range c.AllowedUrls {
+		u, err := url.Parse(allowedUrl)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.New("invalid allowed url " + allowedUrl)
+		}
+	}
+	for plugin, defaults := range c.Defaults {
+		if _, ok := defaults.(map[string]interface{}); !ok {
+			return errors.New("defaults for plugin type " + plugin + " must be an object")
+		}
+	}
+	return nil
+}
+
+// IsPluginAllowed tells if routes of the tenant may use the plugin type
+func (c Config) IsPluginAllowed(plugin string) bool {
+	if contains(c.DeniedPlugins, plugin) {
+		return false
+	}
+	return len(c.AllowedPlugins) == 0 || contains(c.AllowedPlugins, plugin)
+}
+
+// IsHostAllowed tells if plugins of the tenant may connect to the host. The host may
+// include a port. A pattern without port matches any port and a pattern starting
+// with *. matches all subdomains
+func (c Config) IsHostAllowed(host string) bool {
+	if len(c.AllowedHosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(host)
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	for _, pattern := range c.AllowedHosts {
+		pattern = strings.ToLower(pattern)
+		candidate := hostname
+		if _, _, err := net.SplitHostPort(pattern); err == nil {
+			candidate = host
+		}
+		if pattern == candidate {
+			return true
+		}
+		if strings.HasPrefix(pattern, "*.") && strings.HasSuffix(candidate, pattern[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUrlAllowed tells if plugins of the tenant may connect to the url. The url must
+// match one of the allowed url prefixes and its host must be allowed. A prefix matches
+// urls with the same scheme and host whose path is the prefix path or below it
+func (c Config) IsUrlAllowed(rawUrl string) bool {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return false
+	}
+	if !c.IsHostAllowed(u.Host) {
+		return false
+	}
+	if len(c.AllowedUrls) == 0 {
+		return true
+	}
+	for _, prefix := range c.AllowedUrls {
+		if isUrlPrefix(prefix, u) {
+			return true
+		}
+	}
+	return false
+}
+
+func isUrlPrefix(prefix string, u *url.URL) bool {
+	p, err := url.Parse(prefix)
+	if err != nil {
+		return false
+	}
+	if !strings.EqualFold(p.Scheme, u.Scheme) || !strings.EqualFold(p.Host, u.Host) {
+		return false
+	}
+	prefixPath := strings.TrimSuffix(p.Path, "/")
+	if prefixPath == "" {
+		return true
+	}
+	// clean so that dot segments cannot climb out of the prefix
+	urlPath := path.Clean("/" + u.Path)
+	return urlPath == prefixPath || strings.HasPrefix(urlPath, prefixPath+"/")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
 type Quota struct {
-	EventsPerSec int `json:"eventsPerSec"`
+	EventsPerSec   int    `json:"eventsPerSec"`
+	EventsPerDay   int    `json:"eventsPerDay,omitempty"`   // calendar day (UTC) event volume. 0 means no limit
+	EventsPerMonth int    `json:"eventsPerMonth,omitempty"` // calendar month (UTC) event volume. 0 means no limit
+	Policy         string `json:"policy,omitempty"`         // one of wait, drop or reject. Defaults to wait
+}
+
+// EnforcementPolicy returns the quota policy with the default applied
+func (q Quota) EnforcementPolicy() string {
+	if q.Policy == "" {
+		return QuotaPolicyWait
+	}
+	return q.Policy
+}
+
+func (q Quota) Validate() error {
+	if q.EventsPerSec < 0 {
+		return errors.New("eventsPerSec cannot be negative")
+	}
+	if q.EventsPerDay < 0 {
+		return errors.New("eventsPerDay cannot be negative")
+	}
+	if q.EventsPerMonth < 0 {
+		return errors.New("eventsPerMonth cannot be negative")
+	}
+	switch q.Policy {
+	case "", QuotaPolicyWait, QuotaPolicyDrop, QuotaPolicyReject:
+	default:
+		return errors.New("invalid quota policy " + q.Policy)
+	}
+	return nil
 }
 
 type TenantStorer interface {